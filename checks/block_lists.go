@@ -2,127 +2,208 @@ package checks
 
 import (
 	"context"
+	"fmt"
 	"net"
 	"slices"
 	"sort"
 	"sync"
 	"time"
 
+	"github.com/miekg/dns"
 	"github.com/xray-web/web-check-api/checks/clients/ip"
 )
 
-type dnsServer struct {
-	Name string
-	IP   string
+const (
+	BlockStatusBlocked  = "blocked"
+	BlockStatusAllowed  = "allowed"
+	BlockStatusNXDomain = "nxdomain"
+	BlockStatusTimeout  = "timeout"
+	BlockStatusRefused  = "refused"
+	BlockStatusError    = "error"
+)
+
+type Blocklist struct {
+	Server    string   `json:"server"`
+	ServerIP  string   `json:"serverIp"`
+	IsBlocked bool     `json:"isBlocked"`
+	Status    string   `json:"status"`
+	Addresses []string `json:"addresses"`
+	Error     string   `json:"error,omitempty"`
+	Latency   int64    `json:"latencyMs"`
 }
 
-var DNS_SERVERS = []dnsServer{
-	{Name: "AdGuard", IP: "176.103.130.130"},
-	{Name: "AdGuard Family", IP: "176.103.130.132"},
-	{Name: "CleanBrowsing Adult", IP: "185.228.168.10"},
-	{Name: "CleanBrowsing Family", IP: "185.228.168.168"},
-	{Name: "CleanBrowsing Security", IP: "185.228.168.9"},
-	{Name: "CloudFlare", IP: "1.1.1.1"},
-	{Name: "CloudFlare Family", IP: "1.1.1.3"},
-	{Name: "Comodo Secure", IP: "8.26.56.26"},
-	{Name: "Google DNS", IP: "8.8.8.8"},
-	{Name: "Neustar Family", IP: "156.154.70.3"},
-	{Name: "Neustar Protection", IP: "156.154.70.2"},
-	{Name: "Norton Family", IP: "199.85.126.20"},
-	{Name: "OpenDNS", IP: "208.67.222.222"},
-	{Name: "OpenDNS Family", IP: "208.67.222.123"},
-	{Name: "Quad9", IP: "9.9.9.9"},
-	{Name: "Yandex Family", IP: "77.88.8.7"},
-	{Name: "Yandex Safe", IP: "77.88.8.88"},
+type BlockList struct {
+	catalogue CatalogueSource
+	baseline  ip.Exchanger
+	exchanger func(server DNSServer) ip.Exchanger
 }
 
-var knownBlockIPs = []string{
-	"146.112.61.106",
-	"185.228.168.10",
-	"8.26.56.26",
-	"9.9.9.9",
-	"208.69.38.170",
-	"208.69.39.170",
-	"208.67.222.222",
-	"208.67.222.123",
-	"199.85.126.10",
-	"199.85.126.20",
-	"156.154.70.22",
-	"77.88.8.7",
-	"77.88.8.8",
-	"::1",
-	"2a02:6b8::feed:0ff",
-	"2a02:6b8::feed:bad",
-	"2a02:6b8::feed:a11",
-	"2620:119:35::35",
-	"2620:119:53::53",
-	"2606:4700:4700::1111",
-	"2606:4700:4700::1001",
-	"2001:4860:4860::8888",
-	"2a0d:2a00:1::",
-	"2a0d:2a00:2::",
+// NewBlockList returns a check that queries each resolver in the catalogue
+// through exchanger. baseline should be an unfiltered resolver, it is used to tell
+// a resolver hiding the domain with NXDOMAIN from a domain that doesn't
+// exist.
+func NewBlockList(catalogue CatalogueSource, baseline ip.Exchanger, exchanger func(server DNSServer) ip.Exchanger) *BlockList {
+	return &BlockList{catalogue: catalogue, baseline: baseline, exchanger: exchanger}
 }
 
-type Blocklist struct {
-	Server    string `json:"server"`
-	ServerIP  string `json:"serverIp"`
-	IsBlocked bool   `json:"isBlocked"`
+// blockLookup is the outcome of a single query to a resolver.
+type blockLookup struct {
+	rcode int
+	addrs []net.IP
+	err   error
 }
 
-type BlockList struct {
-	lookup ip.DNSLookup
+func lookupBlock(ctx context.Context, exchanger ip.Exchanger, domain string, qtype uint16) blockLookup {
+	msg := new(dns.Msg)
+	msg.SetQuestion(dns.Fqdn(domain), qtype)
+
+	resp, err := exchanger.Exchange(ctx, msg)
+	if err != nil {
+		return blockLookup{err: err}
+	}
+	lookup := blockLookup{rcode: resp.Rcode}
+	for _, rr := range resp.Answer {
+		switch rr := rr.(type) {
+		case *dns.A:
+			lookup.addrs = append(lookup.addrs, rr.A)
+		case *dns.AAAA:
+			lookup.addrs = append(lookup.addrs, rr.AAAA)
+		}
+	}
+	return lookup
 }
 
-func NewBlockList(lookup ip.DNSLookup) *BlockList {
-	return &BlockList{lookup: lookup}
+func isBlockIP(blockIPs []string) func(net.IP) bool {
+	return func(addr net.IP) bool {
+		return addr.IsUnspecified() || slices.ContainsFunc(blockIPs, func(blockIP string) bool {
+			return addr.Equal(net.ParseIP(blockIP))
+		})
+	}
 }
 
-func (b *BlockList) domainBlocked(ctx context.Context, domain, serverIP string) bool {
-	ips, err := b.lookup.DNSLookupIP(ctx, "ip4", domain, serverIP)
-	if err != nil {
-		// if there's an error, consider it not blocked
-		// TODO: return more detailed errors for each server
-		return false
+// verdict decides the status of a resolver from its A and AAAA answers.
+// The AAAA answer is only used to look for the blockIPs sinkholes. exists
+// reports whether the baseline resolver found the domain.
+func verdict(a, aaaa blockLookup, exists bool, blockIPs []string) (status string, err error) {
+	for _, lookup := range []blockLookup{a, aaaa} {
+		if slices.ContainsFunc(lookup.addrs, isBlockIP(blockIPs)) {
+			return BlockStatusBlocked, nil
+		}
 	}
 
-	return slices.ContainsFunc(ips, func(ip net.IP) bool {
-		return slices.Contains(knownBlockIPs, ip.String())
-	})
+	switch {
+	case a.err != nil && ip.IsTimeout(a.err):
+		return BlockStatusTimeout, a.err
+	case a.err != nil:
+		return BlockStatusError, a.err
+	case a.rcode == dns.RcodeNameError && exists:
+		return BlockStatusNXDomain, nil
+	case a.rcode == dns.RcodeRefused:
+		return BlockStatusRefused, nil
+	case a.rcode != dns.RcodeSuccess && a.rcode != dns.RcodeNameError:
+		return BlockStatusError, fmt.Errorf("query failed: %s", dns.RcodeToString[a.rcode])
+	}
+	return BlockStatusAllowed, nil
 }
 
+// serverLookup holds the answers from one resolver.
+type serverLookup struct {
+	a, aaaa blockLookup
+	latency time.Duration
+}
+
+func (b *BlockList) lookupServer(ctx context.Context, domain string, server DNSServer) serverLookup {
+	exchanger := b.exchanger(server)
+	start := time.Now()
+
+	var lookup serverLookup
+	var wg sync.WaitGroup
+	wg.Add(2)
+	go func() {
+		defer wg.Done()
+		lookup.a = lookupBlock(ctx, exchanger, domain, dns.TypeA)
+	}()
+	go func() {
+		defer wg.Done()
+		lookup.aaaa = lookupBlock(ctx, exchanger, domain, dns.TypeAAAA)
+	}()
+	wg.Wait()
+
+	lookup.latency = time.Since(start)
+	return lookup
+}
+
+// BlockedServers queries every resolver in the catalogue for the A and
+// AAAA records of domain and reports whether each one blocks it.
 func (b *BlockList) BlockedServers(ctx context.Context, domain string) []Blocklist {
-	var lock sync.Mutex
+	catalogue := b.catalogue.Catalogue()
 	var wg sync.WaitGroup
 	limit := make(chan struct{}, 5)
 
-	var results []Blocklist
-
-	ctx, cancel := context.WithTimeout(ctx, 5*time.Second)
+	ctx, cancel := context.WithTimeout(ctx, 10*time.Second)
 	defer cancel()
 
-	for _, server := range DNS_SERVERS {
+	var exists bool
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		baseline := lookupBlock(ctx, b.baseline, domain, dns.TypeA)
+		exists = baseline.err == nil && baseline.rcode == dns.RcodeSuccess
+	}()
+
+	lookups := make([]serverLookup, len(catalogue.Resolvers))
+	for i, server := range catalogue.Resolvers {
 		wg.Add(1)
-		go func(server dnsServer) {
+		go func() {
 			limit <- struct{}{}
 			defer func() {
 				<-limit
 				wg.Done()
 			}()
-
-			isBlocked := b.domainBlocked(ctx, domain, server.IP)
-			lock.Lock()
-			defer lock.Unlock()
-			results = append(results, Blocklist{
-				Server:    server.Name,
-				ServerIP:  server.IP,
-				IsBlocked: isBlocked,
-			})
-		}(server)
+			lookups[i] = b.lookupServer(ctx, domain, server)
+		}()
 	}
 	wg.Wait()
 
+	results := make([]Blocklist, 0, len(catalogue.Resolvers))
+	for i, server := range catalogue.Resolvers {
+		lookup := lookups[i]
+		status, err := verdict(lookup.a, lookup.aaaa, exists, catalogue.BlockIPs)
+		result := Blocklist{
+			Server:    server.Name,
+			ServerIP:  server.IP,
+			IsBlocked: status == BlockStatusBlocked || status == BlockStatusNXDomain,
+			Status:    status,
+			Addresses: make([]string, 0),
+			Latency:   lookup.latency.Milliseconds(),
+		}
+		for _, addr := range append(lookup.a.addrs, lookup.aaaa.addrs...) {
+			result.Addresses = append(result.Addresses, addr.String())
+		}
+		if err != nil {
+			result.Error = err.Error()
+		}
+		results = append(results, result)
+	}
+
 	sort.Slice(results, func(i, j int) bool {
 		return results[i].Server < results[j].Server
 	})
 	return results
 }
+
+func (b *BlockList) Name() string {
+	return "block-lists"
+}
+
+func (b *BlockList) Description() string {
+	return "Checks whether the domain is blocked by popular privacy and security DNS resolvers"
+}
+
+func (b *BlockList) Input() Input {
+	return InputHostname
+}
+
+func (b *BlockList) Run(ctx context.Context, target Target) (any, error) {
+	return b.BlockedServers(ctx, target.Hostname()), nil
+}
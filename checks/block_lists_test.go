@@ -2,23 +2,93 @@ package checks
 
 import (
 	"context"
-	"net"
+	"os"
 	"testing"
 
+	"github.com/miekg/dns"
 	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
 	"github.com/xray-web/web-check-api/checks/clients/ip"
 )
 
 func TestBlockList(t *testing.T) {
 	t.Parallel()
 
-	t.Run("blocked IP", func(t *testing.T) {
+	allowed := zone(t,
+		"example.com. 300 IN A 93.184.216.34",
+		"example.com. 300 IN AAAA 2606:2800:220:1::",
+	)
+	rcode := func(rcode int) ip.ExchangeFunc {
+		return func(ctx context.Context, msg *dns.Msg) (*dns.Msg, error) {
+			resp := new(dns.Msg)
+			resp.SetRcode(msg, rcode)
+			return resp, nil
+		}
+	}
+	servers := map[string]ip.Exchanger{
+		// AdGuard
+		"176.103.130.130": zone(t, "example.com. 300 IN A 0.0.0.0"),
+		// CleanBrowsing Adult
+		"185.228.168.10": zone(t,
+			"example.com. 300 IN A 93.184.216.34",
+			"example.com. 300 IN AAAA 2a0d:2a00:1::",
+		),
+		// CloudFlare Family
+		"1.1.1.3": zone(t),
+		// Comodo Secure
+		"8.26.56.26": rcode(dns.RcodeRefused),
+		// Google DNS
+		"8.8.8.8": rcode(dns.RcodeServerFailure),
+		// Quad9
+		"9.9.9.9": ip.ExchangeFunc(func(ctx context.Context, msg *dns.Msg) (*dns.Msg, error) {
+			return nil, os.ErrDeadlineExceeded
+		}),
+	}
+	exchanger := func(server DNSServer) ip.Exchanger {
+		if e, ok := servers[server.IP]; ok {
+			return e
+		}
+		return allowed
+	}
+
+	list := NewBlockList(DefaultCatalogue(), allowed, exchanger).BlockedServers(context.Background(), "example.com")
+	require.Len(t, list, len(DefaultCatalogue().Resolvers))
+
+	results := make(map[string]Blocklist)
+	for _, r := range list {
+		results[r.Server] = r
+	}
+
+	tests := []struct {
+		server    string
+		status    string
+		isBlocked bool
+		err       string
+	}{
+		{server: "AdGuard", status: BlockStatusBlocked, isBlocked: true},
+		{server: "CleanBrowsing Adult", status: BlockStatusBlocked, isBlocked: true},
+		{server: "CloudFlare Family", status: BlockStatusNXDomain, isBlocked: true},
+		{server: "Comodo Secure", status: BlockStatusRefused},
+		{server: "Google DNS", status: BlockStatusError, err: "query failed: SERVFAIL"},
+		{server: "Quad9", status: BlockStatusTimeout, err: "i/o timeout"},
+		{server: "OpenDNS", status: BlockStatusAllowed},
+	}
+	for _, tc := range tests {
+		r := results[tc.server]
+		assert.Equal(t, tc.status, r.Status, tc.server)
+		assert.Equal(t, tc.isBlocked, r.IsBlocked, tc.server)
+		assert.Equal(t, tc.err, r.Error, tc.server)
+	}
+	assert.Equal(t, []string{"93.184.216.34", "2606:2800:220:1::"}, results["OpenDNS"].Addresses)
+
+	t.Run("missing domain", func(t *testing.T) {
 		t.Parallel()
+		list := NewBlockList(DefaultCatalogue(), zone(t), func(server DNSServer) ip.Exchanger {
+			return zone(t)
+		}).BlockedServers(context.Background(), "example.com")
 
-		dnsLookup := ip.DNSLookupFunc(func(ctx context.Context, network, host, dns string) ([]net.IP, error) {
-			return []net.IP{net.ParseIP("146.112.61.106")}, nil
-		})
-		list := NewBlockList(dnsLookup).BlockedServers(context.Background(), "example.com")
-		assert.Contains(t, list, Blocklist{Server: "AdGuard", ServerIP: "176.103.130.130", IsBlocked: true})
+		for _, r := range list {
+			assert.Equal(t, BlockStatusAllowed, r.Status, r.Server)
+		}
 	})
 }
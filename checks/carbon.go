@@ -89,3 +89,35 @@ func (c *Carbon) CarbonData(ctx context.Context, sizeInBytes int) (*CarbonData,
 
 	return &carbonData, nil
 }
+
+func (c *Carbon) Name() string {
+	return "carbon"
+}
+
+func (c *Carbon) Description() string {
+	return "Estimates the carbon footprint of loading the page"
+}
+
+func (c *Carbon) Input() Input {
+	return InputURL
+}
+
+func (c *Carbon) Run(ctx context.Context, target Target) (any, error) {
+	url := target.String()
+	sizeInBytes, err := c.HtmlSize(ctx, url)
+	if err != nil {
+		return nil, fmt.Errorf("error getting HTML size: %v", err)
+	}
+
+	carbonData, err := c.CarbonData(ctx, sizeInBytes)
+	if err != nil {
+		return nil, fmt.Errorf("error getting carbon data: %v", err)
+	}
+
+	if carbonData.Statistics.AdjustedBytes == 0 || carbonData.Statistics.Energy == 0 {
+		return Skipped{Skipped: "Not enough info to get carbon data"}, nil
+	}
+
+	carbonData.ScanUrl = url
+	return carbonData, nil
+}
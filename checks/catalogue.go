@@ -0,0 +1,296 @@
+package checks
+
+import (
+	"crypto/tls"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"sync/atomic"
+	"time"
+
+	"github.com/miekg/dns"
+	"github.com/xray-web/web-check-api/checks/clients/ip"
+	"gopkg.in/yaml.v3"
+)
+
+// Transports a DNSServer can be queried over.
+const (
+	TransportUDP      = "udp"
+	TransportTLS      = "tls"
+	TransportHTTPS    = "https"
+	TransportHTTPSGet = "https-get"
+)
+
+// DNSServer is a public resolver queried by the block-lists and
+// dns-propagation checks.
+type DNSServer struct {
+	Name string `json:"name" yaml:"name"`
+	IP   string `json:"ip" yaml:"ip"`
+	// Transport defaults to udp, which falls back to TCP for large
+	// responses. The https transports send queries to URL with POST or GET.
+	Transport string `json:"transport,omitempty" yaml:"transport,omitempty"`
+	URL       string `json:"url,omitempty" yaml:"url,omitempty"`
+	// ServerName is the name the certificate of a tls server is verified
+	// against, if empty the certificate must be issued for IP.
+	ServerName string `json:"serverName,omitempty" yaml:"serverName,omitempty"`
+}
+
+// newServerClient returns a client for server over its transport, client
+// is used for DNS over HTTPS.
+func newServerClient(server DNSServer, client *http.Client, timeout time.Duration) ip.Exchanger {
+	switch server.Transport {
+	case TransportTLS:
+		dot := ip.NewDoTClient(server.IP, timeout)
+		if server.ServerName != "" {
+			dot.TLSConfig = &tls.Config{ServerName: server.ServerName}
+		}
+		return dot
+	case TransportHTTPS:
+		return ip.NewDoHClient(server.URL, http.MethodPost, client)
+	case TransportHTTPSGet:
+		return ip.NewDoHClient(server.URL, http.MethodGet, client)
+	}
+	return ip.NewDNSClient(server.IP, timeout)
+}
+
+// Catalogue holds the lists of resolvers, sinkhole addresses and ports the
+// checks use. A catalogue must not be modified once it is in use.
+type Catalogue struct {
+	Resolvers []DNSServer `json:"resolvers" yaml:"resolvers"`
+	// BlockIPs are the addresses resolvers answer with when they block a
+	// domain.
+	BlockIPs []string `json:"blockIps" yaml:"blockIps"`
+	Ports    []int    `json:"ports" yaml:"ports"`
+	// Subdomains is the wordlist the subdomains check tries under the
+	// domain.
+	Subdomains []string `json:"subdomains" yaml:"subdomains"`
+}
+
+// DefaultCatalogue returns the built in catalogue.
+func DefaultCatalogue() *Catalogue {
+	return &Catalogue{
+		Resolvers: []DNSServer{
+			{Name: "AdGuard", IP: "176.103.130.130"},
+			{Name: "AdGuard Family", IP: "176.103.130.132"},
+			{Name: "CleanBrowsing Adult", IP: "185.228.168.10"},
+			{Name: "CleanBrowsing Family", IP: "185.228.168.168"},
+			{Name: "CleanBrowsing Security", IP: "185.228.168.9"},
+			{Name: "CloudFlare", IP: "1.1.1.1"},
+			{Name: "CloudFlare Family", IP: "1.1.1.3"},
+			{Name: "Comodo Secure", IP: "8.26.56.26"},
+			{Name: "Google DNS", IP: "8.8.8.8"},
+			{Name: "Neustar Family", IP: "156.154.70.3"},
+			{Name: "Neustar Protection", IP: "156.154.70.2"},
+			{Name: "Norton Family", IP: "199.85.126.20"},
+			{Name: "OpenDNS", IP: "208.67.222.222"},
+			{Name: "OpenDNS Family", IP: "208.67.222.123"},
+			{Name: "Quad9", IP: "9.9.9.9"},
+			{Name: "Yandex Family", IP: "77.88.8.7"},
+			{Name: "Yandex Safe", IP: "77.88.8.88"},
+		},
+		BlockIPs: []string{
+			"146.112.61.106",
+			"185.228.168.10",
+			"8.26.56.26",
+			"9.9.9.9",
+			"208.69.38.170",
+			"208.69.39.170",
+			"208.67.222.222",
+			"208.67.222.123",
+			"199.85.126.10",
+			"199.85.126.20",
+			"156.154.70.22",
+			"77.88.8.7",
+			"77.88.8.8",
+			"::1",
+			"2a02:6b8::feed:0ff",
+			"2a02:6b8::feed:bad",
+			"2a02:6b8::feed:a11",
+			"2620:119:35::35",
+			"2620:119:53::53",
+			"2606:4700:4700::1111",
+			"2606:4700:4700::1001",
+			"2001:4860:4860::8888",
+			"2a0d:2a00:1::",
+			"2a0d:2a00:2::",
+		},
+		Ports: []int{
+			20, 21, 22, 23, 25, 53, 80, 67, 68, 69,
+			110, 119, 123, 143, 156, 161, 162, 179, 194,
+			389, 443, 587, 993, 995,
+			3000, 3306, 3389, 5060, 5900, 8000, 8080, 8888,
+		},
+		Subdomains: []string{
+			"admin", "api", "app", "auth", "beta", "blog", "cdn", "cloud",
+			"cpanel", "dashboard", "db", "demo", "dev", "docs", "ftp", "git",
+			"gitlab", "imap", "intranet", "jenkins", "m", "mail", "mx", "my",
+			"ns1", "ns2", "portal", "pop", "remote", "shop", "smtp", "sso",
+			"stage", "staging", "static", "status", "support", "test", "vpn",
+			"webmail", "www",
+		},
+	}
+}
+
+// LoadCatalogue reads a YAML or JSON catalogue from path, JSON is used if
+// the file has a .json extension. Any list left out of the file keeps its
+// built in value.
+func LoadCatalogue(path string) (*Catalogue, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("reading catalogue: %w", err)
+	}
+
+	var file Catalogue
+	if filepath.Ext(path) == ".json" {
+		err = json.Unmarshal(data, &file)
+	} else {
+		err = yaml.Unmarshal(data, &file)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("parsing catalogue %s: %w", path, err)
+	}
+
+	c := DefaultCatalogue()
+	if file.Resolvers != nil {
+		c.Resolvers = file.Resolvers
+	}
+	if file.BlockIPs != nil {
+		c.BlockIPs = file.BlockIPs
+	}
+	if file.Ports != nil {
+		c.Ports = file.Ports
+	}
+	if file.Subdomains != nil {
+		c.Subdomains = file.Subdomains
+	}
+	if err := c.Validate(); err != nil {
+		return nil, fmt.Errorf("invalid catalogue %s: %w", path, err)
+	}
+	return c, nil
+}
+
+// Validate checks every entry in the catalogue, reporting all the problems
+// found.
+func (c *Catalogue) Validate() error {
+	var errs []error
+	if len(c.Resolvers) == 0 {
+		errs = append(errs, errors.New("no resolvers"))
+	}
+	names := make(map[string]bool)
+	for i, r := range c.Resolvers {
+		if r.Name == "" {
+			errs = append(errs, fmt.Errorf("resolver %d: missing name", i))
+		} else if names[r.Name] {
+			errs = append(errs, fmt.Errorf("resolver %q: duplicate name", r.Name))
+		}
+		names[r.Name] = true
+		if net.ParseIP(r.IP) == nil {
+			errs = append(errs, fmt.Errorf("resolver %q: invalid IP address %q", r.Name, r.IP))
+		}
+		switch r.Transport {
+		case "", TransportUDP:
+		case TransportTLS:
+			if _, ok := dns.IsDomainName(r.ServerName); r.ServerName != "" && !ok {
+				errs = append(errs, fmt.Errorf("resolver %q: invalid server name %q", r.Name, r.ServerName))
+			}
+		case TransportHTTPS, TransportHTTPSGet:
+			if u, err := url.Parse(r.URL); err != nil || u.Scheme != "https" || u.Host == "" {
+				errs = append(errs, fmt.Errorf("resolver %q: invalid DoH URL %q", r.Name, r.URL))
+			}
+		default:
+			errs = append(errs, fmt.Errorf("resolver %q: unknown transport %q", r.Name, r.Transport))
+		}
+	}
+	for _, addr := range c.BlockIPs {
+		if net.ParseIP(addr) == nil {
+			errs = append(errs, fmt.Errorf("block IP %q: invalid IP address", addr))
+		}
+	}
+	if len(c.Ports) == 0 {
+		errs = append(errs, errors.New("no ports"))
+	} else if len(c.Ports) > maxPorts {
+		// the ports check scans them all when no ports are asked for
+		errs = append(errs, fmt.Errorf("more than %d ports", maxPorts))
+	}
+	for i, port := range c.Ports {
+		if port < 1 || port > 65535 {
+			errs = append(errs, fmt.Errorf("port %d: out of range", port))
+		} else if slices.Contains(c.Ports[:i], port) {
+			errs = append(errs, fmt.Errorf("port %d: duplicate", port))
+		}
+	}
+	for _, word := range c.Subdomains {
+		if _, ok := dns.IsDomainName(word); !ok || word == "" || strings.HasSuffix(word, ".") {
+			errs = append(errs, fmt.Errorf("subdomain %q: invalid name", word))
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// CatalogueSource provides the catalogue in use when a check runs.
+type CatalogueSource interface {
+	Catalogue() *Catalogue
+}
+
+// Catalogue returns c, so a fixed catalogue can be used as a source.
+func (c *Catalogue) Catalogue() *Catalogue {
+	return c
+}
+
+// CatalogueStore is a CatalogueSource backed by a file that can be reloaded
+// while the checks are running.
+type CatalogueStore struct {
+	path    string
+	current atomic.Pointer[loadedCatalogue]
+}
+
+type loadedCatalogue struct {
+	catalogue *Catalogue
+	loadedAt  time.Time
+}
+
+// NewCatalogueStore loads the catalogue at path, or uses the built in one
+// if path is empty.
+func NewCatalogueStore(path string) (*CatalogueStore, error) {
+	s := &CatalogueStore{path: path}
+	if err := s.Reload(); err != nil {
+		return nil, err
+	}
+	return s, nil
+}
+
+func (s *CatalogueStore) Catalogue() *Catalogue {
+	return s.current.Load().catalogue
+}
+
+// Path is the file the catalogue is loaded from, empty for the built in
+// catalogue.
+func (s *CatalogueStore) Path() string {
+	return s.path
+}
+
+// LoadedAt is when the current catalogue was loaded.
+func (s *CatalogueStore) LoadedAt() time.Time {
+	return s.current.Load().loadedAt
+}
+
+// Reload reads the catalogue file again. If it is invalid the current
+// catalogue is kept.
+func (s *CatalogueStore) Reload() error {
+	c := DefaultCatalogue()
+	if s.path != "" {
+		var err error
+		if c, err = LoadCatalogue(s.path); err != nil {
+			return err
+		}
+	}
+	s.current.Store(&loadedCatalogue{catalogue: c, loadedAt: time.Now()})
+	return nil
+}
@@ -0,0 +1,151 @@
+package checks
+
+import (
+	"crypto/tls"
+	"encoding/json"
+	"net/http"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/xray-web/web-check-api/checks/clients/ip"
+)
+
+func writeCatalogue(t *testing.T, name, data string) string {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), name)
+	require.NoError(t, os.WriteFile(path, []byte(data), 0o600))
+	return path
+}
+
+func TestLoadCatalogue(t *testing.T) {
+	t.Parallel()
+
+	t.Run("yaml", func(t *testing.T) {
+		t.Parallel()
+		path := writeCatalogue(t, "catalogue.yaml", `
+resolvers:
+  - name: Internal
+    ip: 10.0.0.53
+blockIps:
+  - 10.0.0.1
+`)
+		c, err := LoadCatalogue(path)
+		require.NoError(t, err)
+		assert.Equal(t, []DNSServer{{Name: "Internal", IP: "10.0.0.53"}}, c.Resolvers)
+		assert.Equal(t, []string{"10.0.0.1"}, c.BlockIPs)
+		// lists left out of the file keep their defaults
+		assert.Equal(t, DefaultCatalogue().Ports, c.Ports)
+	})
+
+	t.Run("json", func(t *testing.T) {
+		t.Parallel()
+		path := writeCatalogue(t, "catalogue.json", `{"ports": [22, 443]}`)
+		c, err := LoadCatalogue(path)
+		require.NoError(t, err)
+		assert.Equal(t, []int{22, 443}, c.Ports)
+		assert.Equal(t, DefaultCatalogue().Resolvers, c.Resolvers)
+	})
+
+	t.Run("invalid", func(t *testing.T) {
+		t.Parallel()
+		path := writeCatalogue(t, "catalogue.yaml", `
+resolvers:
+  - name: Internal
+    ip: resolver.internal
+  - name: Internal
+    ip: 10.0.0.53
+  - name: Encrypted
+    ip: 10.0.0.54
+    transport: https
+    url: http://10.0.0.54/dns-query
+  - name: QUIC
+    ip: 10.0.0.55
+    transport: quic
+  - name: Private
+    ip: 10.0.0.56
+    transport: tls
+    serverName: dns..internal
+blockIps: [not-an-ip]
+ports: [22, 22, 70000]
+subdomains: [www, "bad word."]
+`)
+		_, err := LoadCatalogue(path)
+		require.Error(t, err)
+		assert.ErrorContains(t, err, `resolver "Internal": invalid IP address "resolver.internal"`)
+		assert.ErrorContains(t, err, `resolver "Internal": duplicate name`)
+		assert.ErrorContains(t, err, `block IP "not-an-ip": invalid IP address`)
+		assert.ErrorContains(t, err, `resolver "Encrypted": invalid DoH URL "http://10.0.0.54/dns-query"`)
+		assert.ErrorContains(t, err, `resolver "QUIC": unknown transport "quic"`)
+		assert.ErrorContains(t, err, `resolver "Private": invalid server name "dns..internal"`)
+		assert.ErrorContains(t, err, "port 22: duplicate")
+		assert.ErrorContains(t, err, "port 70000: out of range")
+		assert.ErrorContains(t, err, `subdomain "bad word.": invalid name`)
+	})
+
+	t.Run("too many ports", func(t *testing.T) {
+		t.Parallel()
+		ports := make([]int, maxPorts+1)
+		for i := range ports {
+			ports[i] = i + 1
+		}
+		data, err := json.Marshal(map[string][]int{"ports": ports})
+		require.NoError(t, err)
+		_, err = LoadCatalogue(writeCatalogue(t, "catalogue.json", string(data)))
+		assert.ErrorContains(t, err, ": more than 1024 ports")
+	})
+
+	t.Run("missing file", func(t *testing.T) {
+		t.Parallel()
+		_, err := LoadCatalogue(filepath.Join(t.TempDir(), "missing.yaml"))
+		assert.ErrorIs(t, err, os.ErrNotExist)
+	})
+}
+
+func TestCatalogueStore(t *testing.T) {
+	t.Parallel()
+
+	t.Run("built in", func(t *testing.T) {
+		t.Parallel()
+		s, err := NewCatalogueStore("")
+		require.NoError(t, err)
+		assert.Equal(t, DefaultCatalogue(), s.Catalogue())
+		assert.NoError(t, DefaultCatalogue().Validate())
+	})
+
+	t.Run("reload", func(t *testing.T) {
+		t.Parallel()
+		path := writeCatalogue(t, "catalogue.yaml", "ports: [22]")
+		s, err := NewCatalogueStore(path)
+		require.NoError(t, err)
+		assert.Equal(t, []int{22}, s.Catalogue().Ports)
+
+		require.NoError(t, os.WriteFile(path, []byte("ports: [22, 443]"), 0o600))
+		require.NoError(t, s.Reload())
+		assert.Equal(t, []int{22, 443}, s.Catalogue().Ports)
+
+		// an invalid file keeps the current catalogue
+		require.NoError(t, os.WriteFile(path, []byte("ports: [0]"), 0o600))
+		assert.Error(t, s.Reload())
+		assert.Equal(t, []int{22, 443}, s.Catalogue().Ports)
+	})
+}
+
+func TestNewServerClient(t *testing.T) {
+	t.Parallel()
+
+	client := &http.Client{}
+	assert.Equal(t, ip.NewDNSClient("1.1.1.1", time.Second), newServerClient(DNSServer{IP: "1.1.1.1"}, client, time.Second))
+	assert.Equal(t, ip.NewDoTClient("1.1.1.1", time.Second), newServerClient(DNSServer{IP: "1.1.1.1", Transport: TransportTLS}, client, time.Second))
+	dot := ip.NewDoTClient("1.1.1.1", time.Second)
+	dot.TLSConfig = &tls.Config{ServerName: "one.one.one.one"}
+	assert.Equal(t, dot, newServerClient(DNSServer{IP: "1.1.1.1", Transport: TransportTLS, ServerName: "one.one.one.one"}, client, time.Second))
+	assert.Equal(t, ip.NewDoHClient("https://1.1.1.1/dns-query", http.MethodGet, client), newServerClient(DNSServer{
+		IP:        "1.1.1.1",
+		Transport: TransportHTTPSGet,
+		URL:       "https://1.1.1.1/dns-query",
+	}, client, time.Second))
+}
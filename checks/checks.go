@@ -1,38 +1,164 @@
 package checks
 
 import (
+	"log"
+	"net"
 	"net/http"
 	"time"
 
+	"github.com/xray-web/web-check-api/checks/clients/browser"
 	"github.com/xray-web/web-check-api/checks/clients/ip"
+	"github.com/xray-web/web-check-api/checks/clients/traceroute"
+	"github.com/xray-web/web-check-api/checks/clients/whois"
+	"github.com/xray-web/web-check-api/checks/store/ipintel"
 	"github.com/xray-web/web-check-api/checks/store/legacyrank"
+	"github.com/xray-web/web-check-api/checks/store/passivedns"
+	"github.com/xray-web/web-check-api/config"
 )
 
+// Skipped is returned by checks that ran but had nothing to report.
+type Skipped struct {
+	Skipped string `json:"skipped"`
+}
+
 type Checks struct {
-	BlockList   *BlockList
-	Carbon      *Carbon
-	Headers     *Headers
-	IpAddress   *NetIp
-	LegacyRank  *LegacyRank
-	LinkedPages *LinkedPages
-	Rank        *Rank
-	SocialTags  *SocialTags
-	Tls         *Tls
+	BlockList      *BlockList
+	Carbon         *Carbon
+	Cookies        *Cookies
+	Dns            *Dns
+	DnsPropagation *DnsPropagation
+	DnsSec         *DnsSec
+	DnsServer      *DnsServer
+	DualStack      *DualStack
+	Firewall       *Firewall
+	Headers        *Headers
+	Hsts           *Hsts
+	HttpSecurity   *HttpSecurity
+	IpAddress      *NetIp
+	LegacyRank     *LegacyRank
+	LinkedPages    *LinkedPages
+	MailSecurity   *MailSecurity
+	Nameservers    *Nameservers
+	Ports          *Ports
+	Quality        *Quality
+	Rank           *Rank
+	Redirects      *Redirects
+	ReverseIp      *ReverseIp
+	SocialTags     *SocialTags
+	Subdomains     *Subdomains
+	Tls            *Tls
+	TlsCiphers     *TlsCiphers
+	TraceRoute     *TraceRoute
+	Whois          *Whois
 }
 
-func NewChecks() *Checks {
+// NewChecks creates the built in checks configured by conf. Checks that use
+// the resolver, block IP or port lists read them from catalogue each time
+// they run.
+func NewChecks(conf config.Config, catalogue CatalogueSource) *Checks {
 	client := &http.Client{
 		Timeout: 5 * time.Second,
 	}
+	resolver := ip.NewResolverClient(conf.DNSResolver, 2*time.Second)
+	dohClient := &http.Client{Timeout: 2 * time.Second}
+	dnsClient := func(server DNSServer) ip.Exchanger {
+		return newServerClient(server, dohClient, 2*time.Second)
+	}
+	netIp := NewNetIp(&ip.NetLookup{}, resolver, loadIPIntel(conf))
 	return &Checks{
-		BlockList:   NewBlockList(&ip.NetDNSLookup{}),
-		Carbon:      NewCarbon(client),
-		Headers:     NewHeaders(client),
-		IpAddress:   NewNetIp(&ip.NetLookup{}),
-		LegacyRank:  NewLegacyRank(legacyrank.NewInMemoryStore()),
-		LinkedPages: NewLinkedPages(client),
-		Rank:        NewRank(client),
-		SocialTags:  NewSocialTags(client),
-		Tls:         NewTls(client),
+		BlockList:      NewBlockList(catalogue, resolver, dnsClient),
+		Carbon:         NewCarbon(client),
+		Cookies:        NewCookies(client, &browser.Chrome{}),
+		Dns:            NewDns(resolver),
+		DnsPropagation: NewDnsPropagation(catalogue, resolver, dnsClient),
+		DnsSec:         NewDnsSec(resolver, nil),
+		DnsServer:      NewDnsServer(resolver, &net.Dialer{}, nil),
+		DualStack:      NewDualStack(&ip.NetLookup{}, &net.Dialer{Timeout: 5 * time.Second}),
+		Firewall:       NewFirewall(client),
+		Headers:        NewHeaders(client),
+		Hsts:           NewHsts(client),
+		HttpSecurity:   NewHttpSecurity(client),
+		IpAddress:      netIp,
+		LegacyRank:     NewLegacyRank(legacyrank.NewInMemoryStore()),
+		LinkedPages:    NewLinkedPages(client),
+		MailSecurity:   NewMailSecurity(resolver, client, conf.DKIMSelectors),
+		Nameservers:    NewNameservers(resolver, dnsClient, &net.Dialer{}),
+		Ports:          NewPorts(catalogue, &ip.NetLookup{}, &net.Dialer{}, &net.ListenConfig{}),
+		Quality:        NewQuality(&http.Client{Timeout: 60 * time.Second}, conf.GoogleCloudAPIKey),
+		Rank:           NewRank(client),
+		Redirects:      NewRedirects(client),
+		ReverseIp:      NewReverseIp(netIp, resolver, loadPassiveDNS(conf.PassiveDNSFiles)...),
+		SocialTags:     NewSocialTags(client),
+		Subdomains:     NewSubdomains(catalogue, &http.Client{Timeout: 20 * time.Second}, resolver, &net.Dialer{Timeout: 5 * time.Second}),
+		Tls:            NewTls(&net.Dialer{Timeout: 10 * time.Second}, client, nil),
+		TlsCiphers:     NewTlsCiphers(&net.Dialer{Timeout: conf.TLSCiphersTimeout}, conf.TLSCiphersConcurrency, conf.TLSCiphersTimeout),
+		TraceRoute:     NewTraceRoute(netIp, traceroute.NewProber),
+		Whois: NewWhois(
+			whois.NewRDAPClient(client, whois.NewBootstrap(client, conf.RDAPBootstrapCache, 24*time.Hour)),
+			whois.NewClient(&net.Dialer{Timeout: 5 * time.Second}, 10*time.Second),
+		),
+	}
+}
+
+// loadIPIntel opens the address databases named in conf, a missing or
+// broken file only leaves its fields out of the results.
+func loadIPIntel(conf config.Config) *ipintel.Store {
+	intel, err := ipintel.Load(ipintel.Config{
+		CityDB: conf.GeoIPCityDB,
+		ASNDB:  conf.GeoIPASNDB,
+		Ranges: conf.IPRanges,
+	})
+	if err != nil {
+		log.Println(err)
+	}
+	return intel
+}
+
+// loadPassiveDNS opens the passive DNS files at paths, skipping any that
+// can't be read.
+func loadPassiveDNS(paths []string) []passivedns.Provider {
+	var providers []passivedns.Provider
+	for _, path := range paths {
+		file, err := passivedns.LoadFile(path)
+		if err != nil {
+			log.Println(err)
+			continue
+		}
+		providers = append(providers, file)
+	}
+	return providers
+}
+
+// All returns every check in c.
+func (c *Checks) All() []Check {
+	return []Check{
+		c.BlockList,
+		c.Carbon,
+		c.Cookies,
+		c.Dns,
+		c.DnsPropagation,
+		c.DnsSec,
+		c.DnsServer,
+		c.DualStack,
+		c.Firewall,
+		c.Headers,
+		c.Hsts,
+		c.HttpSecurity,
+		c.IpAddress,
+		c.LegacyRank,
+		c.LinkedPages,
+		c.MailSecurity,
+		c.Nameservers,
+		c.Ports,
+		c.Quality,
+		c.Rank,
+		c.Redirects,
+		c.ReverseIp,
+		c.SocialTags,
+		c.Subdomains,
+		c.Tls,
+		c.TlsCiphers,
+		c.TraceRoute,
+		c.Whois,
 	}
 }
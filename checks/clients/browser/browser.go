@@ -0,0 +1,78 @@
+package browser
+
+import (
+	"context"
+	"time"
+
+	"github.com/chromedp/cdproto/network"
+	"github.com/chromedp/chromedp"
+)
+
+type Cookies interface {
+	GetCookies(ctx context.Context, url string) ([]map[string]any, error)
+}
+
+type CookiesFunc func(ctx context.Context, url string) ([]map[string]any, error)
+
+func (fn CookiesFunc) GetCookies(ctx context.Context, url string) ([]map[string]any, error) {
+	return fn(ctx, url)
+}
+
+// Chrome loads pages in a headless Chrome instance using chromedp.
+type Chrome struct{}
+
+func (c *Chrome) GetCookies(ctx context.Context, url string) ([]map[string]any, error) {
+	opts := append(chromedp.DefaultExecAllocatorOptions[:],
+		chromedp.Flag("headless", true),
+		chromedp.Flag("no-sandbox", true),
+		chromedp.Flag("disable-setuid-sandbox", true),
+	)
+
+	allocCtx, cancel := chromedp.NewExecAllocator(ctx, opts...)
+	defer cancel()
+
+	ctx, cancel = chromedp.NewContext(allocCtx)
+	defer cancel()
+
+	// Create a timeout context for chromedp actions
+	timeoutCtx, cancel := context.WithTimeout(ctx, 10*time.Second) // Increased timeout
+	defer cancel()
+
+	var cookies []*network.Cookie
+	err := chromedp.Run(timeoutCtx,
+		chromedp.Navigate(url),
+		chromedp.WaitReady("body", chromedp.ByQuery),
+		chromedp.ActionFunc(func(ctx context.Context) error {
+			cookieParams := network.GetCookies().WithUrls([]string{url})
+			var err error
+			cookies, err = cookieParams.Do(ctx)
+			return err
+		}),
+	)
+
+	if err != nil {
+		return nil, err
+	}
+
+	var cookiesList []map[string]any
+	for _, c := range cookies {
+		cookie := map[string]any{
+			"name":    c.Name,
+			"value":   c.Value,
+			"domain":  c.Domain,
+			"path":    c.Path,
+			"expires": c.Expires,
+			// "size":         cdp.CookieSize(c),
+			"httpOnly": c.HTTPOnly,
+			"secure":   c.Secure,
+			"session":  c.Session,
+			"sameSite": c.SameSite.String(),
+			"priority": c.Priority.String(),
+			// "sameParty":    c.SameParty,
+			"sourceScheme": c.SourceScheme.String(),
+		}
+		cookiesList = append(cookiesList, cookie)
+	}
+
+	return cookiesList, nil
+}
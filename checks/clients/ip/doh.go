@@ -0,0 +1,95 @@
+package ip
+
+import (
+	"bytes"
+	"context"
+	"encoding/base64"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"net/url"
+
+	"github.com/miekg/dns"
+)
+
+const dnsMessageType = "application/dns-message"
+
+// DoHClient sends queries to a DNS over HTTPS server using the RFC 8484
+// wire format.
+type DoHClient struct {
+	URL string
+	// Method is http.MethodGet or http.MethodPost, POST is used if empty.
+	Method string
+	Client *http.Client
+}
+
+func NewDoHClient(endpoint, method string, client *http.Client) *DoHClient {
+	return &DoHClient{URL: endpoint, Method: method, Client: client}
+}
+
+func (c *DoHClient) Exchange(ctx context.Context, msg *dns.Msg) (*dns.Msg, error) {
+	// the ID is always zero so responses can be cached, RFC 8484 section 4.1
+	query := msg.Copy()
+	query.Id = 0
+	packed, err := query.Pack()
+	if err != nil {
+		return nil, err
+	}
+
+	var req *http.Request
+	if c.Method == http.MethodGet {
+		// the URL may already have a query string
+		u, err := url.Parse(c.URL)
+		if err != nil {
+			return nil, fmt.Errorf("invalid DoH URL %q: %w", c.URL, err)
+		}
+		params := u.Query()
+		params.Set("dns", base64.RawURLEncoding.EncodeToString(packed))
+		u.RawQuery = params.Encode()
+		req, err = http.NewRequestWithContext(ctx, http.MethodGet, u.String(), nil)
+		if err != nil {
+			return nil, err
+		}
+	} else {
+		req, err = http.NewRequestWithContext(ctx, http.MethodPost, c.URL, bytes.NewReader(packed))
+		if req != nil {
+			req.Header.Set("Content-Type", dnsMessageType)
+		}
+	}
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Accept", dnsMessageType)
+
+	resp, err := c.Client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("DoH request failed with status %d", resp.StatusCode)
+	}
+	body, err := io.ReadAll(io.LimitReader(resp.Body, dns.MaxMsgSize))
+	if err != nil {
+		return nil, err
+	}
+
+	answer := new(dns.Msg)
+	if err := answer.Unpack(body); err != nil {
+		return nil, fmt.Errorf("invalid DoH response: %w", err)
+	}
+	answer.Id = msg.Id
+	return answer, nil
+}
+
+// DoHLookup looks up IP addresses with the DNS over HTTPS server at the
+// URL passed as dns.
+type DoHLookup struct {
+	Method string
+	Client *http.Client
+}
+
+func (l *DoHLookup) DNSLookupIP(ctx context.Context, network, host, dns string) ([]net.IP, error) {
+	return ExchangeLookup{NewDoHClient(dns, l.Method, l.Client)}.LookupIP(ctx, network, host)
+}
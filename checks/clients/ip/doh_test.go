@@ -0,0 +1,121 @@
+package ip
+
+import (
+	"context"
+	"encoding/base64"
+	"io"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/miekg/dns"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// dohHandler answers RFC 8484 queries for example.com and records the
+// request methods it saw.
+func dohHandler(t *testing.T, methods chan<- string) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var packed []byte
+		var err error
+		switch r.Method {
+		case http.MethodGet:
+			packed, err = base64.RawURLEncoding.DecodeString(r.URL.Query().Get("dns"))
+		case http.MethodPost:
+			assert.Equal(t, dnsMessageType, r.Header.Get("Content-Type"))
+			packed, err = io.ReadAll(r.Body)
+		}
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		methods <- r.Method
+
+		query := new(dns.Msg)
+		if err := query.Unpack(packed); err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		assert.Zero(t, query.Id)
+
+		resp := new(dns.Msg)
+		resp.SetReply(query)
+		q := query.Question[0]
+		switch {
+		case q.Name != "example.com.":
+			resp.Rcode = dns.RcodeNameError
+		case q.Qtype == dns.TypeA:
+			rr, _ := dns.NewRR("example.com. 300 IN A 192.0.2.1")
+			resp.Answer = append(resp.Answer, rr)
+		case q.Qtype == dns.TypeAAAA:
+			rr, _ := dns.NewRR("example.com. 300 IN AAAA 2001:db8::1")
+			resp.Answer = append(resp.Answer, rr)
+		}
+		out, _ := resp.Pack()
+		w.Header().Set("Content-Type", dnsMessageType)
+		w.Write(out)
+	})
+}
+
+func TestDoHClient(t *testing.T) {
+	t.Parallel()
+
+	methods := make(chan string, 10)
+	ts := httptest.NewTLSServer(dohHandler(t, methods))
+	defer ts.Close()
+
+	for _, method := range []string{http.MethodGet, http.MethodPost} {
+		t.Run(method, func(t *testing.T) {
+			msg := new(dns.Msg)
+			msg.SetQuestion("example.com.", dns.TypeA)
+
+			resp, err := NewDoHClient(ts.URL+"/dns-query", method, ts.Client()).Exchange(context.Background(), msg)
+			require.NoError(t, err)
+			assert.Equal(t, method, <-methods)
+			assert.Equal(t, msg.Id, resp.Id)
+			require.Len(t, resp.Answer, 1)
+			assert.Equal(t, "192.0.2.1", resp.Answer[0].(*dns.A).A.String())
+		})
+	}
+
+	t.Run("query string", func(t *testing.T) {
+		msg := new(dns.Msg)
+		msg.SetQuestion("example.com.", dns.TypeA)
+
+		resp, err := NewDoHClient(ts.URL+"/dns-query?ct=application/dns-message", http.MethodGet, ts.Client()).Exchange(context.Background(), msg)
+		require.NoError(t, err)
+		assert.Equal(t, http.MethodGet, <-methods)
+		require.Len(t, resp.Answer, 1)
+	})
+
+	t.Run("error status", func(t *testing.T) {
+		ts := httptest.NewServer(http.NotFoundHandler())
+		defer ts.Close()
+
+		msg := new(dns.Msg)
+		msg.SetQuestion("example.com.", dns.TypeA)
+		_, err := NewDoHClient(ts.URL, http.MethodPost, ts.Client()).Exchange(context.Background(), msg)
+		assert.EqualError(t, err, "DoH request failed with status 404")
+	})
+}
+
+func TestDoHLookup(t *testing.T) {
+	t.Parallel()
+
+	methods := make(chan string, 10)
+	ts := httptest.NewTLSServer(dohHandler(t, methods))
+	defer ts.Close()
+
+	lookup := &DoHLookup{Method: http.MethodGet, Client: ts.Client()}
+
+	ips, err := lookup.DNSLookupIP(context.Background(), "ip", "example.com", ts.URL)
+	require.NoError(t, err)
+	assert.Equal(t, []net.IP{net.ParseIP("192.0.2.1").To4(), net.ParseIP("2001:db8::1")}, ips)
+
+	_, err = lookup.DNSLookupIP(context.Background(), "ip4", "missing.example", ts.URL)
+	var dnsErr *net.DNSError
+	require.ErrorAs(t, err, &dnsErr)
+	assert.True(t, dnsErr.IsNotFound)
+}
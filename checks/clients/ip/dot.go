@@ -0,0 +1,49 @@
+package ip
+
+import (
+	"context"
+	"crypto/tls"
+	"net"
+	"time"
+
+	"github.com/miekg/dns"
+)
+
+// DoTClient sends queries to a DNS over TLS server.
+type DoTClient struct {
+	Addr    string
+	Timeout time.Duration
+	// TLSConfig is used for the connection, if nil the server certificate
+	// is verified against the host in Addr.
+	TLSConfig *tls.Config
+}
+
+// NewDoTClient returns a client for the DNS over TLS server at addr, port
+// 853 is used if addr doesn't include one.
+func NewDoTClient(addr string, timeout time.Duration) *DoTClient {
+	if _, _, err := net.SplitHostPort(addr); err != nil {
+		addr = net.JoinHostPort(addr, "853")
+	}
+	return &DoTClient{Addr: addr, Timeout: timeout}
+}
+
+func (c *DoTClient) Exchange(ctx context.Context, msg *dns.Msg) (*dns.Msg, error) {
+	config := c.TLSConfig
+	if config == nil {
+		host, _, _ := net.SplitHostPort(c.Addr)
+		config = &tls.Config{ServerName: host}
+	}
+	client := &dns.Client{Net: "tcp-tls", Timeout: c.Timeout, TLSConfig: config}
+	resp, _, err := client.ExchangeContext(ctx, msg, c.Addr)
+	return resp, err
+}
+
+// DoTLookup looks up IP addresses with the DNS over TLS server passed as
+// dns.
+type DoTLookup struct {
+	Timeout time.Duration
+}
+
+func (l *DoTLookup) DNSLookupIP(ctx context.Context, network, host, dns string) ([]net.IP, error) {
+	return ExchangeLookup{NewDoTClient(dns, l.Timeout)}.LookupIP(ctx, network, host)
+}
@@ -0,0 +1,64 @@
+package ip
+
+import (
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"net"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/miekg/dns"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestDoTClient(t *testing.T) {
+	t.Parallel()
+
+	// borrow the test certificate, valid for 127.0.0.1, from an HTTPS server
+	ts := httptest.NewTLSServer(nil)
+	ts.Close()
+	listener, err := tls.Listen("tcp", "127.0.0.1:0", ts.TLS)
+	require.NoError(t, err)
+
+	server := &dns.Server{Listener: listener, Net: "tcp-tls", Handler: dns.HandlerFunc(func(w dns.ResponseWriter, r *dns.Msg) {
+		resp := new(dns.Msg)
+		resp.SetReply(r)
+		rr, _ := dns.NewRR("example.com. 300 IN A 192.0.2.1")
+		resp.Answer = append(resp.Answer, rr)
+		w.WriteMsg(resp)
+	})}
+	go server.ActivateAndServe()
+	t.Cleanup(func() { server.Shutdown() })
+
+	roots := x509.NewCertPool()
+	roots.AddCert(ts.Certificate())
+
+	client := NewDoTClient(listener.Addr().String(), time.Second)
+	client.TLSConfig = &tls.Config{RootCAs: roots, ServerName: "127.0.0.1"}
+
+	ips, err := ExchangeLookup{client}.LookupIP(context.Background(), "ip4", "example.com")
+	require.NoError(t, err)
+	assert.Equal(t, []net.IP{net.ParseIP("192.0.2.1").To4()}, ips)
+
+	t.Run("untrusted certificate", func(t *testing.T) {
+		client := NewDoTClient(listener.Addr().String(), time.Second)
+
+		msg := new(dns.Msg)
+		msg.SetQuestion("example.com.", dns.TypeA)
+		_, err := client.Exchange(context.Background(), msg)
+		assert.ErrorContains(t, err, "certificate")
+	})
+}
+
+func TestNewResolverClient(t *testing.T) {
+	t.Parallel()
+
+	assert.Equal(t, &DNSClient{Addr: "8.8.8.8:53", Timeout: time.Second}, NewResolverClient("8.8.8.8", time.Second))
+	assert.Equal(t, &DoTClient{Addr: "1.1.1.1:853", Timeout: time.Second}, NewResolverClient("tls://1.1.1.1", time.Second))
+	doh, ok := NewResolverClient("https://dns.google/dns-query", time.Second).(*DoHClient)
+	require.True(t, ok)
+	assert.Equal(t, "https://dns.google/dns-query", doh.URL)
+}
@@ -0,0 +1,122 @@
+package ip
+
+import (
+	"context"
+	"errors"
+	"net"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/miekg/dns"
+)
+
+// Exchanger sends a DNS query and returns the response.
+type Exchanger interface {
+	Exchange(ctx context.Context, msg *dns.Msg) (*dns.Msg, error)
+}
+
+type ExchangeFunc func(ctx context.Context, msg *dns.Msg) (*dns.Msg, error)
+
+func (fn ExchangeFunc) Exchange(ctx context.Context, msg *dns.Msg) (*dns.Msg, error) {
+	return fn(ctx, msg)
+}
+
+// DNSClient sends queries to a single DNS server over UDP, retrying over
+// TCP when the response is truncated.
+type DNSClient struct {
+	Addr    string
+	Timeout time.Duration
+}
+
+// NewDNSClient returns a client for the DNS server at addr, port 53 is used
+// if addr doesn't include one.
+func NewDNSClient(addr string, timeout time.Duration) *DNSClient {
+	if _, _, err := net.SplitHostPort(addr); err != nil {
+		addr = net.JoinHostPort(addr, "53")
+	}
+	return &DNSClient{Addr: addr, Timeout: timeout}
+}
+
+func (c *DNSClient) Exchange(ctx context.Context, msg *dns.Msg) (*dns.Msg, error) {
+	client := &dns.Client{Net: "udp", Timeout: c.Timeout}
+	resp, _, err := client.ExchangeContext(ctx, msg, c.Addr)
+	if err == nil && !resp.Truncated {
+		return resp, nil
+	}
+	if err != nil && resp == nil {
+		return nil, err
+	}
+	client.Net = "tcp"
+	resp, _, err = client.ExchangeContext(ctx, msg, c.Addr)
+	return resp, err
+}
+
+// NewResolverClient returns a client for addr, which is either a DNS over
+// HTTPS URL (https://dns.google/dns-query), a DNS over TLS server
+// (tls://1.1.1.1) or a plain DNS server (8.8.8.8:53).
+func NewResolverClient(addr string, timeout time.Duration) Exchanger {
+	switch {
+	case strings.HasPrefix(addr, "https://"):
+		return NewDoHClient(addr, http.MethodPost, &http.Client{Timeout: timeout})
+	case strings.HasPrefix(addr, "tls://"):
+		return NewDoTClient(strings.TrimPrefix(addr, "tls://"), timeout)
+	}
+	return NewDNSClient(addr, timeout)
+}
+
+// ExchangeLookup looks up IP addresses by sending queries through an
+// Exchanger. Failed lookups return a *net.DNSError like net.Resolver.
+type ExchangeLookup struct {
+	Exchanger
+}
+
+func (l ExchangeLookup) LookupIP(ctx context.Context, network, host string) ([]net.IP, error) {
+	var qtypes []uint16
+	switch network {
+	case "ip":
+		qtypes = []uint16{dns.TypeA, dns.TypeAAAA}
+	case "ip4":
+		qtypes = []uint16{dns.TypeA}
+	case "ip6":
+		qtypes = []uint16{dns.TypeAAAA}
+	default:
+		return nil, net.UnknownNetworkError(network)
+	}
+
+	var ips []net.IP
+	for _, qtype := range qtypes {
+		msg := new(dns.Msg)
+		msg.SetQuestion(dns.Fqdn(host), qtype)
+		resp, err := l.Exchange(ctx, msg)
+		if err != nil {
+			return nil, &net.DNSError{Err: err.Error(), Name: host, IsTimeout: IsTimeout(err)}
+		}
+		switch resp.Rcode {
+		case dns.RcodeSuccess:
+		case dns.RcodeNameError:
+			return nil, &net.DNSError{Err: "no such host", Name: host, IsNotFound: true}
+		default:
+			return nil, &net.DNSError{Err: dns.RcodeToString[resp.Rcode], Name: host, IsTemporary: resp.Rcode == dns.RcodeServerFailure}
+		}
+		for _, rr := range resp.Answer {
+			switch rr := rr.(type) {
+			case *dns.A:
+				ips = append(ips, rr.A)
+			case *dns.AAAA:
+				ips = append(ips, rr.AAAA)
+			}
+		}
+	}
+	if len(ips) == 0 {
+		return nil, &net.DNSError{Err: "no such host", Name: host, IsNotFound: true}
+	}
+	return ips, nil
+}
+
+// IsTimeout reports whether err is from a query or connection that timed
+// out.
+func IsTimeout(err error) bool {
+	var netErr net.Error
+	return errors.Is(err, context.DeadlineExceeded) || (errors.As(err, &netErr) && netErr.Timeout())
+}
@@ -0,0 +1,60 @@
+package ip
+
+import (
+	"context"
+	"net"
+	"testing"
+	"time"
+
+	"github.com/miekg/dns"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestDNSClientTruncated(t *testing.T) {
+	t.Parallel()
+
+	udp, err := net.ListenPacket("udp", "127.0.0.1:0")
+	require.NoError(t, err)
+	tcp, err := net.Listen("tcp", udp.LocalAddr().String())
+	if err != nil {
+		udp.Close()
+		t.Skipf("tcp port unavailable: %v", err)
+	}
+
+	handler := dns.HandlerFunc(func(w dns.ResponseWriter, r *dns.Msg) {
+		resp := new(dns.Msg)
+		resp.SetReply(r)
+		if w.LocalAddr().Network() == "udp" {
+			resp.Truncated = true
+		} else {
+			rr, _ := dns.NewRR("example.com. 300 IN A 192.0.2.1")
+			resp.Answer = append(resp.Answer, rr)
+		}
+		w.WriteMsg(resp)
+	})
+	udpServer := &dns.Server{PacketConn: udp, Handler: handler}
+	tcpServer := &dns.Server{Listener: tcp, Handler: handler}
+	go udpServer.ActivateAndServe()
+	go tcpServer.ActivateAndServe()
+	t.Cleanup(func() {
+		udpServer.Shutdown()
+		tcpServer.Shutdown()
+	})
+
+	msg := new(dns.Msg)
+	msg.SetQuestion("example.com.", dns.TypeA)
+	resp, err := NewDNSClient(udp.LocalAddr().String(), time.Second).Exchange(context.Background(), msg)
+	require.NoError(t, err)
+	assert.False(t, resp.Truncated)
+	require.Len(t, resp.Answer, 1)
+	assert.Equal(t, "192.0.2.1", resp.Answer[0].(*dns.A).A.String())
+}
+
+func TestNewDNSClient(t *testing.T) {
+	t.Parallel()
+
+	assert.Equal(t, "8.8.8.8:53", NewDNSClient("8.8.8.8", time.Second).Addr)
+	assert.Equal(t, "[2001:4860:4860::8888]:53", NewDNSClient("2001:4860:4860::8888", time.Second).Addr)
+	assert.Equal(t, "127.0.0.1:5353", NewDNSClient("127.0.0.1:5353", time.Second).Addr)
+}
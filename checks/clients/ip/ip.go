@@ -52,3 +52,15 @@ func (l *NetDNSLookup) DNSLookupIP(ctx context.Context, network, host, dns strin
 	}
 	return netResolver.LookupIP(ctx, network, host)
 }
+
+// Resolver looks up DNS records, it is satisfied by *net.Resolver.
+type Resolver interface {
+	LookupIP(ctx context.Context, network, host string) ([]net.IP, error)
+	LookupIPAddr(ctx context.Context, host string) ([]net.IPAddr, error)
+	LookupMX(ctx context.Context, name string) ([]*net.MX, error)
+	LookupTXT(ctx context.Context, name string) ([]string, error)
+	LookupNS(ctx context.Context, name string) ([]*net.NS, error)
+	LookupCNAME(ctx context.Context, host string) (string, error)
+	LookupSRV(ctx context.Context, service, proto, name string) (string, []*net.SRV, error)
+	LookupAddr(ctx context.Context, addr string) ([]string, error)
+}
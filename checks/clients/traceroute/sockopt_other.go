@@ -0,0 +1,11 @@
+//go:build !unix
+
+package traceroute
+
+import "errors"
+
+// prepareSocket is only implemented for unix, where the TTL of a TCP
+// socket can be set before it connects.
+func prepareSocket(fd uintptr, v6 bool, ttl int) (int, error) {
+	return 0, errors.ErrUnsupported
+}
@@ -0,0 +1,36 @@
+//go:build unix
+
+package traceroute
+
+import "syscall"
+
+// prepareSocket sets the TTL of the socket fd and binds it to a free port,
+// returning the port.
+func prepareSocket(fd uintptr, v6 bool, ttl int) (int, error) {
+	var err error
+	var sa syscall.Sockaddr
+	if v6 {
+		err = syscall.SetsockoptInt(int(fd), syscall.IPPROTO_IPV6, syscall.IPV6_UNICAST_HOPS, ttl)
+		sa = &syscall.SockaddrInet6{}
+	} else {
+		err = syscall.SetsockoptInt(int(fd), syscall.IPPROTO_IP, syscall.IP_TTL, ttl)
+		sa = &syscall.SockaddrInet4{}
+	}
+	if err != nil {
+		return 0, err
+	}
+	if err := syscall.Bind(int(fd), sa); err != nil {
+		return 0, err
+	}
+	sa, err = syscall.Getsockname(int(fd))
+	if err != nil {
+		return 0, err
+	}
+	switch sa := sa.(type) {
+	case *syscall.SockaddrInet4:
+		return sa.Port, nil
+	case *syscall.SockaddrInet6:
+		return sa.Port, nil
+	}
+	return 0, syscall.EAFNOSUPPORT
+}
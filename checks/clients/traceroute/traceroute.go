@@ -0,0 +1,353 @@
+// Package traceroute sends probes with a limited TTL to find the routers on
+// the path to a host. The ICMP messages answering probes are read from raw
+// sockets, which need root or CAP_NET_RAW.
+package traceroute
+
+import (
+	"context"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"net"
+	"net/netip"
+	"os"
+	"strconv"
+	"sync/atomic"
+	"syscall"
+	"time"
+
+	"golang.org/x/net/icmp"
+	"golang.org/x/net/ipv4"
+	"golang.org/x/net/ipv6"
+)
+
+// Protocols probes can be sent with.
+const (
+	ICMP = "icmp"
+	UDP  = "udp"
+	TCP  = "tcp"
+)
+
+var ErrUnknownProtocol = errors.New("unknown protocol")
+
+// protocol numbers of ICMP for IPv4 and IPv6
+const (
+	protocolICMP   = 1
+	protocolICMPv6 = 58
+)
+
+// sequence numbers echo requests so concurrent probes can be told apart.
+var sequence atomic.Uint32
+
+// Reply is the answer to a probe.
+type Reply struct {
+	From netip.Addr
+	RTT  time.Duration
+	// Reached is set if the destination itself answered.
+	Reached bool
+}
+
+// Prober sends a probe to dst that expires after ttl hops, waiting for an
+// answer until ctx is done.
+type Prober interface {
+	Probe(ctx context.Context, dst netip.Addr, ttl int) (Reply, error)
+}
+
+type ProberFunc func(ctx context.Context, dst netip.Addr, ttl int) (Reply, error)
+
+func (fn ProberFunc) Probe(ctx context.Context, dst netip.Addr, ttl int) (Reply, error) {
+	return fn(ctx, dst, ttl)
+}
+
+// NewProber returns a Prober sending protocol probes to port, which is
+// ignored for ICMP.
+func NewProber(protocol string, port int) (Prober, error) {
+	switch protocol {
+	case ICMP:
+		return icmpProber{}, nil
+	case UDP:
+		return udpProber{port: port}, nil
+	case TCP:
+		return tcpProber{port: port}, nil
+	}
+	return nil, fmt.Errorf("%w %q", ErrUnknownProtocol, protocol)
+}
+
+// quoted returns the destination and transport header of the packet an
+// ICMP error quotes.
+func quoted(data []byte, v6 bool) (netip.Addr, []byte, bool) {
+	if v6 {
+		if len(data) < 40 {
+			return netip.Addr{}, nil, false
+		}
+		dst, _ := netip.AddrFromSlice(data[24:40])
+		return dst, data[40:], true
+	}
+	if len(data) < 20 || data[0]>>4 != 4 {
+		return netip.Addr{}, nil, false
+	}
+	ihl := int(data[0]&0x0f) * 4
+	if len(data) < ihl {
+		return netip.Addr{}, nil, false
+	}
+	dst, _ := netip.AddrFromSlice(data[16:20])
+	return dst, data[ihl:], true
+}
+
+// ports reads the source and destination ports of a UDP or TCP header.
+func ports(transport []byte) (src, dst int, ok bool) {
+	if len(transport) < 4 {
+		return 0, 0, false
+	}
+	return int(binary.BigEndian.Uint16(transport)), int(binary.BigEndian.Uint16(transport[2:])), true
+}
+
+// matcher recognises the ICMP messages answering a probe to dst.
+type matcher struct {
+	dst netip.Addr
+	// probe reports whether the transport header an ICMP error quotes is
+	// the probe's.
+	probe func(transport []byte) bool
+	// echo reports whether an echo reply answers the probe, it is nil if
+	// the probe isn't an echo request.
+	echo func(*icmp.Echo) bool
+}
+
+// match reports whether the ICMP message b from from answers the probe.
+func (m matcher) match(b []byte, from netip.Addr) bool {
+	proto := protocolICMP
+	if m.dst.Is6() {
+		proto = protocolICMPv6
+	}
+	msg, err := icmp.ParseMessage(proto, b)
+	if err != nil {
+		return false
+	}
+	var data []byte
+	switch body := msg.Body.(type) {
+	case *icmp.TimeExceeded:
+		data = body.Data
+	case *icmp.DstUnreach:
+		data = body.Data
+	case *icmp.Echo:
+		isReply := msg.Type == ipv4.ICMPTypeEchoReply || msg.Type == ipv6.ICMPTypeEchoReply
+		return isReply && m.echo != nil && from == m.dst && m.echo(body)
+	default:
+		return false
+	}
+	dst, transport, ok := quoted(data, m.dst.Is6())
+	return ok && dst == m.dst && m.probe(transport)
+}
+
+// listener reads the ICMP messages sent back to the host.
+type listener struct {
+	conn *icmp.PacketConn
+}
+
+func listen(dst netip.Addr) (*listener, error) {
+	network, address := "ip4:icmp", "0.0.0.0"
+	if dst.Is6() {
+		network, address = "ip6:ipv6-icmp", "::"
+	}
+	conn, err := icmp.ListenPacket(network, address)
+	if err != nil {
+		return nil, err
+	}
+	return &listener{conn: conn}, nil
+}
+
+func (l *listener) Close() error {
+	return l.conn.Close()
+}
+
+// wait returns the first ICMP message m matches as a reply to a probe sent
+// at start, or a reply from the destination if direct is closed first.
+func (l *listener) wait(ctx context.Context, m matcher, start time.Time, direct <-chan struct{}) (Reply, error) {
+	replies := make(chan Reply, 1)
+	go func() {
+		buf := make([]byte, 1500)
+		for {
+			n, peer, err := l.conn.ReadFrom(buf)
+			if err != nil {
+				return
+			}
+			ipAddr, ok := peer.(*net.IPAddr)
+			if !ok {
+				continue
+			}
+			from, _ := netip.AddrFromSlice(ipAddr.IP)
+			from = from.Unmap()
+			if m.match(buf[:n], from) {
+				replies <- Reply{From: from, RTT: time.Since(start), Reached: from == m.dst}
+				return
+			}
+		}
+	}()
+
+	select {
+	case reply := <-replies:
+		return reply, nil
+	case <-direct:
+		return Reply{From: m.dst, RTT: time.Since(start), Reached: true}, nil
+	case <-ctx.Done():
+		return Reply{}, ctx.Err()
+	}
+}
+
+// icmpProber sends echo requests.
+type icmpProber struct{}
+
+func (icmpProber) Probe(ctx context.Context, dst netip.Addr, ttl int) (Reply, error) {
+	l, err := listen(dst)
+	if err != nil {
+		return Reply{}, err
+	}
+	defer l.Close()
+
+	id, seq := os.Getpid()&0xffff, int(sequence.Add(1)&0xffff)
+	var typ icmp.Type = ipv4.ICMPTypeEcho
+	if dst.Is6() {
+		typ = ipv6.ICMPTypeEchoRequest
+		err = l.conn.IPv6PacketConn().SetHopLimit(ttl)
+	} else {
+		err = l.conn.IPv4PacketConn().SetTTL(ttl)
+	}
+	if err != nil {
+		return Reply{}, err
+	}
+	b, err := (&icmp.Message{Type: typ, Body: &icmp.Echo{ID: id, Seq: seq, Data: []byte("web-check")}}).Marshal(nil)
+	if err != nil {
+		return Reply{}, err
+	}
+
+	m := matcher{
+		dst: dst,
+		probe: func(transport []byte) bool {
+			return len(transport) >= 8 &&
+				int(binary.BigEndian.Uint16(transport[4:])) == id &&
+				int(binary.BigEndian.Uint16(transport[6:])) == seq
+		},
+		echo: func(echo *icmp.Echo) bool {
+			return echo.ID == id && echo.Seq == seq
+		},
+	}
+	start := time.Now()
+	if _, err := l.conn.WriteTo(b, &net.IPAddr{IP: dst.AsSlice()}); err != nil {
+		return Reply{}, err
+	}
+	return l.wait(ctx, m, start, nil)
+}
+
+// udpProber sends a datagram to port, which the destination answers with
+// a port unreachable error if nothing is listening.
+type udpProber struct {
+	port int
+}
+
+func (p udpProber) Probe(ctx context.Context, dst netip.Addr, ttl int) (Reply, error) {
+	l, err := listen(dst)
+	if err != nil {
+		return Reply{}, err
+	}
+	defer l.Close()
+
+	conn, err := (&net.Dialer{}).DialContext(ctx, "udp", netip.AddrPortFrom(dst, uint16(p.port)).String())
+	if err != nil {
+		return Reply{}, err
+	}
+	defer conn.Close()
+	if dst.Is6() {
+		err = ipv6.NewConn(conn).SetHopLimit(ttl)
+	} else {
+		err = ipv4.NewConn(conn).SetTTL(ttl)
+	}
+	if err != nil {
+		return Reply{}, err
+	}
+
+	local := conn.LocalAddr().(*net.UDPAddr).Port
+	m := matcher{
+		dst: dst,
+		probe: func(transport []byte) bool {
+			srcPort, dstPort, ok := ports(transport)
+			return ok && srcPort == local && dstPort == p.port
+		},
+	}
+	// a service on the port may answer instead
+	direct := make(chan struct{})
+	go func() {
+		if _, err := conn.Read(make([]byte, 1500)); err == nil {
+			close(direct)
+		}
+	}()
+	start := time.Now()
+	if _, err := conn.Write([]byte("web-check")); err != nil {
+		return Reply{}, err
+	}
+	return l.wait(ctx, m, start, direct)
+}
+
+// tcpProber opens a connection to port, which the destination answers
+// with a SYN-ACK or a reset.
+type tcpProber struct {
+	port int
+}
+
+func (p tcpProber) Probe(ctx context.Context, dst netip.Addr, ttl int) (Reply, error) {
+	l, err := listen(dst)
+	if err != nil {
+		return Reply{}, err
+	}
+	defer l.Close()
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	// the source port is only known once the socket is bound before it
+	// connects
+	var local atomic.Int32
+	bound := make(chan error, 1)
+	dialer := &net.Dialer{Control: func(network, address string, c syscall.RawConn) error {
+		var err error
+		if cerr := c.Control(func(fd uintptr) {
+			var port int
+			port, err = prepareSocket(fd, dst.Is6(), ttl)
+			local.Store(int32(port))
+		}); cerr != nil {
+			err = cerr
+		}
+		bound <- err
+		return err
+	}}
+	m := matcher{
+		dst: dst,
+		probe: func(transport []byte) bool {
+			srcPort, dstPort, ok := ports(transport)
+			return ok && srcPort == int(local.Load()) && dstPort == p.port
+		},
+	}
+
+	network := "tcp4"
+	if dst.Is6() {
+		network = "tcp6"
+	}
+	direct := make(chan struct{})
+	start := time.Now()
+	go func() {
+		conn, err := dialer.DialContext(ctx, network, net.JoinHostPort(dst.String(), strconv.Itoa(p.port)))
+		if err == nil {
+			conn.Close()
+		}
+		if err == nil || errors.Is(err, syscall.ECONNREFUSED) {
+			close(direct)
+		}
+	}()
+	select {
+	case err := <-bound:
+		if err != nil {
+			return Reply{}, err
+		}
+	case <-ctx.Done():
+		return Reply{}, ctx.Err()
+	}
+	return l.wait(ctx, m, start, direct)
+}
@@ -0,0 +1,152 @@
+package traceroute
+
+import (
+	"encoding/binary"
+	"net"
+	"net/netip"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"golang.org/x/net/icmp"
+	"golang.org/x/net/ipv4"
+	"golang.org/x/net/ipv6"
+)
+
+// packet returns an IP header from 192.0.2.10 or 2001:db8::10 to dst
+// followed by transport.
+func packet(t *testing.T, dst netip.Addr, proto int, transport []byte) []byte {
+	t.Helper()
+	if dst.Is6() {
+		h := make([]byte, 40)
+		h[0] = 6 << 4
+		binary.BigEndian.PutUint16(h[4:], uint16(len(transport)))
+		h[6], h[7] = byte(proto), 1
+		copy(h[8:], netip.MustParseAddr("2001:db8::10").AsSlice())
+		copy(h[24:], dst.AsSlice())
+		return append(h, transport...)
+	}
+	h, err := (&ipv4.Header{
+		Version:  4,
+		Len:      20,
+		TotalLen: 20 + len(transport),
+		TTL:      1,
+		Protocol: proto,
+		Src:      net.ParseIP("192.0.2.10"),
+		Dst:      dst.AsSlice(),
+	}).Marshal()
+	require.NoError(t, err)
+	return append(h, transport...)
+}
+
+func message(t *testing.T, typ icmp.Type, body icmp.MessageBody) []byte {
+	t.Helper()
+	b, err := (&icmp.Message{Type: typ, Body: body}).Marshal(nil)
+	require.NoError(t, err)
+	return b
+}
+
+func TestMatch(t *testing.T) {
+	t.Parallel()
+
+	v4 := netip.MustParseAddr("198.51.100.1")
+	v6 := netip.MustParseAddr("2001:db8::1")
+	router := netip.MustParseAddr("203.0.113.1")
+	udp := []byte{0xc3, 0x50, 0x82, 0x9a, 0, 17, 0, 0} // 50000 to 33434
+	byPort := func(transport []byte) bool {
+		src, dst, ok := ports(transport)
+		return ok && src == 50000 && dst == 33434
+	}
+	echo := func(e *icmp.Echo) bool { return e.ID == 7 && e.Seq == 9 }
+
+	tests := []struct {
+		name  string
+		m     matcher
+		b     []byte
+		from  netip.Addr
+		match bool
+	}{
+		{
+			name:  "time exceeded",
+			m:     matcher{dst: v4, probe: byPort},
+			b:     message(t, ipv4.ICMPTypeTimeExceeded, &icmp.TimeExceeded{Data: packet(t, v4, 17, udp)}),
+			from:  router,
+			match: true,
+		},
+		{
+			name: "another probe",
+			m:    matcher{dst: v4, probe: byPort},
+			b:    message(t, ipv4.ICMPTypeTimeExceeded, &icmp.TimeExceeded{Data: packet(t, v4, 17, []byte{0xc3, 0x51, 0x82, 0x9a})}),
+			from: router,
+		},
+		{
+			name: "another destination",
+			m:    matcher{dst: v4, probe: byPort},
+			b:    message(t, ipv4.ICMPTypeTimeExceeded, &icmp.TimeExceeded{Data: packet(t, netip.MustParseAddr("198.51.100.2"), 17, udp)}),
+			from: router,
+		},
+		{
+			name:  "port unreachable",
+			m:     matcher{dst: v4, probe: byPort},
+			b:     message(t, ipv4.ICMPTypeDestinationUnreachable, &icmp.DstUnreach{Data: packet(t, v4, 17, udp)}),
+			from:  v4,
+			match: true,
+		},
+		{
+			name: "truncated",
+			m:    matcher{dst: v4, probe: byPort},
+			b:    message(t, ipv4.ICMPTypeTimeExceeded, &icmp.TimeExceeded{Data: packet(t, v4, 17, udp)[:12]}),
+			from: router,
+		},
+		{
+			name:  "ipv6 time exceeded",
+			m:     matcher{dst: v6, probe: byPort},
+			b:     message(t, ipv6.ICMPTypeTimeExceeded, &icmp.TimeExceeded{Data: packet(t, v6, 6, udp)}),
+			from:  netip.MustParseAddr("2001:db8:ffff::1"),
+			match: true,
+		},
+		{
+			name:  "echo reply",
+			m:     matcher{dst: v4, echo: echo},
+			b:     message(t, ipv4.ICMPTypeEchoReply, &icmp.Echo{ID: 7, Seq: 9}),
+			from:  v4,
+			match: true,
+		},
+		{
+			name: "echo reply to another probe",
+			m:    matcher{dst: v4, echo: echo},
+			b:    message(t, ipv4.ICMPTypeEchoReply, &icmp.Echo{ID: 7, Seq: 10}),
+			from: v4,
+		},
+		{
+			name: "echo request",
+			m:    matcher{dst: v4, echo: echo},
+			b:    message(t, ipv4.ICMPTypeEcho, &icmp.Echo{ID: 7, Seq: 9}),
+			from: v4,
+		},
+		{
+			name:  "ipv6 echo reply",
+			m:     matcher{dst: v6, echo: echo},
+			b:     message(t, ipv6.ICMPTypeEchoReply, &icmp.Echo{ID: 7, Seq: 9}),
+			from:  v6,
+			match: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+			assert.Equal(t, tt.match, tt.m.match(tt.b, tt.from))
+		})
+	}
+}
+
+func TestNewProber(t *testing.T) {
+	t.Parallel()
+
+	for _, protocol := range []string{ICMP, UDP, TCP} {
+		_, err := NewProber(protocol, 443)
+		assert.NoError(t, err)
+	}
+	_, err := NewProber("sctp", 443)
+	assert.ErrorIs(t, err, ErrUnknownProtocol)
+}
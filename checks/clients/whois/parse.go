@@ -0,0 +1,323 @@
+package whois
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"net"
+	"regexp"
+	"slices"
+	"strings"
+	"time"
+)
+
+// Field is a part of a Record found in a WHOIS response.
+type Field string
+
+const (
+	FieldDomain     Field = "domain"
+	FieldRegistrar  Field = "registrar"
+	FieldCreated    Field = "created"
+	FieldUpdated    Field = "updated"
+	FieldExpires    Field = "expires"
+	FieldStatus     Field = "status"
+	FieldNameserver Field = "nameserver"
+	FieldDNSSEC     Field = "dnssec"
+)
+
+// Format describes how a registry or registrar lays out its WHOIS
+// responses.
+type Format struct {
+	Name string
+	// Servers are the WHOIS servers using the format, the last entry in
+	// Formats is used for any other server.
+	Servers []string
+	// Query is the request sent for a domain, with %s replaced by the
+	// domain. The domain alone is sent if empty.
+	Query string
+	// Fields maps each field to the lower case labels used for it. Only the
+	// first value is kept for single valued fields.
+	Fields map[Field][]string
+	// NotFound are lower case phrases only found in the response for an
+	// unregistered domain.
+	NotFound []string
+	// Layouts are tried in order to parse dates, before commonLayouts.
+	Layouts []string
+	// Location is the zone of dates without an offset, UTC if nil.
+	Location *time.Location
+}
+
+// Formats lists the known WHOIS formats, ending with the ICANN format used
+// by gTLD registries and registrars.
+var Formats = []Format{
+	{
+		Name:    "denic",
+		Servers: []string{"whois.denic.de"},
+		Query:   "-T dn,ace %s",
+		Fields: map[Field][]string{
+			FieldDomain:     {"domain"},
+			FieldUpdated:    {"changed"},
+			FieldStatus:     {"status"},
+			FieldNameserver: {"nserver"},
+			FieldDNSSEC:     {"dnskey"},
+		},
+		NotFound: []string{"status: free"},
+	},
+	{
+		Name:    "nominet",
+		Servers: []string{"whois.nic.uk"},
+		Fields: map[Field][]string{
+			FieldDomain:     {"domain name"},
+			FieldRegistrar:  {"registrar"},
+			FieldCreated:    {"registered on"},
+			FieldUpdated:    {"last updated"},
+			FieldExpires:    {"expiry date"},
+			FieldStatus:     {"registration status"},
+			FieldNameserver: {"name servers"},
+			FieldDNSSEC:     {"dnssec"},
+		},
+		NotFound: []string{"no match for", "this domain name has not been registered"},
+		Layouts:  []string{"02-Jan-2006"},
+	},
+	{
+		Name:    "jprs",
+		Servers: []string{"whois.jprs.jp"},
+		// without /e the response is in Japanese
+		Query: "%s/e",
+		Fields: map[Field][]string{
+			FieldDomain:     {"domain name"},
+			FieldCreated:    {"created on"},
+			FieldUpdated:    {"last updated", "last update"},
+			FieldExpires:    {"expires on"},
+			FieldStatus:     {"status", "state"},
+			FieldNameserver: {"name server"},
+			FieldDNSSEC:     {"signing key"},
+		},
+		NotFound: []string{"no match!!"},
+		Layouts:  []string{"2006/01/02 15:04:05", "2006/01/02"},
+		Location: time.FixedZone("JST", 9*60*60),
+	},
+	{
+		Name:    "afnic",
+		Servers: []string{"whois.nic.fr"},
+		Fields: map[Field][]string{
+			FieldDomain:     {"domain"},
+			FieldRegistrar:  {"registrar"},
+			FieldCreated:    {"created"},
+			FieldUpdated:    {"last-update"},
+			FieldExpires:    {"expiry date"},
+			FieldStatus:     {"status"},
+			FieldNameserver: {"nserver"},
+		},
+		NotFound: []string{"%% no entries found"},
+	},
+	{
+		Name: "icann",
+		Fields: map[Field][]string{
+			FieldDomain:     {"domain name", "domain"},
+			FieldRegistrar:  {"registrar", "sponsoring registrar"},
+			FieldCreated:    {"creation date", "created date", "created on", "created", "registered on"},
+			FieldUpdated:    {"updated date", "last updated", "last updated on", "last modified"},
+			FieldExpires:    {"registry expiry date", "registrar registration expiration date", "expiration date", "expiry date", "expires on", "paid-till"},
+			FieldStatus:     {"domain status", "status"},
+			FieldNameserver: {"name server", "nameserver", "nserver"},
+			FieldDNSSEC:     {"dnssec"},
+		},
+		NotFound: []string{
+			"no match for",
+			"domain not found",
+			"no data found",
+			"no entries found",
+			"no object found",
+			"is available for registration",
+		},
+	},
+}
+
+// commonLayouts are the date formats tried for every server.
+var commonLayouts = []string{
+	time.RFC3339,
+	"2006-01-02T15:04:05Z0700",
+	"2006-01-02T15:04:05",
+	"2006-01-02 15:04:05Z07:00",
+	"2006-01-02 15:04:05",
+	"2006-01-02",
+	"02-Jan-2006",
+	"2006.01.02",
+	"2006/01/02",
+}
+
+var (
+	// keyPattern matches a "Key: value" line, keys are words without
+	// digits so values such as addresses aren't mistaken for keys.
+	keyPattern = regexp.MustCompile(`^([A-Za-z][A-Za-z /()_-]*?)\s*:\s*(.*)$`)
+	// bracketPattern matches a "[Key] value" line.
+	bracketPattern = regexp.MustCompile(`^\[([^\]]+)\]\s*(.*)$`)
+	// zonePattern matches a zone name after a date, e.g. "(JST)".
+	zonePattern = regexp.MustCompile(`\s*\([A-Z]+\)$`)
+)
+
+var errUnrecognised = errors.New("unrecognised WHOIS response")
+
+// FormatFor returns the format used by server.
+func FormatFor(server string) Format {
+	if host, _, err := net.SplitHostPort(server); err == nil {
+		server = host
+	}
+	server = strings.ToLower(server)
+	for _, format := range Formats {
+		if slices.Contains(format.Servers, server) {
+			return format
+		}
+	}
+	return Formats[len(Formats)-1]
+}
+
+type pair struct {
+	key, value string
+}
+
+// pairs splits a response into keys and values. A key with no value
+// starts a section, and indented lines that follow without a key of their
+// own are values for it.
+func pairs(resp string) []pair {
+	var out []pair
+	section, sectionIndent := "", 0
+	scanner := bufio.NewScanner(strings.NewReader(resp))
+	for scanner.Scan() {
+		line := strings.TrimRight(scanner.Text(), " \t\r")
+		text := strings.TrimLeft(line, " \t")
+		if text == "" || text[0] == '%' || text[0] == '#' {
+			continue
+		}
+		indent := len(line) - len(text)
+		if section != "" && indent <= sectionIndent {
+			section = ""
+		}
+
+		m := bracketPattern.FindStringSubmatch(text)
+		if m == nil {
+			m = keyPattern.FindStringSubmatch(text)
+		}
+		switch {
+		case m != nil && m[2] == "":
+			section, sectionIndent = strings.ToLower(m[1]), indent
+		case m != nil:
+			out = append(out, pair{strings.ToLower(strings.TrimSpace(m[1])), m[2]})
+		case section != "":
+			out = append(out, pair{section, text})
+		}
+	}
+	return out
+}
+
+// signed reports whether a DNSSEC value means the delegation is signed.
+func signed(value string) bool {
+	switch strings.ToLower(value) {
+	case "", "unsigned", "unsigned delegation", "no", "inactive", "false":
+		return false
+	}
+	return true
+}
+
+func (f Format) parseTime(value string) (time.Time, bool) {
+	value = zonePattern.ReplaceAllString(value, "")
+	loc := f.Location
+	if loc == nil {
+		loc = time.UTC
+	}
+	for _, layout := range append(slices.Clip(f.Layouts), commonLayouts...) {
+		if t, err := time.ParseInLocation(layout, value, loc); err == nil {
+			return t.UTC(), true
+		}
+	}
+	return time.Time{}, false
+}
+
+func (f Format) field(key string) (Field, bool) {
+	for field, labels := range f.Fields {
+		if slices.Contains(labels, key) {
+			return field, true
+		}
+	}
+	return "", false
+}
+
+// query returns the request sent for domain.
+func (f Format) query(domain string) string {
+	if f.Query == "" {
+		return domain
+	}
+	return fmt.Sprintf(f.Query, domain)
+}
+
+// Parse reads a WHOIS response from server into a Record, using the
+// format for the server. ErrNotFound is returned if the response says the
+// domain isn't registered.
+func Parse(server, resp string) (*Record, error) {
+	return FormatFor(server).Parse(resp)
+}
+
+// Parse reads a WHOIS response in the format f.
+func (f Format) Parse(resp string) (*Record, error) {
+	lower := strings.ToLower(resp)
+	for _, phrase := range f.NotFound {
+		if strings.Contains(lower, phrase) {
+			return nil, ErrNotFound
+		}
+	}
+
+	record := &Record{Source: SourceWhois}
+	matched := false
+	setTime := func(t **time.Time, value string) {
+		if *t != nil {
+			return
+		}
+		if parsed, ok := f.parseTime(value); ok {
+			*t = &parsed
+		}
+	}
+	for _, p := range pairs(resp) {
+		field, ok := f.field(p.key)
+		value := strings.TrimSpace(p.value)
+		if !ok || value == "" {
+			continue
+		}
+		matched = true
+		switch field {
+		case FieldDomain:
+			if record.Domain == "" {
+				record.Domain = strings.TrimSuffix(strings.ToLower(value), ".")
+			}
+		case FieldRegistrar:
+			if record.Registrar == "" {
+				record.Registrar = value
+			}
+		case FieldCreated:
+			setTime(&record.Created, value)
+		case FieldUpdated:
+			setTime(&record.Updated, value)
+		case FieldExpires:
+			setTime(&record.Expires, value)
+		case FieldStatus:
+			// ICANN statuses are followed by a link explaining them
+			if code, link, ok := strings.Cut(value, " "); ok && strings.Contains(link, "http") {
+				value = code
+			}
+			if !slices.Contains(record.Status, value) {
+				record.Status = append(record.Status, value)
+			}
+		case FieldNameserver:
+			ns := strings.TrimSuffix(strings.ToLower(strings.Fields(value)[0]), ".")
+			if !slices.Contains(record.Nameservers, ns) {
+				record.Nameservers = append(record.Nameservers, ns)
+			}
+		case FieldDNSSEC:
+			record.DNSSEC = record.DNSSEC || signed(value)
+		}
+	}
+	if !matched {
+		return nil, errUnrecognised
+	}
+	return record, nil
+}
@@ -0,0 +1,154 @@
+package whois
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func fixture(t *testing.T, name string) string {
+	t.Helper()
+	data, err := os.ReadFile(filepath.Join("testdata", name))
+	require.NoError(t, err)
+	return string(data)
+}
+
+func date(value string) *time.Time {
+	t, err := time.Parse(time.RFC3339, value)
+	if err != nil {
+		panic(err)
+	}
+	return &t
+}
+
+func TestParse(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name    string
+		server  string
+		fixture string
+		want    *Record
+		err     error
+	}{
+		{
+			name:    "registry",
+			server:  "whois.verisign-grs.com",
+			fixture: "verisign.txt",
+			want: &Record{
+				Domain:      "example.com",
+				Registrar:   "RESERVED-Internet Assigned Numbers Authority",
+				Created:     date("1995-08-14T04:00:00Z"),
+				Updated:     date("2024-08-14T07:01:34Z"),
+				Expires:     date("2025-08-13T04:00:00Z"),
+				Status:      []string{"clientDeleteProhibited", "clientTransferProhibited", "clientUpdateProhibited"},
+				Nameservers: []string{"a.iana-servers.net", "b.iana-servers.net"},
+				DNSSEC:      true,
+			},
+		},
+		{
+			name:    "registrar",
+			server:  "whois.registrar.test",
+			fixture: "registrar.txt",
+			want: &Record{
+				Domain:      "example.com",
+				Registrar:   "Example Registrar, Inc.",
+				Created:     date("1995-08-14T04:00:00Z"),
+				Updated:     date("2024-08-14T07:01:34Z"),
+				Expires:     date("2025-08-13T04:00:00Z"),
+				Status:      []string{"clientTransferProhibited"},
+				Nameservers: []string{"a.iana-servers.net", "b.iana-servers.net"},
+				DNSSEC:      true,
+			},
+		},
+		{
+			name:    "denic",
+			server:  "whois.denic.de",
+			fixture: "denic.txt",
+			want: &Record{
+				Domain:      "example.de",
+				Updated:     date("2018-03-12T20:44:25Z"),
+				Status:      []string{"connect"},
+				Nameservers: []string{"ns1.example.net", "ns2.example.net"},
+				DNSSEC:      true,
+			},
+		},
+		{
+			name:    "nominet",
+			server:  "whois.nic.uk",
+			fixture: "nominet.txt",
+			want: &Record{
+				Domain:      "example.co.uk",
+				Registrar:   "Example Registrar Ltd [Tag = EXAMPLE]",
+				Created:     date("1996-08-26T00:00:00Z"),
+				Updated:     date("2024-07-25T00:00:00Z"),
+				Expires:     date("2026-08-26T00:00:00Z"),
+				Status:      []string{"Registered until expiry date."},
+				Nameservers: []string{"ns1.example.net", "ns2.example.net"},
+				DNSSEC:      true,
+			},
+		},
+		{
+			name:    "jprs",
+			server:  "whois.jprs.jp",
+			fixture: "jprs.txt",
+			want: &Record{
+				Domain:      "example.jp",
+				Created:     date("2001-05-16T15:00:00Z"),
+				Updated:     date("2024-05-31T16:05:08Z"),
+				Expires:     date("2025-05-30T15:00:00Z"),
+				Status:      []string{"Active"},
+				Nameservers: []string{"ns1.example.jp", "ns2.example.jp"},
+			},
+		},
+		{
+			name:    "afnic",
+			server:  "whois.nic.fr",
+			fixture: "afnic.txt",
+			want: &Record{
+				Domain:      "example.fr",
+				Registrar:   "EXAMPLE REGISTRAR SAS",
+				Created:     date("2000-07-26T22:00:00Z"),
+				Updated:     date("2024-01-02T10:00:00Z"),
+				Expires:     date("2025-12-31T10:00:00Z"),
+				Status:      []string{"ACTIVE"},
+				Nameservers: []string{"ns1.example.fr", "ns2.example.fr"},
+			},
+		},
+		{
+			name:    "not registered",
+			server:  "whois.verisign-grs.com",
+			fixture: "not_found.txt",
+			err:     ErrNotFound,
+		},
+		{
+			name:    "free",
+			server:  "whois.denic.de:43",
+			fixture: "denic_free.txt",
+			err:     ErrNotFound,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+			record, err := Parse(tt.server, fixture(t, tt.fixture))
+			if tt.err != nil {
+				assert.ErrorIs(t, err, tt.err)
+				return
+			}
+			require.NoError(t, err)
+			tt.want.Source = SourceWhois
+			assert.Equal(t, tt.want, record)
+		})
+	}
+
+	t.Run("unrecognised", func(t *testing.T) {
+		t.Parallel()
+		_, err := Parse("whois.verisign-grs.com", "Your connection limit exceeded. Please slow down and try again later.\n")
+		assert.EqualError(t, err, "unrecognised WHOIS response")
+	})
+}
@@ -0,0 +1,333 @@
+package whois
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+)
+
+// BootstrapURL is the IANA registry of RDAP servers for each TLD, RFC 9224.
+const BootstrapURL = "https://data.iana.org/rdap/dns.json"
+
+const rdapType = "application/rdap+json"
+
+const (
+	// maxRegistrySize limits how much of the bootstrap registry is read,
+	// it is around 40KB.
+	maxRegistrySize = 4 << 20
+	// maxRDAPResponse limits how much of an RDAP response is read.
+	maxRDAPResponse = 1 << 20
+)
+
+// Bootstrap finds the RDAP server for a domain from the IANA registry.
+type Bootstrap struct {
+	URL    string
+	Client *http.Client
+	// CacheFile keeps a copy of the registry so it is downloaded at most
+	// once every MaxAge, even across restarts. A stale copy is still used
+	// if the download fails.
+	CacheFile string
+	MaxAge    time.Duration
+
+	mu       sync.Mutex
+	services map[string][]string
+	loaded   time.Time
+	// refresh is the load in progress, callers needing the registry while
+	// it runs wait for it rather than downloading it again.
+	refresh *registryLoad
+}
+
+type registryLoad struct {
+	done     chan struct{}
+	services map[string][]string
+	err      error
+}
+
+func NewBootstrap(client *http.Client, cacheFile string, maxAge time.Duration) *Bootstrap {
+	return &Bootstrap{URL: BootstrapURL, Client: client, CacheFile: cacheFile, MaxAge: maxAge}
+}
+
+// registry is the bootstrap file, each service is a list of TLDs and the
+// base URLs of the RDAP servers for them.
+type registry struct {
+	Services [][][]string `json:"services"`
+}
+
+func parseRegistry(data []byte) (map[string][]string, error) {
+	var r registry
+	if err := json.Unmarshal(data, &r); err != nil {
+		return nil, fmt.Errorf("invalid RDAP bootstrap registry: %w", err)
+	}
+	services := make(map[string][]string)
+	for _, service := range r.Services {
+		if len(service) != 2 {
+			continue
+		}
+		for _, tld := range service[0] {
+			services[strings.ToLower(tld)] = service[1]
+		}
+	}
+	return services, nil
+}
+
+func (b *Bootstrap) download(ctx context.Context) ([]byte, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, b.URL, nil)
+	if err != nil {
+		return nil, err
+	}
+	resp, err := b.Client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("RDAP bootstrap download failed with status %d", resp.StatusCode)
+	}
+	return io.ReadAll(io.LimitReader(resp.Body, maxRegistrySize))
+}
+
+// save writes the registry to the cache file, through a temporary file so
+// a reader never sees it half written.
+func (b *Bootstrap) save(data []byte) error {
+	tmp, err := os.CreateTemp(filepath.Dir(b.CacheFile), filepath.Base(b.CacheFile)+".*")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name())
+	if _, err := tmp.Write(data); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+	return os.Rename(tmp.Name(), b.CacheFile)
+}
+
+// load returns the registry, from memory or the cache file if it is fresh
+// enough and downloading it otherwise. The lock is only held to read and
+// replace the registry in memory, not while it is fetched.
+func (b *Bootstrap) load(ctx context.Context) (map[string][]string, error) {
+	b.mu.Lock()
+	if b.services != nil && time.Since(b.loaded) < b.MaxAge {
+		defer b.mu.Unlock()
+		return b.services, nil
+	}
+	if refresh := b.refresh; refresh != nil {
+		b.mu.Unlock()
+		select {
+		case <-refresh.done:
+			return refresh.services, refresh.err
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		}
+	}
+	refresh := &registryLoad{done: make(chan struct{})}
+	b.refresh = refresh
+	b.mu.Unlock()
+
+	services, loaded, err := b.fetch(ctx)
+
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if err == nil {
+		b.services, b.loaded = services, loaded
+	} else if b.services != nil {
+		services, err = b.services, nil
+	}
+	refresh.services, refresh.err = services, err
+	b.refresh = nil
+	close(refresh.done)
+	return services, err
+}
+
+// fetch reads the registry from the cache file if it is fresh enough and
+// downloads it otherwise, falling back to a stale cache file. It returns
+// when the registry was last downloaded.
+func (b *Bootstrap) fetch(ctx context.Context) (map[string][]string, time.Time, error) {
+	var cached []byte
+	var modified time.Time
+	if b.CacheFile != "" {
+		if info, err := os.Stat(b.CacheFile); err == nil {
+			cached, _ = os.ReadFile(b.CacheFile)
+			modified = info.ModTime()
+		}
+	}
+	if cached != nil && time.Since(modified) < b.MaxAge {
+		if services, err := parseRegistry(cached); err == nil {
+			return services, modified, nil
+		}
+	}
+
+	data, err := b.download(ctx)
+	var services map[string][]string
+	if err == nil {
+		services, err = parseRegistry(data)
+	}
+	if err != nil {
+		if cached != nil {
+			if stale, staleErr := parseRegistry(cached); staleErr == nil {
+				return stale, modified, nil
+			}
+		}
+		return nil, time.Time{}, err
+	}
+	if b.CacheFile != "" {
+		// the registry is still used if it can't be cached
+		b.save(data)
+	}
+	return services, time.Now(), nil
+}
+
+// Server returns the base URL of the RDAP server for domain, matching the
+// longest entry in the registry.
+func (b *Bootstrap) Server(ctx context.Context, domain string) (string, error) {
+	services, err := b.load(ctx)
+	if err != nil {
+		return "", err
+	}
+	labels := strings.Split(strings.ToLower(domain), ".")
+	for i := range labels {
+		urls := services[strings.Join(labels[i:], ".")]
+		// prefer HTTPS where a service lists several servers
+		for _, u := range urls {
+			if strings.HasPrefix(u, "https://") {
+				return u, nil
+			}
+		}
+		if len(urls) > 0 {
+			return urls[0], nil
+		}
+	}
+	return "", ErrNoServer
+}
+
+// RDAPClient looks up domains with the Registration Data Access Protocol,
+// RFC 9083.
+type RDAPClient struct {
+	Client    *http.Client
+	Bootstrap *Bootstrap
+}
+
+func NewRDAPClient(client *http.Client, bootstrap *Bootstrap) *RDAPClient {
+	return &RDAPClient{Client: client, Bootstrap: bootstrap}
+}
+
+type rdapDomain struct {
+	LDHName string   `json:"ldhName"`
+	Status  []string `json:"status"`
+	Events  []struct {
+		Action string    `json:"eventAction"`
+		Date   time.Time `json:"eventDate"`
+	} `json:"events"`
+	Entities []struct {
+		Roles []string `json:"roles"`
+		// VCard is a jCard, RFC 7095: ["vcard", [[name, params, type, value], ...]]
+		VCard []json.RawMessage `json:"vcardArray"`
+	} `json:"entities"`
+	Nameservers []struct {
+		LDHName string `json:"ldhName"`
+	} `json:"nameservers"`
+	SecureDNS struct {
+		DelegationSigned bool `json:"delegationSigned"`
+	} `json:"secureDNS"`
+}
+
+// vcardName returns the fn property of a jCard.
+func vcardName(vcard []json.RawMessage) string {
+	if len(vcard) < 2 {
+		return ""
+	}
+	var properties [][]any
+	if err := json.Unmarshal(vcard[1], &properties); err != nil {
+		return ""
+	}
+	for _, p := range properties {
+		if len(p) == 4 && p[0] == "fn" {
+			name, _ := p[3].(string)
+			return name
+		}
+	}
+	return ""
+}
+
+func (d *rdapDomain) record() *Record {
+	record := &Record{
+		Domain:      strings.TrimSuffix(strings.ToLower(d.LDHName), "."),
+		Status:      d.Status,
+		Nameservers: make([]string, 0, len(d.Nameservers)),
+		DNSSEC:      d.SecureDNS.DelegationSigned,
+		Source:      SourceRDAP,
+	}
+	for _, event := range d.Events {
+		date := event.Date.UTC()
+		switch event.Action {
+		case "registration":
+			record.Created = &date
+		case "expiration":
+			record.Expires = &date
+		case "last changed":
+			record.Updated = &date
+		}
+	}
+	for _, entity := range d.Entities {
+		for _, role := range entity.Roles {
+			if role == "registrar" && record.Registrar == "" {
+				record.Registrar = vcardName(entity.VCard)
+			}
+		}
+	}
+	for _, ns := range d.Nameservers {
+		record.Nameservers = append(record.Nameservers, strings.TrimSuffix(strings.ToLower(ns.LDHName), "."))
+	}
+	return record
+}
+
+// Lookup queries the RDAP server for domain's TLD.
+func (c *RDAPClient) Lookup(ctx context.Context, domain string) (*Record, error) {
+	server, err := c.Bootstrap.Server(ctx, domain)
+	if err != nil {
+		return nil, err
+	}
+	base, err := url.Parse(server)
+	if err != nil {
+		return nil, fmt.Errorf("invalid RDAP server %q: %w", server, err)
+	}
+	endpoint := base.JoinPath("domain", domain)
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, endpoint.String(), nil)
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Accept", rdapType)
+	resp, err := c.Client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	switch {
+	case resp.StatusCode == http.StatusNotFound:
+		return nil, ErrNotFound
+	case resp.StatusCode != http.StatusOK:
+		return nil, fmt.Errorf("RDAP request failed with status %d", resp.StatusCode)
+	}
+
+	var d rdapDomain
+	if err := json.NewDecoder(io.LimitReader(resp.Body, maxRDAPResponse)).Decode(&d); err != nil {
+		return nil, fmt.Errorf("invalid RDAP response: %w", err)
+	}
+	record := d.record()
+	record.Server = base.Host
+	if record.Domain == "" {
+		record.Domain = domain
+	}
+	return record, nil
+}
@@ -0,0 +1,180 @@
+package whois
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const exampleDomain = `{
+	"objectClassName": "domain",
+	"ldhName": "EXAMPLE.COM",
+	"status": ["client delete prohibited", "client transfer prohibited"],
+	"events": [
+		{"eventAction": "registration", "eventDate": "1995-08-14T04:00:00Z"},
+		{"eventAction": "expiration", "eventDate": "2025-08-13T04:00:00Z"},
+		{"eventAction": "last changed", "eventDate": "2024-08-14T07:01:34Z"},
+		{"eventAction": "last update of RDAP database", "eventDate": "2024-09-01T12:00:00Z"}
+	],
+	"entities": [{
+		"objectClassName": "entity",
+		"roles": ["registrar"],
+		"vcardArray": ["vcard", [["version", {}, "text", "4.0"], ["fn", {}, "text", "Example Registrar, Inc."]]]
+	}],
+	"nameservers": [
+		{"objectClassName": "nameserver", "ldhName": "A.IANA-SERVERS.NET"},
+		{"objectClassName": "nameserver", "ldhName": "B.IANA-SERVERS.NET"}
+	],
+	"secureDNS": {"delegationSigned": true}
+}`
+
+// rdapServer serves a bootstrap registry pointing .com at itself, and
+// example.com. It counts the registry downloads.
+func rdapServer(t *testing.T) (*httptest.Server, *atomic.Int32) {
+	var downloads atomic.Int32
+	var srv *httptest.Server
+	srv = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/dns.json":
+			downloads.Add(1)
+			json.NewEncoder(w).Encode(map[string]any{
+				"version": "1.0",
+				"services": [][][]string{
+					{{"net"}, {"http://rdap.invalid/net/"}},
+					{{"com"}, {srv.URL + "/com/"}},
+				},
+			})
+		case "/com/domain/example.com":
+			assert.Equal(t, rdapType, r.Header.Get("Accept"))
+			w.Header().Set("Content-Type", rdapType)
+			w.Write([]byte(exampleDomain))
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	t.Cleanup(srv.Close)
+	return srv, &downloads
+}
+
+func TestRDAPClientLookup(t *testing.T) {
+	t.Parallel()
+
+	srv, _ := rdapServer(t)
+	bootstrap := NewBootstrap(srv.Client(), "", time.Hour)
+	bootstrap.URL = srv.URL + "/dns.json"
+	c := NewRDAPClient(srv.Client(), bootstrap)
+
+	record, err := c.Lookup(context.Background(), "example.com")
+	require.NoError(t, err)
+	assert.Equal(t, &Record{
+		Domain:      "example.com",
+		Registrar:   "Example Registrar, Inc.",
+		Created:     date("1995-08-14T04:00:00Z"),
+		Updated:     date("2024-08-14T07:01:34Z"),
+		Expires:     date("2025-08-13T04:00:00Z"),
+		Status:      []string{"client delete prohibited", "client transfer prohibited"},
+		Nameservers: []string{"a.iana-servers.net", "b.iana-servers.net"},
+		DNSSEC:      true,
+		Source:      SourceRDAP,
+		Server:      srv.Listener.Addr().String(),
+	}, record)
+
+	_, err = c.Lookup(context.Background(), "unregistered-example.com")
+	assert.ErrorIs(t, err, ErrNotFound)
+
+	_, err = c.Lookup(context.Background(), "example.org")
+	assert.ErrorIs(t, err, ErrNoServer)
+}
+
+func TestBootstrapCache(t *testing.T) {
+	t.Parallel()
+
+	srv, downloads := rdapServer(t)
+	cache := filepath.Join(t.TempDir(), "dns.json")
+	newBootstrap := func() *Bootstrap {
+		b := NewBootstrap(srv.Client(), cache, time.Hour)
+		b.URL = srv.URL + "/dns.json"
+		return b
+	}
+
+	server, err := newBootstrap().Server(context.Background(), "www.example.com")
+	require.NoError(t, err)
+	assert.Equal(t, srv.URL+"/com/", server)
+	assert.FileExists(t, cache)
+
+	// a new instance reads the cache rather than downloading again
+	_, err = newBootstrap().Server(context.Background(), "example.com")
+	require.NoError(t, err)
+	assert.Equal(t, int32(1), downloads.Load())
+
+	// a stale cache is refreshed
+	old := time.Now().Add(-2 * time.Hour)
+	require.NoError(t, os.Chtimes(cache, old, old))
+	_, err = newBootstrap().Server(context.Background(), "example.com")
+	require.NoError(t, err)
+	assert.Equal(t, int32(2), downloads.Load())
+
+	// and still used if the registry can't be downloaded
+	require.NoError(t, os.Chtimes(cache, old, old))
+	b := newBootstrap()
+	b.URL = srv.URL + "/missing.json"
+	server, err = b.Server(context.Background(), "example.com")
+	require.NoError(t, err)
+	assert.Equal(t, srv.URL+"/com/", server)
+
+	t.Run("no cache", func(t *testing.T) {
+		t.Parallel()
+		b := NewBootstrap(srv.Client(), filepath.Join(t.TempDir(), "dns.json"), time.Hour)
+		b.URL = srv.URL + "/missing.json"
+		_, err := b.Server(context.Background(), "example.com")
+		assert.EqualError(t, err, "RDAP bootstrap download failed with status 404")
+	})
+}
+
+func TestBootstrapConcurrentLoad(t *testing.T) {
+	t.Parallel()
+
+	started := make(chan struct{})
+	release := make(chan struct{})
+	var downloads atomic.Int32
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if downloads.Add(1) == 1 {
+			close(started)
+		}
+		<-release
+		w.Write([]byte(`{"services": [[["com"], ["https://rdap.example/com/"]]]}`))
+	}))
+	t.Cleanup(srv.Close)
+	b := NewBootstrap(srv.Client(), "", time.Hour)
+	b.URL = srv.URL
+
+	servers := make(chan string, 2)
+	for range 2 {
+		go func() {
+			server, err := b.Server(context.Background(), "example.com")
+			assert.NoError(t, err)
+			servers <- server
+		}()
+	}
+	<-started
+
+	// a caller that gives up isn't held until the slow download finishes
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+	_, err := b.Server(ctx, "example.com")
+	assert.ErrorIs(t, err, context.DeadlineExceeded)
+
+	close(release)
+	assert.Equal(t, "https://rdap.example/com/", <-servers)
+	assert.Equal(t, "https://rdap.example/com/", <-servers)
+	assert.Equal(t, int32(1), downloads.Load())
+}
@@ -0,0 +1,193 @@
+package whois
+
+import (
+	"bufio"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"strings"
+	"sync"
+	"time"
+)
+
+// ianaServer is asked which server holds the WHOIS data for a TLD.
+const ianaServer = "whois.iana.org"
+
+// maxResponseSize limits how much of a WHOIS response is read.
+const maxResponseSize = 1 << 20
+
+// Sources a Record can come from.
+const (
+	SourceRDAP  = "rdap"
+	SourceWhois = "whois"
+)
+
+var (
+	ErrNotFound = errors.New("domain not registered")
+	ErrNoServer = errors.New("no registration data server for TLD")
+)
+
+// Record is the registration data for a domain.
+type Record struct {
+	Domain      string     `json:"domain"`
+	Registrar   string     `json:"registrar"`
+	Created     *time.Time `json:"created"`
+	Updated     *time.Time `json:"updated"`
+	Expires     *time.Time `json:"expires"`
+	Status      []string   `json:"status"`
+	Nameservers []string   `json:"nameservers"`
+	DNSSEC      bool       `json:"dnssec"`
+	// Source is SourceRDAP or SourceWhois and Server the server that
+	// answered.
+	Source string `json:"source"`
+	Server string `json:"server"`
+}
+
+// merge fills the fields missing from r with those from other.
+func (r *Record) merge(other *Record) {
+	if r.Registrar == "" {
+		r.Registrar = other.Registrar
+	}
+	if r.Created == nil {
+		r.Created = other.Created
+	}
+	if r.Updated == nil {
+		r.Updated = other.Updated
+	}
+	if r.Expires == nil {
+		r.Expires = other.Expires
+	}
+	if len(r.Status) == 0 {
+		r.Status = other.Status
+	}
+	if len(r.Nameservers) == 0 {
+		r.Nameservers = other.Nameservers
+	}
+	r.DNSSEC = r.DNSSEC || other.DNSSEC
+}
+
+type Dialer interface {
+	DialContext(ctx context.Context, network, address string) (net.Conn, error)
+}
+
+// Client looks up domains with the WHOIS protocol on port 43, RFC 3912.
+type Client struct {
+	Dialer  Dialer
+	Timeout time.Duration
+
+	mu sync.Mutex
+	// servers caches the WHOIS server IANA refers each TLD to.
+	servers map[string]string
+}
+
+func NewClient(dialer Dialer, timeout time.Duration) *Client {
+	return &Client{Dialer: dialer, Timeout: timeout, servers: make(map[string]string)}
+}
+
+// Query sends query to server and returns the response.
+func (c *Client) Query(ctx context.Context, server, query string) (string, error) {
+	address := server
+	if _, _, err := net.SplitHostPort(server); err != nil {
+		address = net.JoinHostPort(server, "43")
+	}
+	conn, err := c.Dialer.DialContext(ctx, "tcp", address)
+	if err != nil {
+		return "", fmt.Errorf("error connecting to %s: %w", server, err)
+	}
+	defer conn.Close()
+	deadline := time.Now().Add(c.Timeout)
+	if d, ok := ctx.Deadline(); ok && (c.Timeout == 0 || d.Before(deadline)) {
+		deadline = d
+	}
+	if !deadline.IsZero() {
+		conn.SetDeadline(deadline)
+	}
+
+	if _, err := io.WriteString(conn, query+"\r\n"); err != nil {
+		return "", err
+	}
+	resp, err := io.ReadAll(io.LimitReader(conn, maxResponseSize))
+	if err != nil {
+		return "", fmt.Errorf("error reading from %s: %w", server, err)
+	}
+	return string(resp), nil
+}
+
+// Server returns the WHOIS server for the TLD of domain.
+func (c *Client) Server(ctx context.Context, domain string) (string, error) {
+	tld := domain[strings.LastIndex(domain, ".")+1:]
+	c.mu.Lock()
+	server, ok := c.servers[tld]
+	c.mu.Unlock()
+	if ok {
+		return server, nil
+	}
+
+	resp, err := c.Query(ctx, ianaServer, tld)
+	if err != nil {
+		return "", err
+	}
+	server = referral(resp, "whois", "refer")
+	if server == "" {
+		return "", ErrNoServer
+	}
+	c.mu.Lock()
+	c.servers[tld] = server
+	c.mu.Unlock()
+	return server, nil
+}
+
+// referral returns the value of the first line starting with one of keys.
+func referral(resp string, keys ...string) string {
+	scanner := bufio.NewScanner(strings.NewReader(resp))
+	for scanner.Scan() {
+		key, value, ok := strings.Cut(scanner.Text(), ":")
+		if !ok {
+			continue
+		}
+		key = strings.ToLower(strings.TrimSpace(key))
+		for _, k := range keys {
+			if key == k {
+				if value = strings.TrimSpace(value); value != "" {
+					return strings.ToLower(value)
+				}
+			}
+		}
+	}
+	return ""
+}
+
+// Lookup queries the WHOIS server for domain's TLD, following a referral
+// to the registrar's server for any fields the registry leaves out.
+func (c *Client) Lookup(ctx context.Context, domain string) (*Record, error) {
+	server, err := c.Server(ctx, domain)
+	if err != nil {
+		return nil, err
+	}
+	resp, err := c.Query(ctx, server, FormatFor(server).query(domain))
+	if err != nil {
+		return nil, err
+	}
+	record, err := Parse(server, resp)
+	if err != nil {
+		return nil, err
+	}
+	record.Server = server
+
+	registrar := referral(resp, "registrar whois server")
+	registrar = strings.TrimPrefix(strings.TrimPrefix(registrar, "whois://"), "http://")
+	if registrar != "" && registrar != server {
+		// the registry answer is still useful if the registrar's isn't
+		if resp, err := c.Query(ctx, registrar, FormatFor(registrar).query(domain)); err == nil {
+			if other, err := Parse(registrar, resp); err == nil {
+				record.merge(other)
+			}
+		}
+	}
+	if record.Domain == "" {
+		record.Domain = domain
+	}
+	return record, nil
+}
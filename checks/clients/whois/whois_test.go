@@ -0,0 +1,112 @@
+package whois
+
+import (
+	"bufio"
+	"context"
+	"errors"
+	"net"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type dialFunc func(ctx context.Context, network, address string) (net.Conn, error)
+
+func (fn dialFunc) DialContext(ctx context.Context, network, address string) (net.Conn, error) {
+	return fn(ctx, network, address)
+}
+
+// servers answers WHOIS queries from responses, keyed by address, and
+// records the queries it was sent.
+func servers(responses map[string]string) (Dialer, func() []string) {
+	var mu sync.Mutex
+	var queries []string
+	dialer := dialFunc(func(ctx context.Context, network, address string) (net.Conn, error) {
+		resp, ok := responses[address]
+		if !ok {
+			return nil, errors.New("connection refused")
+		}
+		server, client := net.Pipe()
+		go func() {
+			defer server.Close()
+			query, _ := bufio.NewReader(server).ReadString('\n')
+			mu.Lock()
+			queries = append(queries, address+" "+query[:len(query)-2])
+			mu.Unlock()
+			server.Write([]byte(resp))
+		}()
+		return client, nil
+	})
+	return dialer, func() []string {
+		mu.Lock()
+		defer mu.Unlock()
+		return append([]string(nil), queries...)
+	}
+}
+
+func TestClientLookup(t *testing.T) {
+	t.Parallel()
+
+	dialer, queries := servers(map[string]string{
+		"whois.iana.org:43":         "domain:       COM\n\nwhois:        whois.verisign-grs.com\n",
+		"whois.verisign-grs.com:43": "Domain Name: EXAMPLE.COM\nRegistrar WHOIS Server: whois.registrar.test\nRegistry Expiry Date: 2025-08-13T04:00:00Z\nName Server: A.IANA-SERVERS.NET\n",
+		"whois.registrar.test:43":   fixture(t, "registrar.txt"),
+	})
+	c := NewClient(dialer, time.Second)
+
+	record, err := c.Lookup(context.Background(), "example.com")
+	require.NoError(t, err)
+	assert.Equal(t, &Record{
+		Domain:    "example.com",
+		Registrar: "Example Registrar, Inc.",
+		Created:   date("1995-08-14T04:00:00Z"),
+		Updated:   date("2024-08-14T07:01:34Z"),
+		Expires:   date("2025-08-13T04:00:00Z"),
+		Status:    []string{"clientTransferProhibited"},
+		// the registry's answer is kept where it has one
+		Nameservers: []string{"a.iana-servers.net"},
+		DNSSEC:      true,
+		Source:      SourceWhois,
+		Server:      "whois.verisign-grs.com",
+	}, record)
+
+	// the server for the TLD is only asked for once
+	_, err = c.Lookup(context.Background(), "example.com")
+	require.NoError(t, err)
+	assert.Equal(t, []string{
+		"whois.iana.org:43 com",
+		"whois.verisign-grs.com:43 example.com",
+		"whois.registrar.test:43 example.com",
+		"whois.verisign-grs.com:43 example.com",
+		"whois.registrar.test:43 example.com",
+	}, queries())
+
+	t.Run("query format", func(t *testing.T) {
+		t.Parallel()
+		dialer, queries := servers(map[string]string{
+			"whois.iana.org:43": "refer:        whois.jprs.jp\n",
+			"whois.jprs.jp:43":  fixture(t, "jprs.txt"),
+		})
+		record, err := NewClient(dialer, time.Second).Lookup(context.Background(), "example.jp")
+		require.NoError(t, err)
+		assert.Equal(t, "example.jp", record.Domain)
+		assert.Equal(t, []string{"whois.iana.org:43 jp", "whois.jprs.jp:43 example.jp/e"}, queries())
+	})
+
+	t.Run("no server", func(t *testing.T) {
+		t.Parallel()
+		dialer, _ := servers(map[string]string{"whois.iana.org:43": "% This query returned 0 objects.\n"})
+		_, err := NewClient(dialer, time.Second).Lookup(context.Background(), "example.invalid")
+		assert.ErrorIs(t, err, ErrNoServer)
+	})
+
+	t.Run("unreachable", func(t *testing.T) {
+		t.Parallel()
+		dialer, _ := servers(nil)
+		_, err := NewClient(dialer, time.Second).Lookup(context.Background(), "example.com")
+		assert.EqualError(t, err, "error connecting to whois.iana.org: connection refused")
+	})
+}
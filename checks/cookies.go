@@ -0,0 +1,75 @@
+package checks
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/xray-web/web-check-api/checks/clients/browser"
+)
+
+type CookiesData struct {
+	HeaderCookies []string         `json:"headerCookies"`
+	ClientCookies []map[string]any `json:"clientCookies"`
+}
+
+type Cookies struct {
+	client  *http.Client
+	browser browser.Cookies
+}
+
+func NewCookies(client *http.Client, browser browser.Cookies) *Cookies {
+	return &Cookies{client: client, browser: browser}
+}
+
+// GetCookies returns the cookies set in the response headers and those set
+// once the page has been rendered in a browser.
+func (c *Cookies) GetCookies(ctx context.Context, url string) (*CookiesData, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
+	if err != nil {
+		return nil, fmt.Errorf("request failed: %v", err)
+	}
+	resp, err := c.client.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("request failed: %v", err)
+	}
+	defer resp.Body.Close()
+
+	clientCookies, err := c.browser.GetCookies(ctx, url)
+	if err != nil {
+		clientCookies = nil
+	}
+
+	return &CookiesData{
+		HeaderCookies: resp.Header.Values("Set-Cookie"),
+		ClientCookies: clientCookies,
+	}, nil
+}
+
+func (c *Cookies) Name() string {
+	return "cookies"
+}
+
+func (c *Cookies) Description() string {
+	return "Lists the cookies set by the site, both in headers and by client side scripts"
+}
+
+func (c *Cookies) Input() Input {
+	return InputURL
+}
+
+func (c *Cookies) Timeout() time.Duration {
+	return 30 * time.Second
+}
+
+func (c *Cookies) Run(ctx context.Context, target Target) (any, error) {
+	cookies, err := c.GetCookies(ctx, target.String())
+	if err != nil {
+		return nil, err
+	}
+	if len(cookies.HeaderCookies) == 0 && len(cookies.ClientCookies) == 0 {
+		return Skipped{Skipped: "No cookies"}, nil
+	}
+	return cookies, nil
+}
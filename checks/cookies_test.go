@@ -0,0 +1,43 @@
+package checks
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/url"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/xray-web/web-check-api/checks/clients/browser"
+	"github.com/xray-web/web-check-api/testutils"
+)
+
+func TestGetCookies(t *testing.T) {
+	t.Parallel()
+
+	t.Run("header and client cookies", func(t *testing.T) {
+		t.Parallel()
+		resp := testutils.Response(http.StatusOK, nil)
+		resp.Header = http.Header{"Set-Cookie": {"session=abc; HttpOnly"}}
+		c := NewCookies(testutils.MockClient(resp), browser.CookiesFunc(func(ctx context.Context, rawURL string) ([]map[string]any, error) {
+			return []map[string]any{{"name": "theme"}}, nil
+		}))
+
+		actual, err := c.GetCookies(context.Background(), "http://example.com")
+		assert.NoError(t, err)
+		assert.Equal(t, []string{"session=abc; HttpOnly"}, actual.HeaderCookies)
+		assert.Equal(t, []map[string]any{{"name": "theme"}}, actual.ClientCookies)
+	})
+
+	t.Run("no cookies", func(t *testing.T) {
+		t.Parallel()
+		c := NewCookies(testutils.MockClient(testutils.Response(http.StatusOK, nil)), browser.CookiesFunc(func(ctx context.Context, rawURL string) ([]map[string]any, error) {
+			return nil, errors.New("no browser")
+		}))
+
+		u, _ := url.Parse("http://example.com")
+		actual, err := c.Run(context.Background(), Target{URL: u})
+		assert.NoError(t, err)
+		assert.Equal(t, Skipped{Skipped: "No cookies"}, actual)
+	})
+}
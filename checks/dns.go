@@ -0,0 +1,403 @@
+package checks
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/miekg/dns"
+	"github.com/xray-web/web-check-api/checks/clients/ip"
+)
+
+// ErrDomainNotFound is returned when a DNS server answers NXDOMAIN.
+var ErrDomainNotFound = errors.New("domain does not exist")
+
+type AddressRecord struct {
+	Address string `json:"address"`
+	TTL     uint32 `json:"ttl"`
+}
+
+type MXRecord struct {
+	Host       string `json:"host"`
+	Preference uint16 `json:"preference"`
+	TTL        uint32 `json:"ttl"`
+}
+
+type TXTRecord struct {
+	Value string `json:"value"`
+	TTL   uint32 `json:"ttl"`
+}
+
+type NSRecord struct {
+	Host string `json:"host"`
+	TTL  uint32 `json:"ttl"`
+}
+
+type CNAMERecord struct {
+	Target string `json:"target"`
+	TTL    uint32 `json:"ttl"`
+}
+
+type SOARecord struct {
+	PrimaryNS  string `json:"primaryNs"`
+	Mailbox    string `json:"mailbox"`
+	Serial     uint32 `json:"serial"`
+	Refresh    uint32 `json:"refresh"`
+	Retry      uint32 `json:"retry"`
+	Expire     uint32 `json:"expire"`
+	MinimumTTL uint32 `json:"minimumTtl"`
+	TTL        uint32 `json:"ttl"`
+}
+
+type CAARecord struct {
+	// Name is where the record was found, CAA records are inherited from
+	// parent domains.
+	Name  string `json:"name"`
+	Flag  uint8  `json:"flag"`
+	Tag   string `json:"tag"`
+	Value string `json:"value"`
+	TTL   uint32 `json:"ttl"`
+}
+
+type TLSARecord struct {
+	Usage        uint8  `json:"usage"`
+	Selector     uint8  `json:"selector"`
+	MatchingType uint8  `json:"matchingType"`
+	Certificate  string `json:"certificate"`
+	TTL          uint32 `json:"ttl"`
+}
+
+type DSRecord struct {
+	KeyTag     uint16 `json:"keyTag"`
+	Algorithm  uint8  `json:"algorithm"`
+	DigestType uint8  `json:"digestType"`
+	Digest     string `json:"digest"`
+	TTL        uint32 `json:"ttl"`
+}
+
+type SVCBRecord struct {
+	Priority uint16            `json:"priority"`
+	Target   string            `json:"target"`
+	Params   map[string]string `json:"params"`
+	TTL      uint32            `json:"ttl"`
+}
+
+type NAPTRRecord struct {
+	Order       uint16 `json:"order"`
+	Preference  uint16 `json:"preference"`
+	Flags       string `json:"flags"`
+	Service     string `json:"service"`
+	Regexp      string `json:"regexp"`
+	Replacement string `json:"replacement"`
+	TTL         uint32 `json:"ttl"`
+}
+
+type PTRRecord struct {
+	Address string `json:"address"`
+	Host    string `json:"host"`
+	TTL     uint32 `json:"ttl"`
+}
+
+// DNSResponse holds the records of a host by type. Lookups that fail are
+// left empty with the reason in Errors keyed by record type.
+type DNSResponse struct {
+	A      []AddressRecord   `json:"A"`
+	AAAA   []AddressRecord   `json:"AAAA"`
+	MX     []MXRecord        `json:"MX"`
+	TXT    []TXTRecord       `json:"TXT"`
+	NS     []NSRecord        `json:"NS"`
+	CNAME  []CNAMERecord     `json:"CNAME"`
+	SOA    *SOARecord        `json:"SOA"`
+	CAA    []CAARecord       `json:"CAA"`
+	TLSA   []TLSARecord      `json:"TLSA"`
+	DS     []DSRecord        `json:"DS"`
+	HTTPS  []SVCBRecord      `json:"HTTPS"`
+	SVCB   []SVCBRecord      `json:"SVCB"`
+	NAPTR  []NAPTRRecord     `json:"NAPTR"`
+	PTR    []PTRRecord       `json:"PTR"`
+	Errors map[string]string `json:"errors,omitempty"`
+}
+
+type Dns struct {
+	exchanger ip.Exchanger
+}
+
+func NewDns(exchanger ip.Exchanger) *Dns {
+	return &Dns{exchanger: exchanger}
+}
+
+// queryDNS sends a recursive query for name and returns the answers of
+// type qtype, a name with no records of that type is not an error.
+func queryDNS(ctx context.Context, exchanger ip.Exchanger, name string, qtype uint16) ([]dns.RR, error) {
+	msg := new(dns.Msg)
+	msg.SetQuestion(dns.Fqdn(name), qtype)
+	msg.SetEdns0(4096, false)
+
+	resp, err := exchanger.Exchange(ctx, msg)
+	if err != nil {
+		return nil, err
+	}
+	switch resp.Rcode {
+	case dns.RcodeSuccess:
+	case dns.RcodeNameError:
+		return nil, fmt.Errorf("%s: %w", strings.TrimSuffix(name, "."), ErrDomainNotFound)
+	default:
+		return nil, fmt.Errorf("query failed: %s", dns.RcodeToString[resp.Rcode])
+	}
+
+	var answers []dns.RR
+	for _, rr := range resp.Answer {
+		if rr.Header().Rrtype == qtype {
+			answers = append(answers, rr)
+		}
+	}
+	return answers, nil
+}
+
+// zoneNameservers returns the closest zone containing name and its sorted
+// NS records.
+func zoneNameservers(ctx context.Context, exchanger ip.Exchanger, name string) (string, []string, error) {
+	labels := dns.SplitDomainName(name)
+	for i := range labels {
+		zone := dns.Fqdn(strings.Join(labels[i:], "."))
+		records, err := queryDNS(ctx, exchanger, zone, dns.TypeNS)
+		if errors.Is(err, ErrDomainNotFound) {
+			continue
+		}
+		if err != nil {
+			return "", nil, err
+		}
+		var nameservers []string
+		for _, rr := range records {
+			nameservers = append(nameservers, rr.(*dns.NS).Ns)
+		}
+		if len(nameservers) > 0 {
+			sort.Strings(nameservers)
+			return zone, nameservers, nil
+		}
+	}
+	return "", nil, errors.New("no nameservers found")
+}
+
+// GetRecords looks up every supported record type for hostname
+// concurrently. An error is only returned if every lookup failed.
+func (d *Dns) GetRecords(ctx context.Context, hostname string) (*DNSResponse, error) {
+	response := &DNSResponse{
+		A:      make([]AddressRecord, 0),
+		AAAA:   make([]AddressRecord, 0),
+		MX:     make([]MXRecord, 0),
+		TXT:    make([]TXTRecord, 0),
+		NS:     make([]NSRecord, 0),
+		CNAME:  make([]CNAMERecord, 0),
+		CAA:    make([]CAARecord, 0),
+		TLSA:   make([]TLSARecord, 0),
+		DS:     make([]DSRecord, 0),
+		HTTPS:  make([]SVCBRecord, 0),
+		SVCB:   make([]SVCBRecord, 0),
+		NAPTR:  make([]NAPTRRecord, 0),
+		PTR:    make([]PTRRecord, 0),
+		Errors: make(map[string]string),
+	}
+
+	var wg sync.WaitGroup
+	var mu sync.Mutex
+	succeeded := 0
+	lookup := func(qtype uint16, name string, add func(rr dns.RR)) {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			answers, err := queryDNS(ctx, d.exchanger, name, qtype)
+			// only a missing host is worth reporting, not missing TLSA or
+			// PTR names below or beside it
+			if errors.Is(err, ErrDomainNotFound) && name != hostname {
+				err = nil
+			}
+			mu.Lock()
+			defer mu.Unlock()
+			if err != nil {
+				response.Errors[dns.TypeToString[qtype]] = err.Error()
+				return
+			}
+			succeeded++
+			for _, rr := range answers {
+				add(rr)
+			}
+		}()
+	}
+
+	lookup(dns.TypeA, hostname, func(rr dns.RR) {
+		response.A = append(response.A, AddressRecord{Address: rr.(*dns.A).A.String(), TTL: rr.Header().Ttl})
+	})
+	lookup(dns.TypeAAAA, hostname, func(rr dns.RR) {
+		response.AAAA = append(response.AAAA, AddressRecord{Address: rr.(*dns.AAAA).AAAA.String(), TTL: rr.Header().Ttl})
+	})
+	lookup(dns.TypeMX, hostname, func(rr dns.RR) {
+		mx := rr.(*dns.MX)
+		response.MX = append(response.MX, MXRecord{Host: mx.Mx, Preference: mx.Preference, TTL: mx.Hdr.Ttl})
+	})
+	lookup(dns.TypeTXT, hostname, func(rr dns.RR) {
+		txt := rr.(*dns.TXT)
+		response.TXT = append(response.TXT, TXTRecord{Value: strings.Join(txt.Txt, ""), TTL: txt.Hdr.Ttl})
+	})
+	lookup(dns.TypeNS, hostname, func(rr dns.RR) {
+		ns := rr.(*dns.NS)
+		response.NS = append(response.NS, NSRecord{Host: ns.Ns, TTL: ns.Hdr.Ttl})
+	})
+	lookup(dns.TypeCNAME, hostname, func(rr dns.RR) {
+		cname := rr.(*dns.CNAME)
+		response.CNAME = append(response.CNAME, CNAMERecord{Target: cname.Target, TTL: cname.Hdr.Ttl})
+	})
+	lookup(dns.TypeSOA, hostname, func(rr dns.RR) {
+		soa := rr.(*dns.SOA)
+		response.SOA = &SOARecord{
+			PrimaryNS:  soa.Ns,
+			Mailbox:    soa.Mbox,
+			Serial:     soa.Serial,
+			Refresh:    soa.Refresh,
+			Retry:      soa.Retry,
+			Expire:     soa.Expire,
+			MinimumTTL: soa.Minttl,
+			TTL:        soa.Hdr.Ttl,
+		}
+	})
+	lookup(dns.TypeTLSA, "_443._tcp."+hostname, func(rr dns.RR) {
+		tlsa := rr.(*dns.TLSA)
+		response.TLSA = append(response.TLSA, TLSARecord{
+			Usage:        tlsa.Usage,
+			Selector:     tlsa.Selector,
+			MatchingType: tlsa.MatchingType,
+			Certificate:  tlsa.Certificate,
+			TTL:          tlsa.Hdr.Ttl,
+		})
+	})
+	lookup(dns.TypeDS, hostname, func(rr dns.RR) {
+		ds := rr.(*dns.DS)
+		response.DS = append(response.DS, DSRecord{
+			KeyTag:     ds.KeyTag,
+			Algorithm:  ds.Algorithm,
+			DigestType: ds.DigestType,
+			Digest:     ds.Digest,
+			TTL:        ds.Hdr.Ttl,
+		})
+	})
+	lookup(dns.TypeHTTPS, hostname, func(rr dns.RR) {
+		response.HTTPS = append(response.HTTPS, newSVCBRecord(&rr.(*dns.HTTPS).SVCB))
+	})
+	lookup(dns.TypeSVCB, hostname, func(rr dns.RR) {
+		response.SVCB = append(response.SVCB, newSVCBRecord(rr.(*dns.SVCB)))
+	})
+	lookup(dns.TypeNAPTR, hostname, func(rr dns.RR) {
+		naptr := rr.(*dns.NAPTR)
+		response.NAPTR = append(response.NAPTR, NAPTRRecord{
+			Order:       naptr.Order,
+			Preference:  naptr.Preference,
+			Flags:       naptr.Flags,
+			Service:     naptr.Service,
+			Regexp:      naptr.Regexp,
+			Replacement: naptr.Replacement,
+			TTL:         naptr.Hdr.Ttl,
+		})
+	})
+
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		caa, err := d.lookupCAA(ctx, hostname)
+		mu.Lock()
+		defer mu.Unlock()
+		if err != nil {
+			response.Errors["CAA"] = err.Error()
+			return
+		}
+		succeeded++
+		response.CAA = append(response.CAA, caa...)
+	}()
+	wg.Wait()
+
+	// reverse lookups need the addresses so run once they're known
+	for _, addr := range append(response.A, response.AAAA...) {
+		name, err := dns.ReverseAddr(addr.Address)
+		if err != nil {
+			continue
+		}
+		lookup(dns.TypePTR, name, func(rr dns.RR) {
+			ptr := rr.(*dns.PTR)
+			response.PTR = append(response.PTR, PTRRecord{Address: addr.Address, Host: ptr.Ptr, TTL: ptr.Hdr.Ttl})
+		})
+	}
+	wg.Wait()
+
+	if succeeded == 0 {
+		return nil, errors.New(response.Errors["A"])
+	}
+	return response, nil
+}
+
+// lookupCAA returns the CAA records that apply to hostname, the closest
+// ancestor with any CAA records is used, RFC 8659 section 3.
+func (d *Dns) lookupCAA(ctx context.Context, hostname string) ([]CAARecord, error) {
+	labels := dns.SplitDomainName(hostname)
+	var records []CAARecord
+	for i := range labels {
+		name := strings.Join(labels[i:], ".")
+		answers, err := queryDNS(ctx, d.exchanger, name, dns.TypeCAA)
+		// a missing subdomain can still inherit from its parent
+		if err != nil && i == 0 && !errors.Is(err, ErrDomainNotFound) {
+			return nil, err
+		}
+		for _, rr := range answers {
+			caa := rr.(*dns.CAA)
+			records = append(records, CAARecord{
+				Name:  name,
+				Flag:  caa.Flag,
+				Tag:   caa.Tag,
+				Value: caa.Value,
+				TTL:   caa.Hdr.Ttl,
+			})
+		}
+		if len(records) > 0 {
+			break
+		}
+	}
+	return records, nil
+}
+
+func newSVCBRecord(svcb *dns.SVCB) SVCBRecord {
+	params := make(map[string]string, len(svcb.Value))
+	for _, kv := range svcb.Value {
+		params[kv.Key().String()] = kv.String()
+	}
+	return SVCBRecord{
+		Priority: svcb.Priority,
+		Target:   svcb.Target,
+		Params:   params,
+		TTL:      svcb.Hdr.Ttl,
+	}
+}
+
+func (d *Dns) Name() string {
+	return "dns"
+}
+
+func (d *Dns) Description() string {
+	return "Resolves the address, mail, name server, SOA, CAA, TLSA, DS, HTTPS, SVCB, NAPTR and PTR records of the host"
+}
+
+func (d *Dns) Input() Input {
+	return InputHostname
+}
+
+func (d *Dns) Run(ctx context.Context, target Target) (any, error) {
+	ctx, cancel := context.WithTimeout(ctx, 10*time.Second)
+	defer cancel()
+
+	records, err := d.GetRecords(ctx, target.Hostname())
+	if err != nil {
+		return nil, fmt.Errorf("error resolving DNS: %v", err)
+	}
+	return records, nil
+}
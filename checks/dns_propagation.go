@@ -0,0 +1,278 @@
+package checks
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"slices"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/miekg/dns"
+	"github.com/xray-web/web-check-api/checks/clients/ip"
+)
+
+// ErrUnsupportedRecordType is returned for record types that can't be compared
+// across resolvers.
+var ErrUnsupportedRecordType = fmt.Errorf("%w: unsupported record type", ErrInvalidParams)
+
+// propagationTypes are the record types that can be compared across
+// resolvers.
+var propagationTypes = []string{"A", "AAAA", "CAA", "CNAME", "DS", "HTTPS", "MX", "NS", "SOA", "SRV", "TXT"}
+
+const (
+	PropagationConsistent = "consistent"
+	PropagationDivergent  = "divergent"
+	PropagationStale      = "stale"
+	PropagationServFail   = "servfail"
+	PropagationNotFound   = "nxdomain"
+	PropagationError      = "error"
+)
+
+// PropagationResult is the answer a single resolver gave.
+type PropagationResult struct {
+	Server   string   `json:"server"`
+	ServerIP string   `json:"serverIp"`
+	Status   string   `json:"status"`
+	Rcode    string   `json:"rcode,omitempty"`
+	Answers  []string `json:"answers"`
+	TTL      uint32   `json:"ttl"`
+	Latency  int64    `json:"latencyMs"`
+	Error    string   `json:"error,omitempty"`
+}
+
+type DnsPropagationReport struct {
+	Domain string `json:"domain"`
+	Type   string `json:"type"`
+	// Reference is where Expected came from, either the authoritative
+	// nameserver or the most common answer among the resolvers.
+	Reference     string              `json:"reference"`
+	Authoritative string              `json:"authoritative,omitempty"`
+	Expected      []string            `json:"expected"`
+	ExpectedTTL   uint32              `json:"expectedTtl,omitempty"`
+	Consistent    int                 `json:"consistent"`
+	Total         int                 `json:"total"`
+	Propagated    bool                `json:"propagated"`
+	Resolvers     []PropagationResult `json:"resolvers"`
+}
+
+type DnsPropagation struct {
+	catalogue CatalogueSource
+	resolver  ip.Exchanger
+	exchanger func(server DNSServer) ip.Exchanger
+}
+
+// NewDnsPropagation returns a check that compares the answers of the
+// resolvers in the catalogue. resolver is used to find the authoritative
+// nameservers and exchanger connects to a resolver, or to a
+// nameserver given only by IP.
+func NewDnsPropagation(catalogue CatalogueSource, resolver ip.Exchanger, exchanger func(server DNSServer) ip.Exchanger) *DnsPropagation {
+	return &DnsPropagation{catalogue: catalogue, resolver: resolver, exchanger: exchanger}
+}
+
+// propagationAnswer is a normalised answer set.
+type propagationAnswer struct {
+	rcode   int
+	answers []string
+	ttl     uint32
+}
+
+func (d *DnsPropagation) query(ctx context.Context, exchanger ip.Exchanger, name string, qtype uint16) (*propagationAnswer, error) {
+	msg := new(dns.Msg)
+	msg.SetQuestion(dns.Fqdn(name), qtype)
+	msg.SetEdns0(4096, false)
+
+	resp, err := exchanger.Exchange(ctx, msg)
+	if err != nil {
+		return nil, err
+	}
+
+	answer := &propagationAnswer{rcode: resp.Rcode, answers: make([]string, 0)}
+	for _, rr := range resp.Answer {
+		if rr.Header().Rrtype != qtype {
+			continue
+		}
+		value := strings.TrimSpace(strings.TrimPrefix(rr.String(), rr.Header().String()))
+		answer.answers = append(answer.answers, strings.ToLower(value))
+		if answer.ttl == 0 || rr.Header().Ttl > answer.ttl {
+			answer.ttl = rr.Header().Ttl
+		}
+	}
+	sort.Strings(answer.answers)
+	answer.answers = slices.Compact(answer.answers)
+	return answer, nil
+}
+
+// authoritative asks a nameserver of the zone containing name for the
+// record directly, returning the nameserver queried and its answer.
+func (d *DnsPropagation) authoritative(ctx context.Context, name string, qtype uint16) (string, *propagationAnswer, error) {
+	_, nameservers, err := zoneNameservers(ctx, d.resolver, name)
+	if err != nil {
+		return "", nil, err
+	}
+
+	var lastErr error
+	for _, ns := range nameservers {
+		addrs, err := queryDNS(ctx, d.resolver, ns, dns.TypeA)
+		if err != nil {
+			lastErr = err
+			continue
+		}
+		for _, rr := range addrs {
+			answer, err := d.query(ctx, d.exchanger(DNSServer{IP: rr.(*dns.A).A.String()}), name, qtype)
+			if err != nil {
+				lastErr = err
+				continue
+			}
+			return strings.TrimSuffix(ns, "."), answer, nil
+		}
+	}
+	if lastErr == nil {
+		lastErr = errors.New("no nameserver addresses found")
+	}
+	return "", nil, lastErr
+}
+
+// Check queries every resolver for the recordType records of domain in
+// parallel and compares them with the authoritative answer. If the
+// authoritative nameserver can't be reached, or doesn't answer with the
+// record itself (for example because the name is a CNAME to another
+// zone), the most common answer among the resolvers is expected instead.
+func (d *DnsPropagation) Check(ctx context.Context, domain, recordType string) (*DnsPropagationReport, error) {
+	recordType = strings.ToUpper(recordType)
+	if !slices.Contains(propagationTypes, recordType) {
+		return nil, fmt.Errorf("%w: %s", ErrUnsupportedRecordType, recordType)
+	}
+	qtype := dns.StringToType[recordType]
+	servers := d.catalogue.Catalogue().Resolvers
+
+	report := &DnsPropagationReport{
+		Domain:    domain,
+		Type:      recordType,
+		Total:     len(servers),
+		Resolvers: make([]PropagationResult, len(servers)),
+	}
+
+	var wg sync.WaitGroup
+	wg.Add(1)
+	var authNS string
+	var auth *propagationAnswer
+	go func() {
+		defer wg.Done()
+		authNS, auth, _ = d.authoritative(ctx, domain, qtype)
+	}()
+
+	answers := make([]*propagationAnswer, len(servers))
+	for i, server := range servers {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			start := time.Now()
+			answer, err := d.query(ctx, d.exchanger(server), domain, qtype)
+			result := PropagationResult{
+				Server:   server.Name,
+				ServerIP: server.IP,
+				Answers:  make([]string, 0),
+				Latency:  time.Since(start).Milliseconds(),
+			}
+			if err != nil {
+				result.Status = PropagationError
+				result.Error = err.Error()
+			} else {
+				result.Rcode = dns.RcodeToString[answer.rcode]
+				result.Answers = answer.answers
+				result.TTL = answer.ttl
+				answers[i] = answer
+			}
+			report.Resolvers[i] = result
+		}()
+	}
+	wg.Wait()
+
+	if auth != nil && auth.rcode == dns.RcodeSuccess && len(auth.answers) > 0 {
+		report.Reference = "authoritative"
+		report.Authoritative = authNS
+		report.Expected = auth.answers
+		report.ExpectedTTL = auth.ttl
+	} else {
+		report.Reference = "consensus"
+		report.Expected = consensusAnswer(answers)
+	}
+
+	for i, answer := range answers {
+		result := &report.Resolvers[i]
+		switch {
+		case answer == nil:
+		case answer.rcode == dns.RcodeServerFailure:
+			result.Status = PropagationServFail
+		case answer.rcode == dns.RcodeNameError:
+			result.Status = PropagationNotFound
+		case answer.rcode != dns.RcodeSuccess:
+			result.Status = PropagationError
+		case !slices.Equal(answer.answers, report.Expected):
+			result.Status = PropagationDivergent
+		case report.ExpectedTTL > 0 && answer.ttl > report.ExpectedTTL:
+			// a cache can only count down from the TTL published by the
+			// zone, anything higher was cached before the TTL changed or
+			// is being overridden by the resolver
+			result.Status = PropagationStale
+		default:
+			result.Status = PropagationConsistent
+			report.Consistent++
+		}
+	}
+	report.Propagated = len(report.Expected) > 0 && report.Consistent == report.Total
+	return report, nil
+}
+
+// consensusAnswer returns the answer set given by the most resolvers that
+// answered successfully. Ties go to the set seen first.
+func consensusAnswer(answers []*propagationAnswer) []string {
+	counts := make(map[string]int)
+	var order [][]string
+	for _, answer := range answers {
+		if answer == nil || answer.rcode != dns.RcodeSuccess || len(answer.answers) == 0 {
+			continue
+		}
+		key := strings.Join(answer.answers, "\n")
+		if counts[key] == 0 {
+			order = append(order, answer.answers)
+		}
+		counts[key]++
+	}
+
+	best := make([]string, 0)
+	bestCount := 0
+	for _, set := range order {
+		if count := counts[strings.Join(set, "\n")]; count > bestCount {
+			best, bestCount = set, count
+		}
+	}
+	return best
+}
+
+func (d *DnsPropagation) Name() string {
+	return "dns-propagation"
+}
+
+func (d *DnsPropagation) Description() string {
+	return "Compares the answers of public resolvers for a record type (type parameter, default A) to see whether a change has propagated"
+}
+
+func (d *DnsPropagation) Input() Input {
+	return InputHostname
+}
+
+func (d *DnsPropagation) Timeout() time.Duration {
+	return 15 * time.Second
+}
+
+func (d *DnsPropagation) Run(ctx context.Context, target Target) (any, error) {
+	recordType := target.Params.Get("type")
+	if recordType == "" {
+		recordType = "A"
+	}
+	return d.Check(ctx, target.Hostname(), recordType)
+}
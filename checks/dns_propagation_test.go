@@ -0,0 +1,109 @@
+package checks
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/miekg/dns"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/xray-web/web-check-api/checks/clients/ip"
+)
+
+func TestDnsPropagation(t *testing.T) {
+	t.Parallel()
+
+	delegation := zone(t,
+		"example.com. 86400 IN NS ns1.example.com.",
+		"ns1.example.com. 86400 IN A 192.0.2.53",
+	)
+	servfail := ip.ExchangeFunc(func(ctx context.Context, msg *dns.Msg) (*dns.Msg, error) {
+		resp := new(dns.Msg)
+		resp.SetRcode(msg, dns.RcodeServerFailure)
+		return resp, nil
+	})
+	servers := map[string]ip.Exchanger{
+		"192.0.2.53": zone(t, "www.example.com. 300 IN A 192.0.2.2"),
+		"192.0.2.1":  zone(t, "www.example.com. 120 IN A 192.0.2.2"),
+		"192.0.2.2":  zone(t, "www.example.com. 3600 IN A 192.0.2.1"),
+		"192.0.2.3":  zone(t, "www.example.com. 86400 IN A 192.0.2.2"),
+		"192.0.2.4":  servfail,
+		"192.0.2.5": ip.ExchangeFunc(func(ctx context.Context, msg *dns.Msg) (*dns.Msg, error) {
+			return nil, errors.New("i/o timeout")
+		}),
+	}
+	exchanger := func(server DNSServer) ip.Exchanger {
+		return servers[server.IP]
+	}
+	resolvers := []DNSServer{
+		{Name: "Updated", IP: "192.0.2.1"},
+		{Name: "Old", IP: "192.0.2.2"},
+		{Name: "Pinned", IP: "192.0.2.3"},
+		{Name: "Broken", IP: "192.0.2.4"},
+		{Name: "Offline", IP: "192.0.2.5"},
+	}
+	catalogue := &Catalogue{Resolvers: resolvers}
+
+	t.Run("authoritative reference", func(t *testing.T) {
+		t.Parallel()
+		d := NewDnsPropagation(catalogue, delegation, exchanger)
+
+		report, err := d.Check(context.Background(), "www.example.com", "a")
+		require.NoError(t, err)
+
+		assert.Equal(t, "A", report.Type)
+		assert.Equal(t, "authoritative", report.Reference)
+		assert.Equal(t, "ns1.example.com", report.Authoritative)
+		assert.Equal(t, []string{"192.0.2.2"}, report.Expected)
+		assert.Equal(t, uint32(300), report.ExpectedTTL)
+		assert.Equal(t, 1, report.Consistent)
+		assert.Equal(t, 5, report.Total)
+		assert.False(t, report.Propagated)
+
+		statuses := make(map[string]string)
+		for _, r := range report.Resolvers {
+			statuses[r.Server] = r.Status
+		}
+		assert.Equal(t, map[string]string{
+			"Updated": PropagationConsistent,
+			"Old":     PropagationDivergent,
+			"Pinned":  PropagationStale,
+			"Broken":  PropagationServFail,
+			"Offline": PropagationError,
+		}, statuses)
+		assert.Equal(t, "i/o timeout", report.Resolvers[4].Error)
+		assert.Equal(t, uint32(3600), report.Resolvers[1].TTL)
+	})
+
+	t.Run("consensus reference", func(t *testing.T) {
+		t.Parallel()
+		d := NewDnsPropagation(catalogue, servfail, exchanger)
+
+		report, err := d.Check(context.Background(), "www.example.com", "A")
+		require.NoError(t, err)
+
+		assert.Equal(t, "consensus", report.Reference)
+		assert.Empty(t, report.Authoritative)
+		assert.Equal(t, []string{"192.0.2.2"}, report.Expected)
+		// without the published TTL a high TTL isn't reported as stale
+		assert.Equal(t, 2, report.Consistent)
+	})
+
+	t.Run("propagated", func(t *testing.T) {
+		t.Parallel()
+		d := NewDnsPropagation(&Catalogue{Resolvers: resolvers[:1]}, delegation, exchanger)
+
+		report, err := d.Check(context.Background(), "www.example.com", "A")
+		require.NoError(t, err)
+		assert.True(t, report.Propagated)
+	})
+
+	t.Run("unsupported type", func(t *testing.T) {
+		t.Parallel()
+		d := NewDnsPropagation(catalogue, delegation, exchanger)
+
+		_, err := d.Check(context.Background(), "www.example.com", "AXFR")
+		assert.ErrorIs(t, err, ErrUnsupportedRecordType)
+	})
+}
@@ -0,0 +1,359 @@
+package checks
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/miekg/dns"
+	"github.com/xray-web/web-check-api/checks/clients/ip"
+)
+
+const (
+	DnsServerHost       = "host"
+	DnsServerNameserver = "nameserver"
+)
+
+// dnsProbeTimeout bounds each probe of a single server and transport.
+const dnsProbeTimeout = 3 * time.Second
+
+// DnsProbe is the outcome of sending a query to a server over one
+// transport.
+type DnsProbe struct {
+	Supported bool   `json:"supported"`
+	Latency   int64  `json:"latencyMs"`
+	Error     string `json:"error,omitempty"`
+}
+
+// DnsServerResult holds the information for each resolved address.
+type DnsServerResult struct {
+	Address  string   `json:"address"`
+	Hostname []string `json:"hostname"`
+	// Role is whether the address serves the host itself or one of the
+	// nameservers of its zone, named by Nameserver.
+	Role       string `json:"role"`
+	Nameserver string `json:"nameserver,omitempty"`
+	// Recursive is set if the server offers recursion over plain DNS.
+	Recursive bool `json:"recursive"`
+	// DOHDirectSupports is the same as DoH.Supported.
+	DOHDirectSupports bool     `json:"dohDirectSupports"`
+	DNS               DnsProbe `json:"dns"`
+	DoH               DnsProbe `json:"doh"`
+	DoT               DnsProbe `json:"dot"`
+	// DoQ only detects a QUIC server on port 853, see probeDoQ.
+	DoQ DnsProbe `json:"doq"`
+}
+
+type DnsServerResponse struct {
+	Domain string            `json:"domain"`
+	DNS    []DnsServerResult `json:"dns"`
+}
+
+type DnsServer struct {
+	exchanger ip.Exchanger
+	dialer    Dialer
+	roots     *x509.CertPool
+}
+
+// NewDnsServer returns a check that looks up the host's addresses and
+// nameservers with exchanger and sends test queries to each through
+// dialer. Certificates are verified against roots, or the system roots if
+// nil.
+func NewDnsServer(exchanger ip.Exchanger, dialer Dialer, roots *x509.CertPool) *DnsServer {
+	return &DnsServer{exchanger: exchanger, dialer: dialer, roots: roots}
+}
+
+// dnsTarget is a server address along with the name its certificate should
+// be valid for.
+type dnsTarget struct {
+	address    string
+	serverName string
+	role       string
+	nameserver string
+}
+
+func (d *DnsServer) addresses(ctx context.Context, name string) []string {
+	var addrs []string
+	records, _ := queryDNS(ctx, d.exchanger, name, dns.TypeA)
+	for _, rr := range records {
+		addrs = append(addrs, rr.(*dns.A).A.String())
+	}
+	return addrs
+}
+
+// targets returns the addresses of domain and of the nameservers of its
+// zone.
+func (d *DnsServer) targets(ctx context.Context, domain string) ([]dnsTarget, error) {
+	hostAddrs, hostErr := queryDNS(ctx, d.exchanger, domain, dns.TypeA)
+	var targets []dnsTarget
+	for _, rr := range hostAddrs {
+		targets = append(targets, dnsTarget{address: rr.(*dns.A).A.String(), serverName: domain, role: DnsServerHost})
+	}
+
+	_, nameservers, nsErr := zoneNameservers(ctx, d.exchanger, domain)
+	for _, ns := range nameservers {
+		name := strings.TrimSuffix(ns, ".")
+		for _, addr := range d.addresses(ctx, ns) {
+			targets = append(targets, dnsTarget{address: addr, serverName: name, role: DnsServerNameserver, nameserver: name})
+		}
+	}
+
+	if len(targets) == 0 {
+		return nil, errors.Join(hostErr, nsErr)
+	}
+	return targets, nil
+}
+
+// GetServers finds the host's addresses and nameservers and checks which
+// transports each one answers DNS queries over.
+func (d *DnsServer) GetServers(ctx context.Context, domain string) ([]DnsServerResult, error) {
+	targets, err := d.targets(ctx, domain)
+	if err != nil {
+		return nil, fmt.Errorf("could not resolve DNS: %v", err)
+	}
+
+	results := make([]DnsServerResult, len(targets))
+	var wg sync.WaitGroup
+	for i, target := range targets {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			results[i] = d.probe(ctx, domain, target)
+		}()
+	}
+	wg.Wait()
+
+	sort.SliceStable(results, func(i, j int) bool {
+		return results[i].Role < results[j].Role
+	})
+	return results, nil
+}
+
+func (d *DnsServer) probe(ctx context.Context, domain string, target dnsTarget) DnsServerResult {
+	result := DnsServerResult{
+		Address:    target.address,
+		Role:       target.role,
+		Nameserver: target.nameserver,
+	}
+
+	query := new(dns.Msg)
+	query.SetQuestion(dns.Fqdn(domain), dns.TypeA)
+
+	var wg sync.WaitGroup
+	run := func(probe *DnsProbe, fn func(ctx context.Context) error) {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			ctx, cancel := context.WithTimeout(ctx, dnsProbeTimeout)
+			defer cancel()
+			start := time.Now()
+			if err := fn(ctx); err != nil {
+				probe.Error = err.Error()
+				return
+			}
+			probe.Supported = true
+			probe.Latency = time.Since(start).Milliseconds()
+		}()
+	}
+	run(&result.DNS, func(ctx context.Context) error {
+		resp, err := d.probeDNS(ctx, target, query)
+		if err == nil {
+			result.Recursive = resp.RecursionAvailable
+		}
+		return err
+	})
+	run(&result.DoH, func(ctx context.Context) error {
+		return d.probeDoH(ctx, target, query)
+	})
+	run(&result.DoT, func(ctx context.Context) error {
+		return d.probeDoT(ctx, target, query)
+	})
+	run(&result.DoQ, func(ctx context.Context) error {
+		return d.probeDoQ(ctx, target)
+	})
+
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		name, err := dns.ReverseAddr(target.address)
+		if err != nil {
+			return
+		}
+		records, _ := queryDNS(ctx, d.exchanger, name, dns.TypePTR)
+		for _, rr := range records {
+			result.Hostname = append(result.Hostname, rr.(*dns.PTR).Ptr)
+		}
+	}()
+	wg.Wait()
+
+	result.DOHDirectSupports = result.DoH.Supported
+	return result
+}
+
+// exchangeConn sends msg over conn, which is framed for TCP unless it is a
+// net.PacketConn.
+func exchangeConn(ctx context.Context, conn net.Conn, msg *dns.Msg) (*dns.Msg, error) {
+	if deadline, ok := ctx.Deadline(); ok {
+		conn.SetDeadline(deadline)
+	}
+	dc := &dns.Conn{Conn: conn}
+	if err := dc.WriteMsg(msg); err != nil {
+		return nil, err
+	}
+	resp, err := dc.ReadMsg()
+	if err != nil {
+		return nil, err
+	}
+	if resp.Id != msg.Id {
+		return nil, dns.ErrId
+	}
+	return resp, nil
+}
+
+func (d *DnsServer) probeDNS(ctx context.Context, target dnsTarget, query *dns.Msg) (*dns.Msg, error) {
+	conn, err := d.dialer.DialContext(ctx, "udp", net.JoinHostPort(target.address, "53"))
+	if err != nil {
+		return nil, err
+	}
+	defer conn.Close()
+	return exchangeConn(ctx, conn, query)
+}
+
+// probeDoH sends an RFC 8484 POST query to the /dns-query path.
+func (d *DnsServer) probeDoH(ctx context.Context, target dnsTarget, query *dns.Msg) error {
+	transport := &http.Transport{
+		DialContext: func(ctx context.Context, network, _ string) (net.Conn, error) {
+			return d.dialer.DialContext(ctx, network, net.JoinHostPort(target.address, "443"))
+		},
+		TLSClientConfig:   &tls.Config{RootCAs: d.roots},
+		ForceAttemptHTTP2: true,
+	}
+	defer transport.CloseIdleConnections()
+
+	url := fmt.Sprintf("https://%s/dns-query", target.serverName)
+	client := ip.NewDoHClient(url, http.MethodPost, &http.Client{Transport: transport})
+	_, err := client.Exchange(ctx, query)
+	return err
+}
+
+// probeDoT sends a query over TLS on port 853, RFC 7858.
+func (d *DnsServer) probeDoT(ctx context.Context, target dnsTarget, query *dns.Msg) error {
+	conn, err := d.dialer.DialContext(ctx, "tcp", net.JoinHostPort(target.address, "853"))
+	if err != nil {
+		return err
+	}
+	defer conn.Close()
+
+	tlsConn := tls.Client(conn, &tls.Config{
+		ServerName: target.serverName,
+		RootCAs:    d.roots,
+	})
+	if err := tlsConn.HandshakeContext(ctx); err != nil {
+		return fmt.Errorf("TLS handshake failed: %w", err)
+	}
+	_, err = exchangeConn(ctx, tlsConn, query)
+	return err
+}
+
+// probeDoQ looks for a QUIC server on UDP port 853, the DNS over QUIC port
+// from RFC 9250. It sends a packet with a version reserved for negotiation
+// which a QUIC server must answer with a version negotiation packet, RFC
+// 9000 section 6. The DoQ application protocol itself isn't checked.
+func (d *DnsServer) probeDoQ(ctx context.Context, target dnsTarget) error {
+	conn, err := d.dialer.DialContext(ctx, "udp", net.JoinHostPort(target.address, "853"))
+	if err != nil {
+		return err
+	}
+	defer conn.Close()
+	if deadline, ok := ctx.Deadline(); ok {
+		conn.SetDeadline(deadline)
+	}
+
+	dcid := make([]byte, 8)
+	scid := make([]byte, 8)
+	rand.Read(dcid)
+	rand.Read(scid)
+
+	// long header with a reserved version, padded to the 1200 byte minimum
+	// a server will respond to
+	packet := make([]byte, 1200)
+	packet[0] = 0xc0
+	binary.BigEndian.PutUint32(packet[1:5], 0x1a2a3a4a)
+	packet[5] = byte(len(dcid))
+	copy(packet[6:], dcid)
+	packet[14] = byte(len(scid))
+	copy(packet[15:], scid)
+	if _, err := conn.Write(packet); err != nil {
+		return err
+	}
+
+	buf := make([]byte, 1500)
+	n, err := conn.Read(buf)
+	if err != nil {
+		return err
+	}
+	return parseVersionNegotiation(buf[:n], dcid, scid)
+}
+
+// parseVersionNegotiation checks resp is a version negotiation packet
+// answering a packet sent with dcid and scid.
+func parseVersionNegotiation(resp, dcid, scid []byte) error {
+	errInvalid := errors.New("not a QUIC version negotiation packet")
+	if len(resp) < 7 || resp[0]&0x80 == 0 || binary.BigEndian.Uint32(resp[1:5]) != 0 {
+		return errInvalid
+	}
+	// the connection IDs are echoed back swapped
+	rest := resp[5:]
+	for _, want := range [][]byte{scid, dcid} {
+		if len(rest) < 1 || int(rest[0]) != len(want) || len(rest) < 1+len(want) || string(rest[1:1+len(want)]) != string(want) {
+			return errInvalid
+		}
+		rest = rest[1+len(want):]
+	}
+	if len(rest) < 4 || len(rest)%4 != 0 {
+		return errInvalid
+	}
+	return nil
+}
+
+func (d *DnsServer) Name() string {
+	return "dns-server"
+}
+
+func (d *DnsServer) Description() string {
+	return "Checks whether the host and its nameservers answer DNS over HTTPS, TLS and QUIC"
+}
+
+func (d *DnsServer) Input() Input {
+	return InputHostname
+}
+
+func (d *DnsServer) Timeout() time.Duration {
+	return 15 * time.Second
+}
+
+func (d *DnsServer) Run(ctx context.Context, target Target) (any, error) {
+	ctx, cancel := context.WithTimeout(ctx, 10*time.Second)
+	defer cancel()
+
+	hostname := target.Hostname()
+	results, err := d.GetServers(ctx, hostname)
+	if err != nil {
+		return nil, fmt.Errorf("error resolving DNS: %v", err)
+	}
+
+	return DnsServerResponse{
+		Domain: hostname,
+		DNS:    results,
+	}, nil
+}
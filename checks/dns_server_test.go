@@ -0,0 +1,179 @@
+package checks
+
+import (
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"encoding/binary"
+	"errors"
+	"io"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/miekg/dns"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// answerA replies to every query with a single A record.
+var answerA = dns.HandlerFunc(func(w dns.ResponseWriter, r *dns.Msg) {
+	resp := new(dns.Msg)
+	resp.SetReply(r)
+	resp.RecursionAvailable = true
+	rr, _ := dns.NewRR(r.Question[0].Name + " 300 IN A 192.0.2.1")
+	resp.Answer = append(resp.Answer, rr)
+	w.WriteMsg(resp)
+})
+
+func serveDNS(t *testing.T, server *dns.Server) {
+	t.Helper()
+	server.Handler = answerA
+	go server.ActivateAndServe()
+	t.Cleanup(func() { server.Shutdown() })
+}
+
+// versionNegotiator answers every packet like a QUIC server that doesn't
+// support the requested version.
+func versionNegotiator(t *testing.T) net.PacketConn {
+	conn, err := net.ListenPacket("udp", "127.0.0.1:0")
+	require.NoError(t, err)
+	t.Cleanup(func() { conn.Close() })
+	go func() {
+		buf := make([]byte, 1500)
+		for {
+			n, addr, err := conn.ReadFrom(buf)
+			if err != nil {
+				return
+			}
+			packet := buf[:n]
+			dcid := packet[6 : 6+packet[5]]
+			scid := packet[7+packet[5] : 7+packet[5]+packet[6+packet[5]]]
+
+			resp := []byte{0x80, 0, 0, 0, 0, byte(len(scid))}
+			resp = append(resp, scid...)
+			resp = append(resp, byte(len(dcid)))
+			resp = append(resp, dcid...)
+			resp = binary.BigEndian.AppendUint32(resp, 1)
+			conn.WriteTo(resp, addr)
+		}
+	}()
+	return conn
+}
+
+func TestDnsServerGetServers(t *testing.T) {
+	t.Parallel()
+
+	doh := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		query := new(dns.Msg)
+		if r.URL.Path != "/dns-query" || query.Unpack(body) != nil {
+			http.NotFound(w, r)
+			return
+		}
+		resp := new(dns.Msg)
+		resp.SetReply(query)
+		out, _ := resp.Pack()
+		w.Header().Set("Content-Type", "application/dns-message")
+		w.Write(out)
+	}))
+	defer doh.Close()
+
+	dot, err := tls.Listen("tcp", "127.0.0.1:0", doh.TLS)
+	require.NoError(t, err)
+	serveDNS(t, &dns.Server{Listener: dot, Net: "tcp-tls"})
+
+	plain, err := net.ListenPacket("udp", "127.0.0.1:0")
+	require.NoError(t, err)
+	serveDNS(t, &dns.Server{PacketConn: plain})
+
+	doq := versionNegotiator(t)
+
+	local := map[string]string{
+		"tcp 192.0.2.1:443": doh.Listener.Addr().String(),
+		"tcp 192.0.2.1:853": dot.Addr().String(),
+		"udp 192.0.2.1:53":  plain.LocalAddr().String(),
+		"udp 192.0.2.1:853": doq.LocalAddr().String(),
+	}
+	dialer := DialFunc(func(ctx context.Context, network, address string) (net.Conn, error) {
+		if addr, ok := local[network+" "+address]; ok {
+			return (&net.Dialer{}).DialContext(ctx, network, addr)
+		}
+		return nil, errors.New("connection refused")
+	})
+
+	roots := x509.NewCertPool()
+	roots.AddCert(doh.Certificate())
+
+	d := NewDnsServer(zone(t,
+		"example.com. 300 IN A 192.0.2.1",
+		"example.com. 86400 IN NS ns1.example.net.",
+		"ns1.example.net. 86400 IN A 192.0.2.53",
+		"1.2.0.192.in-addr.arpa. 3600 IN PTR www.example.com.",
+	), dialer, roots)
+
+	results, err := d.GetServers(context.Background(), "example.com")
+	require.NoError(t, err)
+	require.Len(t, results, 2)
+
+	host := results[0]
+	assert.Equal(t, "192.0.2.1", host.Address)
+	assert.Equal(t, DnsServerHost, host.Role)
+	assert.Equal(t, []string{"www.example.com."}, host.Hostname)
+	for name, probe := range map[string]DnsProbe{"dns": host.DNS, "doh": host.DoH, "dot": host.DoT, "doq": host.DoQ} {
+		assert.True(t, probe.Supported, name)
+		assert.Empty(t, probe.Error, name)
+	}
+	assert.True(t, host.Recursive)
+	assert.True(t, host.DOHDirectSupports)
+
+	ns := results[1]
+	assert.Equal(t, "192.0.2.53", ns.Address)
+	assert.Equal(t, DnsServerNameserver, ns.Role)
+	assert.Equal(t, "ns1.example.net", ns.Nameserver)
+	assert.False(t, ns.Recursive)
+	for name, probe := range map[string]DnsProbe{"dns": ns.DNS, "doh": ns.DoH, "dot": ns.DoT, "doq": ns.DoQ} {
+		assert.False(t, probe.Supported, name)
+		assert.Contains(t, probe.Error, "connection refused", name)
+	}
+
+	t.Run("certificate for another name", func(t *testing.T) {
+		t.Parallel()
+		d := NewDnsServer(zone(t, "other.test. 300 IN A 192.0.2.1"), dialer, roots)
+
+		results, err := d.GetServers(context.Background(), "other.test")
+		require.NoError(t, err)
+		require.Len(t, results, 1)
+		assert.False(t, results[0].DoT.Supported)
+		assert.Contains(t, results[0].DoT.Error, "TLS handshake failed")
+		assert.False(t, results[0].DoH.Supported)
+	})
+
+	t.Run("unresolvable", func(t *testing.T) {
+		t.Parallel()
+		d := NewDnsServer(zone(t), dialer, roots)
+
+		_, err := d.GetServers(context.Background(), "example.com")
+		assert.ErrorContains(t, err, "could not resolve DNS")
+	})
+}
+
+func TestParseVersionNegotiation(t *testing.T) {
+	t.Parallel()
+
+	dcid, scid := []byte{1, 2}, []byte{3, 4}
+	valid := []byte{0x80, 0, 0, 0, 0, 2, 3, 4, 2, 1, 2, 0, 0, 0, 1}
+	assert.NoError(t, parseVersionNegotiation(valid, dcid, scid))
+
+	for name, packet := range map[string][]byte{
+		"short header":      {0x40, 0, 0, 0, 0, 2, 3, 4, 2, 1, 2, 0, 0, 0, 1},
+		"not negotiation":   {0x80, 0, 0, 0, 1, 2, 3, 4, 2, 1, 2, 0, 0, 0, 1},
+		"wrong connection":  {0x80, 0, 0, 0, 0, 2, 1, 2, 2, 3, 4, 0, 0, 0, 1},
+		"truncated":         {0x80, 0, 0, 0, 0, 2, 3},
+		"no versions":       {0x80, 0, 0, 0, 0, 2, 3, 4, 2, 1, 2},
+		"truncated version": {0x80, 0, 0, 0, 0, 2, 3, 4, 2, 1, 2, 0, 0},
+	} {
+		assert.Error(t, parseVersionNegotiation(packet, dcid, scid), name)
+	}
+}
@@ -0,0 +1,136 @@
+package checks
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/miekg/dns"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/xray-web/web-check-api/checks/clients/ip"
+)
+
+// zone answers queries from records in presentation format. Names with no
+// records at all are NXDOMAIN.
+func zone(t *testing.T, records ...string) ip.ExchangeFunc {
+	t.Helper()
+	var rrs []dns.RR
+	for _, record := range records {
+		rr, err := dns.NewRR(record)
+		require.NoError(t, err)
+		rrs = append(rrs, rr)
+	}
+	return func(ctx context.Context, msg *dns.Msg) (*dns.Msg, error) {
+		q := msg.Question[0]
+		resp := new(dns.Msg)
+		resp.SetReply(msg)
+		resp.Rcode = dns.RcodeNameError
+		for _, rr := range rrs {
+			if !dns.IsSubDomain(q.Name, rr.Header().Name) {
+				continue
+			}
+			resp.Rcode = dns.RcodeSuccess
+			if rr.Header().Name == q.Name && rr.Header().Rrtype == q.Qtype {
+				resp.Answer = append(resp.Answer, rr)
+			}
+		}
+		return resp, nil
+	}
+}
+
+func TestGetRecords(t *testing.T) {
+	t.Parallel()
+
+	t.Run("records", func(t *testing.T) {
+		t.Parallel()
+		d := NewDns(zone(t,
+			"example.com. 300 IN A 93.184.216.34",
+			"example.com. 300 IN AAAA 2606:2800:220:1::",
+			"example.com. 3600 IN MX 10 mail.example.com.",
+			`example.com. 60 IN TXT "v=spf1 " "-all"`,
+			"example.com. 86400 IN NS a.iana-servers.net.",
+			"example.com. 3600 IN SOA ns.icann.org. noc.dns.icann.org. 2024081414 7200 3600 1209600 3600",
+			`com. 3600 IN CAA 0 issue "letsencrypt.org"`,
+			"example.com. 3600 IN DS 370 13 2 BE74359954660069D5C63D200C39F5603827D7DD02B56F120EE9F3A86764247C",
+			`example.com. 300 IN HTTPS 1 . alpn="h2,h3"`,
+			`example.com. 300 IN NAPTR 100 10 "S" "SIP+D2U" "" _sip._udp.example.com.`,
+			"34.216.184.93.in-addr.arpa. 3600 IN PTR example.com.",
+		))
+
+		actual, err := d.GetRecords(context.Background(), "example.com")
+		require.NoError(t, err)
+
+		assert.Empty(t, actual.Errors)
+		assert.Equal(t, []AddressRecord{{Address: "93.184.216.34", TTL: 300}}, actual.A)
+		assert.Equal(t, []AddressRecord{{Address: "2606:2800:220:1::", TTL: 300}}, actual.AAAA)
+		assert.Equal(t, []MXRecord{{Host: "mail.example.com.", Preference: 10, TTL: 3600}}, actual.MX)
+		assert.Equal(t, []TXTRecord{{Value: "v=spf1 -all", TTL: 60}}, actual.TXT)
+		assert.Equal(t, []NSRecord{{Host: "a.iana-servers.net.", TTL: 86400}}, actual.NS)
+		assert.Empty(t, actual.CNAME)
+		assert.Equal(t, &SOARecord{
+			PrimaryNS:  "ns.icann.org.",
+			Mailbox:    "noc.dns.icann.org.",
+			Serial:     2024081414,
+			Refresh:    7200,
+			Retry:      3600,
+			Expire:     1209600,
+			MinimumTTL: 3600,
+			TTL:        3600,
+		}, actual.SOA)
+		assert.Equal(t, []CAARecord{{Name: "com", Tag: "issue", Value: "letsencrypt.org", TTL: 3600}}, actual.CAA)
+		assert.Empty(t, actual.TLSA)
+		assert.Equal(t, []DSRecord{{
+			KeyTag:     370,
+			Algorithm:  13,
+			DigestType: 2,
+			Digest:     "BE74359954660069D5C63D200C39F5603827D7DD02B56F120EE9F3A86764247C",
+			TTL:        3600,
+		}}, actual.DS)
+		assert.Equal(t, []SVCBRecord{{Priority: 1, Target: ".", Params: map[string]string{"alpn": "h2,h3"}, TTL: 300}}, actual.HTTPS)
+		assert.Equal(t, []NAPTRRecord{{
+			Order:       100,
+			Preference:  10,
+			Flags:       "S",
+			Service:     "SIP+D2U",
+			Replacement: "_sip._udp.example.com.",
+			TTL:         300,
+		}}, actual.NAPTR)
+		assert.Equal(t, []PTRRecord{{Address: "93.184.216.34", Host: "example.com.", TTL: 3600}}, actual.PTR)
+	})
+
+	t.Run("errors per record type", func(t *testing.T) {
+		t.Parallel()
+		d := NewDns(ip.ExchangeFunc(func(ctx context.Context, msg *dns.Msg) (*dns.Msg, error) {
+			resp := new(dns.Msg)
+			resp.SetReply(msg)
+			if msg.Question[0].Qtype == dns.TypeMX {
+				resp.Rcode = dns.RcodeServerFailure
+			}
+			return resp, nil
+		}))
+
+		actual, err := d.GetRecords(context.Background(), "example.com")
+		require.NoError(t, err)
+		assert.Equal(t, map[string]string{"MX": "query failed: SERVFAIL"}, actual.Errors)
+	})
+
+	t.Run("lookup error", func(t *testing.T) {
+		t.Parallel()
+		d := NewDns(ip.ExchangeFunc(func(ctx context.Context, msg *dns.Msg) (*dns.Msg, error) {
+			return nil, errors.New("connection refused")
+		}))
+
+		_, err := d.GetRecords(context.Background(), "example.com")
+		assert.EqualError(t, err, "connection refused")
+	})
+
+	t.Run("missing host", func(t *testing.T) {
+		t.Parallel()
+		d := NewDns(zone(t))
+
+		actual, err := d.GetRecords(context.Background(), "example.com")
+		require.NoError(t, err)
+		assert.ErrorContains(t, errors.New(actual.Errors["A"]), ErrDomainNotFound.Error())
+	})
+}
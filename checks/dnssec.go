@@ -0,0 +1,539 @@
+package checks
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/miekg/dns"
+	"github.com/xray-web/web-check-api/checks/clients/ip"
+)
+
+const (
+	DnsSecSecure   = "secure"
+	DnsSecInsecure = "insecure"
+	DnsSecBogus    = "bogus"
+)
+
+// RootTrustAnchors are the DS records of the root zone key signing keys
+// published by IANA, KSK-2017 and KSK-2024.
+var RootTrustAnchors = []*dns.DS{
+	mustParseDS(". IN DS 20326 8 2 E06D44B80B8F1D39A95C0B0D7C65D08458E880409BBC683457104237C7F8EC8D"),
+	mustParseDS(". IN DS 38696 8 2 683D2D0ACB8C9B712A1948B27F741219298D0A450D612C483AF444A4C0FB2B16"),
+}
+
+func mustParseDS(s string) *dns.DS {
+	rr, err := dns.NewRR(s)
+	if err != nil {
+		panic(err)
+	}
+	return rr.(*dns.DS)
+}
+
+// supportedAlgorithms are the DNSSEC algorithms that can be validated,
+// zones signed only with others are treated as insecure, RFC 6840 section
+// 5.2.
+var supportedAlgorithms = map[uint8]bool{
+	dns.RSASHA1:          true,
+	dns.RSASHA1NSEC3SHA1: true,
+	dns.RSASHA256:        true,
+	dns.RSASHA512:        true,
+	dns.ECDSAP256SHA256:  true,
+	dns.ECDSAP384SHA384:  true,
+	dns.ED25519:          true,
+}
+
+type DnsSecKey struct {
+	KeyTag    uint16 `json:"keyTag"`
+	Algorithm string `json:"algorithm"`
+	Flags     uint16 `json:"flags"`
+	// SEP is set on key signing keys
+	SEP bool `json:"sep"`
+}
+
+type DnsSecSignature struct {
+	TypeCovered string    `json:"typeCovered"`
+	KeyTag      uint16    `json:"keyTag"`
+	Algorithm   string    `json:"algorithm"`
+	Inception   time.Time `json:"inception"`
+	Expiration  time.Time `json:"expiration"`
+	Valid       bool      `json:"valid"`
+	Error       string    `json:"error,omitempty"`
+}
+
+type DnsSecZone struct {
+	Zone       string            `json:"zone"`
+	Status     string            `json:"status"`
+	Reason     string            `json:"reason,omitempty"`
+	DS         []DSRecord        `json:"ds"`
+	Keys       []DnsSecKey       `json:"keys"`
+	Signatures []DnsSecSignature `json:"signatures"`
+}
+
+type DnsSecReport struct {
+	Domain string       `json:"domain"`
+	Status string       `json:"status"`
+	Reason string       `json:"reason,omitempty"`
+	Zones  []DnsSecZone `json:"zones"`
+}
+
+type DnsSec struct {
+	exchanger ip.Exchanger
+	anchors   []*dns.DS
+}
+
+// NewDnsSec returns a validator that queries exchanger, which should be a
+// recursive resolver. Anchors are the DS records trusted for the root zone,
+// nil uses RootTrustAnchors.
+func NewDnsSec(exchanger ip.Exchanger, anchors []*dns.DS) *DnsSec {
+	if anchors == nil {
+		anchors = RootTrustAnchors
+	}
+	return &DnsSec{exchanger: exchanger, anchors: anchors}
+}
+
+// query asks for name with the DO bit set so signatures are included and
+// CD set so a validating upstream returns data even if it is bogus.
+func (d *DnsSec) query(ctx context.Context, name string, qtype uint16) (*dns.Msg, error) {
+	msg := new(dns.Msg)
+	msg.SetQuestion(name, qtype)
+	msg.SetEdns0(4096, true)
+	msg.CheckingDisabled = true
+	resp, err := d.exchanger.Exchange(ctx, msg)
+	if err != nil {
+		return nil, fmt.Errorf("%s %s query failed: %w", name, dns.TypeToString[qtype], err)
+	}
+	if resp.Rcode != dns.RcodeSuccess && resp.Rcode != dns.RcodeNameError {
+		return nil, fmt.Errorf("%s %s query failed: %s", name, dns.TypeToString[qtype], dns.RcodeToString[resp.Rcode])
+	}
+	return resp, nil
+}
+
+// rrset returns the records of qtype owned by name and the signatures over
+// them.
+func rrset(rrs []dns.RR, name string, qtype uint16) ([]dns.RR, []*dns.RRSIG) {
+	var set []dns.RR
+	var sigs []*dns.RRSIG
+	for _, rr := range rrs {
+		if !strings.EqualFold(rr.Header().Name, name) {
+			continue
+		}
+		if sig, ok := rr.(*dns.RRSIG); ok {
+			if sig.TypeCovered == qtype {
+				sigs = append(sigs, sig)
+			}
+		} else if rr.Header().Rrtype == qtype {
+			set = append(set, rr)
+		}
+	}
+	return set, sigs
+}
+
+// verify checks that one of sigs over set was made by one of keys by
+// signer, recording every signature checked on zone.
+func (z *DnsSecZone) verify(set []dns.RR, sigs []*dns.RRSIG, keys []*dns.DNSKEY, signer string) error {
+	if len(sigs) == 0 {
+		return fmt.Errorf("%s %s is not signed", set[0].Header().Name, dns.TypeToString[set[0].Header().Rrtype])
+	}
+	now := time.Now()
+	var errs []error
+	unsupported := true
+	for _, sig := range sigs {
+		result := DnsSecSignature{
+			TypeCovered: dns.TypeToString[sig.TypeCovered],
+			KeyTag:      sig.KeyTag,
+			Algorithm:   dns.AlgorithmToString[sig.Algorithm],
+			Inception:   time.Unix(int64(sig.Inception), 0).UTC(),
+			Expiration:  time.Unix(int64(sig.Expiration), 0).UTC(),
+		}
+		err := verifySignature(sig, set, keys, signer, now)
+		if err == nil {
+			result.Valid = true
+		} else {
+			result.Error = err.Error()
+			errs = append(errs, err)
+		}
+		z.Signatures = append(z.Signatures, result)
+		if supportedAlgorithms[sig.Algorithm] {
+			unsupported = false
+		}
+		if err == nil {
+			return nil
+		}
+	}
+	if unsupported {
+		return errUnsupportedAlgorithm
+	}
+	return errors.Join(errs...)
+}
+
+var errUnsupportedAlgorithm = errors.New("zone is only signed with unsupported algorithms")
+
+func verifySignature(sig *dns.RRSIG, set []dns.RR, keys []*dns.DNSKEY, signer string, now time.Time) error {
+	if !supportedAlgorithms[sig.Algorithm] {
+		return fmt.Errorf("unsupported algorithm %s", dns.AlgorithmToString[sig.Algorithm])
+	}
+	if !strings.EqualFold(sig.SignerName, signer) {
+		return fmt.Errorf("signed by %s, expected %s", sig.SignerName, signer)
+	}
+	if !sig.ValidityPeriod(now) {
+		return fmt.Errorf("signature by key %d is outside its validity period", sig.KeyTag)
+	}
+	for _, key := range keys {
+		if key.KeyTag() != sig.KeyTag || key.Algorithm != sig.Algorithm {
+			continue
+		}
+		if err := sig.Verify(key, set); err != nil {
+			return fmt.Errorf("signature by key %d does not verify: %w", sig.KeyTag, err)
+		}
+		return nil
+	}
+	return fmt.Errorf("no DNSKEY with tag %d", sig.KeyTag)
+}
+
+// validateKeys checks the DNSKEY set of zone in resp is signed by a key
+// matching one of ds, returning the keys.
+func validateKeys(z *DnsSecZone, resp *dns.Msg, ds []*dns.DS) ([]*dns.DNSKEY, error) {
+	set, sigs := rrset(resp.Answer, z.Zone, dns.TypeDNSKEY)
+	if len(set) == 0 {
+		return nil, errors.New("zone has DS records but no DNSKEY records")
+	}
+
+	var keys, trusted []*dns.DNSKEY
+	for _, rr := range set {
+		key := rr.(*dns.DNSKEY)
+		keys = append(keys, key)
+		z.Keys = append(z.Keys, DnsSecKey{
+			KeyTag:    key.KeyTag(),
+			Algorithm: dns.AlgorithmToString[key.Algorithm],
+			Flags:     key.Flags,
+			SEP:       key.Flags&dns.SEP != 0,
+		})
+		for _, parent := range ds {
+			if key.KeyTag() != parent.KeyTag || key.Algorithm != parent.Algorithm {
+				continue
+			}
+			if digest := key.ToDS(parent.DigestType); digest != nil && strings.EqualFold(digest.Digest, parent.Digest) {
+				trusted = append(trusted, key)
+				break
+			}
+		}
+	}
+	if len(trusted) == 0 {
+		return nil, errors.New("no DNSKEY matches the DS records in the parent zone")
+	}
+	if err := z.verify(set, sigs, trusted, z.Zone); err != nil {
+		return nil, fmt.Errorf("DNSKEY set: %w", err)
+	}
+	return keys, nil
+}
+
+// provesNoDS checks the authority section of a NODATA response for name
+// holds a signed NSEC or NSEC3 record proving there is no DS record, or an
+// opt-out NSEC3 covering it.
+func provesNoDS(z *DnsSecZone, resp *dns.Msg, name, parent string, keys []*dns.DNSKEY) error {
+	for _, rr := range resp.Ns {
+		switch denial := rr.(type) {
+		case *dns.NSEC:
+			if !strings.EqualFold(denial.Hdr.Name, name) || hasType(denial.TypeBitMap, dns.TypeDS) {
+				continue
+			}
+		case *dns.NSEC3:
+			matches := denial.Match(name) && !hasType(denial.TypeBitMap, dns.TypeDS)
+			optOut := denial.Flags&1 == 1 && denial.Cover(name)
+			if !matches && !optOut {
+				continue
+			}
+		default:
+			continue
+		}
+		set, sigs := rrset(resp.Ns, rr.Header().Name, rr.Header().Rrtype)
+		if err := z.verify(set, sigs, keys, parent); err != nil {
+			return fmt.Errorf("%s proof: %w", dns.TypeToString[rr.Header().Rrtype], err)
+		}
+		return nil
+	}
+	return errors.New("no NSEC or NSEC3 record proves the DS record is absent")
+}
+
+// provesNameError checks the authority section of an NXDOMAIN response for
+// name holds signed NSEC or NSEC3 records proving neither name nor a
+// wildcard that would match it exist, RFC 4035 section 5.4 and RFC 5155
+// section 8.4.
+func provesNameError(z *DnsSecZone, resp *dns.Msg, name, parent string, keys []*dns.DNSKEY) error {
+	var proof []dns.RR
+	var nsecs []*dns.NSEC
+	var nsec3s []*dns.NSEC3
+	for _, rr := range resp.Ns {
+		switch denial := rr.(type) {
+		case *dns.NSEC:
+			nsecs = append(nsecs, denial)
+		case *dns.NSEC3:
+			nsec3s = append(nsec3s, denial)
+		}
+	}
+
+	if cover := nsecCovering(nsecs, name); cover != nil {
+		// the closest encloser is the longest ancestor of name shared with
+		// either end of the covering NSEC
+		encloser := parent
+		for _, end := range []string{cover.Hdr.Name, cover.NextDomain} {
+			if common := commonAncestor(name, end); dns.CountLabel(common) > dns.CountLabel(encloser) {
+				encloser = common
+			}
+		}
+		wildcard := nsecCovering(nsecs, "*."+encloser)
+		if wildcard == nil {
+			return fmt.Errorf("no NSEC record proves there is no wildcard at %s", encloser)
+		}
+		proof = append(proof, cover, wildcard)
+	} else if len(nsec3s) > 0 {
+		// the closest encloser is the first ancestor of name with an NSEC3,
+		// its child on the way to name and its wildcard must be covered
+		var encloser, nextCloser string
+		var match *dns.NSEC3
+		labels := append(dns.Split(name), len(name)-1)
+		for i := 1; i < len(labels); i++ {
+			if match = nsec3Matching(nsec3s, name[labels[i]:]); match != nil {
+				encloser, nextCloser = name[labels[i]:], name[labels[i-1]:]
+				break
+			}
+		}
+		if match == nil {
+			return errors.New("no NSEC3 record matches a closest encloser")
+		}
+		next := nsec3Covering(nsec3s, nextCloser)
+		if next == nil {
+			return fmt.Errorf("no NSEC3 record covers %s", nextCloser)
+		}
+		wildcard := nsec3Covering(nsec3s, "*."+encloser)
+		if wildcard == nil {
+			return fmt.Errorf("no NSEC3 record proves there is no wildcard at %s", encloser)
+		}
+		proof = append(proof, match, next, wildcard)
+	} else {
+		return fmt.Errorf("no NSEC or NSEC3 record proves %s does not exist", name)
+	}
+
+	for _, rr := range proof {
+		set, sigs := rrset(resp.Ns, rr.Header().Name, rr.Header().Rrtype)
+		if err := z.verify(set, sigs, keys, parent); err != nil {
+			return fmt.Errorf("%s proof: %w", dns.TypeToString[rr.Header().Rrtype], err)
+		}
+	}
+	return nil
+}
+
+// nsecCovering returns the NSEC record whose owner sorts before name and
+// next name after it, the last NSEC in a zone wraps around to the apex.
+func nsecCovering(nsecs []*dns.NSEC, name string) *dns.NSEC {
+	for _, nsec := range nsecs {
+		after := canonicalCompare(nsec.Hdr.Name, name) < 0
+		before := canonicalCompare(name, nsec.NextDomain) < 0
+		wraps := canonicalCompare(nsec.NextDomain, nsec.Hdr.Name) <= 0
+		if (after && before) || (wraps && (after || before)) {
+			return nsec
+		}
+	}
+	return nil
+}
+
+func nsec3Matching(nsec3s []*dns.NSEC3, name string) *dns.NSEC3 {
+	for _, nsec3 := range nsec3s {
+		if nsec3.Match(name) {
+			return nsec3
+		}
+	}
+	return nil
+}
+
+func nsec3Covering(nsec3s []*dns.NSEC3, name string) *dns.NSEC3 {
+	for _, nsec3 := range nsec3s {
+		if nsec3.Cover(name) {
+			return nsec3
+		}
+	}
+	return nil
+}
+
+// canonicalCompare orders names as DNSSEC does, label by label from the
+// root with each label compared as lower case bytes, RFC 4034 section 6.1.
+func canonicalCompare(a, b string) int {
+	la, lb := dns.SplitDomainName(strings.ToLower(a)), dns.SplitDomainName(strings.ToLower(b))
+	for i := 1; i <= len(la) && i <= len(lb); i++ {
+		if c := strings.Compare(la[len(la)-i], lb[len(lb)-i]); c != 0 {
+			return c
+		}
+	}
+	return len(la) - len(lb)
+}
+
+// commonAncestor returns the longest name both a and b are under.
+func commonAncestor(a, b string) string {
+	n := dns.CompareDomainName(a, b)
+	if n == 0 {
+		return "."
+	}
+	labels := dns.SplitDomainName(a)
+	return dns.Fqdn(strings.Join(labels[len(labels)-n:], "."))
+}
+
+func hasType(bitmap []uint16, qtype uint16) bool {
+	for _, t := range bitmap {
+		if t == qtype {
+			return true
+		}
+	}
+	return false
+}
+
+// Validate walks the chain of trust from the root down to the zone
+// containing domain, returning a verdict for each zone on the way.
+func (d *DnsSec) Validate(ctx context.Context, domain string) (*DnsSecReport, error) {
+	domain = dns.Fqdn(strings.ToLower(domain))
+	report := &DnsSecReport{Domain: domain, Zones: make([]DnsSecZone, 0)}
+	finish := func(z DnsSecZone) (*DnsSecReport, error) {
+		report.Zones = append(report.Zones, z)
+		report.Status, report.Reason = z.Status, z.Reason
+		return report, nil
+	}
+
+	root := newDnsSecZone(".")
+	for _, ds := range d.anchors {
+		root.DS = append(root.DS, newDSRecord(ds))
+	}
+	resp, err := d.query(ctx, root.Zone, dns.TypeDNSKEY)
+	if err != nil {
+		return nil, err
+	}
+	keys, err := validateKeys(&root, resp, d.anchors)
+	if err != nil {
+		root.Status, root.Reason = DnsSecBogus, err.Error()
+		return finish(root)
+	}
+	root.Status = DnsSecSecure
+	report.Zones = append(report.Zones, root)
+	report.Status = DnsSecSecure
+
+	parent := "."
+	labels := dns.SplitDomainName(domain)
+	for i := len(labels) - 1; i >= 0; i-- {
+		name := dns.Fqdn(strings.Join(labels[i:], "."))
+		z := newDnsSecZone(name)
+
+		resp, err := d.query(ctx, name, dns.TypeDS)
+		if err != nil {
+			return nil, err
+		}
+		if resp.Rcode == dns.RcodeNameError {
+			if err := provesNameError(&z, resp, name, parent, keys); err != nil {
+				z.Status, z.Reason = DnsSecBogus, fmt.Sprintf("%s does not exist but the denial is not proven: %s", name, err)
+				if errors.Is(err, errUnsupportedAlgorithm) {
+					z.Status = DnsSecInsecure
+				}
+				return finish(z)
+			}
+			report.Reason = fmt.Sprintf("%s does not exist", name)
+			return report, nil
+		}
+
+		set, sigs := rrset(resp.Answer, name, dns.TypeDS)
+		if len(set) == 0 {
+			// no DS, either name is inside the parent zone or it is an
+			// unsigned delegation
+			apex, err := d.isZoneApex(ctx, name)
+			if err != nil {
+				return nil, err
+			}
+			if !apex {
+				continue
+			}
+			if err := provesNoDS(&z, resp, name, parent, keys); err != nil {
+				z.Status, z.Reason = DnsSecBogus, err.Error()
+			} else {
+				z.Status, z.Reason = DnsSecInsecure, "unsigned delegation from "+parent
+			}
+			return finish(z)
+		}
+
+		var ds []*dns.DS
+		for _, rr := range set {
+			ds = append(ds, rr.(*dns.DS))
+			z.DS = append(z.DS, newDSRecord(rr.(*dns.DS)))
+		}
+		if err := z.verify(set, sigs, keys, parent); err != nil {
+			z.Status, z.Reason = DnsSecBogus, "DS set: "+err.Error()
+			if errors.Is(err, errUnsupportedAlgorithm) {
+				z.Status = DnsSecInsecure
+			}
+			return finish(z)
+		}
+		resp, err = d.query(ctx, name, dns.TypeDNSKEY)
+		if err != nil {
+			return nil, err
+		}
+		keys, err = validateKeys(&z, resp, ds)
+		if err != nil {
+			z.Status, z.Reason = DnsSecBogus, err.Error()
+			if errors.Is(err, errUnsupportedAlgorithm) {
+				z.Status = DnsSecInsecure
+			}
+			return finish(z)
+		}
+		z.Status = DnsSecSecure
+		report.Zones = append(report.Zones, z)
+		parent = name
+	}
+	return report, nil
+}
+
+// isZoneApex reports whether name has its own SOA record.
+func (d *DnsSec) isZoneApex(ctx context.Context, name string) (bool, error) {
+	resp, err := d.query(ctx, name, dns.TypeSOA)
+	if err != nil {
+		return false, err
+	}
+	set, _ := rrset(resp.Answer, name, dns.TypeSOA)
+	return len(set) > 0, nil
+}
+
+func newDnsSecZone(name string) DnsSecZone {
+	return DnsSecZone{
+		Zone:       name,
+		DS:         make([]DSRecord, 0),
+		Keys:       make([]DnsSecKey, 0),
+		Signatures: make([]DnsSecSignature, 0),
+	}
+}
+
+func newDSRecord(ds *dns.DS) DSRecord {
+	return DSRecord{
+		KeyTag:     ds.KeyTag,
+		Algorithm:  ds.Algorithm,
+		DigestType: ds.DigestType,
+		Digest:     ds.Digest,
+		TTL:        ds.Hdr.Ttl,
+	}
+}
+
+func (d *DnsSec) Name() string {
+	return "dnssec"
+}
+
+func (d *DnsSec) Description() string {
+	return "Validates the DNSSEC chain of trust from the root to the domain"
+}
+
+func (d *DnsSec) Input() Input {
+	return InputHostname
+}
+
+func (d *DnsSec) Timeout() time.Duration {
+	return 20 * time.Second
+}
+
+func (d *DnsSec) Run(ctx context.Context, target Target) (any, error) {
+	return d.Validate(ctx, target.Hostname())
+}
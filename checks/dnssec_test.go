@@ -0,0 +1,285 @@
+package checks
+
+import (
+	"context"
+	"crypto"
+	"fmt"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/miekg/dns"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/xray-web/web-check-api/checks/clients/ip"
+)
+
+// signedTree stands in for a recursive resolver over a set of signed zones.
+type signedTree struct {
+	t       *testing.T
+	records []dns.RR
+	keys    map[string]*dns.DNSKEY
+	signers map[string]crypto.Signer
+}
+
+func newSignedTree(t *testing.T) *signedTree {
+	return &signedTree{t: t, keys: make(map[string]*dns.DNSKEY), signers: make(map[string]crypto.Signer)}
+}
+
+// zone creates a signing key for name and publishes a signed DNSKEY set
+// and SOA record valid between inception and expiration.
+func (s *signedTree) zone(name string, inception, expiration time.Time) *dns.DNSKEY {
+	key := &dns.DNSKEY{
+		Hdr:       dns.RR_Header{Name: name, Rrtype: dns.TypeDNSKEY, Class: dns.ClassINET, Ttl: 3600},
+		Flags:     257,
+		Protocol:  3,
+		Algorithm: dns.ECDSAP256SHA256,
+	}
+	priv, err := key.Generate(256)
+	require.NoError(s.t, err)
+	s.keys[name] = key
+	s.signers[name] = priv.(crypto.Signer)
+
+	s.add(name, inception, expiration, key)
+	apex := strings.TrimSuffix(name, ".")
+	soa := fmt.Sprintf("%s 3600 IN SOA %s %s 1 7200 3600 1209600 3600", name, dns.Fqdn("ns."+apex), dns.Fqdn("hostmaster."+apex))
+	s.add(name, inception, expiration, s.rr(soa))
+	return key
+}
+
+// delegate publishes a DS record for child signed by parent.
+func (s *signedTree) delegate(parent, child string) {
+	ds := s.keys[child].ToDS(dns.SHA256)
+	s.add(parent, time.Now().Add(-time.Hour), time.Now().Add(time.Hour), ds)
+}
+
+// add publishes rrs signed by the key of zone.
+func (s *signedTree) add(zone string, inception, expiration time.Time, rrs ...dns.RR) {
+	sig := &dns.RRSIG{
+		Hdr:        dns.RR_Header{Name: rrs[0].Header().Name, Rrtype: dns.TypeRRSIG, Class: dns.ClassINET, Ttl: 3600},
+		Algorithm:  dns.ECDSAP256SHA256,
+		SignerName: zone,
+		KeyTag:     s.keys[zone].KeyTag(),
+		Inception:  uint32(inception.Unix()),
+		Expiration: uint32(expiration.Unix()),
+	}
+	require.NoError(s.t, sig.Sign(s.signers[zone], rrs))
+	s.records = append(s.records, rrs...)
+	s.records = append(s.records, sig)
+}
+
+func (s *signedTree) rr(record string) dns.RR {
+	rr, err := dns.NewRR(record)
+	require.NoError(s.t, err)
+	return rr
+}
+
+func (s *signedTree) Exchange(ctx context.Context, msg *dns.Msg) (*dns.Msg, error) {
+	q := msg.Question[0]
+	resp := new(dns.Msg)
+	resp.SetReply(msg)
+	resp.Rcode = dns.RcodeNameError
+	for _, rr := range s.records {
+		if !dns.IsSubDomain(q.Name, rr.Header().Name) {
+			continue
+		}
+		resp.Rcode = dns.RcodeSuccess
+		if rr.Header().Name != q.Name {
+			continue
+		}
+		covered := rr.Header().Rrtype
+		if sig, ok := rr.(*dns.RRSIG); ok {
+			covered = sig.TypeCovered
+		}
+		switch covered {
+		case q.Qtype:
+			resp.Answer = append(resp.Answer, rr)
+		case dns.TypeNSEC:
+			resp.Ns = append(resp.Ns, rr)
+		}
+	}
+	if len(resp.Answer) > 0 {
+		resp.Ns = nil
+	}
+	if resp.Rcode == dns.RcodeNameError {
+		// deny the name with every NSEC and NSEC3 signed by a zone above it
+		for _, rr := range s.records {
+			sig, ok := rr.(*dns.RRSIG)
+			if !ok || (sig.TypeCovered != dns.TypeNSEC && sig.TypeCovered != dns.TypeNSEC3) || !dns.IsSubDomain(sig.SignerName, q.Name) {
+				continue
+			}
+			for _, denial := range s.records {
+				if denial.Header().Name == sig.Hdr.Name && denial.Header().Rrtype == sig.TypeCovered {
+					resp.Ns = append(resp.Ns, denial)
+				}
+			}
+			resp.Ns = append(resp.Ns, sig)
+		}
+	}
+	return resp, nil
+}
+
+func TestDnsSecValidate(t *testing.T) {
+	t.Parallel()
+
+	now := time.Now()
+	tree := newSignedTree(t)
+	root := tree.zone(".", now.Add(-time.Hour), now.Add(time.Hour))
+	tree.zone("com.", now.Add(-time.Hour), now.Add(time.Hour))
+	tree.delegate(".", "com.")
+
+	tree.zone("example.com.", now.Add(-time.Hour), now.Add(time.Hour))
+	tree.delegate("com.", "example.com.")
+	tree.add("example.com.", now.Add(-time.Hour), now.Add(time.Hour), tree.rr("www.example.com. 300 IN A 192.0.2.1"))
+
+	// signed, but the DNSKEY signature has expired
+	tree.zone("expired.com.", now.Add(-2*time.Hour), now.Add(-time.Hour))
+	tree.delegate("com.", "expired.com.")
+
+	// delegated without a DS record, proven by a signed NSEC
+	tree.records = append(tree.records, tree.rr("insecure.com. 3600 IN SOA ns.insecure.com. hostmaster.insecure.com. 1 7200 3600 1209600 3600"))
+	tree.add("com.", now.Add(-time.Hour), now.Add(time.Hour), tree.rr("insecure.com. 3600 IN NSEC z.com. NS RRSIG NSEC"))
+
+	// delegated without a DS record or a proof
+	tree.records = append(tree.records, tree.rr("unproven.com. 3600 IN SOA ns.unproven.com. hostmaster.unproven.com. 1 7200 3600 1209600 3600"))
+
+	// the NSEC chain of com. starts at the apex, so *.com. is covered and
+	// with insecure.com. to z.com. proves missing.com. does not exist
+	tree.add("com.", now.Add(-time.Hour), now.Add(time.Hour), tree.rr("com. 3600 IN NSEC a.com. NS SOA RRSIG NSEC DNSKEY"))
+
+	// a denial of x.example.com. forged with the key of com.
+	tree.add("com.", now.Add(-time.Hour), now.Add(time.Hour), tree.rr("example.com. 3600 IN NSEC www.example.com. NS SOA RRSIG NSEC DNSKEY"))
+	tree.add("com.", now.Add(-time.Hour), now.Add(time.Hour), tree.rr("www.example.com. 3600 IN NSEC zzz.example.com. A RRSIG NSEC"))
+
+	// a single NSEC3 whose next hash is its own covers every other name
+	tree.zone("hashed.com.", now.Add(-time.Hour), now.Add(time.Hour))
+	tree.delegate("com.", "hashed.com.")
+	apexHash := dns.HashName("hashed.com.", dns.SHA1, 0, "")
+	tree.add("hashed.com.", now.Add(-time.Hour), now.Add(time.Hour),
+		tree.rr(fmt.Sprintf("%s.hashed.com. 3600 IN NSEC3 1 0 0 - %s NS SOA RRSIG DNSKEY NSEC3PARAM", apexHash, apexHash)))
+
+	anchors := []*dns.DS{root.ToDS(dns.SHA256)}
+
+	tests := []struct {
+		name    string
+		domain  string
+		anchors []*dns.DS
+		status  string
+		reason  string
+		zones   []string
+	}{
+		{
+			name:   "secure",
+			domain: "www.example.com",
+			status: DnsSecSecure,
+			zones:  []string{".", "com.", "example.com."},
+		},
+		{
+			name:   "expired signature",
+			domain: "expired.com",
+			status: DnsSecBogus,
+			reason: "DNSKEY set: signature by key",
+			zones:  []string{".", "com.", "expired.com."},
+		},
+		{
+			name:   "unsigned delegation",
+			domain: "insecure.com",
+			status: DnsSecInsecure,
+			reason: "unsigned delegation from com.",
+			zones:  []string{".", "com.", "insecure.com."},
+		},
+		{
+			name:   "missing denial proof",
+			domain: "unproven.com",
+			status: DnsSecBogus,
+			reason: "no NSEC or NSEC3 record proves the DS record is absent",
+			zones:  []string{".", "com.", "unproven.com."},
+		},
+		{
+			name:   "proven name error",
+			domain: "missing.com",
+			status: DnsSecSecure,
+			reason: "missing.com. does not exist",
+			zones:  []string{".", "com."},
+		},
+		{
+			name:   "proven hashed name error",
+			domain: "gone.hashed.com",
+			status: DnsSecSecure,
+			reason: "gone.hashed.com. does not exist",
+			zones:  []string{".", "com.", "hashed.com."},
+		},
+		{
+			name:   "unproven name error",
+			domain: "zzzz.example.com",
+			status: DnsSecBogus,
+			reason: "zzzz.example.com. does not exist but the denial is not proven: no NSEC or NSEC3 record proves",
+			zones:  []string{".", "com.", "example.com.", "zzzz.example.com."},
+		},
+		{
+			name:   "forged name error",
+			domain: "x.example.com",
+			status: DnsSecBogus,
+			reason: "NSEC proof:",
+			zones:  []string{".", "com.", "example.com.", "x.example.com."},
+		},
+		{
+			name:    "untrusted root",
+			domain:  "example.com",
+			anchors: RootTrustAnchors,
+			status:  DnsSecBogus,
+			reason:  "no DNSKEY matches the DS records in the parent zone",
+			zones:   []string{"."},
+		},
+	}
+
+	for _, tc := range tests {
+		tc := tc
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+			if tc.anchors == nil {
+				tc.anchors = anchors
+			}
+
+			report, err := NewDnsSec(tree, tc.anchors).Validate(context.Background(), tc.domain)
+			require.NoError(t, err)
+
+			assert.Equal(t, tc.status, report.Status)
+			assert.Contains(t, report.Reason, tc.reason)
+			var zones []string
+			for _, z := range report.Zones {
+				zones = append(zones, z.Zone)
+			}
+			assert.Equal(t, tc.zones, zones)
+		})
+	}
+
+	t.Run("signatures reported", func(t *testing.T) {
+		t.Parallel()
+		report, err := NewDnsSec(tree, anchors).Validate(context.Background(), "example.com")
+		require.NoError(t, err)
+
+		z := report.Zones[2]
+		assert.Equal(t, DnsSecSecure, z.Status)
+		require.Len(t, z.Keys, 1)
+		assert.True(t, z.Keys[0].SEP)
+		assert.Equal(t, "ECDSAP256SHA256", z.Keys[0].Algorithm)
+		require.Len(t, z.DS, 1)
+		assert.Equal(t, z.Keys[0].KeyTag, z.DS[0].KeyTag)
+		for _, sig := range z.Signatures {
+			assert.True(t, sig.Valid)
+		}
+	})
+
+	t.Run("upstream failure", func(t *testing.T) {
+		t.Parallel()
+		d := NewDnsSec(ip.ExchangeFunc(func(ctx context.Context, msg *dns.Msg) (*dns.Msg, error) {
+			resp := new(dns.Msg)
+			resp.SetRcode(msg, dns.RcodeServerFailure)
+			return resp, nil
+		}), anchors)
+
+		_, err := d.Validate(context.Background(), "example.com")
+		assert.EqualError(t, err, ". DNSKEY query failed: SERVFAIL")
+	})
+}
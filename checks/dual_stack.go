@@ -0,0 +1,298 @@
+package checks
+
+import (
+	"context"
+	"crypto/sha256"
+	"crypto/tls"
+	"encoding/hex"
+	"errors"
+	"io"
+	"net"
+	"net/http"
+	"net/url"
+	"slices"
+	"strconv"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/xray-web/web-check-api/checks/clients/ip"
+)
+
+// How the site works over the two stacks.
+const (
+	DualStackOK       = "dual-stack"
+	DualStackIPv4Only = "ipv4-only"
+	DualStackIPv6Only = "ipv6-only"
+	// DualStackIPv6Broken has IPv6 addresses but none of them work, so
+	// clients preferring IPv6 are left waiting for IPv4.
+	DualStackIPv6Broken  = "ipv6-broken"
+	DualStackIPv4Broken  = "ipv4-broken"
+	DualStackUnreachable = "unreachable"
+)
+
+const (
+	// happyEyeballsDelay is how long a client gives IPv6 to connect before
+	// also trying IPv4, the Connection Attempt Delay of RFC 8305.
+	happyEyeballsDelay = 250 * time.Millisecond
+	// stackTimeout bounds the request to each address.
+	stackTimeout = 10 * time.Second
+	// maxStackBody is the most of a response read to measure its size.
+	maxStackBody = 5 << 20
+	// stackSizeTolerance is the fraction response sizes can differ by
+	// between the stacks before it is reported, as pages vary.
+	stackSizeTolerance = 0.1
+)
+
+// stackHeaders are the response headers compared between the stacks.
+var stackHeaders = []string{
+	"Server",
+	"Location",
+	"Content-Type",
+	"Strict-Transport-Security",
+	"Content-Security-Policy",
+	"X-Frame-Options",
+}
+
+// StackResponse is how one address of the site responded.
+type StackResponse struct {
+	Address net.IP `json:"ip"`
+	Family  int    `json:"family"`
+	// Connected is whether the TCP connection opened, Connect is how long
+	// it took to open or fail and Total how long until the whole response
+	// was read.
+	Connected bool   `json:"connected"`
+	Connect   int64  `json:"connectMs"`
+	Total     int64  `json:"totalMs"`
+	Error     string `json:"error,omitempty"`
+	Status    int    `json:"status,omitempty"`
+	Size      int64  `json:"size,omitempty"`
+	// Certificate is the SHA-256 fingerprint of the leaf certificate.
+	Certificate string            `json:"certificate,omitempty"`
+	Headers     map[string]string `json:"headers,omitempty"`
+}
+
+// StackDifference is a part of the response that isn't the same over IPv4
+// and IPv6. Field is status, certificate, size or the name of a header.
+type StackDifference struct {
+	Field string `json:"field"`
+	IPv4  string `json:"ipv4"`
+	IPv6  string `json:"ipv6"`
+}
+
+// HappyEyeballs is how a client racing the first address of each family as
+// in RFC 8305 would connect, trying IPv6 first then IPv4 once IPv6 has
+// failed or had happyEyeballsDelay to connect.
+type HappyEyeballs struct {
+	IPv4Connect int64 `json:"ipv4ConnectMs"`
+	IPv6Connect int64 `json:"ipv6ConnectMs"`
+	// Family is the stack the client ends up on, after Connect.
+	Family  int   `json:"family"`
+	Connect int64 `json:"connectMs"`
+}
+
+type DualStackReport struct {
+	Status    string          `json:"status"`
+	Addresses []StackResponse `json:"addresses"`
+	// Differences compares the first working address of each family.
+	Differences []StackDifference `json:"differences"`
+	// HappyEyeballs is missing unless the site has addresses in both
+	// families and one of them connected.
+	HappyEyeballs *HappyEyeballs `json:"happyEyeballs,omitempty"`
+}
+
+type DualStack struct {
+	lookup ip.Lookup
+	dialer Dialer
+}
+
+// NewDualStack returns a check that requests the site from each address
+// lookup finds for it, connecting with dialer.
+func NewDualStack(lookup ip.Lookup, dialer Dialer) *DualStack {
+	return &DualStack{lookup: lookup, dialer: dialer}
+}
+
+// fetch requests target from addr without following redirects, the
+// certificate is recorded rather than verified so the stacks can be
+// compared even when it is wrong.
+func (d *DualStack) fetch(ctx context.Context, target *url.URL, addr net.IP) StackResponse {
+	result := StackResponse{Address: addr, Family: 6}
+	if addr.To4() != nil {
+		result.Family = 4
+	}
+	port := target.Port()
+	if port == "" {
+		port = "80"
+		if target.Scheme == "https" {
+			port = defaultTLSPort
+		}
+	}
+	ctx, cancel := context.WithTimeout(ctx, stackTimeout)
+	defer cancel()
+
+	// the transport may still be dialling when a cancelled request returns
+	var connected atomic.Bool
+	var connect atomic.Int64
+	start := time.Now()
+	transport := &http.Transport{
+		DialContext: func(ctx context.Context, network, _ string) (net.Conn, error) {
+			conn, err := d.dialer.DialContext(ctx, "tcp", net.JoinHostPort(addr.String(), port))
+			connect.Store(time.Since(start).Milliseconds())
+			connected.Store(err == nil)
+			return conn, err
+		},
+		TLSClientConfig:   &tls.Config{InsecureSkipVerify: true},
+		DisableKeepAlives: true,
+	}
+	defer transport.CloseIdleConnections()
+	client := &http.Client{
+		Transport: transport,
+		CheckRedirect: func(*http.Request, []*http.Request) error {
+			return http.ErrUseLastResponse
+		},
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, target.String(), nil)
+	if err != nil {
+		result.Error = err.Error()
+		return result
+	}
+	resp, err := client.Do(req)
+	result.Connected, result.Connect = connected.Load(), connect.Load()
+	if err != nil {
+		result.Error = err.Error()
+		result.Total = time.Since(start).Milliseconds()
+		return result
+	}
+	defer resp.Body.Close()
+
+	result.Status = resp.StatusCode
+	result.Size, err = io.Copy(io.Discard, io.LimitReader(resp.Body, maxStackBody))
+	if err != nil {
+		result.Error = err.Error()
+	}
+	result.Total = time.Since(start).Milliseconds()
+	if resp.TLS != nil && len(resp.TLS.PeerCertificates) > 0 {
+		fingerprint := sha256.Sum256(resp.TLS.PeerCertificates[0].Raw)
+		result.Certificate = hex.EncodeToString(fingerprint[:])
+	}
+	for _, key := range stackHeaders {
+		if value := resp.Header.Get(key); value != "" {
+			if result.Headers == nil {
+				result.Headers = make(map[string]string)
+			}
+			result.Headers[key] = value
+		}
+	}
+	return result
+}
+
+// compareStacks lists what differs between a response over IPv4 and one
+// over IPv6.
+func compareStacks(v4, v6 StackResponse) []StackDifference {
+	differences := make([]StackDifference, 0)
+	compare := func(field, a, b string) {
+		if a != b {
+			differences = append(differences, StackDifference{Field: field, IPv4: a, IPv6: b})
+		}
+	}
+	compare("status", strconv.Itoa(v4.Status), strconv.Itoa(v6.Status))
+	compare("certificate", v4.Certificate, v6.Certificate)
+	if larger := max(v4.Size, v6.Size); float64(larger-min(v4.Size, v6.Size)) > stackSizeTolerance*float64(larger) {
+		compare("size", strconv.FormatInt(v4.Size, 10), strconv.FormatInt(v6.Size, 10))
+	}
+	for _, key := range stackHeaders {
+		compare(key, v4.Headers[key], v6.Headers[key])
+	}
+	return differences
+}
+
+// happyEyeballs races the connections v4 and v6 measured, it returns nil if
+// neither connected.
+func happyEyeballs(v4, v6 StackResponse) *HappyEyeballs {
+	if !v4.Connected && !v6.Connected {
+		return nil
+	}
+	he := &HappyEyeballs{IPv4Connect: v4.Connect, IPv6Connect: v6.Connect}
+	fallback := happyEyeballsDelay.Milliseconds()
+	if !v6.Connected {
+		fallback = min(fallback, v6.Connect)
+	}
+	if v6.Connected && (!v4.Connected || v6.Connect <= fallback+v4.Connect) {
+		he.Family, he.Connect = 6, v6.Connect
+	} else {
+		he.Family, he.Connect = 4, fallback+v4.Connect
+	}
+	return he
+}
+
+func (d *DualStack) Compare(ctx context.Context, target *url.URL) (*DualStackReport, error) {
+	ip4, err4 := d.lookup.LookupIP(ctx, "ip4", target.Hostname())
+	ip6, err6 := d.lookup.LookupIP(ctx, "ip6", target.Hostname())
+	if len(ip4) == 0 && len(ip6) == 0 {
+		if err := errors.Join(err4, err6); err != nil {
+			return nil, err
+		}
+		return nil, errors.New("no addresses found")
+	}
+
+	addrs := slices.Concat(ip4, ip6)
+	report := &DualStackReport{Addresses: make([]StackResponse, len(addrs)), Differences: make([]StackDifference, 0)}
+	var wg sync.WaitGroup
+	for i, addr := range addrs {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			report.Addresses[i] = d.fetch(ctx, target, addr)
+		}()
+	}
+	wg.Wait()
+
+	// the first working address of each family
+	working := make(map[int]*StackResponse)
+	for i := range report.Addresses {
+		if result := &report.Addresses[i]; result.Status != 0 && working[result.Family] == nil {
+			working[result.Family] = result
+		}
+	}
+	v4, v6 := working[4], working[6]
+	switch {
+	case len(ip6) == 0 && v4 != nil:
+		report.Status = DualStackIPv4Only
+	case len(ip4) == 0 && v6 != nil:
+		report.Status = DualStackIPv6Only
+	case v4 != nil && v6 != nil:
+		report.Status = DualStackOK
+		report.Differences = compareStacks(*v4, *v6)
+	case v4 != nil:
+		report.Status = DualStackIPv6Broken
+	case v6 != nil:
+		report.Status = DualStackIPv4Broken
+	default:
+		report.Status = DualStackUnreachable
+	}
+	if len(ip4) > 0 && len(ip6) > 0 {
+		report.HappyEyeballs = happyEyeballs(report.Addresses[0], report.Addresses[len(ip4)])
+	}
+	return report, nil
+}
+
+func (d *DualStack) Name() string {
+	return "dual-stack"
+}
+
+func (d *DualStack) Description() string {
+	return "Requests the site from each of its IPv4 and IPv6 addresses to compare how it works over both stacks"
+}
+
+func (d *DualStack) Input() Input {
+	return InputURL
+}
+
+func (d *DualStack) Timeout() time.Duration {
+	return 20 * time.Second
+}
+
+func (d *DualStack) Run(ctx context.Context, target Target) (any, error) {
+	return d.Compare(ctx, target.URL)
+}
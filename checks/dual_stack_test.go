@@ -0,0 +1,187 @@
+package checks
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"syscall"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/xray-web/web-check-api/checks/clients/ip"
+)
+
+// dualStack returns a check that resolves example.com to 192.0.2.1 and
+// 2001:db8::1, connecting to the servers given for them. A nil server
+// refuses connections and a missing address isn't resolved.
+func dualStack(t *testing.T, servers map[string]*httptest.Server) *DualStack {
+	t.Helper()
+	return NewDualStack(ip.LookupFunc(func(ctx context.Context, network, host string) ([]net.IP, error) {
+		addr := "192.0.2.1"
+		if network == "ip6" {
+			addr = "2001:db8::1"
+		}
+		if _, ok := servers[addr]; !ok {
+			return nil, &net.DNSError{Err: "no such host", Name: host, IsNotFound: true}
+		}
+		return []net.IP{net.ParseIP(addr)}, nil
+	}), DialFunc(func(ctx context.Context, network, address string) (net.Conn, error) {
+		host, port, err := net.SplitHostPort(address)
+		require.NoError(t, err)
+		assert.Equal(t, "443", port)
+		server := servers[host]
+		if server == nil {
+			return nil, &net.OpError{Op: "dial", Net: network, Err: syscall.ECONNREFUSED}
+		}
+		return (&net.Dialer{}).DialContext(ctx, network, server.Listener.Addr().String())
+	}))
+}
+
+func site(t *testing.T, server string, size int) *httptest.Server {
+	s := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, "example.com", r.Host)
+		w.Header().Set("Server", server)
+		w.Header().Set("Content-Type", "text/html")
+		fmt.Fprint(w, strings.Repeat("x", size))
+	}))
+	t.Cleanup(s.Close)
+	return s
+}
+
+func TestDualStack(t *testing.T) {
+	t.Parallel()
+
+	target := &url.URL{Scheme: "https", Host: "example.com", Path: "/"}
+	v4 := site(t, "nginx", 1000)
+
+	t.Run("dual stack", func(t *testing.T) {
+		t.Parallel()
+		report, err := dualStack(t, map[string]*httptest.Server{
+			"192.0.2.1":   v4,
+			"2001:db8::1": site(t, "apache", 950),
+		}).Compare(context.Background(), target)
+		require.NoError(t, err)
+
+		assert.Equal(t, DualStackOK, report.Status)
+		require.Len(t, report.Addresses, 2)
+		for i, family := range []int{4, 6} {
+			result := report.Addresses[i]
+			assert.Equal(t, family, result.Family)
+			assert.True(t, result.Connected)
+			assert.Equal(t, http.StatusOK, result.Status)
+			assert.Len(t, result.Certificate, 64)
+		}
+		assert.Equal(t, int64(1000), report.Addresses[0].Size)
+		assert.Equal(t, "2001:db8::1", report.Addresses[1].Address.String())
+
+		// sizes within the tolerance aren't a difference
+		assert.Equal(t, []StackDifference{{Field: "Server", IPv4: "nginx", IPv6: "apache"}}, report.Differences)
+		require.NotNil(t, report.HappyEyeballs)
+		assert.Equal(t, 6, report.HappyEyeballs.Family)
+	})
+
+	t.Run("ipv6 broken", func(t *testing.T) {
+		t.Parallel()
+		report, err := dualStack(t, map[string]*httptest.Server{
+			"192.0.2.1":   v4,
+			"2001:db8::1": nil,
+		}).Compare(context.Background(), target)
+		require.NoError(t, err)
+
+		assert.Equal(t, DualStackIPv6Broken, report.Status)
+		assert.False(t, report.Addresses[1].Connected)
+		assert.Contains(t, report.Addresses[1].Error, "connection refused")
+		assert.Empty(t, report.Differences)
+		require.NotNil(t, report.HappyEyeballs)
+		assert.Equal(t, 4, report.HappyEyeballs.Family)
+	})
+
+	t.Run("ipv4 only", func(t *testing.T) {
+		t.Parallel()
+		report, err := dualStack(t, map[string]*httptest.Server{"192.0.2.1": v4}).Compare(context.Background(), target)
+		require.NoError(t, err)
+
+		assert.Equal(t, DualStackIPv4Only, report.Status)
+		assert.Len(t, report.Addresses, 1)
+		assert.Nil(t, report.HappyEyeballs)
+	})
+
+	t.Run("unreachable", func(t *testing.T) {
+		t.Parallel()
+		report, err := dualStack(t, map[string]*httptest.Server{"2001:db8::1": nil}).Compare(context.Background(), target)
+		require.NoError(t, err)
+		assert.Equal(t, DualStackUnreachable, report.Status)
+	})
+
+	t.Run("no addresses", func(t *testing.T) {
+		t.Parallel()
+		_, err := dualStack(t, nil).Compare(context.Background(), target)
+		assert.ErrorContains(t, err, "no such host")
+	})
+}
+
+func TestCompareStacks(t *testing.T) {
+	t.Parallel()
+
+	v4 := StackResponse{Status: 200, Size: 1000, Certificate: "aa", Headers: map[string]string{"Strict-Transport-Security": "max-age=63072000"}}
+	v6 := StackResponse{Status: 301, Size: 100, Certificate: "bb", Headers: map[string]string{"Location": "https://www.example.com/"}}
+	assert.Equal(t, []StackDifference{
+		{Field: "status", IPv4: "200", IPv6: "301"},
+		{Field: "certificate", IPv4: "aa", IPv6: "bb"},
+		{Field: "size", IPv4: "1000", IPv6: "100"},
+		{Field: "Location", IPv6: "https://www.example.com/"},
+		{Field: "Strict-Transport-Security", IPv4: "max-age=63072000"},
+	}, compareStacks(v4, v6))
+	assert.Empty(t, compareStacks(v4, v4))
+}
+
+func TestHappyEyeballs(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name   string
+		v4, v6 StackResponse
+		want   *HappyEyeballs
+	}{
+		{
+			name: "ipv6 within the delay",
+			v4:   StackResponse{Connected: true, Connect: 10},
+			v6:   StackResponse{Connected: true, Connect: 100},
+			want: &HappyEyeballs{IPv4Connect: 10, IPv6Connect: 100, Family: 6, Connect: 100},
+		},
+		{
+			name: "slow ipv6",
+			v4:   StackResponse{Connected: true, Connect: 20},
+			v6:   StackResponse{Connected: true, Connect: 300},
+			want: &HappyEyeballs{IPv4Connect: 20, IPv6Connect: 300, Family: 4, Connect: 270},
+		},
+		{
+			name: "ipv6 refused",
+			v4:   StackResponse{Connected: true, Connect: 20},
+			v6:   StackResponse{Connect: 5},
+			want: &HappyEyeballs{IPv4Connect: 20, IPv6Connect: 5, Family: 4, Connect: 25},
+		},
+		{
+			name: "ipv6 timed out",
+			v4:   StackResponse{Connected: true, Connect: 20},
+			v6:   StackResponse{Connect: 5000},
+			want: &HappyEyeballs{IPv4Connect: 20, IPv6Connect: 5000, Family: 4, Connect: 270},
+		},
+		{
+			name: "neither",
+			v4:   StackResponse{Connect: 5000},
+			v6:   StackResponse{Connect: 5000},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+			assert.Equal(t, tt.want, happyEyeballs(tt.v4, tt.v6))
+		})
+	}
+}
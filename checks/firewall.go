@@ -0,0 +1,133 @@
+package checks
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"strings"
+)
+
+const (
+	cloudflare  = "Cloudflare"
+	awsWAF      = "AWS WAF"
+	akamai      = "Akamai"
+	sucuri      = "Sucuri"
+	barracuda   = "Barracuda WAF"
+	f5          = "F5 BIG-IP"
+	sucuriProxy = "Sucuri CloudProxy WAF"
+	fortinet    = "Fortinet FortiWeb WAF"
+	imperva     = "Imperva SecureSphere WAF"
+	sqreen      = "Sqreen"
+	reblaze     = "Reblaze WAF"
+	citrix      = "Citrix NetScaler"
+	wzb         = "WangZhanBao WAF"
+	webcoment   = "Webcoment Firewall"
+	yundun      = "Yundun WAF"
+	safe3       = "Safe3 Web Application Firewall"
+	naxsi       = "NAXSI WAF"
+	ibm         = "IBM WebSphere DataPower"
+	qrator      = "QRATOR WAF"
+	ddosGuard   = "DDoS-Guard WAF"
+)
+
+type WafResponse struct {
+	HasWaf bool   `json:"hasWaf"`
+	Waf    string `json:"waf,omitempty"`
+}
+
+type Firewall struct {
+	client *http.Client
+}
+
+func NewFirewall(client *http.Client) *Firewall {
+	return &Firewall{client: client}
+}
+
+// GetWaf detects a web application firewall from the response headers.
+func (f *Firewall) GetWaf(ctx context.Context, url string) (WafResponse, error) {
+	// TODO(Lissy93): does this test require we set scheme to http?
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
+	if err != nil {
+		return WafResponse{}, fmt.Errorf("error creating request: %s", err.Error())
+	}
+	resp, err := f.client.Do(req)
+	if err != nil {
+		return WafResponse{}, fmt.Errorf("error fetching URL: %s", err.Error())
+	}
+	defer resp.Body.Close()
+
+	return detectWaf(resp.Header), nil
+}
+
+func detectWaf(headers http.Header) WafResponse {
+	for header, values := range headers {
+		lowerHeader := strings.ToLower(header)
+
+		for _, value := range values {
+			lowerValue := strings.ToLower(value)
+
+			switch {
+			case lowerHeader == "server" && strings.Contains(lowerValue, "cloudflare"):
+				return WafResponse{HasWaf: true, Waf: cloudflare}
+			case lowerHeader == "x-powered-by" && strings.Contains(lowerValue, "aws lambda"):
+				return WafResponse{HasWaf: true, Waf: awsWAF}
+			case lowerHeader == "server" && strings.Contains(lowerValue, "akamaighost"):
+				return WafResponse{HasWaf: true, Waf: akamai}
+			case lowerHeader == "server" && strings.Contains(lowerValue, "sucuri"):
+				return WafResponse{HasWaf: true, Waf: sucuri}
+			case lowerHeader == "server" && strings.Contains(lowerValue, "barracudawaf"):
+				return WafResponse{HasWaf: true, Waf: barracuda}
+			case lowerHeader == "server" && (strings.Contains(lowerValue, "f5 big-ip") || strings.Contains(lowerValue, "big-ip")):
+				return WafResponse{HasWaf: true, Waf: f5}
+			case lowerHeader == "x-sucuri-id" || lowerHeader == "x-sucuri-cache":
+				return WafResponse{HasWaf: true, Waf: sucuriProxy}
+			case lowerHeader == "server" && strings.Contains(lowerValue, "fortiweb"):
+				return WafResponse{HasWaf: true, Waf: fortinet}
+			case lowerHeader == "server" && strings.Contains(lowerValue, "imperva"):
+				return WafResponse{HasWaf: true, Waf: imperva}
+			case lowerHeader == "x-protected-by" && strings.Contains(lowerValue, "sqreen"):
+				return WafResponse{HasWaf: true, Waf: sqreen}
+			case lowerHeader == "x-waf-event-info":
+				return WafResponse{HasWaf: true, Waf: reblaze}
+			case lowerHeader == "set-cookie" && strings.Contains(lowerValue, "_citrix_ns_id"):
+				return WafResponse{HasWaf: true, Waf: citrix}
+			case lowerHeader == "x-denied-reason" || lowerHeader == "x-wzws-requested-method":
+				return WafResponse{HasWaf: true, Waf: wzb}
+			case lowerHeader == "x-webcoment":
+				return WafResponse{HasWaf: true, Waf: webcoment}
+			case lowerHeader == "server" && strings.Contains(lowerValue, "yundun"):
+				return WafResponse{HasWaf: true, Waf: yundun}
+			case lowerHeader == "x-yd-waf-info" || lowerHeader == "x-yd-info":
+				return WafResponse{HasWaf: true, Waf: yundun}
+			case lowerHeader == "server" && strings.Contains(lowerValue, "safe3waf"):
+				return WafResponse{HasWaf: true, Waf: safe3}
+			case lowerHeader == "server" && strings.Contains(lowerValue, "naxsi"):
+				return WafResponse{HasWaf: true, Waf: naxsi}
+			case lowerHeader == "x-datapower-transactionid":
+				return WafResponse{HasWaf: true, Waf: ibm}
+			case lowerHeader == "server" && strings.Contains(lowerValue, "qrator"):
+				return WafResponse{HasWaf: true, Waf: qrator}
+			case lowerHeader == "server" && strings.Contains(lowerValue, "ddos-guard"):
+				return WafResponse{HasWaf: true, Waf: ddosGuard}
+			}
+		}
+	}
+
+	return WafResponse{HasWaf: false}
+}
+
+func (f *Firewall) Name() string {
+	return "firewall"
+}
+
+func (f *Firewall) Description() string {
+	return "Detects whether the site is behind a web application firewall"
+}
+
+func (f *Firewall) Input() Input {
+	return InputURL
+}
+
+func (f *Firewall) Run(ctx context.Context, target Target) (any, error) {
+	return f.GetWaf(ctx, target.String())
+}
@@ -0,0 +1,50 @@
+package checks
+
+import (
+	"context"
+	"net/http"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/xray-web/web-check-api/testutils"
+)
+
+func TestGetWaf(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name     string
+		header   http.Header
+		expected WafResponse
+	}{
+		{
+			name:     "cloudflare",
+			header:   http.Header{"Server": {"cloudflare"}},
+			expected: WafResponse{HasWaf: true, Waf: cloudflare},
+		},
+		{
+			name:     "sucuri proxy",
+			header:   http.Header{"X-Sucuri-Id": {"12345"}},
+			expected: WafResponse{HasWaf: true, Waf: sucuriProxy},
+		},
+		{
+			name:     "no waf",
+			header:   http.Header{"Server": {"nginx"}},
+			expected: WafResponse{HasWaf: false},
+		},
+	}
+
+	for _, tc := range tests {
+		tc := tc
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+			resp := testutils.Response(http.StatusOK, nil)
+			resp.Header = tc.header
+			f := NewFirewall(testutils.MockClient(resp))
+
+			actual, err := f.GetWaf(context.Background(), "http://example.com")
+			assert.NoError(t, err)
+			assert.Equal(t, tc.expected, actual)
+		})
+	}
+}
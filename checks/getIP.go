@@ -3,21 +3,47 @@ package checks
 import (
 	"context"
 	"net"
+	"strings"
+	"sync"
 
+	"github.com/miekg/dns"
 	"github.com/xray-web/web-check-api/checks/clients/ip"
+	"github.com/xray-web/web-check-api/checks/store/ipintel"
 )
 
 type IpAddress struct {
 	Address net.IP `json:"ip"`
 	Family  int    `json:"family"`
+	ipintel.Info
+	ReverseDNS []string `json:"reverseDns,omitempty"`
 }
 
 type NetIp struct {
-	lookup ip.Lookup
+	lookup   ip.Lookup
+	resolver ip.Exchanger
+	intel    ipintel.Getter
 }
 
-func NewNetIp(lookup ip.Lookup) *NetIp {
-	return &NetIp{lookup: lookup}
+// NewNetIp returns a check that resolves a host with lookup, and describes
+// each address from the local intel databases and its PTR records from
+// resolver. Either can be nil to skip it.
+func NewNetIp(lookup ip.Lookup, resolver ip.Exchanger, intel ipintel.Getter) *NetIp {
+	return &NetIp{lookup: lookup, resolver: resolver, intel: intel}
+}
+
+func (l *NetIp) reverseDNS(ctx context.Context, addr net.IP) []string {
+	name, err := dns.ReverseAddr(addr.String())
+	if err != nil {
+		return nil
+	}
+	records, _ := queryDNS(ctx, l.resolver, name, dns.TypePTR)
+	var names []string
+	for _, rr := range records {
+		if ptr, ok := rr.(*dns.PTR); ok {
+			names = append(names, strings.TrimSuffix(ptr.Ptr, "."))
+		}
+	}
+	return names
 }
 
 func (l *NetIp) GetIp(ctx context.Context, host string) ([]IpAddress, error) {
@@ -38,5 +64,37 @@ func (l *NetIp) GetIp(ctx context.Context, host string) ([]IpAddress, error) {
 		ipAddresses = append(ipAddresses, IpAddress{Address: ip, Family: 6})
 	}
 
+	var wg sync.WaitGroup
+	for i := range ipAddresses {
+		addr := &ipAddresses[i]
+		if l.intel != nil {
+			addr.Info = l.intel.GetIPInfo(addr.Address)
+		}
+		if l.resolver != nil {
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				addr.ReverseDNS = l.reverseDNS(ctx, addr.Address)
+			}()
+		}
+	}
+	wg.Wait()
+
 	return ipAddresses, nil
 }
+
+func (l *NetIp) Name() string {
+	return "get-ip"
+}
+
+func (l *NetIp) Description() string {
+	return "Resolves the IPv4 and IPv6 addresses of the host with their network, location and reverse DNS"
+}
+
+func (l *NetIp) Input() Input {
+	return InputHostname
+}
+
+func (l *NetIp) Run(ctx context.Context, target Target) (any, error) {
+	return l.GetIp(ctx, target.Hostname())
+}
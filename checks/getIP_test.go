@@ -7,6 +7,7 @@ import (
 
 	"github.com/stretchr/testify/assert"
 	"github.com/xray-web/web-check-api/checks/clients/ip"
+	"github.com/xray-web/web-check-api/checks/store/ipintel"
 )
 
 func TestLookup(t *testing.T) {
@@ -14,9 +15,39 @@ func TestLookup(t *testing.T) {
 
 	n := NewNetIp(ip.LookupFunc(func(ctx context.Context, network string, host string) ([]net.IP, error) {
 		return []net.IP{net.ParseIP("216.58.201.110")}, nil
-	}))
+	}), nil, nil)
 	actual, err := n.GetIp(context.Background(), "google.com")
 	assert.NoError(t, err)
 
 	assert.Contains(t, actual, IpAddress{Address: net.ParseIP("216.58.201.110"), Family: 4})
 }
+
+func TestGetIpEnriched(t *testing.T) {
+	t.Parallel()
+
+	n := NewNetIp(ip.LookupFunc(func(ctx context.Context, network string, host string) ([]net.IP, error) {
+		if network == "ip6" {
+			return []net.IP{net.ParseIP("2001:db8::1")}, nil
+		}
+		return []net.IP{net.ParseIP("192.0.2.1")}, nil
+	}), zone(t,
+		"1.2.0.192.in-addr.arpa. 3600 IN PTR www.example.com.",
+	), ipintel.GetterFunc(func(addr net.IP) ipintel.Info {
+		if addr.To4() == nil {
+			return ipintel.Info{}
+		}
+		return ipintel.Info{ASN: 64500, Organisation: "EXAMPLE-NET", Prefix: "192.0.2.0/24", CountryCode: "US"}
+	}))
+
+	actual, err := n.GetIp(context.Background(), "example.com")
+	assert.NoError(t, err)
+	assert.Equal(t, []IpAddress{
+		{
+			Address:    net.ParseIP("192.0.2.1"),
+			Family:     4,
+			Info:       ipintel.Info{ASN: 64500, Organisation: "EXAMPLE-NET", Prefix: "192.0.2.0/24", CountryCode: "US"},
+			ReverseDNS: []string{"www.example.com"},
+		},
+		{Address: net.ParseIP("2001:db8::1"), Family: 6},
+	}, actual)
+}
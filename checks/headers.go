@@ -27,3 +27,19 @@ func (h *Headers) List(ctx context.Context, url string) (http.Header, error) {
 
 	return resp.Header, nil
 }
+
+func (h *Headers) Name() string {
+	return "headers"
+}
+
+func (h *Headers) Description() string {
+	return "Lists the HTTP response headers returned by the site"
+}
+
+func (h *Headers) Input() Input {
+	return InputURL
+}
+
+func (h *Headers) Run(ctx context.Context, target Target) (any, error) {
+	return h.List(ctx, target.String())
+}
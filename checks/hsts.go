@@ -0,0 +1,80 @@
+package checks
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+var hstsMaxAge = regexp.MustCompile(`max-age=(\d+)`)
+
+type HSTSResponse struct {
+	Message    string `json:"message"`
+	Compatible bool   `json:"compatible"`
+	HSTSHeader string `json:"hstsHeader"`
+}
+
+type Hsts struct {
+	client *http.Client
+}
+
+func NewHsts(client *http.Client) *Hsts {
+	return &Hsts{client: client}
+}
+
+// GetHsts checks whether the site's Strict-Transport-Security header makes
+// it eligible for the HSTS preload list.
+func (h *Hsts) GetHsts(ctx context.Context, url string) (HSTSResponse, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodHead, url, nil)
+	if err != nil {
+		return HSTSResponse{}, fmt.Errorf("error creating request: %s", err.Error())
+	}
+
+	resp, err := h.client.Do(req)
+	if err != nil {
+		return HSTSResponse{}, fmt.Errorf("error making request: %s", err.Error())
+	}
+	defer resp.Body.Close()
+
+	hstsHeader := resp.Header.Get("strict-transport-security")
+	if hstsHeader == "" {
+		return HSTSResponse{Message: "Site does not serve any HSTS headers."}, nil
+	}
+
+	maxAgeMatch := hstsMaxAge.FindStringSubmatch(hstsHeader)
+	if maxAgeMatch == nil {
+		return HSTSResponse{Message: "HSTS max-age is less than 10886400."}, nil
+	}
+	if maxAge, err := strconv.Atoi(maxAgeMatch[1]); err != nil || maxAge < 10886400 {
+		return HSTSResponse{Message: "HSTS max-age is less than 10886400."}, nil
+	}
+
+	if !strings.Contains(hstsHeader, "includeSubDomains") {
+		return HSTSResponse{Message: "HSTS header does not include all subdomains."}, nil
+	}
+
+	if !strings.Contains(hstsHeader, "preload") {
+		return HSTSResponse{Message: "HSTS header does not contain the preload directive."}, nil
+	}
+
+	return HSTSResponse{Message: "Site is compatible with the HSTS preload list!", Compatible: true, HSTSHeader: hstsHeader}, nil
+}
+
+func (h *Hsts) Name() string {
+	return "hsts"
+}
+
+func (h *Hsts) Description() string {
+	return "Checks whether the site is eligible for the HSTS preload list"
+}
+
+func (h *Hsts) Input() Input {
+	return InputURL
+}
+
+func (h *Hsts) Run(ctx context.Context, target Target) (any, error) {
+	return h.GetHsts(ctx, target.String())
+}
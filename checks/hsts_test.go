@@ -0,0 +1,66 @@
+package checks
+
+import (
+	"context"
+	"net/http"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/xray-web/web-check-api/testutils"
+)
+
+func TestGetHsts(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name     string
+		header   string
+		expected HSTSResponse
+	}{
+		{
+			name:     "no header",
+			expected: HSTSResponse{Message: "Site does not serve any HSTS headers."},
+		},
+		{
+			name:     "short max-age",
+			header:   "max-age=300",
+			expected: HSTSResponse{Message: "HSTS max-age is less than 10886400."},
+		},
+		{
+			name:     "missing subdomains",
+			header:   "max-age=31536000; preload",
+			expected: HSTSResponse{Message: "HSTS header does not include all subdomains."},
+		},
+		{
+			name:     "missing preload",
+			header:   "max-age=31536000; includeSubDomains",
+			expected: HSTSResponse{Message: "HSTS header does not contain the preload directive."},
+		},
+		{
+			name:   "compatible",
+			header: "max-age=31536000; includeSubDomains; preload",
+			expected: HSTSResponse{
+				Message:    "Site is compatible with the HSTS preload list!",
+				Compatible: true,
+				HSTSHeader: "max-age=31536000; includeSubDomains; preload",
+			},
+		},
+	}
+
+	for _, tc := range tests {
+		tc := tc
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+			resp := testutils.Response(http.StatusOK, nil)
+			resp.Header = http.Header{}
+			if tc.header != "" {
+				resp.Header.Set("Strict-Transport-Security", tc.header)
+			}
+			h := NewHsts(testutils.MockClient(resp))
+
+			actual, err := h.GetHsts(context.Background(), "https://example.com")
+			assert.NoError(t, err)
+			assert.Equal(t, tc.expected, actual)
+		})
+	}
+}
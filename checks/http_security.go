@@ -0,0 +1,63 @@
+package checks
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+)
+
+type HTTPSecurityResponse struct {
+	StrictTransportPolicy bool `json:"strictTransportPolicy"`
+	XFrameOptions         bool `json:"xFrameOptions"`
+	XContentTypeOptions   bool `json:"xContentTypeOptions"`
+	XXSSProtection        bool `json:"xXSSProtection"`
+	ContentSecurityPolicy bool `json:"contentSecurityPolicy"`
+}
+
+type HttpSecurity struct {
+	client *http.Client
+}
+
+func NewHttpSecurity(client *http.Client) *HttpSecurity {
+	return &HttpSecurity{client: client}
+}
+
+// GetHttpSecurity reports which common security headers the site sets.
+func (h *HttpSecurity) GetHttpSecurity(ctx context.Context, url string) (HTTPSecurityResponse, error) {
+	// TODO(Lissy93): does this test require we set scheme to http?
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
+	if err != nil {
+		return HTTPSecurityResponse{}, fmt.Errorf("error creating request: %s", err.Error())
+	}
+	resp, err := h.client.Do(req)
+	if err != nil {
+		return HTTPSecurityResponse{}, fmt.Errorf("error making request: %s", err.Error())
+	}
+	defer resp.Body.Close()
+
+	headers := resp.Header
+
+	return HTTPSecurityResponse{
+		StrictTransportPolicy: headers.Get("strict-transport-security") != "",
+		XFrameOptions:         headers.Get("x-frame-options") != "",
+		XContentTypeOptions:   headers.Get("x-content-type-options") != "",
+		XXSSProtection:        headers.Get("x-xss-protection") != "",
+		ContentSecurityPolicy: headers.Get("content-security-policy") != "",
+	}, nil
+}
+
+func (h *HttpSecurity) Name() string {
+	return "http-security"
+}
+
+func (h *HttpSecurity) Description() string {
+	return "Checks which common HTTP security headers the site sets"
+}
+
+func (h *HttpSecurity) Input() Input {
+	return InputURL
+}
+
+func (h *HttpSecurity) Run(ctx context.Context, target Target) (any, error) {
+	return h.GetHttpSecurity(ctx, target.String())
+}
@@ -1,6 +1,10 @@
 package checks
 
-import "github.com/xray-web/web-check-api/checks/store/legacyrank"
+import (
+	"context"
+
+	"github.com/xray-web/web-check-api/checks/store/legacyrank"
+)
 
 type DomainRank struct {
 	Domain string `json:"domain"`
@@ -25,3 +29,19 @@ func (lr *LegacyRank) LegacyRank(domain string) (*DomainRank, error) {
 		Rank:   rank,
 	}, nil
 }
+
+func (lr *LegacyRank) Name() string {
+	return "legacy-rank"
+}
+
+func (lr *LegacyRank) Description() string {
+	return "Looks up the domain in the Umbrella top 1 million list"
+}
+
+func (lr *LegacyRank) Input() Input {
+	return InputHostname
+}
+
+func (lr *LegacyRank) Run(ctx context.Context, target Target) (any, error) {
+	return lr.LegacyRank(target.Hostname())
+}
@@ -118,3 +118,33 @@ func sortURLsByFrequency(linksMap map[string]int) []string {
 
 	return sortedLinks
 }
+
+func (l *LinkedPages) Name() string {
+	return "linked-pages"
+}
+
+func (l *LinkedPages) Description() string {
+	return "Lists the internal and external links found on the page"
+}
+
+func (l *LinkedPages) Input() Input {
+	return InputURL
+}
+
+func (l *LinkedPages) Run(ctx context.Context, target Target) (any, error) {
+	links, err := l.GetLinkedPages(ctx, target.URL)
+	if err != nil {
+		return nil, fmt.Errorf("error getting linked pages: %v", err)
+	}
+
+	if len(links.Internal) == 0 && len(links.External) == 0 {
+		return Skipped{
+			Skipped: `No internal or external links found. 
+				This may be due to the website being dynamically rendered, using a client-side framework (like React), and without SSR enabled. 
+				That would mean that the static HTML returned from the HTTP request doesn't contain any meaningful content for Web-Check to analyze. 
+				You can rectify this by using a headless browser to render the page instead.`,
+		}, nil
+	}
+
+	return links, nil
+}
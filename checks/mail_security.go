@@ -0,0 +1,569 @@
+package checks
+
+import (
+	"bufio"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/miekg/dns"
+	"github.com/xray-web/web-check-api/checks/clients/ip"
+	"golang.org/x/net/publicsuffix"
+)
+
+const (
+	SeverityInfo     = "info"
+	SeverityWarning  = "warning"
+	SeverityCritical = "critical"
+
+	// spfLookupLimit is the maximum number of DNS querying terms an SPF
+	// evaluation may use, RFC 7208 section 4.6.4.
+	spfLookupLimit = 10
+
+	maxMTASTSPolicy = 64 << 10
+)
+
+// DefaultDKIMSelectors are the selectors tried when looking for DKIM keys,
+// there is no way to list the selectors a domain uses.
+var DefaultDKIMSelectors = []string{
+	"default", "dkim", "google", "k1", "k2", "mail", "mandrill", "mxvault",
+	"s1", "s2", "selector1", "selector2", "smtp",
+}
+
+type MailFinding struct {
+	Record   string `json:"record"`
+	Severity string `json:"severity"`
+	Message  string `json:"message"`
+}
+
+type SPFResult struct {
+	Found      bool     `json:"found"`
+	Record     string   `json:"record,omitempty"`
+	Mechanisms []string `json:"mechanisms"`
+	All        string   `json:"all,omitempty"`
+	Includes   []string `json:"includes"`
+	Lookups    int      `json:"lookups"`
+}
+
+type DMARCResult struct {
+	Found bool `json:"found"`
+	// Domain is where the record was found, the organisational domain if
+	// the host has no record of its own, RFC 7489 section 6.6.3.
+	Domain          string            `json:"domain,omitempty"`
+	Record          string            `json:"record,omitempty"`
+	Tags            map[string]string `json:"tags,omitempty"`
+	Policy          string            `json:"policy,omitempty"`
+	SubdomainPolicy string            `json:"subdomainPolicy,omitempty"`
+	Percentage      int               `json:"percentage"`
+}
+
+type DKIMResult struct {
+	Selector string            `json:"selector"`
+	Record   string            `json:"record"`
+	Tags     map[string]string `json:"tags"`
+	KeyType  string            `json:"keyType"`
+	Revoked  bool              `json:"revoked"`
+}
+
+type MTASTSPolicy struct {
+	Version string   `json:"version"`
+	Mode    string   `json:"mode"`
+	MX      []string `json:"mx"`
+	MaxAge  int      `json:"maxAge"`
+}
+
+type MTASTSResult struct {
+	Found       bool          `json:"found"`
+	Record      string        `json:"record,omitempty"`
+	ID          string        `json:"id,omitempty"`
+	Policy      *MTASTSPolicy `json:"policy,omitempty"`
+	PolicyError string        `json:"policyError,omitempty"`
+}
+
+type TLSRPTResult struct {
+	Found  bool     `json:"found"`
+	Record string   `json:"record,omitempty"`
+	Rua    []string `json:"rua,omitempty"`
+}
+
+type BIMIResult struct {
+	Found     bool   `json:"found"`
+	Record    string `json:"record,omitempty"`
+	Location  string `json:"location,omitempty"`
+	Authority string `json:"authority,omitempty"`
+}
+
+type MailSecurityReport struct {
+	Domain   string        `json:"domain"`
+	SPF      SPFResult     `json:"spf"`
+	DMARC    DMARCResult   `json:"dmarc"`
+	DKIM     []DKIMResult  `json:"dkim"`
+	MTASTS   MTASTSResult  `json:"mtaSts"`
+	TLSRPT   TLSRPTResult  `json:"tlsRpt"`
+	BIMI     BIMIResult    `json:"bimi"`
+	Findings []MailFinding `json:"findings"`
+}
+
+func (r *MailSecurityReport) finding(record, severity, format string, args ...any) {
+	r.Findings = append(r.Findings, MailFinding{
+		Record:   record,
+		Severity: severity,
+		Message:  fmt.Sprintf(format, args...),
+	})
+}
+
+type MailSecurity struct {
+	resolver ip.Exchanger
+	// client fetches MTA-STS policies, it doesn't follow redirects as
+	// policy fetches must not.
+	client    *http.Client
+	selectors []string
+}
+
+func NewMailSecurity(resolver ip.Exchanger, client *http.Client, selectors []string) *MailSecurity {
+	if len(selectors) == 0 {
+		selectors = DefaultDKIMSelectors
+	}
+	m := &MailSecurity{resolver: resolver, selectors: selectors}
+	if client != nil {
+		noRedirects := *client
+		noRedirects.CheckRedirect = func(req *http.Request, via []*http.Request) error {
+			return http.ErrUseLastResponse
+		}
+		m.client = &noRedirects
+	}
+	return m
+}
+
+// lookupTXT returns the TXT records at name starting with prefix, a name
+// that doesn't exist is not an error. The strings of each record are
+// joined, long records are split into several.
+func (m *MailSecurity) lookupTXT(ctx context.Context, name, prefix string) ([]string, error) {
+	answers, err := queryDNS(ctx, m.resolver, name, dns.TypeTXT)
+	if errors.Is(err, ErrDomainNotFound) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	var records []string
+	for _, rr := range answers {
+		record := strings.Join(rr.(*dns.TXT).Txt, "")
+		if len(record) >= len(prefix) && strings.EqualFold(record[:len(prefix)], prefix) {
+			records = append(records, record)
+		}
+	}
+	return records, nil
+}
+
+// parseTags parses a semicolon separated tag=value list as used by DMARC,
+// DKIM, MTA-STS, TLS-RPT and BIMI records.
+func parseTags(record string) map[string]string {
+	tags := make(map[string]string)
+	for _, part := range strings.Split(record, ";") {
+		k, v, ok := strings.Cut(part, "=")
+		if !ok {
+			continue
+		}
+		tags[strings.ToLower(strings.TrimSpace(k))] = strings.TrimSpace(v)
+	}
+	return tags
+}
+
+// GetMailSecurity looks up and validates the email authentication and
+// transport security records of domain.
+func (m *MailSecurity) GetMailSecurity(ctx context.Context, domain string) (*MailSecurityReport, error) {
+	report := &MailSecurityReport{
+		Domain:   domain,
+		DKIM:     make([]DKIMResult, 0),
+		Findings: make([]MailFinding, 0),
+	}
+	m.checkSPF(ctx, report)
+	m.checkDMARC(ctx, report)
+	m.checkDKIM(ctx, report)
+	m.checkMTASTS(ctx, report)
+	m.checkTLSRPT(ctx, report)
+	m.checkBIMI(ctx, report)
+	return report, ctx.Err()
+}
+
+// spfEvaluation tracks state while following include and redirect terms.
+type spfEvaluation struct {
+	lookups  int
+	includes []string
+	visited  map[string]bool
+}
+
+func (m *MailSecurity) checkSPF(ctx context.Context, report *MailSecurityReport) {
+	report.SPF = SPFResult{Mechanisms: make([]string, 0), Includes: make([]string, 0)}
+	records, err := m.lookupTXT(ctx, report.Domain, "v=spf1")
+	switch {
+	case err != nil:
+		report.finding("spf", SeverityWarning, "SPF lookup failed: %v", err)
+		return
+	case len(records) == 0:
+		report.finding("spf", SeverityWarning, "No SPF record found")
+		return
+	case len(records) > 1:
+		report.finding("spf", SeverityCritical, "Multiple SPF records found, receivers will treat SPF as a permanent error")
+	}
+
+	report.SPF.Found = true
+	report.SPF.Record = records[0]
+	eval := &spfEvaluation{visited: map[string]bool{report.Domain: true}}
+	mechanisms, all := m.evaluateSPF(ctx, report, eval, records[0])
+	report.SPF.Mechanisms = append(report.SPF.Mechanisms, mechanisms...)
+	report.SPF.All = all
+	report.SPF.Includes = append(report.SPF.Includes, eval.includes...)
+	report.SPF.Lookups = eval.lookups
+
+	if eval.lookups > spfLookupLimit {
+		report.finding("spf", SeverityCritical, "SPF record needs %d DNS lookups, more than the limit of %d", eval.lookups, spfLookupLimit)
+	}
+	switch all {
+	case "+all":
+		report.finding("spf", SeverityCritical, "SPF record ends with +all, allowing any server to send mail")
+	case "?all":
+		report.finding("spf", SeverityWarning, "SPF record ends with ?all, which gives no protection")
+	case "~all":
+		report.finding("spf", SeverityInfo, "SPF record ends with ~all, unauthorised mail will soft fail")
+	case "":
+		report.finding("spf", SeverityWarning, "SPF record has no all mechanism")
+	}
+}
+
+// evaluateSPF walks record counting DNS lookups and following include and
+// redirect terms. It returns the top level mechanisms and the all term.
+func (m *MailSecurity) evaluateSPF(ctx context.Context, report *MailSecurityReport, eval *spfEvaluation, record string) ([]string, string) {
+	var mechanisms []string
+	var all, redirect string
+	for _, term := range strings.Fields(record)[1:] {
+		term = strings.ToLower(term)
+		mechanism := strings.TrimLeft(term, "+-~?")
+		name, value := mechanism, ""
+		if i := strings.IndexAny(mechanism, ":=/"); i >= 0 {
+			name, value = mechanism[:i], mechanism[i+1:]
+		}
+		mechanisms = append(mechanisms, term)
+
+		switch name {
+		case "all":
+			all = term
+			if all == "all" {
+				all = "+all"
+			}
+		case "a", "mx", "exists":
+			eval.lookups++
+		case "ptr":
+			eval.lookups++
+			report.finding("spf", SeverityWarning, "SPF record uses the deprecated ptr mechanism")
+		case "include":
+			eval.lookups++
+			m.followSPF(ctx, report, eval, value)
+		case "redirect":
+			eval.lookups++
+			redirect = value
+		}
+	}
+	// redirect is ignored if there is an all mechanism
+	if redirect != "" && all == "" {
+		all = m.followSPF(ctx, report, eval, redirect)
+	}
+	return mechanisms, all
+}
+
+// followSPF evaluates the SPF record of domain from an include or
+// redirect, returning its all term.
+func (m *MailSecurity) followSPF(ctx context.Context, report *MailSecurityReport, eval *spfEvaluation, domain string) string {
+	if eval.visited[domain] {
+		report.finding("spf", SeverityCritical, "SPF record for %s is included more than once, possible loop", domain)
+		return ""
+	}
+	eval.visited[domain] = true
+	eval.includes = append(eval.includes, domain)
+	// stop following once over the limit, receivers will have given up
+	if eval.lookups > spfLookupLimit {
+		return ""
+	}
+
+	records, err := m.lookupTXT(ctx, domain, "v=spf1")
+	if err != nil {
+		report.finding("spf", SeverityWarning, "SPF lookup for %s failed: %v", domain, err)
+		return ""
+	}
+	if len(records) != 1 {
+		report.finding("spf", SeverityCritical, "%s has %d SPF records, receivers will treat SPF as a permanent error", domain, len(records))
+		return ""
+	}
+	_, all := m.evaluateSPF(ctx, report, eval, records[0])
+	return all
+}
+
+func (m *MailSecurity) checkDMARC(ctx context.Context, report *MailSecurityReport) {
+	domain := report.Domain
+	records, err := m.lookupTXT(ctx, "_dmarc."+domain, "v=DMARC1")
+	if org, orgErr := publicsuffix.EffectiveTLDPlusOne(domain); err == nil && len(records) == 0 && orgErr == nil && org != domain {
+		domain = org
+		records, err = m.lookupTXT(ctx, "_dmarc."+domain, "v=DMARC1")
+	}
+	switch {
+	case err != nil:
+		report.finding("dmarc", SeverityWarning, "DMARC lookup failed: %v", err)
+		return
+	case len(records) == 0:
+		report.finding("dmarc", SeverityCritical, "No DMARC record found")
+		return
+	case len(records) > 1:
+		report.finding("dmarc", SeverityCritical, "Multiple DMARC records found, receivers will ignore DMARC")
+	}
+
+	tags := parseTags(records[0])
+	dmarc := DMARCResult{
+		Found:           true,
+		Domain:          domain,
+		Record:          records[0],
+		Tags:            tags,
+		Policy:          strings.ToLower(tags["p"]),
+		SubdomainPolicy: strings.ToLower(tags["sp"]),
+		Percentage:      100,
+	}
+	if pct, ok := tags["pct"]; ok {
+		n, err := strconv.Atoi(pct)
+		if err != nil || n < 0 || n > 100 {
+			report.finding("dmarc", SeverityWarning, "DMARC pct tag %q is invalid", pct)
+		} else {
+			dmarc.Percentage = n
+		}
+	}
+	report.DMARC = dmarc
+
+	// a policy inherited from the organisational domain applies its
+	// subdomain policy, if it has one
+	policy := dmarc.Policy
+	if domain != report.Domain {
+		report.finding("dmarc", SeverityInfo, "No DMARC record for %s, the policy of %s applies", report.Domain, domain)
+		if dmarc.SubdomainPolicy != "" {
+			policy = dmarc.SubdomainPolicy
+		}
+	}
+	switch policy {
+	case "reject", "quarantine":
+	case "none":
+		report.finding("dmarc", SeverityWarning, "DMARC policy is none, failing mail is only monitored")
+	case "":
+		report.finding("dmarc", SeverityCritical, "DMARC record has no policy tag")
+	default:
+		report.finding("dmarc", SeverityCritical, "DMARC policy %q is invalid", policy)
+	}
+	if dmarc.SubdomainPolicy == "none" && dmarc.Policy != "none" {
+		report.finding("dmarc", SeverityWarning, "DMARC subdomain policy is none")
+	}
+	if dmarc.Percentage < 100 {
+		report.finding("dmarc", SeverityWarning, "DMARC policy only applies to %d%% of mail", dmarc.Percentage)
+	}
+	if tags["rua"] == "" {
+		report.finding("dmarc", SeverityInfo, "DMARC record has no aggregate report address (rua)")
+	}
+}
+
+func (m *MailSecurity) checkDKIM(ctx context.Context, report *MailSecurityReport) {
+	for _, selector := range m.selectors {
+		txt, err := m.lookupTXT(ctx, selector+"._domainkey."+report.Domain, "")
+		if err != nil {
+			report.finding("dkim", SeverityWarning, "DKIM lookup for selector %s failed: %v", selector, err)
+			continue
+		}
+		// the version tag is optional, a key record always has a key tag
+		var keys []string
+		for _, record := range txt {
+			if _, ok := parseTags(record)["p"]; ok {
+				keys = append(keys, record)
+			}
+		}
+		if len(keys) == 0 {
+			continue
+		}
+		if len(keys) > 1 {
+			report.finding("dkim", SeverityWarning, "DKIM selector %s has %d key records, verifiers may use any of them", selector, len(keys))
+		}
+		record := keys[0]
+		tags := parseTags(record)
+		dkim := DKIMResult{
+			Selector: selector,
+			Record:   record,
+			Tags:     tags,
+			KeyType:  "rsa",
+			Revoked:  tags["p"] == "",
+		}
+		if k, ok := tags["k"]; ok {
+			dkim.KeyType = strings.ToLower(k)
+		}
+		if dkim.Revoked {
+			report.finding("dkim", SeverityInfo, "DKIM key for selector %s has been revoked", selector)
+		}
+		if tags["t"] == "y" {
+			report.finding("dkim", SeverityInfo, "DKIM selector %s is in testing mode", selector)
+		}
+		report.DKIM = append(report.DKIM, dkim)
+	}
+	if len(report.DKIM) == 0 {
+		report.finding("dkim", SeverityInfo, "No DKIM keys found for the %d common selectors checked", len(m.selectors))
+	}
+}
+
+func (m *MailSecurity) checkMTASTS(ctx context.Context, report *MailSecurityReport) {
+	records, err := m.lookupTXT(ctx, "_mta-sts."+report.Domain, "v=STSv1")
+	switch {
+	case err != nil:
+		report.finding("mta-sts", SeverityWarning, "MTA-STS lookup failed: %v", err)
+		return
+	case len(records) == 0:
+		report.finding("mta-sts", SeverityInfo, "No MTA-STS record found")
+		return
+	}
+
+	report.MTASTS = MTASTSResult{
+		Found:  true,
+		Record: records[0],
+		ID:     parseTags(records[0])["id"],
+	}
+	policy, err := m.fetchMTASTSPolicy(ctx, report.Domain)
+	if err != nil {
+		report.MTASTS.PolicyError = err.Error()
+		report.finding("mta-sts", SeverityCritical, "MTA-STS record found but the policy could not be fetched: %v", err)
+		return
+	}
+	report.MTASTS.Policy = policy
+
+	switch policy.Mode {
+	case "enforce":
+	case "testing":
+		report.finding("mta-sts", SeverityInfo, "MTA-STS policy is in testing mode")
+	case "none":
+		report.finding("mta-sts", SeverityWarning, "MTA-STS policy mode is none")
+	default:
+		report.finding("mta-sts", SeverityCritical, "MTA-STS policy mode %q is invalid", policy.Mode)
+	}
+	if policy.Version != "STSv1" {
+		report.finding("mta-sts", SeverityCritical, "MTA-STS policy version %q is invalid", policy.Version)
+	}
+	if len(policy.MX) == 0 && policy.Mode != "none" {
+		report.finding("mta-sts", SeverityCritical, "MTA-STS policy lists no mx hosts")
+	}
+}
+
+// fetchMTASTSPolicy fetches the policy file for domain, RFC 8461 section
+// 3.3. Redirects are treated as a failure.
+func (m *MailSecurity) fetchMTASTSPolicy(ctx context.Context, domain string) (*MTASTSPolicy, error) {
+	url := "https://mta-sts." + domain + "/.well-known/mta-sts.txt"
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
+	if err != nil {
+		return nil, err
+	}
+	resp, err := m.client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode >= 300 && resp.StatusCode < 400 {
+		return nil, fmt.Errorf("policy redirects to %q, redirects are not allowed", resp.Header.Get("Location"))
+	}
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("unexpected status %d", resp.StatusCode)
+	}
+
+	policy := &MTASTSPolicy{MX: make([]string, 0)}
+	scanner := bufio.NewScanner(io.LimitReader(resp.Body, maxMTASTSPolicy))
+	for scanner.Scan() {
+		k, v, ok := strings.Cut(scanner.Text(), ":")
+		if !ok {
+			continue
+		}
+		v = strings.TrimSpace(v)
+		switch strings.TrimSpace(k) {
+		case "version":
+			policy.Version = v
+		case "mode":
+			policy.Mode = v
+		case "mx":
+			policy.MX = append(policy.MX, v)
+		case "max_age":
+			policy.MaxAge, _ = strconv.Atoi(v)
+		}
+	}
+	return policy, scanner.Err()
+}
+
+func (m *MailSecurity) checkTLSRPT(ctx context.Context, report *MailSecurityReport) {
+	records, err := m.lookupTXT(ctx, "_smtp._tls."+report.Domain, "v=TLSRPTv1")
+	switch {
+	case err != nil:
+		report.finding("tls-rpt", SeverityWarning, "TLS-RPT lookup failed: %v", err)
+		return
+	case len(records) == 0:
+		report.finding("tls-rpt", SeverityInfo, "No TLS-RPT record found")
+		return
+	}
+
+	report.TLSRPT = TLSRPTResult{Found: true, Record: records[0]}
+	for _, rua := range strings.Split(parseTags(records[0])["rua"], ",") {
+		if rua = strings.TrimSpace(rua); rua != "" {
+			report.TLSRPT.Rua = append(report.TLSRPT.Rua, rua)
+		}
+	}
+	if len(report.TLSRPT.Rua) == 0 {
+		report.finding("tls-rpt", SeverityWarning, "TLS-RPT record has no report address (rua)")
+	}
+}
+
+func (m *MailSecurity) checkBIMI(ctx context.Context, report *MailSecurityReport) {
+	records, err := m.lookupTXT(ctx, "default._bimi."+report.Domain, "v=BIMI1")
+	switch {
+	case err != nil:
+		report.finding("bimi", SeverityWarning, "BIMI lookup failed: %v", err)
+		return
+	case len(records) == 0:
+		report.finding("bimi", SeverityInfo, "No BIMI record found")
+		return
+	}
+
+	tags := parseTags(records[0])
+	report.BIMI = BIMIResult{
+		Found:     true,
+		Record:    records[0],
+		Location:  tags["l"],
+		Authority: tags["a"],
+	}
+	if report.BIMI.Location != "" && !strings.HasPrefix(report.BIMI.Location, "https://") {
+		report.finding("bimi", SeverityWarning, "BIMI logo location must use HTTPS")
+	}
+	// BIMI is only honoured when DMARC is enforced
+	if report.DMARC.Policy != "quarantine" && report.DMARC.Policy != "reject" {
+		report.finding("bimi", SeverityWarning, "BIMI requires a DMARC policy of quarantine or reject")
+	}
+}
+
+func (m *MailSecurity) Name() string {
+	return "mail-security"
+}
+
+func (m *MailSecurity) Description() string {
+	return "Validates the SPF, DMARC, DKIM, MTA-STS, TLS-RPT and BIMI records of the domain"
+}
+
+func (m *MailSecurity) Input() Input {
+	return InputHostname
+}
+
+func (m *MailSecurity) Timeout() time.Duration {
+	return 30 * time.Second
+}
+
+func (m *MailSecurity) Run(ctx context.Context, target Target) (any, error) {
+	return m.GetMailSecurity(ctx, target.Hostname())
+}
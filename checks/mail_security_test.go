@@ -0,0 +1,199 @@
+package checks
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"testing"
+
+	"github.com/miekg/dns"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/xray-web/web-check-api/checks/clients/ip"
+	"github.com/xray-web/web-check-api/testutils"
+)
+
+func TestMailSecurity(t *testing.T) {
+	t.Parallel()
+
+	t.Run("well configured", func(t *testing.T) {
+		t.Parallel()
+		resolver := zone(t,
+			`example.com. 300 IN TXT "google-site-verification=abc"`,
+			`example.com. 300 IN TXT "v=spf1 include:_spf.example.net mx -all"`,
+			`_spf.example.net. 300 IN TXT "v=spf1 ip4:192.0.2.0/24 ~all"`,
+			`_dmarc.example.com. 300 IN TXT "v=DMARC1; p=reject; rua=mailto:dmarc@example.com"`,
+			`google._domainkey.example.com. 300 IN TXT "v=DKIM1; k=rsa; p=MIIBIjAN" "BgkqhkiG9w0BAQEFAAOCAQ8A"`,
+			`_mta-sts.example.com. 300 IN TXT "v=STSv1; id=20240601"`,
+			`_smtp._tls.example.com. 300 IN TXT "v=TLSRPTv1; rua=mailto:tls@example.com"`,
+			`default._bimi.example.com. 300 IN TXT "v=BIMI1; l=https://example.com/logo.svg"`,
+		)
+		client := testutils.MockClient(testutils.Response(http.StatusOK, []byte(
+			"version: STSv1\r\nmode: enforce\r\nmx: mx1.example.com\r\nmx: *.example.net\r\nmax_age: 604800\r\n",
+		)))
+		m := NewMailSecurity(resolver, client, []string{"google", "selector1"})
+
+		report, err := m.GetMailSecurity(context.Background(), "example.com")
+		require.NoError(t, err)
+
+		assert.Empty(t, report.Findings)
+		assert.Equal(t, SPFResult{
+			Found:      true,
+			Record:     "v=spf1 include:_spf.example.net mx -all",
+			Mechanisms: []string{"include:_spf.example.net", "mx", "-all"},
+			All:        "-all",
+			Includes:   []string{"_spf.example.net"},
+			Lookups:    2,
+		}, report.SPF)
+		assert.Equal(t, "reject", report.DMARC.Policy)
+		assert.Equal(t, 100, report.DMARC.Percentage)
+		require.Len(t, report.DKIM, 1)
+		assert.Equal(t, "google", report.DKIM[0].Selector)
+		assert.Equal(t, "MIIBIjANBgkqhkiG9w0BAQEFAAOCAQ8A", report.DKIM[0].Tags["p"])
+		assert.Equal(t, "20240601", report.MTASTS.ID)
+		assert.Equal(t, &MTASTSPolicy{
+			Version: "STSv1",
+			Mode:    "enforce",
+			MX:      []string{"mx1.example.com", "*.example.net"},
+			MaxAge:  604800,
+		}, report.MTASTS.Policy)
+		assert.Equal(t, []string{"mailto:tls@example.com"}, report.TLSRPT.Rua)
+		assert.Equal(t, "https://example.com/logo.svg", report.BIMI.Location)
+	})
+
+	t.Run("missing records", func(t *testing.T) {
+		t.Parallel()
+		m := NewMailSecurity(zone(t), testutils.MockClient(), nil)
+
+		report, err := m.GetMailSecurity(context.Background(), "example.com")
+		require.NoError(t, err)
+
+		assert.False(t, report.SPF.Found)
+		assert.False(t, report.DMARC.Found)
+		assert.Empty(t, report.DKIM)
+		assert.Contains(t, report.Findings, MailFinding{Record: "spf", Severity: SeverityWarning, Message: "No SPF record found"})
+		assert.Contains(t, report.Findings, MailFinding{Record: "dmarc", Severity: SeverityCritical, Message: "No DMARC record found"})
+		assert.Contains(t, report.Findings, MailFinding{Record: "dkim", Severity: SeverityInfo, Message: fmt.Sprintf("No DKIM keys found for the %d common selectors checked", len(DefaultDKIMSelectors))})
+	})
+
+	t.Run("weak policies", func(t *testing.T) {
+		t.Parallel()
+		resolver := zone(t,
+			`example.com. 300 IN TXT "v=spf1 +all"`,
+			`_dmarc.example.com. 300 IN TXT "v=DMARC1; p=none; pct=50"`,
+			`_mta-sts.example.com. 300 IN TXT "v=STSv1; id=1"`,
+			`default._bimi.example.com. 300 IN TXT "v=BIMI1; l=http://example.com/logo.svg"`,
+		)
+		m := NewMailSecurity(resolver, testutils.MockClient(testutils.Response(http.StatusNotFound, nil)), nil)
+
+		report, err := m.GetMailSecurity(context.Background(), "example.com")
+		require.NoError(t, err)
+
+		assert.Contains(t, report.Findings, MailFinding{Record: "spf", Severity: SeverityCritical, Message: "SPF record ends with +all, allowing any server to send mail"})
+		assert.Contains(t, report.Findings, MailFinding{Record: "dmarc", Severity: SeverityWarning, Message: "DMARC policy is none, failing mail is only monitored"})
+		assert.Contains(t, report.Findings, MailFinding{Record: "dmarc", Severity: SeverityWarning, Message: "DMARC policy only applies to 50% of mail"})
+		assert.Contains(t, report.Findings, MailFinding{Record: "mta-sts", Severity: SeverityCritical, Message: "MTA-STS record found but the policy could not be fetched: unexpected status 404"})
+		assert.Contains(t, report.Findings, MailFinding{Record: "bimi", Severity: SeverityWarning, Message: "BIMI logo location must use HTTPS"})
+		assert.Contains(t, report.Findings, MailFinding{Record: "bimi", Severity: SeverityWarning, Message: "BIMI requires a DMARC policy of quarantine or reject"})
+	})
+
+	t.Run("organisational DMARC", func(t *testing.T) {
+		t.Parallel()
+		resolver := zone(t,
+			`_dmarc.example.com. 300 IN TXT "v=DMARC1; p=none; sp=reject; rua=mailto:dmarc@example.com"`,
+		)
+		m := NewMailSecurity(resolver, testutils.MockClient(), nil)
+
+		report, err := m.GetMailSecurity(context.Background(), "mail.example.com")
+		require.NoError(t, err)
+
+		assert.True(t, report.DMARC.Found)
+		assert.Equal(t, "example.com", report.DMARC.Domain)
+		assert.Equal(t, "reject", report.DMARC.SubdomainPolicy)
+		assert.Contains(t, report.Findings, MailFinding{Record: "dmarc", Severity: SeverityInfo, Message: "No DMARC record for mail.example.com, the policy of example.com applies"})
+		assert.NotContains(t, report.Findings, MailFinding{Record: "dmarc", Severity: SeverityWarning, Message: "DMARC policy is none, failing mail is only monitored"})
+	})
+}
+
+func TestMTASTSRedirect(t *testing.T) {
+	t.Parallel()
+
+	redirect := testutils.Response(http.StatusFound, nil)
+	redirect.Header = http.Header{"Location": {"https://example.net/mta-sts.txt"}}
+	m := NewMailSecurity(zone(t,
+		`_mta-sts.example.com. 300 IN TXT "v=STSv1; id=1"`,
+	), testutils.MockClient(redirect, testutils.Response(http.StatusOK, []byte("version: STSv1\r\nmode: enforce\r\n"))), nil)
+
+	report := &MailSecurityReport{Domain: "example.com"}
+	m.checkMTASTS(context.Background(), report)
+
+	assert.Nil(t, report.MTASTS.Policy)
+	assert.Equal(t, `policy redirects to "https://example.net/mta-sts.txt", redirects are not allowed`, report.MTASTS.PolicyError)
+}
+
+func TestDKIM(t *testing.T) {
+	t.Parallel()
+
+	records := zone(t,
+		`google._domainkey.example.com. 300 IN TXT "google-site-verification=abc"`,
+		`google._domainkey.example.com. 300 IN TXT "v=DKIM1; k=ed25519; p=11qYAYKxCrfVS/7TyWQHOg7hcvPapiMlrwIaaPcHURo="`,
+		`selector1._domainkey.example.com. 300 IN TXT "v=DKIM1; p=MIIBIjAN"`,
+		`selector1._domainkey.example.com. 300 IN TXT "v=DKIM1; p=MIGfMA0G"`,
+	)
+	m := NewMailSecurity(ip.ExchangeFunc(func(ctx context.Context, msg *dns.Msg) (*dns.Msg, error) {
+		if msg.Question[0].Name == "s1._domainkey.example.com." {
+			resp := new(dns.Msg)
+			resp.SetRcode(msg, dns.RcodeServerFailure)
+			return resp, nil
+		}
+		return records(ctx, msg)
+	}), nil, []string{"google", "selector1", "s1", "missing"})
+
+	report := &MailSecurityReport{Domain: "example.com"}
+	m.checkDKIM(context.Background(), report)
+
+	require.Len(t, report.DKIM, 2)
+	assert.Equal(t, "v=DKIM1; k=ed25519; p=11qYAYKxCrfVS/7TyWQHOg7hcvPapiMlrwIaaPcHURo=", report.DKIM[0].Record)
+	assert.Equal(t, "ed25519", report.DKIM[0].KeyType)
+	assert.Equal(t, "MIIBIjAN", report.DKIM[1].Tags["p"])
+	assert.Equal(t, []MailFinding{
+		{Record: "dkim", Severity: SeverityWarning, Message: "DKIM selector selector1 has 2 key records, verifiers may use any of them"},
+		{Record: "dkim", Severity: SeverityWarning, Message: "DKIM lookup for selector s1 failed: query failed: SERVFAIL"},
+	}, report.Findings)
+}
+
+func TestSPFLookupLimit(t *testing.T) {
+	t.Parallel()
+
+	// each include adds another include until the chain is over the limit
+	records := []string{`example.com. 300 IN TXT "v=spf1 include:0.example.net -all"`}
+	for i := 0; i < 12; i++ {
+		records = append(records, fmt.Sprintf(`%d.example.net. 300 IN TXT "v=spf1 a include:%d.example.net"`, i, i+1))
+	}
+	m := NewMailSecurity(zone(t, records...), nil, nil)
+
+	report := &MailSecurityReport{Domain: "example.com"}
+	m.checkSPF(context.Background(), report)
+
+	assert.Greater(t, report.SPF.Lookups, spfLookupLimit)
+	assert.Contains(t, report.Findings, MailFinding{
+		Record:   "spf",
+		Severity: SeverityCritical,
+		Message:  fmt.Sprintf("SPF record needs %d DNS lookups, more than the limit of 10", report.SPF.Lookups),
+	})
+}
+
+func TestSPFLoop(t *testing.T) {
+	t.Parallel()
+
+	m := NewMailSecurity(zone(t,
+		`example.com. 300 IN TXT "v=spf1 include:a.example.com -all"`,
+		`a.example.com. 300 IN TXT "v=spf1 include:example.com -all"`,
+	), nil, nil)
+
+	report := &MailSecurityReport{Domain: "example.com"}
+	m.checkSPF(context.Background(), report)
+
+	assert.Equal(t, 2, report.SPF.Lookups)
+	assert.Contains(t, report.Findings, MailFinding{Record: "spf", Severity: SeverityCritical, Message: "SPF record for example.com is included more than once, possible loop"})
+}
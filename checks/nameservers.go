@@ -0,0 +1,439 @@
+package checks
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"slices"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/miekg/dns"
+	"github.com/xray-web/web-check-api/checks/clients/ip"
+)
+
+// NameserverFinding is a problem found with the delegation or one of the
+// nameservers.
+type NameserverFinding struct {
+	Issue    string `json:"issue"`
+	Severity string `json:"severity"`
+	Message  string `json:"message"`
+}
+
+// NameserverAddress is what a single nameserver address answered when
+// queried directly.
+type NameserverAddress struct {
+	Address       string   `json:"address"`
+	ASN           string   `json:"asn,omitempty"`
+	Authoritative bool     `json:"authoritative"`
+	Serial        uint32   `json:"serial,omitempty"`
+	NS            []string `json:"ns"`
+	OpenRecursion bool     `json:"openRecursion"`
+	ZoneTransfer  bool     `json:"zoneTransfer"`
+	Latency       int64    `json:"latencyMs"`
+	Error         string   `json:"error,omitempty"`
+}
+
+type NameserverResult struct {
+	Host string `json:"host"`
+	// InParent and InChild report whether the host is listed in the
+	// delegation from the parent zone and in the zone's own NS records.
+	InParent  bool                `json:"inParent"`
+	InChild   bool                `json:"inChild"`
+	Glue      []string            `json:"glue"`
+	Lame      bool                `json:"lame"`
+	Addresses []NameserverAddress `json:"addresses"`
+}
+
+type NameserversReport struct {
+	Zone        string              `json:"zone"`
+	Parent      string              `json:"parent"`
+	ParentNS    []string            `json:"parentNs"`
+	ChildNS     []string            `json:"childNs"`
+	Nameservers []NameserverResult  `json:"nameservers"`
+	Findings    []NameserverFinding `json:"findings"`
+}
+
+func (r *NameserversReport) finding(issue, severity, format string, args ...any) {
+	r.Findings = append(r.Findings, NameserverFinding{
+		Issue:    issue,
+		Severity: severity,
+		Message:  fmt.Sprintf(format, args...),
+	})
+}
+
+type Nameservers struct {
+	resolver  ip.Exchanger
+	exchanger func(server DNSServer) ip.Exchanger
+	dialer    Dialer
+}
+
+// NewNameservers returns a check that audits the delegation of a zone.
+// resolver is a recursive resolver used to find the nameservers, which are
+// then queried directly through exchanger. Zone transfers are attempted
+// over TCP connections opened with dialer.
+func NewNameservers(resolver ip.Exchanger, exchanger func(server DNSServer) ip.Exchanger, dialer Dialer) *Nameservers {
+	return &Nameservers{resolver: resolver, exchanger: exchanger, dialer: dialer}
+}
+
+// queryDirect sends a non-recursive query to the server at addr.
+func (n *Nameservers) queryDirect(ctx context.Context, addr, name string, qtype uint16) (*dns.Msg, error) {
+	msg := new(dns.Msg)
+	msg.SetQuestion(dns.Fqdn(name), qtype)
+	msg.RecursionDesired = false
+	return n.exchanger(DNSServer{IP: addr}).Exchange(ctx, msg)
+}
+
+func (n *Nameservers) addresses(ctx context.Context, host string) []string {
+	var addrs []string
+	records, _ := queryDNS(ctx, n.resolver, host, dns.TypeA)
+	for _, rr := range records {
+		addrs = append(addrs, rr.(*dns.A).A.String())
+	}
+	return addrs
+}
+
+// delegation asks the nameservers of the parent zone for the referral to
+// zone, returning the delegated nameservers and any glue addresses.
+func (n *Nameservers) delegation(ctx context.Context, zone, parent string) ([]string, map[string][]string, error) {
+	var parentNS []string
+	if parent == "." {
+		records, err := queryDNS(ctx, n.resolver, parent, dns.TypeNS)
+		if err != nil {
+			return nil, nil, err
+		}
+		for _, rr := range records {
+			parentNS = append(parentNS, rr.(*dns.NS).Ns)
+		}
+		sort.Strings(parentNS)
+	} else {
+		var err error
+		if _, parentNS, err = zoneNameservers(ctx, n.resolver, parent); err != nil {
+			return nil, nil, err
+		}
+	}
+
+	lastErr := errors.New("no parent nameserver addresses found")
+	for _, ns := range parentNS {
+		for _, addr := range n.addresses(ctx, ns) {
+			resp, err := n.queryDirect(ctx, addr, zone, dns.TypeNS)
+			if err != nil {
+				lastErr = err
+				continue
+			}
+			if resp.Rcode != dns.RcodeSuccess {
+				lastErr = fmt.Errorf("%s answered %s", strings.TrimSuffix(ns, "."), dns.RcodeToString[resp.Rcode])
+				continue
+			}
+
+			var hosts []string
+			// a parent that also serves the child answers directly rather
+			// than with a referral
+			for _, rr := range append(resp.Ns, resp.Answer...) {
+				if ns, ok := rr.(*dns.NS); ok && strings.EqualFold(ns.Hdr.Name, zone) {
+					hosts = append(hosts, dns.CanonicalName(ns.Ns))
+				}
+			}
+			glue := make(map[string][]string)
+			for _, rr := range resp.Extra {
+				switch rr := rr.(type) {
+				case *dns.A:
+					glue[dns.CanonicalName(rr.Hdr.Name)] = append(glue[dns.CanonicalName(rr.Hdr.Name)], rr.A.String())
+				case *dns.AAAA:
+					glue[dns.CanonicalName(rr.Hdr.Name)] = append(glue[dns.CanonicalName(rr.Hdr.Name)], rr.AAAA.String())
+				}
+			}
+			if len(hosts) == 0 {
+				lastErr = fmt.Errorf("%s returned no delegation", strings.TrimSuffix(ns, "."))
+				continue
+			}
+			sort.Strings(hosts)
+			return slices.Compact(hosts), glue, nil
+		}
+	}
+	return nil, nil, lastErr
+}
+
+// probe queries a nameserver address directly for the SOA and NS records
+// of zone and checks whether it allows recursion and zone transfers.
+func (n *Nameservers) probe(ctx context.Context, zone, addr string) NameserverAddress {
+	result := NameserverAddress{Address: addr, NS: make([]string, 0)}
+
+	var wg sync.WaitGroup
+	wg.Add(4)
+	go func() {
+		defer wg.Done()
+		start := time.Now()
+		resp, err := n.queryDirect(ctx, addr, zone, dns.TypeSOA)
+		result.Latency = time.Since(start).Milliseconds()
+		switch {
+		case err != nil:
+			result.Error = err.Error()
+		case resp.Rcode != dns.RcodeSuccess:
+			result.Error = fmt.Sprintf("SOA query failed: %s", dns.RcodeToString[resp.Rcode])
+		default:
+			result.Authoritative = resp.Authoritative
+			for _, rr := range resp.Answer {
+				if soa, ok := rr.(*dns.SOA); ok {
+					result.Serial = soa.Serial
+				}
+			}
+		}
+	}()
+	go func() {
+		defer wg.Done()
+		resp, err := n.queryDirect(ctx, addr, zone, dns.TypeNS)
+		if err != nil || resp.Rcode != dns.RcodeSuccess {
+			return
+		}
+		for _, rr := range resp.Answer {
+			if ns, ok := rr.(*dns.NS); ok {
+				result.NS = append(result.NS, dns.CanonicalName(ns.Ns))
+			}
+		}
+		sort.Strings(result.NS)
+	}()
+	go func() {
+		defer wg.Done()
+		// an authoritative only server refuses, or at most refers, a
+		// recursive query for a name outside its zones
+		msg := new(dns.Msg)
+		msg.SetQuestion(".", dns.TypeNS)
+		resp, err := n.exchanger(DNSServer{IP: addr}).Exchange(ctx, msg)
+		result.OpenRecursion = err == nil && resp.Rcode == dns.RcodeSuccess && resp.RecursionAvailable && !resp.Authoritative && len(resp.Answer) > 0
+	}()
+	go func() {
+		defer wg.Done()
+		result.ZoneTransfer = n.zoneTransfer(ctx, zone, addr)
+	}()
+	wg.Wait()
+
+	if asn, err := lookupASN(ctx, n.resolver, addr); err == nil {
+		result.ASN = asn
+	}
+	return result
+}
+
+// zoneTransfer reports whether the server at addr starts an AXFR of zone.
+func (n *Nameservers) zoneTransfer(ctx context.Context, zone, addr string) bool {
+	ctx, cancel := context.WithTimeout(ctx, 5*time.Second)
+	defer cancel()
+	conn, err := n.dialer.DialContext(ctx, "tcp", net.JoinHostPort(addr, "53"))
+	if err != nil {
+		return false
+	}
+	defer conn.Close()
+	if deadline, ok := ctx.Deadline(); ok {
+		conn.SetDeadline(deadline)
+	}
+
+	msg := new(dns.Msg)
+	msg.SetAxfr(zone)
+	transfer := &dns.Transfer{Conn: &dns.Conn{Conn: conn}}
+	envelopes, err := transfer.In(msg, conn.RemoteAddr().String())
+	if err != nil {
+		return false
+	}
+	envelope, ok := <-envelopes
+	// stop the transfer after the first message, the rest of the zone
+	// isn't needed
+	conn.Close()
+	for range envelopes {
+	}
+	if !ok || envelope.Error != nil {
+		return false
+	}
+	return slices.ContainsFunc(envelope.RR, func(rr dns.RR) bool {
+		return rr.Header().Rrtype == dns.TypeSOA
+	})
+}
+
+// lookupASN finds the origin AS number of addr from the Team Cymru IP to
+// ASN DNS service.
+func lookupASN(ctx context.Context, exchanger ip.Exchanger, addr string) (string, error) {
+	ip := net.ParseIP(addr).To4()
+	if ip == nil {
+		return "", errors.New("only IPv4 addresses are supported")
+	}
+	name := fmt.Sprintf("%d.%d.%d.%d.origin.asn.cymru.com", ip[3], ip[2], ip[1], ip[0])
+	records, err := queryDNS(ctx, exchanger, name, dns.TypeTXT)
+	if err != nil {
+		return "", err
+	}
+	for _, rr := range records {
+		// "15169 | 8.8.8.0/24 | US | arin | 1992-12-01"
+		fields := strings.Fields(strings.Join(rr.(*dns.TXT).Txt, ""))
+		if len(fields) > 0 {
+			return fields[0], nil
+		}
+	}
+	return "", errors.New("no ASN found")
+}
+
+// Audit finds the zone containing domain and checks its delegation and
+// nameservers.
+func (n *Nameservers) Audit(ctx context.Context, domain string) (*NameserversReport, error) {
+	zone, childNS, err := zoneNameservers(ctx, n.resolver, domain)
+	if err != nil {
+		return nil, err
+	}
+	for i := range childNS {
+		childNS[i] = dns.CanonicalName(childNS[i])
+	}
+
+	report := &NameserversReport{
+		Zone:        zone,
+		Parent:      ".",
+		ParentNS:    make([]string, 0),
+		ChildNS:     childNS,
+		Nameservers: make([]NameserverResult, 0),
+		Findings:    make([]NameserverFinding, 0),
+	}
+	if labels := dns.SplitDomainName(zone); len(labels) > 1 {
+		report.Parent = dns.Fqdn(strings.Join(labels[1:], "."))
+	}
+
+	parentNS, glue, err := n.delegation(ctx, zone, report.Parent)
+	if err != nil {
+		report.finding("delegation", SeverityWarning, "Could not get the delegation from the parent zone: %v", err)
+	} else {
+		report.ParentNS = parentNS
+	}
+
+	hosts := slices.Clone(childNS)
+	hosts = append(hosts, parentNS...)
+	sort.Strings(hosts)
+	hosts = slices.Compact(hosts)
+
+	results := make([]NameserverResult, len(hosts))
+	var wg sync.WaitGroup
+	for i, host := range hosts {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			result := NameserverResult{
+				Host:      host,
+				InParent:  slices.Contains(parentNS, host),
+				InChild:   slices.Contains(childNS, host),
+				Glue:      glue[host],
+				Addresses: make([]NameserverAddress, 0),
+			}
+			if result.Glue == nil {
+				result.Glue = make([]string, 0)
+			}
+			addrs := result.Glue
+			if len(addrs) == 0 {
+				addrs = n.addresses(ctx, host)
+			}
+
+			probes := make([]NameserverAddress, len(addrs))
+			var probeWg sync.WaitGroup
+			for j, addr := range addrs {
+				probeWg.Add(1)
+				go func() {
+					defer probeWg.Done()
+					probes[j] = n.probe(ctx, zone, addr)
+				}()
+			}
+			probeWg.Wait()
+			result.Addresses = append(result.Addresses, probes...)
+
+			result.Lame = !slices.ContainsFunc(result.Addresses, func(a NameserverAddress) bool {
+				return a.Authoritative
+			})
+			results[i] = result
+		}()
+	}
+	wg.Wait()
+	report.Nameservers = results
+
+	report.audit()
+	return report, nil
+}
+
+// audit adds the findings for the nameservers in r.
+func (r *NameserversReport) audit() {
+	if len(r.Nameservers) < 2 {
+		r.finding("too-few-nameservers", SeverityWarning, "Only %d nameserver, at least two are needed for redundancy", len(r.Nameservers))
+	}
+	if len(r.ParentNS) > 0 && !slices.Equal(r.ParentNS, r.ChildNS) {
+		r.finding("delegation-mismatch", SeverityWarning, "The parent zone delegates to %s but the zone lists %s",
+			strings.Join(r.ParentNS, ", "), strings.Join(r.ChildNS, ", "))
+	}
+
+	serials := make(map[uint32][]string)
+	var subnets, asns []string
+	addresses := 0
+	for _, ns := range r.Nameservers {
+		if ns.Lame {
+			r.finding("lame-delegation", SeverityCritical, "%s does not answer authoritatively for %s", ns.Host, r.Zone)
+		}
+		if ns.InParent && dns.IsSubDomain(r.Zone, ns.Host) && len(ns.Glue) == 0 {
+			r.finding("missing-glue", SeverityCritical, "%s is inside %s but the parent zone has no glue records for it", ns.Host, r.Zone)
+		}
+		for _, addr := range ns.Addresses {
+			addresses++
+			if addr.Authoritative {
+				serials[addr.Serial] = append(serials[addr.Serial], addr.Address)
+			}
+			if addr.OpenRecursion {
+				r.finding("open-recursion", SeverityWarning, "%s (%s) answers recursive queries for anyone", ns.Host, addr.Address)
+			}
+			if addr.ZoneTransfer {
+				r.finding("zone-transfer", SeverityWarning, "%s (%s) allows anyone to transfer the zone", ns.Host, addr.Address)
+			}
+			if ip := net.ParseIP(addr.Address).To4(); ip != nil {
+				subnets = append(subnets, ip.Mask(net.CIDRMask(24, 32)).String())
+			}
+			if addr.ASN != "" {
+				asns = append(asns, addr.ASN)
+			}
+		}
+	}
+
+	if len(serials) > 1 {
+		var parts []string
+		for serial, addrs := range serials {
+			parts = append(parts, fmt.Sprintf("%d on %s", serial, strings.Join(addrs, ", ")))
+		}
+		sort.Strings(parts)
+		r.finding("serial-mismatch", SeverityWarning, "Nameservers disagree on the SOA serial: %s", strings.Join(parts, "; "))
+	}
+	if addresses > 1 && len(subnets) == addresses && distinct(subnets) == 1 {
+		r.finding("single-subnet", SeverityWarning, "All nameservers are in %s/24", subnets[0])
+	}
+	if addresses > 1 && len(asns) == addresses && distinct(asns) == 1 {
+		r.finding("single-asn", SeverityWarning, "All nameservers are in AS%s", asns[0])
+	}
+}
+
+func distinct(values []string) int {
+	seen := make(map[string]bool)
+	for _, v := range values {
+		seen[v] = true
+	}
+	return len(seen)
+}
+
+func (n *Nameservers) Name() string {
+	return "nameservers"
+}
+
+func (n *Nameservers) Description() string {
+	return "Audits the delegation and authoritative nameservers of the domain's zone"
+}
+
+func (n *Nameservers) Input() Input {
+	return InputHostname
+}
+
+func (n *Nameservers) Timeout() time.Duration {
+	return 20 * time.Second
+}
+
+func (n *Nameservers) Run(ctx context.Context, target Target) (any, error) {
+	return n.Audit(ctx, target.Hostname())
+}
@@ -0,0 +1,149 @@
+package checks
+
+import (
+	"context"
+	"errors"
+	"net"
+	"testing"
+
+	"github.com/miekg/dns"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/xray-web/web-check-api/checks/clients/ip"
+)
+
+// authoritative answers for example.com with the given SOA serial, and
+// recursively for other names if recursive is set.
+func authoritative(t *testing.T, serial string, recursive bool) ip.ExchangeFunc {
+	records := zone(t,
+		"example.com. 3600 IN SOA ns1.example.com. hostmaster.example.com. "+serial+" 7200 3600 1209600 3600",
+		"example.com. 3600 IN NS ns1.example.com.",
+		"example.com. 3600 IN NS ns2.example.net.",
+		". 3600 IN NS a.root-servers.net.",
+	)
+	return func(ctx context.Context, msg *dns.Msg) (*dns.Msg, error) {
+		resp, err := records(ctx, msg)
+		if !dns.IsSubDomain("example.com.", msg.Question[0].Name) {
+			if !recursive || !msg.RecursionDesired {
+				resp.Answer = nil
+				resp.Rcode = dns.RcodeRefused
+			}
+			resp.RecursionAvailable = recursive
+			return resp, err
+		}
+		resp.Authoritative = true
+		return resp, err
+	}
+}
+
+func TestNameserversAudit(t *testing.T) {
+	t.Parallel()
+
+	resolver := zone(t,
+		"com. 86400 IN NS a.gtld.test.",
+		"a.gtld.test. 86400 IN A 192.0.2.10",
+		"example.com. 3600 IN NS ns1.example.com.",
+		"example.com. 3600 IN NS ns2.example.net.",
+		"ns1.example.com. 3600 IN A 192.0.2.1",
+		"ns2.example.net. 3600 IN A 192.0.2.2",
+		"ns3.example.net. 3600 IN A 192.0.2.3",
+		`1.2.0.192.origin.asn.cymru.com. 3600 IN TXT "64500 | 192.0.2.0/24 | ZZ | test | 2024-01-01"`,
+		`2.2.0.192.origin.asn.cymru.com. 3600 IN TXT "64500 | 192.0.2.0/24 | ZZ | test | 2024-01-01"`,
+		`3.2.0.192.origin.asn.cymru.com. 3600 IN TXT "64500 | 192.0.2.0/24 | ZZ | test | 2024-01-01"`,
+	)
+
+	// the parent delegates to ns1, without glue, and to ns3
+	parent := ip.ExchangeFunc(func(ctx context.Context, msg *dns.Msg) (*dns.Msg, error) {
+		resp := new(dns.Msg)
+		resp.SetReply(msg)
+		for _, record := range []string{"example.com. 86400 IN NS ns1.example.com.", "example.com. 86400 IN NS ns3.example.net."} {
+			rr, err := dns.NewRR(record)
+			require.NoError(t, err)
+			resp.Ns = append(resp.Ns, rr)
+		}
+		return resp, nil
+	})
+	servers := map[string]ip.Exchanger{
+		"192.0.2.10": parent,
+		"192.0.2.1":  authoritative(t, "2024010101", false),
+		"192.0.2.2":  authoritative(t, "2024010102", true),
+		"192.0.2.3": ip.ExchangeFunc(func(ctx context.Context, msg *dns.Msg) (*dns.Msg, error) {
+			resp := new(dns.Msg)
+			resp.SetRcode(msg, dns.RcodeRefused)
+			return resp, nil
+		}),
+	}
+	exchanger := func(server DNSServer) ip.Exchanger {
+		return servers[server.IP]
+	}
+
+	// ns1 allows zone transfers
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	axfr := &dns.Server{Listener: listener, Handler: dns.HandlerFunc(func(w dns.ResponseWriter, r *dns.Msg) {
+		resp := new(dns.Msg)
+		resp.SetReply(r)
+		soa, _ := dns.NewRR("example.com. 3600 IN SOA ns1.example.com. hostmaster.example.com. 2024010101 7200 3600 1209600 3600")
+		resp.Answer = append(resp.Answer, soa)
+		w.WriteMsg(resp)
+	})}
+	go axfr.ActivateAndServe()
+	t.Cleanup(func() { axfr.Shutdown() })
+	dialer := DialFunc(func(ctx context.Context, network, address string) (net.Conn, error) {
+		if address == "192.0.2.1:53" {
+			return (&net.Dialer{}).DialContext(ctx, network, listener.Addr().String())
+		}
+		return nil, errors.New("connection refused")
+	})
+
+	report, err := NewNameservers(resolver, exchanger, dialer).Audit(context.Background(), "example.com")
+	require.NoError(t, err)
+
+	assert.Equal(t, "example.com.", report.Zone)
+	assert.Equal(t, "com.", report.Parent)
+	assert.Equal(t, []string{"ns1.example.com.", "ns3.example.net."}, report.ParentNS)
+	assert.Equal(t, []string{"ns1.example.com.", "ns2.example.net."}, report.ChildNS)
+
+	require.Len(t, report.Nameservers, 3)
+	ns1, ns2, ns3 := report.Nameservers[0], report.Nameservers[1], report.Nameservers[2]
+	assert.True(t, ns1.InParent && ns1.InChild)
+	assert.False(t, ns1.Lame)
+	require.Len(t, ns1.Addresses, 1)
+	assert.Equal(t, NameserverAddress{
+		Address:       "192.0.2.1",
+		ASN:           "64500",
+		Authoritative: true,
+		Serial:        2024010101,
+		NS:            []string{"ns1.example.com.", "ns2.example.net."},
+		ZoneTransfer:  true,
+		Latency:       ns1.Addresses[0].Latency,
+	}, ns1.Addresses[0])
+
+	assert.False(t, ns2.InParent)
+	assert.True(t, ns2.Addresses[0].OpenRecursion)
+	assert.False(t, ns2.Addresses[0].ZoneTransfer)
+
+	assert.True(t, ns3.Lame)
+	assert.Equal(t, "SOA query failed: REFUSED", ns3.Addresses[0].Error)
+
+	var issues []string
+	for _, f := range report.Findings {
+		issues = append(issues, f.Issue)
+	}
+	assert.ElementsMatch(t, []string{
+		"delegation-mismatch",
+		"lame-delegation",
+		"missing-glue",
+		"open-recursion",
+		"zone-transfer",
+		"serial-mismatch",
+		"single-subnet",
+		"single-asn",
+	}, issues)
+
+	t.Run("unknown domain", func(t *testing.T) {
+		t.Parallel()
+		_, err := NewNameservers(zone(t), exchanger, dialer).Audit(context.Background(), "example.com")
+		assert.EqualError(t, err, "no nameservers found")
+	})
+}
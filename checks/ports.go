@@ -0,0 +1,445 @@
+package checks
+
+import (
+	"bytes"
+	"context"
+	"crypto/tls"
+	"errors"
+	"fmt"
+	"net"
+	"net/netip"
+	"regexp"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
+	"syscall"
+	"time"
+
+	"github.com/xray-web/web-check-api/checks/clients/ip"
+)
+
+// Dialer opens network connections, it is satisfied by *net.Dialer.
+type Dialer interface {
+	DialContext(ctx context.Context, network, address string) (net.Conn, error)
+}
+
+type DialFunc func(ctx context.Context, network, address string) (net.Conn, error)
+
+func (fn DialFunc) DialContext(ctx context.Context, network, address string) (net.Conn, error) {
+	return fn(ctx, network, address)
+}
+
+// ErrInvalidPorts is returned for a ports parameter that can't be scanned.
+var ErrInvalidPorts = fmt.Errorf("%w: ports", ErrInvalidParams)
+
+// States a scanned port can be in.
+const (
+	// PortOpen accepted the connection.
+	PortOpen = "open"
+	// PortClosed refused the connection.
+	PortClosed = "closed"
+	// PortFiltered didn't answer, or answered with an error other than a
+	// refusal.
+	PortFiltered = "filtered"
+	// PortUnknown wasn't scanned before the scan ran out of time.
+	PortUnknown = "unknown"
+)
+
+const (
+	// maxPorts caps the number of ports in a single scan.
+	maxPorts = 1024
+	// portWorkers is the number of ports scanned at once.
+	portWorkers = 50
+	// portTimeout is how long a connection can take to open, and
+	// bannerTimeout how long to wait for the service to say something.
+	portTimeout   = 1500 * time.Millisecond
+	bannerTimeout = time.Second
+	// portsTimeout bounds the whole scan.
+	portsTimeout = 20 * time.Second
+	// maxBannerLength is the most of a banner returned.
+	maxBannerLength = 128
+)
+
+// tlsPorts are connected to with TLS before looking for a banner.
+var tlsPorts = []int{443, 465, 636, 853, 993, 995, 8443}
+
+// clientFirstPorts are ports whose services wait for the client to send a
+// request, so the probe is sent straight away.
+var clientFirstPorts = map[int]string{
+	80:   "http",
+	443:  "http",
+	3000: "http",
+	5000: "http",
+	6379: "redis",
+	8000: "http",
+	8008: "http",
+	8080: "http",
+	8443: "http",
+	8888: "http",
+	9200: "http",
+}
+
+// serviceMatcher recognises a service from what it sends.
+type serviceMatcher struct {
+	Name  string
+	Match func(b []byte) bool
+	// Banner extracts the part of the response worth returning, the first
+	// line is used if nil.
+	Banner func(b []byte) string
+}
+
+func pattern(expr string) func(b []byte) bool {
+	return regexp.MustCompile(expr).Match
+}
+
+// services are tried in order on what an open port sends.
+var services = []serviceMatcher{
+	{Name: "ssh", Match: pattern(`^SSH-\d`)},
+	{Name: "ftp", Match: pattern(`(?i)^220[ -].*(ftp|filezilla)`)},
+	{Name: "smtp", Match: pattern(`(?i)^220[ -].*(smtp|mail|postfix|exim|sendmail)`)},
+	{Name: "pop3", Match: pattern(`^\+OK`)},
+	{Name: "imap", Match: pattern(`^\* (OK|PREAUTH)`)},
+	{Name: "http", Match: pattern(`^HTTP/\d`), Banner: httpBanner},
+	{Name: "redis", Match: pattern(`^(\+PONG|-NOAUTH|-DENIED)`)},
+	{Name: "mysql", Match: isMySQL, Banner: mysqlBanner},
+	{Name: "vnc", Match: pattern(`^RFB \d{3}\.\d{3}`)},
+	{Name: "telnet", Match: func(b []byte) bool { return len(b) > 1 && b[0] == 0xff && b[1] >= 0xfb }},
+}
+
+// probes are sent to a port that doesn't greet the client.
+var probes = map[string]func(host string) []byte{
+	"http": func(host string) []byte {
+		return []byte("HEAD / HTTP/1.0\r\nHost: " + host + "\r\nUser-Agent: web-check\r\n\r\n")
+	},
+	"redis": func(host string) []byte {
+		return []byte("PING\r\n")
+	},
+}
+
+// isMySQL matches the handshake packet a MySQL server starts with, or the
+// error packet it sends to hosts it doesn't accept.
+func isMySQL(b []byte) bool {
+	if len(b) < 5 || b[3] != 0 {
+		return false
+	}
+	length := int(b[0]) | int(b[1])<<8 | int(b[2])<<16
+	return length == len(b)-4 && (b[4] == 0x0a || b[4] == 0xff)
+}
+
+func mysqlBanner(b []byte) string {
+	if b[4] == 0xff && len(b) > 7 {
+		return printable(b[7:])
+	}
+	version, _, _ := bytes.Cut(b[5:], []byte{0})
+	return printable(version)
+}
+
+// httpBanner returns the Server header, or the status line if there isn't
+// one.
+func httpBanner(b []byte) string {
+	lines := strings.Split(string(b), "\n")
+	for _, line := range lines[1:] {
+		if key, value, ok := strings.Cut(line, ":"); ok && strings.EqualFold(key, "server") {
+			return printable([]byte(strings.TrimSpace(value)))
+		}
+	}
+	return printable([]byte(lines[0]))
+}
+
+// printable returns the first line of b without control characters,
+// truncated to maxBannerLength.
+func printable(b []byte) string {
+	line, _, _ := bytes.Cut(b, []byte("\n"))
+	out := make([]rune, 0, len(line))
+	for _, r := range string(line) {
+		if r >= ' ' && r != 0x7f && r != 0xfffd {
+			out = append(out, r)
+		}
+	}
+	s := strings.TrimSpace(string(out))
+	if len(s) > maxBannerLength {
+		s = s[:maxBannerLength]
+	}
+	return s
+}
+
+// identify names the service that sent b.
+func identify(b []byte) (service, banner string) {
+	for _, s := range services {
+		if s.Match(b) {
+			if s.Banner != nil {
+				return s.Name, s.Banner(b)
+			}
+			return s.Name, printable(b)
+		}
+	}
+	return "", printable(b)
+}
+
+type PortResult struct {
+	Port  int    `json:"port"`
+	State string `json:"state"`
+	// Service is the service recognised from the Banner the port sent.
+	Service string `json:"service,omitempty"`
+	Banner  string `json:"banner,omitempty"`
+	TLS     bool   `json:"tls,omitempty"`
+	// Latency is how long the connection took to open or be refused.
+	Latency int64 `json:"latencyMs"`
+}
+
+type PortsData struct {
+	Ports []PortResult `json:"ports"`
+	// UDP has the results of the scanned ports with a UDP probe.
+	UDP []UDPResult `json:"udp"`
+	// OpenPorts and FailedPorts list the ports that were and weren't
+	// open over either protocol.
+	OpenPorts   []int `json:"openPorts"`
+	FailedPorts []int `json:"failedPorts"`
+	// UDPError is why the host couldn't be resolved to send the UDP probes
+	// to, the UDP ports are left unknown.
+	UDPError string `json:"udpError,omitempty"`
+}
+
+type Ports struct {
+	catalogue CatalogueSource
+	lookup    ip.Lookup
+	dialer    Dialer
+	listener  PacketListener
+}
+
+// NewPorts returns a check that connects to ports with dialer, and probes
+// those with a UDP service at the address found with lookup from sockets
+// opened with listener.
+func NewPorts(catalogue CatalogueSource, lookup ip.Lookup, dialer Dialer, listener PacketListener) *Ports {
+	return &Ports{catalogue: catalogue, lookup: lookup, dialer: dialer, listener: listener}
+}
+
+// ParsePorts reads a comma separated list of ports and ranges such as
+// "22,80,8000-8100", returning them sorted without duplicates.
+func ParsePorts(spec string) ([]int, error) {
+	var ports []int
+	for _, part := range strings.Split(spec, ",") {
+		part = strings.TrimSpace(part)
+		if part == "" {
+			continue
+		}
+		first, last, isRange := strings.Cut(part, "-")
+		from, err := strconv.Atoi(first)
+		if err != nil {
+			return nil, fmt.Errorf("%w: invalid port %q", ErrInvalidPorts, part)
+		}
+		to := from
+		if isRange {
+			if to, err = strconv.Atoi(last); err != nil {
+				return nil, fmt.Errorf("%w: invalid port %q", ErrInvalidPorts, part)
+			}
+		}
+		if from < 1 || to > 65535 || from > to {
+			return nil, fmt.Errorf("%w: %q out of range", ErrInvalidPorts, part)
+		}
+		if len(ports)+to-from+1 > maxPorts {
+			return nil, fmt.Errorf("%w: more than %d ports", ErrInvalidPorts, maxPorts)
+		}
+		for port := from; port <= to; port++ {
+			ports = append(ports, port)
+		}
+	}
+	if len(ports) == 0 {
+		return nil, fmt.Errorf("%w: no ports", ErrInvalidPorts)
+	}
+	slices.Sort(ports)
+	return slices.Compact(ports), nil
+}
+
+// portState classifies a failed connection.
+func portState(err error) string {
+	if errors.Is(err, syscall.ECONNREFUSED) || errors.Is(err, syscall.ECONNRESET) {
+		return PortClosed
+	}
+	return PortFiltered
+}
+
+// connDeadline is the earlier of ctx's deadline and d from now.
+func connDeadline(ctx context.Context, d time.Duration) time.Time {
+	t := time.Now().Add(d)
+	if ctxDeadline, ok := ctx.Deadline(); ok && ctxDeadline.Before(t) {
+		return ctxDeadline
+	}
+	return t
+}
+
+// banner reads what the service on conn sends, sending the probe for the
+// port first if it is one where the client speaks first, and falling back
+// to an HTTP request if the service doesn't greet the client.
+func (p *Ports) banner(ctx context.Context, conn net.Conn, host string, port int) []byte {
+	buf := make([]byte, 512)
+	read := func(probe []byte) []byte {
+		conn.SetDeadline(connDeadline(ctx, bannerTimeout))
+		if probe != nil {
+			if _, err := conn.Write(probe); err != nil {
+				return nil
+			}
+		}
+		n, _ := conn.Read(buf)
+		return buf[:n]
+	}
+
+	if name, ok := clientFirstPorts[port]; ok {
+		return read(probes[name](host))
+	}
+	if b := read(nil); len(b) > 0 {
+		return b
+	}
+	return read(probes["http"](host))
+}
+
+func (p *Ports) scanPort(ctx context.Context, host string, port int) PortResult {
+	result := PortResult{Port: port}
+	dialCtx, cancel := context.WithTimeout(ctx, portTimeout)
+	defer cancel()
+
+	start := time.Now()
+	conn, err := p.dialer.DialContext(dialCtx, "tcp", net.JoinHostPort(host, strconv.Itoa(port)))
+	result.Latency = time.Since(start).Milliseconds()
+	if err != nil {
+		result.State = portState(err)
+		return result
+	}
+	defer conn.Close()
+	result.State = PortOpen
+
+	if slices.Contains(tlsPorts, port) {
+		tlsConn := tls.Client(conn, &tls.Config{ServerName: host, InsecureSkipVerify: true})
+		tlsConn.SetDeadline(connDeadline(ctx, bannerTimeout))
+		if err := tlsConn.Handshake(); err != nil {
+			return result
+		}
+		result.TLS = true
+		conn = tlsConn
+	}
+	if b := p.banner(ctx, conn, host, port); len(b) > 0 {
+		result.Service, result.Banner = identify(b)
+	}
+	return result
+}
+
+// resolve returns the first address of host.
+func (p *Ports) resolve(ctx context.Context, host string) (netip.Addr, error) {
+	ips, err := p.lookup.LookupIP(ctx, "ip", host)
+	if err != nil {
+		return netip.Addr{}, err
+	}
+	if len(ips) == 0 {
+		return netip.Addr{}, fmt.Errorf("no addresses found for %s", host)
+	}
+	addr, _ := netip.AddrFromSlice(ips[0])
+	return addr.Unmap(), nil
+}
+
+// Scan connects to each of ports on host, and probes those with a UDP
+// service, with a pool of workers. Ports not reached before ctx is done are
+// left PortUnknown.
+func (p *Ports) Scan(ctx context.Context, host string, ports []int) PortsData {
+	ctx, cancel := context.WithTimeout(ctx, portsTimeout)
+	defer cancel()
+
+	results := make([]PortResult, len(ports))
+	jobs := make([]func(), len(ports))
+	for i, port := range ports {
+		results[i] = PortResult{Port: port, State: PortUnknown}
+		jobs[i] = func() { results[i] = p.scanPort(ctx, host, port) }
+	}
+	udp := make([]UDPResult, 0)
+	for _, port := range ports {
+		if probe, ok := udpProbes[port]; ok {
+			udp = append(udp, UDPResult{Port: port, Service: probe.Service, State: PortUnknown})
+		}
+	}
+	var udpErr error
+	if len(udp) > 0 {
+		// UDP probes are sent to an address rather than dialled
+		var addr netip.Addr
+		addr, udpErr = p.resolve(ctx, host)
+		if udpErr == nil {
+			for i := range udp {
+				jobs = append(jobs, func() { udp[i] = p.scanUDP(ctx, addr, udp[i].Port) })
+			}
+		}
+	}
+
+	queue := make(chan func())
+	var wg sync.WaitGroup
+	for range min(portWorkers, len(jobs)) {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for job := range queue {
+				job()
+			}
+		}()
+	}
+queue:
+	for _, job := range jobs {
+		select {
+		case queue <- job:
+		case <-ctx.Done():
+			break queue
+		}
+	}
+	close(queue)
+	wg.Wait()
+
+	data := PortsData{Ports: results, UDP: udp, OpenPorts: make([]int, 0), FailedPorts: make([]int, 0)}
+	if udpErr != nil {
+		data.UDPError = udpErr.Error()
+	}
+	open := make(map[int]bool)
+	for _, result := range udp {
+		open[result.Port] = result.State == PortOpen
+	}
+	for _, result := range results {
+		if result.State == PortOpen || open[result.Port] {
+			data.OpenPorts = append(data.OpenPorts, result.Port)
+		} else {
+			data.FailedPorts = append(data.FailedPorts, result.Port)
+		}
+	}
+	return data
+}
+
+// GetPorts scans the ports in the catalogue on domain.
+func (p *Ports) GetPorts(ctx context.Context, domain string) PortsData {
+	ports := slices.Clone(p.catalogue.Catalogue().Ports)
+	slices.Sort(ports)
+	return p.Scan(ctx, domain, ports)
+}
+
+func (p *Ports) Name() string {
+	return "ports"
+}
+
+func (p *Ports) Description() string {
+	return "Checks which TCP ports are open on the host and identifies the services on them, probing common UDP services too"
+}
+
+func (p *Ports) Input() Input {
+	return InputHostname
+}
+
+func (p *Ports) Timeout() time.Duration {
+	return portsTimeout
+}
+
+// Run scans the ports in the catalogue, or those in the ports parameter.
+func (p *Ports) Run(ctx context.Context, target Target) (any, error) {
+	spec := target.Params.Get("ports")
+	if spec == "" {
+		return p.GetPorts(ctx, target.Hostname()), nil
+	}
+	ports, err := ParsePorts(spec)
+	if err != nil {
+		return nil, err
+	}
+	return p.Scan(ctx, target.Hostname(), ports), nil
+}
@@ -0,0 +1,164 @@
+package checks
+
+import (
+	"context"
+	"errors"
+	"net"
+	"net/url"
+	"os"
+	"sync/atomic"
+	"syscall"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestGetPorts(t *testing.T) {
+	t.Parallel()
+
+	p := NewPorts(DefaultCatalogue(), lookupAddr("192.0.2.1"), DialFunc(func(ctx context.Context, network, address string) (net.Conn, error) {
+		switch address {
+		case "192.0.2.1:22", "192.0.2.1:443":
+			server, client := net.Pipe()
+			server.Close()
+			return client, nil
+		}
+		return nil, errors.New("connection refused")
+	}), udpServer{})
+
+	actual := p.GetPorts(context.Background(), "192.0.2.1")
+	assert.Equal(t, []int{22, 443}, actual.OpenPorts)
+	assert.Len(t, actual.FailedPorts, len(DefaultCatalogue().Ports)-2)
+	assert.NotContains(t, actual.FailedPorts, 22)
+}
+
+// service answers a connection like a server that sends greeting, then
+// replies to whatever the client sends with reply.
+func service(greeting, reply string) (net.Conn, error) {
+	server, client := net.Pipe()
+	go func() {
+		defer server.Close()
+		if greeting != "" {
+			server.Write([]byte(greeting))
+		}
+		buf := make([]byte, 512)
+		if _, err := server.Read(buf); err == nil && reply != "" {
+			server.Write([]byte(reply))
+		}
+	}()
+	return client, nil
+}
+
+func TestPortsScan(t *testing.T) {
+	t.Parallel()
+
+	tlsDialer := serveNames(t, "example.com")
+	mysql := "\x0a8.0.36\x00\x01\x00\x00\x00"
+	mysql = string([]byte{byte(len(mysql)), 0, 0, 0}) + mysql
+	p := NewPorts(DefaultCatalogue(), lookupAddr("192.0.2.1"), DialFunc(func(ctx context.Context, network, address string) (net.Conn, error) {
+		switch address {
+		case "example.com:21":
+			return service("220 ProFTPD Server (mail.example.com)\r\n", "")
+		case "example.com:22":
+			return service("SSH-2.0-OpenSSH_9.6\r\n", "")
+		case "example.com:25":
+			return service("220 mx.example.com ESMTP Postfix\r\n", "")
+		case "example.com:80":
+			return service("", "HTTP/1.1 301 Moved Permanently\r\nServer: nginx/1.25\r\nLocation: https://example.com/\r\n\r\n")
+		case "example.com:443":
+			return tlsDialer.DialContext(ctx, network, address)
+		case "example.com:3306":
+			return service(mysql, "")
+		case "example.com:6379":
+			return service("", "-NOAUTH Authentication required.\r\n")
+		case "example.com:9000":
+			// says nothing, then answers the HTTP fallback with garbage
+			return service("", "\x00\x01hello\x02")
+		case "example.com:8080":
+			return nil, &net.OpError{Op: "dial", Net: network, Err: os.ErrDeadlineExceeded}
+		}
+		return nil, &net.OpError{Op: "dial", Net: network, Err: syscall.ECONNREFUSED}
+	}), nil)
+
+	data, err := p.Run(context.Background(), Target{
+		URL:    &url.URL{Host: "example.com"},
+		Params: url.Values{"ports": {"21,22,25,80,443,3306,6379,8080,9000,9001"}},
+	})
+	require.NoError(t, err)
+	actual := data.(PortsData)
+	for i := range actual.Ports {
+		actual.Ports[i].Latency = 0
+	}
+	assert.Equal(t, []PortResult{
+		{Port: 21, State: PortOpen, Service: "ftp", Banner: "220 ProFTPD Server (mail.example.com)"},
+		{Port: 22, State: PortOpen, Service: "ssh", Banner: "SSH-2.0-OpenSSH_9.6"},
+		{Port: 25, State: PortOpen, Service: "smtp", Banner: "220 mx.example.com ESMTP Postfix"},
+		{Port: 80, State: PortOpen, Service: "http", Banner: "nginx/1.25"},
+		{Port: 443, State: PortOpen, Service: "http", Banner: "HTTP/1.0 404 Not Found", TLS: true},
+		{Port: 3306, State: PortOpen, Service: "mysql", Banner: "8.0.36"},
+		{Port: 6379, State: PortOpen, Service: "redis", Banner: "-NOAUTH Authentication required."},
+		{Port: 8080, State: PortFiltered},
+		{Port: 9000, State: PortOpen, Banner: "hello"},
+		{Port: 9001, State: PortClosed},
+	}, actual.Ports)
+	assert.Equal(t, []int{21, 22, 25, 80, 443, 3306, 6379, 9000}, actual.OpenPorts)
+	assert.Equal(t, []int{8080, 9001}, actual.FailedPorts)
+
+	t.Run("invalid ports", func(t *testing.T) {
+		t.Parallel()
+		_, err := p.Run(context.Background(), Target{URL: &url.URL{Host: "example.com"}, Params: url.Values{"ports": {"0-10"}}})
+		assert.ErrorIs(t, err, ErrInvalidParams)
+	})
+
+	t.Run("cancelled", func(t *testing.T) {
+		t.Parallel()
+		var dials atomic.Int32
+		p := NewPorts(DefaultCatalogue(), lookupAddr("192.0.2.1"), DialFunc(func(ctx context.Context, network, address string) (net.Conn, error) {
+			dials.Add(1)
+			<-ctx.Done()
+			return nil, ctx.Err()
+		}), udpServer{})
+		ports, err := ParsePorts("1-1000")
+		require.NoError(t, err)
+
+		ctx, cancel := context.WithTimeout(context.Background(), 100*time.Millisecond)
+		defer cancel()
+		start := time.Now()
+		actual := p.Scan(ctx, "192.0.2.1", ports)
+		assert.Less(t, time.Since(start), time.Second)
+
+		// only a pool's worth of ports were tried
+		assert.Equal(t, int32(portWorkers), dials.Load())
+		states := map[string]int{}
+		for _, result := range actual.Ports {
+			states[result.State]++
+		}
+		assert.Equal(t, map[string]int{PortFiltered: portWorkers, PortUnknown: 1000 - portWorkers}, states)
+		assert.Len(t, actual.FailedPorts, 1000)
+	})
+}
+
+func TestParsePorts(t *testing.T) {
+	t.Parallel()
+
+	ports, err := ParsePorts("443, 22,8000-8003,22")
+	require.NoError(t, err)
+	assert.Equal(t, []int{22, 443, 8000, 8001, 8002, 8003}, ports)
+
+	for spec, message := range map[string]string{
+		"":          "no ports",
+		"ssh":       `invalid port "ssh"`,
+		"80-http":   `invalid port "80-http"`,
+		"0":         `"0" out of range`,
+		"65536":     `"65536" out of range`,
+		"100-10":    `"100-10" out of range`,
+		"1-2000":    "more than 1024 ports",
+		"1-1000,2-": `invalid port "2-"`,
+	} {
+		_, err := ParsePorts(spec)
+		assert.ErrorIs(t, err, ErrInvalidPorts, spec)
+		assert.ErrorContains(t, err, message, spec)
+	}
+}
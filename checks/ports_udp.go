@@ -0,0 +1,292 @@
+package checks
+
+import (
+	"context"
+	"encoding/asn1"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"net"
+	"net/netip"
+	"os"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/miekg/dns"
+)
+
+// PacketListener opens packet sockets, it is satisfied by
+// *net.ListenConfig.
+type PacketListener interface {
+	ListenPacket(ctx context.Context, network, address string) (net.PacketConn, error)
+}
+
+const (
+	// udpTimeout is how long to wait for a reply to a UDP probe, which is
+	// sent udpAttempts times as a datagram can be lost.
+	udpTimeout  = time.Second
+	udpAttempts = 2
+)
+
+var errUnexpectedReply = errors.New("reply isn't from the service")
+
+// UDPResult is how a UDP service answered its probe. Without a reply an
+// open port can't be told from a filtered one, so a port is only open if
+// the service replied.
+type UDPResult struct {
+	Port  int    `json:"port"`
+	State string `json:"state"`
+	// Service is the protocol the port was probed with.
+	Service string `json:"service"`
+	Banner  string `json:"banner,omitempty"`
+	// Amplification says how the service can be used to amplify traffic
+	// reflected at someone else, it is missing if it can't.
+	Amplification string `json:"amplification,omitempty"`
+	// Latency is how long the first reply took.
+	Latency int64 `json:"latencyMs"`
+}
+
+// udpExchange sends req to the service and returns its reply.
+type udpExchange func(req []byte) ([]byte, error)
+
+// udpProbe speaks enough of a UDP protocol to get a reply from a service.
+type udpProbe struct {
+	Service string
+	// Probe returns the banner and any amplification risk of the service
+	// it exchanges requests with, or errUnexpectedReply if a reply isn't
+	// from the service.
+	Probe func(exchange udpExchange) (banner, amplification string, err error)
+}
+
+// udpProbes are sent to the scanned ports they are listed under. DHCP (67
+// and 68) isn't probed as servers reply to the client port rather than the
+// sender, nor SNMP traps (162) as their receivers never reply.
+var udpProbes = map[int]udpProbe{
+	53:    {Service: "dns", Probe: probeDNS},
+	69:    {Service: "tftp", Probe: probeTFTP},
+	123:   {Service: "ntp", Probe: probeNTP},
+	161:   {Service: "snmp", Probe: probeSNMP},
+	1900:  {Service: "ssdp", Probe: probeSSDP},
+	11211: {Service: "memcached", Probe: probeMemcached},
+}
+
+// probeDNS asks for the root nameservers with recursion desired, a server
+// that answers is an open resolver.
+func probeDNS(exchange udpExchange) (string, string, error) {
+	query := new(dns.Msg)
+	query.SetQuestion(".", dns.TypeNS)
+	req, err := query.Pack()
+	if err != nil {
+		return "", "", err
+	}
+	b, err := exchange(req)
+	if err != nil {
+		return "", "", err
+	}
+	var resp dns.Msg
+	if err := resp.Unpack(b); err != nil || !resp.Response || resp.Id != query.Id {
+		return "", "", errUnexpectedReply
+	}
+	banner := dns.RcodeToString[resp.Rcode]
+	if !resp.RecursionAvailable {
+		return banner, "", nil
+	}
+	banner += ", recursion available"
+	if resp.Rcode == dns.RcodeSuccess && len(resp.Answer) > 0 {
+		return banner, "open resolver answering recursive queries from anyone", nil
+	}
+	return banner, "", nil
+}
+
+// probeTFTP asks to read a file, which a server answers with the file or
+// an error.
+func probeTFTP(exchange udpExchange) (string, string, error) {
+	b, err := exchange([]byte("\x00\x01web-check\x00octet\x00"))
+	if err != nil {
+		return "", "", err
+	}
+	if len(b) < 4 {
+		return "", "", errUnexpectedReply
+	}
+	switch binary.BigEndian.Uint16(b) {
+	case 3:
+		return "read request accepted", "", nil
+	case 5:
+		return printable(b[4:]), "", nil
+	}
+	return "", "", errUnexpectedReply
+}
+
+// probeNTP sends a client request, then a monlist request for the recent
+// clients of the server which old ntpd versions answer with up to 100
+// packets.
+func probeNTP(exchange udpExchange) (string, string, error) {
+	req := make([]byte, 48)
+	req[0] = 4<<3 | 3 // version 4, client mode
+	b, err := exchange(req)
+	if err != nil {
+		return "", "", err
+	}
+	if len(b) < 48 || b[0]&7 != 4 {
+		return "", "", errUnexpectedReply
+	}
+	banner := fmt.Sprintf("NTPv%d stratum %d", b[0]>>3&7, b[1])
+
+	// version 2, mode 7 request to the ntpd implementation for
+	// MON_GETLIST_1
+	monlist := make([]byte, 48)
+	copy(monlist, []byte{0x17, 0x00, 0x03, 0x2a})
+	b, err = exchange(monlist)
+	if err == nil && len(b) >= 8 && b[0]&0x87 == 0x87 && b[3] == 0x2a && b[4]>>4 == 0 {
+		return banner, "answers monlist requests with many times their size", nil
+	}
+	return banner, "", nil
+}
+
+type snmpMessage struct {
+	Version   int
+	Community []byte
+	PDU       asn1.RawValue
+}
+
+type snmpPDU struct {
+	RequestID   int32
+	ErrorStatus int
+	ErrorIndex  int
+	Bindings    []snmpBinding
+}
+
+type snmpBinding struct {
+	Name  asn1.ObjectIdentifier
+	Value asn1.RawValue
+}
+
+// snmpSysDescr is the OID of the system description.
+var snmpSysDescr = asn1.ObjectIdentifier{1, 3, 6, 1, 2, 1, 1, 1, 0}
+
+const (
+	snmpRequestID = 0x5743
+	// PDU tags of an SNMP GetRequest and its response.
+	snmpGetRequest  = 0
+	snmpGetResponse = 2
+)
+
+// probeSNMP gets the system description with the public community over
+// SNMPv2c.
+func probeSNMP(exchange udpExchange) (string, string, error) {
+	pdu, err := asn1.MarshalWithParams(snmpPDU{
+		RequestID: snmpRequestID,
+		Bindings:  []snmpBinding{{Name: snmpSysDescr, Value: asn1.NullRawValue}},
+	}, fmt.Sprintf("tag:%d", snmpGetRequest))
+	if err != nil {
+		return "", "", err
+	}
+	req, err := asn1.Marshal(snmpMessage{Version: 1, Community: []byte("public"), PDU: asn1.RawValue{FullBytes: pdu}})
+	if err != nil {
+		return "", "", err
+	}
+	b, err := exchange(req)
+	if err != nil {
+		return "", "", err
+	}
+
+	var msg snmpMessage
+	var resp snmpPDU
+	if _, err := asn1.Unmarshal(b, &msg); err != nil {
+		return "", "", errUnexpectedReply
+	}
+	if _, err := asn1.UnmarshalWithParams(msg.PDU.FullBytes, &resp, fmt.Sprintf("tag:%d", snmpGetResponse)); err != nil || resp.RequestID != snmpRequestID {
+		return "", "", errUnexpectedReply
+	}
+	var banner string
+	if len(resp.Bindings) > 0 && resp.Bindings[0].Value.Tag == asn1.TagOctetString {
+		banner = printable(resp.Bindings[0].Value.Bytes)
+	}
+	return banner, "answers the public community, GetBulk replies are many times the request size", nil
+}
+
+// probeSSDP sends a discovery request, which UPnP devices answer with a
+// reply for each of their services.
+func probeSSDP(exchange udpExchange) (string, string, error) {
+	b, err := exchange([]byte("M-SEARCH * HTTP/1.1\r\nHOST: 239.255.255.250:1900\r\nMAN: \"ssdp:discover\"\r\nMX: 1\r\nST: ssdp:all\r\n\r\n"))
+	if err != nil {
+		return "", "", err
+	}
+	if !strings.HasPrefix(string(b), "HTTP/1.") {
+		return "", "", errUnexpectedReply
+	}
+	return httpBanner(b), "answers discovery requests from anyone", nil
+}
+
+// probeMemcached asks for the version, memcached listening on UDP at all
+// lets its stats replies be reflected.
+func probeMemcached(exchange udpExchange) (string, string, error) {
+	// frame header of request 1, datagram 0 of 1
+	b, err := exchange([]byte("\x00\x01\x00\x00\x00\x01\x00\x00version\r\n"))
+	if err != nil {
+		return "", "", err
+	}
+	if len(b) < 8 || !strings.HasPrefix(string(b[8:]), "VERSION ") {
+		return "", "", errUnexpectedReply
+	}
+	return printable(b[8:]), "answers over UDP, stats replies are many times the request size", nil
+}
+
+// exchangeUDP sends req to addr from conn until a reply comes back from
+// the address, on any port as some services reply from a new one.
+func exchangeUDP(ctx context.Context, conn net.PacketConn, addr netip.AddrPort, req []byte) ([]byte, error) {
+	buf := make([]byte, 1500)
+	for range udpAttempts {
+		if err := ctx.Err(); err != nil {
+			return nil, err
+		}
+		if _, err := conn.WriteTo(req, net.UDPAddrFromAddrPort(addr)); err != nil {
+			return nil, err
+		}
+		conn.SetReadDeadline(connDeadline(ctx, udpTimeout))
+		for {
+			n, from, err := conn.ReadFrom(buf)
+			if errors.Is(err, os.ErrDeadlineExceeded) {
+				break
+			}
+			if err != nil {
+				return nil, err
+			}
+			if from, ok := from.(*net.UDPAddr); ok && from.AddrPort().Addr().Unmap() == addr.Addr() {
+				return slices.Clone(buf[:n]), nil
+			}
+		}
+	}
+	return nil, os.ErrDeadlineExceeded
+}
+
+func (p *Ports) scanUDP(ctx context.Context, addr netip.Addr, port int) UDPResult {
+	probe := udpProbes[port]
+	result := UDPResult{Port: port, Service: probe.Service, State: PortUnknown}
+	conn, err := p.listener.ListenPacket(ctx, "udp", ":0")
+	if err != nil {
+		return result
+	}
+	defer conn.Close()
+
+	var reply []byte
+	start := time.Now()
+	banner, amplification, err := probe.Probe(func(req []byte) ([]byte, error) {
+		b, err := exchangeUDP(ctx, conn, netip.AddrPortFrom(addr, uint16(port)), req)
+		if err == nil && reply == nil {
+			reply = b
+			result.Latency = time.Since(start).Milliseconds()
+		}
+		return b, err
+	})
+	switch {
+	case reply == nil:
+		result.State = portState(err)
+	case err != nil:
+		result.State, result.Banner = PortOpen, printable(reply)
+	default:
+		result.State, result.Banner, result.Amplification = PortOpen, banner, amplification
+	}
+	return result
+}
@@ -0,0 +1,241 @@
+package checks
+
+import (
+	"context"
+	"encoding/asn1"
+	"errors"
+	"net"
+	"net/url"
+	"os"
+	"sync"
+	"syscall"
+	"testing"
+	"time"
+
+	"github.com/miekg/dns"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/xray-web/web-check-api/checks/clients/ip"
+)
+
+// udpServer is a PacketListener whose sockets are answered by the handler
+// for the port written to, from another port. Ports without a handler
+// never answer.
+type udpServer map[int]func(req []byte) []byte
+
+func (s udpServer) ListenPacket(ctx context.Context, network, address string) (net.PacketConn, error) {
+	return &udpConn{server: s, replies: make(chan udpReply, 8)}, nil
+}
+
+type udpReply struct {
+	b    []byte
+	from net.Addr
+}
+
+type udpConn struct {
+	server  udpServer
+	replies chan udpReply
+
+	mu       sync.Mutex
+	deadline time.Time
+}
+
+func (c *udpConn) WriteTo(b []byte, addr net.Addr) (int, error) {
+	to := addr.(*net.UDPAddr)
+	if handler, ok := c.server[to.Port]; ok {
+		if reply := handler(b); reply != nil {
+			// a stray datagram from elsewhere comes first
+			c.replies <- udpReply{b: []byte("stray"), from: &net.UDPAddr{IP: net.ParseIP("198.51.100.1"), Port: to.Port}}
+			c.replies <- udpReply{b: reply, from: &net.UDPAddr{IP: to.IP, Port: 40000}}
+		}
+	}
+	return len(b), nil
+}
+
+func (c *udpConn) ReadFrom(b []byte) (int, net.Addr, error) {
+	c.mu.Lock()
+	timer := time.NewTimer(time.Until(c.deadline))
+	c.mu.Unlock()
+	defer timer.Stop()
+	select {
+	case reply := <-c.replies:
+		return copy(b, reply.b), reply.from, nil
+	case <-timer.C:
+		return 0, nil, os.ErrDeadlineExceeded
+	}
+}
+
+func (c *udpConn) SetReadDeadline(t time.Time) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.deadline = t
+	return nil
+}
+
+func (c *udpConn) SetDeadline(t time.Time) error      { return c.SetReadDeadline(t) }
+func (c *udpConn) SetWriteDeadline(t time.Time) error { return nil }
+func (c *udpConn) Close() error                       { return nil }
+func (c *udpConn) LocalAddr() net.Addr                { return &net.UDPAddr{IP: net.IPv4zero, Port: 50000} }
+
+// snmpResponse answers an SNMP GetRequest for the public community with
+// the system description.
+func snmpResponse(t *testing.T, req []byte, descr string) []byte {
+	var msg snmpMessage
+	var pdu snmpPDU
+	_, err := asn1.Unmarshal(req, &msg)
+	require.NoError(t, err)
+	_, err = asn1.UnmarshalWithParams(msg.PDU.FullBytes, &pdu, "tag:0")
+	require.NoError(t, err)
+	assert.Equal(t, "public", string(msg.Community))
+	assert.Equal(t, snmpSysDescr, pdu.Bindings[0].Name)
+
+	value, err := asn1.Marshal([]byte(descr))
+	require.NoError(t, err)
+	pdu.Bindings[0].Value = asn1.RawValue{FullBytes: value}
+	b, err := asn1.MarshalWithParams(pdu, "tag:2")
+	require.NoError(t, err)
+	msg.PDU = asn1.RawValue{FullBytes: b}
+	b, err = asn1.Marshal(msg)
+	require.NoError(t, err)
+	return b
+}
+
+func TestPortsUDP(t *testing.T) {
+	t.Parallel()
+
+	server := udpServer{
+		53: func(req []byte) []byte {
+			var query dns.Msg
+			require.NoError(t, query.Unpack(req))
+			resp := new(dns.Msg).SetReply(&query)
+			resp.RecursionAvailable = true
+			resp.Answer = []dns.RR{&dns.NS{Hdr: dns.RR_Header{Name: ".", Rrtype: dns.TypeNS, Class: dns.ClassINET}, Ns: "a.root-servers.net."}}
+			b, err := resp.Pack()
+			require.NoError(t, err)
+			return b
+		},
+		69: func(req []byte) []byte {
+			assert.Equal(t, "\x00\x01web-check\x00octet\x00", string(req))
+			return []byte("\x00\x05\x00\x01File not found\x00")
+		},
+		123: func(req []byte) []byte {
+			if req[0]&7 == 7 {
+				// monlist reply with one entry
+				return append([]byte{0x97, 0x00, 0x03, 0x2a, 0x00, 0x01, 0x00, 0x48}, make([]byte, 72)...)
+			}
+			resp := make([]byte, 48)
+			resp[0], resp[1] = 4<<3|4, 2
+			return resp
+		},
+		161: func(req []byte) []byte {
+			return snmpResponse(t, req, "Linux router 5.10.0\n")
+		},
+		1900: func(req []byte) []byte {
+			return []byte("HTTP/1.1 200 OK\r\nCACHE-CONTROL: max-age=1800\r\nSERVER: Linux/3.14 UPnP/1.0 MiniUPnPd/2.1\r\n\r\n")
+		},
+		11211: func(req []byte) []byte {
+			return []byte("\x00\x01\x00\x00\x00\x01\x00\x00ERROR\r\n")
+		},
+	}
+	p := NewPorts(DefaultCatalogue(), lookupAddr("192.0.2.1"), DialFunc(func(ctx context.Context, network, address string) (net.Conn, error) {
+		return nil, &net.OpError{Op: "dial", Net: network, Err: syscall.ECONNREFUSED}
+	}), server)
+
+	data, err := p.Run(context.Background(), Target{
+		URL:    &url.URL{Host: "192.0.2.1"},
+		Params: url.Values{"ports": {"53,67,69,123,161,1900,5353,11211"}},
+	})
+	require.NoError(t, err)
+	actual := data.(PortsData)
+	for i := range actual.UDP {
+		actual.UDP[i].Latency = 0
+	}
+	assert.Equal(t, []UDPResult{
+		{Port: 53, State: PortOpen, Service: "dns", Banner: "NOERROR, recursion available", Amplification: "open resolver answering recursive queries from anyone"},
+		{Port: 69, State: PortOpen, Service: "tftp", Banner: "File not found"},
+		{Port: 123, State: PortOpen, Service: "ntp", Banner: "NTPv4 stratum 2", Amplification: "answers monlist requests with many times their size"},
+		{Port: 161, State: PortOpen, Service: "snmp", Banner: "Linux router 5.10.0", Amplification: "answers the public community, GetBulk replies are many times the request size"},
+		{Port: 1900, State: PortOpen, Service: "ssdp", Banner: "Linux/3.14 UPnP/1.0 MiniUPnPd/2.1", Amplification: "answers discovery requests from anyone"},
+		{Port: 11211, State: PortOpen, Service: "memcached", Banner: "ERROR"},
+	}, actual.UDP)
+	assert.Equal(t, []int{53, 69, 123, 161, 1900, 11211}, actual.OpenPorts)
+	assert.Equal(t, []int{67, 5353}, actual.FailedPorts)
+	for _, result := range actual.Ports {
+		assert.Equal(t, PortClosed, result.State)
+	}
+
+	t.Run("no reply", func(t *testing.T) {
+		t.Parallel()
+		p := NewPorts(DefaultCatalogue(), p.lookup, p.dialer, udpServer{
+			123: func(req []byte) []byte {
+				if req[0]&7 == 7 {
+					return nil
+				}
+				resp := make([]byte, 48)
+				resp[0], resp[1] = 3<<3|4, 1
+				return resp
+			},
+		})
+		actual := p.Scan(context.Background(), "192.0.2.1", []int{123, 161})
+		for i := range actual.UDP {
+			actual.UDP[i].Latency = 0
+		}
+		assert.Equal(t, []UDPResult{
+			{Port: 123, State: PortOpen, Service: "ntp", Banner: "NTPv3 stratum 1"},
+			{Port: 161, State: PortFiltered, Service: "snmp"},
+		}, actual.UDP)
+		assert.Equal(t, []int{123}, actual.OpenPorts)
+	})
+
+	t.Run("lookup failure", func(t *testing.T) {
+		t.Parallel()
+		lookup := ip.LookupFunc(func(ctx context.Context, network, host string) ([]net.IP, error) {
+			return nil, &net.DNSError{Err: "no such host", Name: host, IsNotFound: true}
+		})
+		p := NewPorts(DefaultCatalogue(), lookup, p.dialer, server)
+		actual := p.Scan(context.Background(), "missing.example.com", []int{53, 123})
+		assert.Equal(t, []UDPResult{
+			{Port: 53, State: PortUnknown, Service: "dns"},
+			{Port: 123, State: PortUnknown, Service: "ntp"},
+		}, actual.UDP)
+		assert.Equal(t, "lookup missing.example.com: no such host", actual.UDPError)
+	})
+}
+
+// lookupAddr returns a lookup that resolves every host to addr.
+func lookupAddr(addr string) ip.Lookup {
+	return ip.LookupFunc(func(ctx context.Context, network, host string) ([]net.IP, error) {
+		return []net.IP{net.ParseIP(addr)}, nil
+	})
+}
+
+func TestProbeDNS(t *testing.T) {
+	t.Parallel()
+
+	reply := func(rcode int, ra bool) udpExchange {
+		return func(req []byte) ([]byte, error) {
+			var query dns.Msg
+			require.NoError(t, query.Unpack(req))
+			assert.True(t, query.RecursionDesired)
+			resp := new(dns.Msg).SetRcode(&query, rcode)
+			resp.RecursionAvailable = ra
+			return resp.Pack()
+		}
+	}
+
+	banner, amplification, err := probeDNS(reply(dns.RcodeRefused, false))
+	require.NoError(t, err)
+	assert.Equal(t, "REFUSED", banner)
+	assert.Empty(t, amplification)
+
+	// recursion is offered but nothing came back
+	banner, amplification, err = probeDNS(reply(dns.RcodeSuccess, true))
+	require.NoError(t, err)
+	assert.Equal(t, "NOERROR, recursion available", banner)
+	assert.Empty(t, amplification)
+
+	_, _, err = probeDNS(func(req []byte) ([]byte, error) {
+		return []byte("SSH-2.0-OpenSSH_9.6\r\n"), nil
+	})
+	assert.True(t, errors.Is(err, errUnexpectedReply))
+}
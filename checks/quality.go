@@ -0,0 +1,86 @@
+package checks
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"time"
+)
+
+type Quality struct {
+	client *http.Client
+	apiKey string
+}
+
+func NewQuality(client *http.Client, apiKey string) *Quality {
+	return &Quality{client: client, apiKey: apiKey}
+}
+
+// GetQuality fetches the PageSpeed Insights report for the given URL. If
+// PageSpeed fails its status and error are returned as an UpstreamError.
+func (q *Quality) GetQuality(ctx context.Context, rawURL string) (map[string]any, error) {
+	if q.apiKey == "" {
+		return nil, errors.New("missing Google API. You need to set the `GOOGLE_CLOUD_API_KEY` environment variable")
+	}
+
+	encodedURL := url.QueryEscape(rawURL)
+	endpoint := fmt.Sprintf("https://www.googleapis.com/pagespeedonline/v5/runPagespeed?url=%s&category=PERFORMANCE&category=ACCESSIBILITY&category=BEST_PRACTICES&category=SEO&category=PWA&strategy=mobile&key=%s", encodedURL, q.apiKey)
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, endpoint, nil)
+	if err != nil {
+		return nil, err
+	}
+	resp, err := q.client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		var errorResult map[string]any
+		if err := json.NewDecoder(resp.Body).Decode(&errorResult); err != nil {
+			return nil, err
+		}
+		var message any
+		if e, ok := errorResult["error"].(map[string]any); ok {
+			message = e["message"]
+		} else {
+			message = errorResult["error"]
+		}
+		return nil, &UpstreamError{
+			StatusCode: resp.StatusCode,
+			Body:       errorResult,
+			Err:        fmt.Errorf("pagespeed request failed with status %d: %v", resp.StatusCode, message),
+		}
+	}
+
+	var result map[string]any
+	if err := json.NewDecoder(resp.Body).Decode(&result); err != nil {
+		return nil, err
+	}
+
+	return result, nil
+}
+
+func (q *Quality) Name() string {
+	return "quality"
+}
+
+func (q *Quality) Description() string {
+	return "Fetches the Google PageSpeed Insights report for the page"
+}
+
+func (q *Quality) Input() Input {
+	return InputURL
+}
+
+func (q *Quality) Timeout() time.Duration {
+	return 60 * time.Second
+}
+
+func (q *Quality) Run(ctx context.Context, target Target) (any, error) {
+	return q.GetQuality(ctx, target.String())
+}
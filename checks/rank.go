@@ -38,3 +38,19 @@ func (r *Rank) GetRank(ctx context.Context, url string) (*TrancoRanks, error) {
 	var result TrancoRanks
 	return &result, json.NewDecoder(resp.Body).Decode(&result)
 }
+
+func (r *Rank) Name() string {
+	return "rank"
+}
+
+func (r *Rank) Description() string {
+	return "Looks up the domain in the Tranco top sites ranking"
+}
+
+func (r *Rank) Input() Input {
+	return InputHostname
+}
+
+func (r *Rank) Run(ctx context.Context, target Target) (any, error) {
+	return r.GetRank(ctx, target.Hostname())
+}
@@ -0,0 +1,69 @@
+package checks
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+)
+
+const maxRedirects = 12
+
+type RedirectsData struct {
+	Redirects []string `json:"redirects"`
+}
+
+type Redirects struct {
+	client *http.Client
+}
+
+func NewRedirects(client *http.Client) *Redirects {
+	return &Redirects{client: client}
+}
+
+// GetRedirects follows the redirects from url and returns every URL
+// visited, starting with url itself.
+func (r *Redirects) GetRedirects(ctx context.Context, url string) ([]string, error) {
+	redirects := []string{url}
+	// copy the client so recording redirects doesn't affect other users of it
+	client := *r.client
+	client.CheckRedirect = func(req *http.Request, via []*http.Request) error {
+		if len(via) >= maxRedirects {
+			return fmt.Errorf("stopped after %d redirects", maxRedirects)
+		}
+		redirects = append(redirects, req.URL.String())
+		return nil
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
+	if err != nil {
+		return nil, fmt.Errorf("error: %v", err)
+	}
+
+	resp, err := client.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("error: %v", err)
+	}
+	defer resp.Body.Close()
+
+	return redirects, nil
+}
+
+func (r *Redirects) Name() string {
+	return "redirects"
+}
+
+func (r *Redirects) Description() string {
+	return "Follows and lists the redirects from the URL"
+}
+
+func (r *Redirects) Input() Input {
+	return InputURL
+}
+
+func (r *Redirects) Run(ctx context.Context, target Target) (any, error) {
+	redirects, err := r.GetRedirects(ctx, target.String())
+	if err != nil {
+		return nil, err
+	}
+	return RedirectsData{Redirects: redirects}, nil
+}
@@ -0,0 +1,22 @@
+package checks
+
+import (
+	"context"
+	"net/http"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/xray-web/web-check-api/testutils"
+)
+
+func TestGetRedirects(t *testing.T) {
+	t.Parallel()
+
+	redirect := testutils.Response(http.StatusMovedPermanently, nil)
+	redirect.Header = http.Header{"Location": {"https://www.example.com/"}}
+	r := NewRedirects(testutils.MockClient(redirect, testutils.Response(http.StatusOK, nil)))
+
+	actual, err := r.GetRedirects(context.Background(), "http://example.com")
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"http://example.com", "https://www.example.com/"}, actual)
+}
@@ -0,0 +1,163 @@
+package checks
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/url"
+	"sort"
+	"sync"
+	"time"
+)
+
+var ErrDuplicateCheck = errors.New("check already registered")
+
+// ErrInvalidParams is wrapped by the errors checks return for request
+// parameters they can't use.
+var ErrInvalidParams = errors.New("invalid parameters")
+
+// UpstreamError is returned by checks when a service they call answers with
+// an error, the check's route answers with the same status and body.
+type UpstreamError struct {
+	StatusCode int
+	Body       any
+	Err        error
+}
+
+func (e *UpstreamError) Error() string {
+	return e.Err.Error()
+}
+
+func (e *UpstreamError) Unwrap() error {
+	return e.Err
+}
+
+// Input describes which part of the target a check needs.
+type Input string
+
+const (
+	InputURL      Input = "url"
+	InputHostname Input = "hostname"
+)
+
+// Target is the site a check is run against.
+type Target struct {
+	URL *url.URL
+	// Params holds any other query parameters of a single check request.
+	// It is empty when the check runs as part of a scan.
+	Params url.Values
+}
+
+func (t Target) Hostname() string {
+	return t.URL.Hostname()
+}
+
+func (t Target) String() string {
+	return t.URL.String()
+}
+
+// Check is a single named check that can be mounted as an API route and
+// run as part of a scan.
+type Check interface {
+	Name() string
+	Description() string
+	Input() Input
+	Run(ctx context.Context, target Target) (any, error)
+}
+
+// TimeoutCheck is implemented by checks that need a different deadline to
+// the default when run as part of a scan.
+type TimeoutCheck interface {
+	Timeout() time.Duration
+}
+
+// StreamingCheck is implemented by checks that can report partial results
+// while they run.
+type StreamingCheck interface {
+	// RunStream runs the check like Run, calling emit with each partial
+	// result as it is found. emit is never called concurrently.
+	RunStream(ctx context.Context, target Target, emit func(v any)) (any, error)
+}
+
+type funcCheck struct {
+	name        string
+	description string
+	input       Input
+	run         func(ctx context.Context, target Target) (any, error)
+}
+
+func (c *funcCheck) Name() string        { return c.name }
+func (c *funcCheck) Description() string { return c.description }
+func (c *funcCheck) Input() Input        { return c.input }
+
+func (c *funcCheck) Run(ctx context.Context, target Target) (any, error) {
+	return c.run(ctx, target)
+}
+
+// NewCheck returns a Check that calls run.
+func NewCheck(name, description string, input Input, run func(ctx context.Context, target Target) (any, error)) Check {
+	return &funcCheck{name: name, description: description, input: input, run: run}
+}
+
+type timeoutCheck struct {
+	Check
+	timeout time.Duration
+}
+
+func (c *timeoutCheck) Timeout() time.Duration {
+	return c.timeout
+}
+
+// WithTimeout overrides the scan deadline of c.
+func WithTimeout(c Check, timeout time.Duration) Check {
+	return &timeoutCheck{Check: c, timeout: timeout}
+}
+
+// Registry holds the set of available checks keyed by name.
+type Registry struct {
+	mu     sync.RWMutex
+	checks map[string]Check
+}
+
+func NewRegistry() *Registry {
+	return &Registry{checks: make(map[string]Check)}
+}
+
+// Register adds checks to the registry. Names must be unique, if any name
+// is already taken none of the checks are added.
+func (r *Registry) Register(checks ...Check) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	seen := make(map[string]bool, len(checks))
+	for _, c := range checks {
+		if _, ok := r.checks[c.Name()]; ok || seen[c.Name()] {
+			return fmt.Errorf("%w: %s", ErrDuplicateCheck, c.Name())
+		}
+		seen[c.Name()] = true
+	}
+	for _, c := range checks {
+		r.checks[c.Name()] = c
+	}
+	return nil
+}
+
+func (r *Registry) Get(name string) (Check, bool) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	c, ok := r.checks[name]
+	return c, ok
+}
+
+// Checks returns every registered check sorted by name.
+func (r *Registry) Checks() []Check {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	list := make([]Check, 0, len(r.checks))
+	for _, c := range r.checks {
+		list = append(list, c)
+	}
+	sort.Slice(list, func(i, j int) bool {
+		return list[i].Name() < list[j].Name()
+	})
+	return list
+}
@@ -0,0 +1,64 @@
+package checks
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/xray-web/web-check-api/config"
+)
+
+func TestRegistry(t *testing.T) {
+	t.Parallel()
+
+	noop := func(ctx context.Context, target Target) (any, error) { return nil, nil }
+
+	t.Run("register and list checks", func(t *testing.T) {
+		t.Parallel()
+		r := NewRegistry()
+		assert.NoError(t, r.Register(
+			NewCheck("b", "second", InputURL, noop),
+			NewCheck("a", "first", InputHostname, noop),
+		))
+
+		list := r.Checks()
+		assert.Len(t, list, 2)
+		assert.Equal(t, "a", list[0].Name())
+		assert.Equal(t, "b", list[1].Name())
+
+		c, ok := r.Get("a")
+		assert.True(t, ok)
+		assert.Equal(t, "first", c.Description())
+		assert.Equal(t, InputHostname, c.Input())
+	})
+
+	t.Run("duplicate names", func(t *testing.T) {
+		t.Parallel()
+		r := NewRegistry()
+		assert.NoError(t, r.Register(NewCheck("a", "", InputURL, noop)))
+		assert.ErrorIs(t, r.Register(NewCheck("b", "", InputURL, noop), NewCheck("a", "", InputURL, noop)), ErrDuplicateCheck)
+		assert.ErrorIs(t, r.Register(NewCheck("c", "", InputURL, noop), NewCheck("c", "", InputURL, noop)), ErrDuplicateCheck)
+
+		_, ok := r.Get("b")
+		assert.False(t, ok)
+		assert.Len(t, r.Checks(), 1)
+	})
+
+	t.Run("timeout", func(t *testing.T) {
+		t.Parallel()
+		c := WithTimeout(NewCheck("a", "", InputURL, noop), time.Minute)
+		tc, ok := c.(TimeoutCheck)
+		assert.True(t, ok)
+		assert.Equal(t, time.Minute, tc.Timeout())
+		assert.Equal(t, "a", c.Name())
+	})
+
+	t.Run("built in checks", func(t *testing.T) {
+		t.Parallel()
+		r := NewRegistry()
+		assert.NoError(t, r.Register(NewChecks(config.Config{DNSResolver: "127.0.0.1:53"}, DefaultCatalogue()).All()...))
+		_, ok := r.Get("block-lists")
+		assert.True(t, ok)
+	})
+}
@@ -0,0 +1,199 @@
+package checks
+
+import (
+	"context"
+	"net"
+	"net/netip"
+	"slices"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/miekg/dns"
+	"github.com/xray-web/web-check-api/checks/clients/ip"
+	"github.com/xray-web/web-check-api/checks/store/passivedns"
+	"golang.org/x/net/publicsuffix"
+)
+
+// ReverseIpSourcePTR marks hostnames found from PTR records.
+const ReverseIpSourcePTR = "ptr"
+
+// reverseIpResolveLimit caps how many hostnames per address are resolved
+// to see if they still point at it.
+const reverseIpResolveLimit = 100
+
+type Neighbour struct {
+	Name    string   `json:"name"`
+	Sources []string `json:"sources"`
+	// Current is whether the name still resolves to the address, a name
+	// that doesn't may be an orphaned service. It is missing if the name
+	// wasn't checked.
+	Current   *bool      `json:"current,omitempty"`
+	FirstSeen *time.Time `json:"firstSeen,omitempty"`
+	LastSeen  *time.Time `json:"lastSeen,omitempty"`
+}
+
+type ReverseIpResult struct {
+	Address net.IP   `json:"ip"`
+	Family  int      `json:"family"`
+	PTR     []string `json:"ptr"`
+	// Hostnames are the other names seen on the address.
+	Hostnames []Neighbour `json:"hostnames"`
+	// Shared is set if any hostname is under a different registrable
+	// domain to the target.
+	Shared bool              `json:"shared"`
+	Errors map[string]string `json:"errors,omitempty"`
+}
+
+type ReverseIp struct {
+	netIp     *NetIp
+	resolver  ip.Exchanger
+	providers []passivedns.Provider
+}
+
+// NewReverseIp returns a check that looks up the hostnames seen on each
+// address netIp finds for the target in the passive DNS providers and PTR
+// records, using resolver to see which still point there.
+func NewReverseIp(netIp *NetIp, resolver ip.Exchanger, providers ...passivedns.Provider) *ReverseIp {
+	return &ReverseIp{netIp: netIp, resolver: resolver, providers: providers}
+}
+
+// resolvesTo reports whether name has an A or AAAA record for addr.
+func (r *ReverseIp) resolvesTo(ctx context.Context, name string, addr net.IP) bool {
+	qtype := dns.TypeA
+	if addr.To4() == nil {
+		qtype = dns.TypeAAAA
+	}
+	records, _ := queryDNS(ctx, r.resolver, name, qtype)
+	for _, rr := range records {
+		switch rr := rr.(type) {
+		case *dns.A:
+			if rr.A.Equal(addr) {
+				return true
+			}
+		case *dns.AAAA:
+			if rr.AAAA.Equal(addr) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+func (r *ReverseIp) neighbours(ctx context.Context, host string, addr IpAddress) ReverseIpResult {
+	result := ReverseIpResult{
+		Address:   addr.Address,
+		Family:    addr.Family,
+		PTR:       make([]string, 0),
+		Hostnames: make([]Neighbour, 0),
+	}
+	found := make(map[string]*Neighbour)
+	add := func(source string, record passivedns.Record) {
+		name := strings.TrimSuffix(strings.ToLower(record.Name), ".")
+		if name == "" || name == host {
+			return
+		}
+		n, ok := found[name]
+		if !ok {
+			n = &Neighbour{Name: name}
+			found[name] = n
+		}
+		if !slices.Contains(n.Sources, source) {
+			n.Sources = append(n.Sources, source)
+		}
+		if record.FirstSeen != nil && (n.FirstSeen == nil || record.FirstSeen.Before(*n.FirstSeen)) {
+			n.FirstSeen = record.FirstSeen
+		}
+		if record.LastSeen != nil && (n.LastSeen == nil || record.LastSeen.After(*n.LastSeen)) {
+			n.LastSeen = record.LastSeen
+		}
+	}
+
+	for _, name := range addr.ReverseDNS {
+		result.PTR = append(result.PTR, name)
+		add(ReverseIpSourcePTR, passivedns.Record{Name: name})
+	}
+	if a, ok := netip.AddrFromSlice(addr.Address); ok {
+		for _, provider := range r.providers {
+			records, err := provider.Lookup(ctx, a.Unmap())
+			if err != nil {
+				if result.Errors == nil {
+					result.Errors = make(map[string]string)
+				}
+				result.Errors[provider.Name()] = err.Error()
+				continue
+			}
+			for _, record := range records {
+				add(provider.Name(), record)
+			}
+		}
+	}
+
+	domain, _ := publicsuffix.EffectiveTLDPlusOne(host)
+	for _, n := range found {
+		sort.Strings(n.Sources)
+		if other, err := publicsuffix.EffectiveTLDPlusOne(n.Name); err == nil && other != domain {
+			result.Shared = true
+		}
+		result.Hostnames = append(result.Hostnames, *n)
+	}
+	sort.Slice(result.Hostnames, func(i, j int) bool {
+		return result.Hostnames[i].Name < result.Hostnames[j].Name
+	})
+
+	var wg sync.WaitGroup
+	workers := make(chan struct{}, subdomainWorkers)
+	for i := range min(len(result.Hostnames), reverseIpResolveLimit) {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			workers <- struct{}{}
+			defer func() { <-workers }()
+			current := r.resolvesTo(ctx, result.Hostnames[i].Name, addr.Address)
+			result.Hostnames[i].Current = &current
+		}()
+	}
+	wg.Wait()
+	return result
+}
+
+func (r *ReverseIp) Lookup(ctx context.Context, host string) ([]ReverseIpResult, error) {
+	host = strings.TrimSuffix(strings.ToLower(host), ".")
+	addrs, err := r.netIp.GetIp(ctx, host)
+	if err != nil {
+		return nil, err
+	}
+
+	results := make([]ReverseIpResult, len(addrs))
+	var wg sync.WaitGroup
+	for i, addr := range addrs {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			results[i] = r.neighbours(ctx, host, addr)
+		}()
+	}
+	wg.Wait()
+	return results, nil
+}
+
+func (r *ReverseIp) Name() string {
+	return "reverse-ip"
+}
+
+func (r *ReverseIp) Description() string {
+	return "Lists the other hostnames seen on the addresses of the host from passive DNS and PTR records"
+}
+
+func (r *ReverseIp) Input() Input {
+	return InputHostname
+}
+
+func (r *ReverseIp) Timeout() time.Duration {
+	return 20 * time.Second
+}
+
+func (r *ReverseIp) Run(ctx context.Context, target Target) (any, error) {
+	return r.Lookup(ctx, target.Hostname())
+}
@@ -0,0 +1,80 @@
+package checks
+
+import (
+	"context"
+	"errors"
+	"net"
+	"net/netip"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/xray-web/web-check-api/checks/clients/ip"
+	"github.com/xray-web/web-check-api/checks/store/passivedns"
+)
+
+type passiveDNS struct {
+	name    string
+	records map[string][]passivedns.Record
+	err     error
+}
+
+func (p *passiveDNS) Name() string {
+	return p.name
+}
+
+func (p *passiveDNS) Lookup(ctx context.Context, addr netip.Addr) ([]passivedns.Record, error) {
+	return p.records[addr.String()], p.err
+}
+
+func TestReverseIpLookup(t *testing.T) {
+	t.Parallel()
+
+	resolver := zone(t,
+		"example.com. 300 IN A 192.0.2.1",
+		"shop.example.com. 300 IN A 192.0.2.1",
+		"blog.example.net. 300 IN A 198.51.100.7",
+		"1.2.0.192.in-addr.arpa. 3600 IN PTR host1.hosting.test.",
+	)
+	lookup := ip.LookupFunc(func(ctx context.Context, network string, host string) ([]net.IP, error) {
+		if network == "ip6" {
+			return nil, errors.New("no such host")
+		}
+		return []net.IP{net.ParseIP("192.0.2.1")}, nil
+	})
+	providers := []passivedns.Provider{
+		&passiveDNS{name: "one", records: map[string][]passivedns.Record{
+			"192.0.2.1": {{Name: "example.com"}, {Name: "shop.example.com"}, {Name: "blog.example.net"}},
+		}},
+		&passiveDNS{name: "two", records: map[string][]passivedns.Record{
+			"192.0.2.1": {{Name: "Shop.Example.com."}},
+		}},
+		&passiveDNS{name: "broken", err: errors.New("rate limited")},
+	}
+	r := NewReverseIp(NewNetIp(lookup, resolver, nil), resolver, providers...)
+
+	results, err := r.Lookup(context.Background(), "example.com")
+	require.NoError(t, err)
+	require.Len(t, results, 1)
+
+	yes, no := true, false
+	result := results[0]
+	assert.Equal(t, []string{"host1.hosting.test"}, result.PTR)
+	assert.Equal(t, []Neighbour{
+		{Name: "blog.example.net", Sources: []string{"one"}, Current: &no},
+		{Name: "host1.hosting.test", Sources: []string{ReverseIpSourcePTR}, Current: &no},
+		{Name: "shop.example.com", Sources: []string{"one", "two"}, Current: &yes},
+	}, result.Hostnames)
+	assert.True(t, result.Shared)
+	assert.Equal(t, map[string]string{"broken": "rate limited"}, result.Errors)
+
+	t.Run("own hostnames only", func(t *testing.T) {
+		t.Parallel()
+		r := NewReverseIp(NewNetIp(lookup, zone(t), nil), resolver, providers[1])
+		results, err := r.Lookup(context.Background(), "example.com")
+		require.NoError(t, err)
+		assert.False(t, results[0].Shared)
+		assert.Empty(t, results[0].PTR)
+		assert.Len(t, results[0].Hostnames, 1)
+	})
+}
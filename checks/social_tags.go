@@ -92,3 +92,19 @@ func (s *SocialTags) GetSocialTags(ctx context.Context, url string) (*SocialTags
 	}
 	return tags, nil
 }
+
+func (s *SocialTags) Name() string {
+	return "social-tags"
+}
+
+func (s *SocialTags) Description() string {
+	return "Extracts the social media and SEO meta tags from the page"
+}
+
+func (s *SocialTags) Input() Input {
+	return InputURL
+}
+
+func (s *SocialTags) Run(ctx context.Context, target Target) (any, error) {
+	return s.GetSocialTags(ctx, target.String())
+}
@@ -0,0 +1,137 @@
+package ipintel
+
+import (
+	"errors"
+	"fmt"
+	"net"
+	"strings"
+
+	"github.com/oschwald/maxminddb-golang"
+)
+
+// Info is what the local databases know about an address.
+type Info struct {
+	ASN          uint   `json:"asn,omitempty"`
+	Organisation string `json:"organisation,omitempty"`
+	// Prefix is the network the ASN database has for the address, which is
+	// the prefix announced for it.
+	Prefix      string `json:"prefix,omitempty"`
+	Country     string `json:"country,omitempty"`
+	CountryCode string `json:"countryCode,omitempty"`
+	City        string `json:"city,omitempty"`
+	// Cloud is set if the address is in a published cloud or CDN range.
+	Cloud *Range `json:"cloud,omitempty"`
+}
+
+type Getter interface {
+	GetIPInfo(ip net.IP) Info
+}
+
+type GetterFunc func(ip net.IP) Info
+
+func (f GetterFunc) GetIPInfo(ip net.IP) Info {
+	return f(ip)
+}
+
+// Config lists the files to load, any left empty are skipped.
+type Config struct {
+	// CityDB and ASNDB are MMDB files in the layout of the MaxMind
+	// GeoLite2 City (or Country) and ASN databases.
+	CityDB string
+	ASNDB  string
+	// Ranges are "provider=path" entries, see RangeParsers for the
+	// providers and file formats.
+	Ranges []string
+}
+
+// Store answers lookups from databases loaded from local files, so nothing
+// is fetched while a check runs.
+type Store struct {
+	city   *maxminddb.Reader
+	asn    *maxminddb.Reader
+	ranges []Range
+}
+
+// Load opens the files in config. Files that fail to load are reported in
+// the error, but the store can still be used with the rest.
+func Load(config Config) (*Store, error) {
+	s := &Store{}
+	var errs []error
+	var err error
+	if config.CityDB != "" {
+		if s.city, err = maxminddb.Open(config.CityDB); err != nil {
+			errs = append(errs, fmt.Errorf("city database: %w", err))
+		}
+	}
+	if config.ASNDB != "" {
+		if s.asn, err = maxminddb.Open(config.ASNDB); err != nil {
+			errs = append(errs, fmt.Errorf("ASN database: %w", err))
+		}
+	}
+	for _, entry := range config.Ranges {
+		provider, path, ok := strings.Cut(entry, "=")
+		if !ok {
+			errs = append(errs, fmt.Errorf("IP ranges %q: expected provider=path", entry))
+			continue
+		}
+		ranges, err := LoadRanges(provider, path)
+		if err != nil {
+			errs = append(errs, err)
+			continue
+		}
+		s.ranges = append(s.ranges, ranges...)
+	}
+	return s, errors.Join(errs...)
+}
+
+// Close releases the databases.
+func (s *Store) Close() error {
+	var errs []error
+	for _, db := range []*maxminddb.Reader{s.city, s.asn} {
+		if db != nil {
+			errs = append(errs, db.Close())
+		}
+	}
+	return errors.Join(errs...)
+}
+
+type cityRecord struct {
+	City struct {
+		Names map[string]string `maxminddb:"names"`
+	} `maxminddb:"city"`
+	Country struct {
+		ISOCode string            `maxminddb:"iso_code"`
+		Names   map[string]string `maxminddb:"names"`
+	} `maxminddb:"country"`
+}
+
+type asnRecord struct {
+	Number       uint   `maxminddb:"autonomous_system_number"`
+	Organisation string `maxminddb:"autonomous_system_organization"`
+}
+
+// GetIPInfo looks ip up in each database, fields are left empty where a
+// database is missing or has no entry.
+func (s *Store) GetIPInfo(ip net.IP) Info {
+	var info Info
+	if s.city != nil {
+		var record cityRecord
+		// an IPv6 address in an IPv4 only database is an error, and is no
+		// different from the address not being found
+		if err := s.city.Lookup(ip, &record); err == nil {
+			info.City = record.City.Names["en"]
+			info.Country = record.Country.Names["en"]
+			info.CountryCode = record.Country.ISOCode
+		}
+	}
+	if s.asn != nil {
+		var record asnRecord
+		if network, ok, err := s.asn.LookupNetwork(ip, &record); err == nil && ok {
+			info.ASN = record.Number
+			info.Organisation = record.Organisation
+			info.Prefix = network.String()
+		}
+	}
+	info.Cloud = matchRange(s.ranges, ip)
+	return info
+}
@@ -0,0 +1,226 @@
+package ipintel
+
+import (
+	"bytes"
+	"encoding/binary"
+	"net"
+	"net/netip"
+	"os"
+	"path/filepath"
+	"sort"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// encode writes v in the MaxMind DB data section format.
+func encode(buf *bytes.Buffer, v any) {
+	control := func(typ, size int) {
+		var first byte
+		var extended []byte
+		if typ > 7 {
+			extended = []byte{byte(typ - 7)}
+		} else {
+			first = byte(typ << 5)
+		}
+		var sizeBytes []byte
+		switch {
+		case size < 29:
+			first |= byte(size)
+		case size < 285:
+			first |= 29
+			sizeBytes = []byte{byte(size - 29)}
+		default:
+			first |= 30
+			sizeBytes = binary.BigEndian.AppendUint16(nil, uint16(size-285))
+		}
+		buf.WriteByte(first)
+		buf.Write(extended)
+		buf.Write(sizeBytes)
+	}
+	unsigned := func(typ int, n uint64) {
+		b := bytes.TrimLeft(binary.BigEndian.AppendUint64(nil, n), "\x00")
+		control(typ, len(b))
+		buf.Write(b)
+	}
+	switch v := v.(type) {
+	case string:
+		control(2, len(v))
+		buf.WriteString(v)
+	case uint16:
+		unsigned(5, uint64(v))
+	case uint32:
+		unsigned(6, uint64(v))
+	case uint64:
+		unsigned(9, v)
+	case map[string]any:
+		control(7, len(v))
+		keys := make([]string, 0, len(v))
+		for k := range v {
+			keys = append(keys, k)
+		}
+		sort.Strings(keys)
+		for _, k := range keys {
+			encode(buf, k)
+			encode(buf, v[k])
+		}
+	case []any:
+		control(11, len(v))
+		for _, item := range v {
+			encode(buf, item)
+		}
+	default:
+		panic("unsupported type")
+	}
+}
+
+// writeMMDB writes a MaxMind DB mapping each network to its record, with
+// IPv4 networks in the ::/96 subtree where readers look for them. Networks
+// must not overlap.
+func writeMMDB(t *testing.T, networks map[string]map[string]any) string {
+	t.Helper()
+
+	// records point at nodes, data as -(index+2), or nowhere as -1
+	nodes := [][2]int{{-1, -1}}
+	var data []map[string]any
+	for network, record := range networks {
+		prefix := netip.MustParsePrefix(network)
+		addr := prefix.Addr().As16()
+		bits := prefix.Bits()
+		if prefix.Addr().Is4() {
+			copy(addr[:], make([]byte, 12))
+			bits += 96
+		}
+		data = append(data, record)
+		node := 0
+		for i := range bits {
+			bit := addr[i/8] >> (7 - i%8) & 1
+			if i == bits-1 {
+				nodes[node][bit] = -(len(data) + 1)
+				break
+			}
+			if nodes[node][bit] == -1 {
+				nodes = append(nodes, [2]int{-1, -1})
+				nodes[node][bit] = len(nodes) - 1
+			}
+			node = nodes[node][bit]
+		}
+	}
+
+	var section bytes.Buffer
+	offsets := make([]int, len(data))
+	for i, record := range data {
+		offsets[i] = section.Len()
+		encode(&section, record)
+	}
+
+	var db bytes.Buffer
+	for _, node := range nodes {
+		for _, record := range node {
+			value := len(nodes)
+			if record >= 0 {
+				value = record
+			} else if record < -1 {
+				value = len(nodes) + 16 + offsets[-record-2]
+			}
+			db.Write([]byte{byte(value >> 16), byte(value >> 8), byte(value)})
+		}
+	}
+	db.Write(make([]byte, 16))
+	db.Write(section.Bytes())
+	db.WriteString("\xAB\xCD\xEFMaxMind.com")
+	encode(&db, map[string]any{
+		"binary_format_major_version": uint16(2),
+		"binary_format_minor_version": uint16(0),
+		"build_epoch":                 uint64(1700000000),
+		"database_type":               "Test",
+		"description":                 map[string]any{"en": "Test database"},
+		"ip_version":                  uint16(6),
+		"languages":                   []any{"en"},
+		"node_count":                  uint32(len(nodes)),
+		"record_size":                 uint16(24),
+	})
+
+	path := filepath.Join(t.TempDir(), "test.mmdb")
+	require.NoError(t, os.WriteFile(path, db.Bytes(), 0o600))
+	return path
+}
+
+func writeFile(t *testing.T, name, data string) string {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), name)
+	require.NoError(t, os.WriteFile(path, []byte(data), 0o600))
+	return path
+}
+
+func TestStore(t *testing.T) {
+	t.Parallel()
+
+	city := writeMMDB(t, map[string]map[string]any{
+		"192.0.2.0/24": {
+			"city":    map[string]any{"names": map[string]any{"en": "Mountain View"}},
+			"country": map[string]any{"iso_code": "US", "names": map[string]any{"en": "United States"}},
+		},
+	})
+	asn := writeMMDB(t, map[string]map[string]any{
+		"192.0.2.0/24":    {"autonomous_system_number": uint32(64500), "autonomous_system_organization": "EXAMPLE-NET"},
+		"2001:db8::/32":   {"autonomous_system_number": uint32(64501), "autonomous_system_organization": "EXAMPLE-V6"},
+		"198.51.100.0/25": {"autonomous_system_number": uint32(64502), "autonomous_system_organization": "EXAMPLE-CLOUD"},
+	})
+	aws := writeFile(t, "ip-ranges.json", `{
+		"prefixes": [
+			{"ip_prefix": "198.51.100.0/24", "region": "us-east-1", "service": "AMAZON"},
+			{"ip_prefix": "198.51.100.0/25", "region": "us-east-1", "service": "EC2"}
+		],
+		"ipv6_prefixes": [{"ipv6_prefix": "2001:db8:a::/48", "region": "eu-west-1", "service": "S3"}]
+	}`)
+	cloudflare := writeFile(t, "ips-v4", "203.0.113.0/24\n# comment\n\n2001:db8:cf::/48\n")
+
+	s, err := Load(Config{CityDB: city, ASNDB: asn, Ranges: []string{"aws=" + aws, "cloudflare=" + cloudflare}})
+	require.NoError(t, err)
+	t.Cleanup(func() { s.Close() })
+
+	assert.Equal(t, Info{
+		ASN:          64500,
+		Organisation: "EXAMPLE-NET",
+		Prefix:       "192.0.2.0/24",
+		Country:      "United States",
+		CountryCode:  "US",
+		City:         "Mountain View",
+	}, s.GetIPInfo(net.ParseIP("192.0.2.1")))
+
+	assert.Equal(t, Info{
+		ASN:          64502,
+		Organisation: "EXAMPLE-CLOUD",
+		Prefix:       "198.51.100.0/25",
+		Cloud:        &Range{Provider: "aws", Prefix: "198.51.100.0/25", Service: "EC2", Region: "us-east-1", network: netip.MustParsePrefix("198.51.100.0/25")},
+	}, s.GetIPInfo(net.ParseIP("198.51.100.10")))
+
+	// only the wider range covers the upper half
+	assert.Equal(t, "AMAZON", s.GetIPInfo(net.ParseIP("198.51.100.200")).Cloud.Service)
+
+	info := s.GetIPInfo(net.ParseIP("2001:db8:cf::1"))
+	assert.Equal(t, uint(64501), info.ASN)
+	assert.Equal(t, "2001:db8::/32", info.Prefix)
+	assert.Empty(t, info.Country)
+	assert.Equal(t, "cloudflare", info.Cloud.Provider)
+
+	assert.Equal(t, Info{}, s.GetIPInfo(net.ParseIP("10.0.0.1")))
+
+	t.Run("broken files", func(t *testing.T) {
+		t.Parallel()
+		s, err := Load(Config{
+			CityDB: filepath.Join(t.TempDir(), "missing.mmdb"),
+			ASNDB:  asn,
+			Ranges: []string{aws, "oracle=" + aws, "cloudflare=" + writeFile(t, "ips", "not-a-prefix\n")},
+		})
+		assert.ErrorContains(t, err, "city database:")
+		assert.ErrorContains(t, err, `expected provider=path`)
+		assert.ErrorContains(t, err, `unknown provider "oracle"`)
+		assert.ErrorContains(t, err, "not-a-prefix")
+
+		// what did load is still used
+		assert.Equal(t, uint(64500), s.GetIPInfo(net.ParseIP("192.0.2.1")).ASN)
+	})
+}
@@ -0,0 +1,189 @@
+package ipintel
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"net"
+	"net/netip"
+	"os"
+	"strings"
+)
+
+// Range is a network published by a cloud or CDN provider.
+type Range struct {
+	Provider string `json:"provider"`
+	Prefix   string `json:"prefix"`
+	Service  string `json:"service,omitempty"`
+	Region   string `json:"region,omitempty"`
+
+	network netip.Prefix
+}
+
+// RangeParsers reads the range files each provider publishes:
+//
+//   - aws: https://ip-ranges.amazonaws.com/ip-ranges.json
+//   - gcp: https://www.gstatic.com/ipranges/cloud.json
+//   - azure: the Azure IP Ranges and Service Tags JSON download
+//   - cloudflare: https://www.cloudflare.com/ips-v4 and ips-v6
+var RangeParsers = map[string]func(data []byte) ([]Range, error){
+	"aws":        parseAWS,
+	"gcp":        parseGCP,
+	"azure":      parseAzure,
+	"cloudflare": parseList,
+}
+
+// LoadRanges reads the ranges file at path in the format for provider.
+func LoadRanges(provider, path string) ([]Range, error) {
+	parse, ok := RangeParsers[provider]
+	if !ok {
+		return nil, fmt.Errorf("IP ranges %s: unknown provider %q", path, provider)
+	}
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("IP ranges %s: %w", path, err)
+	}
+	ranges, err := parse(data)
+	if err != nil {
+		return nil, fmt.Errorf("IP ranges %s: %w", path, err)
+	}
+	for i := range ranges {
+		ranges[i].Provider = provider
+	}
+	return ranges, nil
+}
+
+func newRange(prefix, service, region string) (Range, error) {
+	network, err := netip.ParsePrefix(prefix)
+	if err != nil {
+		return Range{}, err
+	}
+	return Range{Prefix: network.String(), Service: service, Region: region, network: network.Masked()}, nil
+}
+
+func parseAWS(data []byte) ([]Range, error) {
+	var file struct {
+		Prefixes []struct {
+			IPv4    string `json:"ip_prefix"`
+			IPv6    string `json:"ipv6_prefix"`
+			Region  string `json:"region"`
+			Service string `json:"service"`
+		} `json:"prefixes"`
+		IPv6Prefixes []struct {
+			IPv6    string `json:"ipv6_prefix"`
+			Region  string `json:"region"`
+			Service string `json:"service"`
+		} `json:"ipv6_prefixes"`
+	}
+	if err := json.Unmarshal(data, &file); err != nil {
+		return nil, err
+	}
+	var ranges []Range
+	for _, p := range file.Prefixes {
+		r, err := newRange(p.IPv4+p.IPv6, p.Service, p.Region)
+		if err != nil {
+			return nil, err
+		}
+		ranges = append(ranges, r)
+	}
+	for _, p := range file.IPv6Prefixes {
+		r, err := newRange(p.IPv6, p.Service, p.Region)
+		if err != nil {
+			return nil, err
+		}
+		ranges = append(ranges, r)
+	}
+	return ranges, nil
+}
+
+func parseGCP(data []byte) ([]Range, error) {
+	var file struct {
+		Prefixes []struct {
+			IPv4    string `json:"ipv4Prefix"`
+			IPv6    string `json:"ipv6Prefix"`
+			Service string `json:"service"`
+			Scope   string `json:"scope"`
+		} `json:"prefixes"`
+	}
+	if err := json.Unmarshal(data, &file); err != nil {
+		return nil, err
+	}
+	var ranges []Range
+	for _, p := range file.Prefixes {
+		r, err := newRange(p.IPv4+p.IPv6, p.Service, p.Scope)
+		if err != nil {
+			return nil, err
+		}
+		ranges = append(ranges, r)
+	}
+	return ranges, nil
+}
+
+func parseAzure(data []byte) ([]Range, error) {
+	var file struct {
+		Values []struct {
+			Name       string `json:"name"`
+			Properties struct {
+				Region          string   `json:"region"`
+				SystemService   string   `json:"systemService"`
+				AddressPrefixes []string `json:"addressPrefixes"`
+			} `json:"properties"`
+		} `json:"values"`
+	}
+	if err := json.Unmarshal(data, &file); err != nil {
+		return nil, err
+	}
+	var ranges []Range
+	for _, v := range file.Values {
+		service := v.Properties.SystemService
+		if service == "" {
+			service = v.Name
+		}
+		for _, prefix := range v.Properties.AddressPrefixes {
+			r, err := newRange(prefix, service, v.Properties.Region)
+			if err != nil {
+				return nil, err
+			}
+			ranges = append(ranges, r)
+		}
+	}
+	return ranges, nil
+}
+
+// parseList reads one prefix per line, ignoring blank lines and comments.
+func parseList(data []byte) ([]Range, error) {
+	var ranges []Range
+	scanner := bufio.NewScanner(bytes.NewReader(data))
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		r, err := newRange(line, "", "")
+		if err != nil {
+			return nil, err
+		}
+		ranges = append(ranges, r)
+	}
+	return ranges, scanner.Err()
+}
+
+// matchRange returns the most specific range containing ip, the first
+// listed if several are as specific.
+func matchRange(ranges []Range, ip net.IP) *Range {
+	addr, ok := netip.AddrFromSlice(ip)
+	if !ok {
+		return nil
+	}
+	addr = addr.Unmap()
+	var best *Range
+	for i := range ranges {
+		r := &ranges[i]
+		if r.network.Contains(addr) && (best == nil || r.network.Bits() > best.network.Bits()) {
+			match := *r
+			best = &match
+		}
+	}
+	return best
+}
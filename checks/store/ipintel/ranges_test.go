@@ -0,0 +1,62 @@
+package ipintel
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRangeParsers(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		provider string
+		data     string
+		want     []Range
+	}{
+		{
+			provider: "gcp",
+			data: `{"syncToken": "1", "prefixes": [
+				{"ipv4Prefix": "34.1.208.0/20", "service": "Google Cloud", "scope": "africa-south1"},
+				{"ipv6Prefix": "2600:1900:8000::/44", "service": "Google Cloud", "scope": "us-east1"}
+			]}`,
+			want: []Range{
+				{Prefix: "34.1.208.0/20", Service: "Google Cloud", Region: "africa-south1"},
+				{Prefix: "2600:1900:8000::/44", Service: "Google Cloud", Region: "us-east1"},
+			},
+		},
+		{
+			provider: "azure",
+			data: `{"changeNumber": 1, "values": [
+				{"name": "AzureCloud.eastus", "properties": {"region": "eastus", "systemService": "", "addressPrefixes": ["20.42.0.0/17", "2603:1030:210::/47"]}},
+				{"name": "Storage.westeurope", "properties": {"region": "westeurope", "systemService": "AzureStorage", "addressPrefixes": ["20.38.108.0/23"]}}
+			]}`,
+			want: []Range{
+				{Prefix: "20.42.0.0/17", Service: "AzureCloud.eastus", Region: "eastus"},
+				{Prefix: "2603:1030:210::/47", Service: "AzureCloud.eastus", Region: "eastus"},
+				{Prefix: "20.38.108.0/23", Service: "AzureStorage", Region: "westeurope"},
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.provider, func(t *testing.T) {
+			t.Parallel()
+			ranges, err := LoadRanges(tt.provider, writeFile(t, tt.provider+".json", tt.data))
+			require.NoError(t, err)
+			require.Len(t, ranges, len(tt.want))
+			for i, r := range ranges {
+				assert.Equal(t, tt.provider, r.Provider)
+				assert.Equal(t, tt.want[i].Prefix, r.Prefix)
+				assert.Equal(t, tt.want[i].Service, r.Service)
+				assert.Equal(t, tt.want[i].Region, r.Region)
+			}
+		})
+	}
+
+	t.Run("invalid", func(t *testing.T) {
+		t.Parallel()
+		_, err := LoadRanges("aws", writeFile(t, "aws.json", `{"prefixes": [`))
+		assert.Error(t, err)
+	})
+}
@@ -0,0 +1,89 @@
+package passivedns
+
+import (
+	"context"
+	"encoding/csv"
+	"errors"
+	"fmt"
+	"io"
+	"net/netip"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+// Record is a hostname seen resolving to an address.
+type Record struct {
+	Name      string     `json:"name"`
+	FirstSeen *time.Time `json:"firstSeen,omitempty"`
+	LastSeen  *time.Time `json:"lastSeen,omitempty"`
+}
+
+// Provider is a source of passive DNS observations.
+type Provider interface {
+	Name() string
+	Lookup(ctx context.Context, addr netip.Addr) ([]Record, error)
+}
+
+// File is a provider answering from observations loaded from a CSV file,
+// one "ip,hostname[,first seen[,last seen]]" per line with RFC 3339 dates.
+// Lines starting with # are ignored.
+type File struct {
+	name    string
+	records map[netip.Addr][]Record
+}
+
+// LoadFile reads the observations in the file at path.
+func LoadFile(path string) (*File, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	r := csv.NewReader(f)
+	r.Comment = '#'
+	r.FieldsPerRecord = -1
+	r.TrimLeadingSpace = true
+	file := &File{name: filepath.Base(path), records: make(map[netip.Addr][]Record)}
+	for {
+		line, err := r.Read()
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		if err != nil {
+			return nil, fmt.Errorf("passive DNS %s: %w", path, err)
+		}
+		row, _ := r.FieldPos(0)
+		if len(line) < 2 || len(line) > 4 {
+			return nil, fmt.Errorf("passive DNS %s: line %d: expected ip,hostname[,first seen[,last seen]]", path, row)
+		}
+		addr, err := netip.ParseAddr(strings.TrimSpace(line[0]))
+		if err != nil {
+			return nil, fmt.Errorf("passive DNS %s: line %d: %w", path, row, err)
+		}
+		record := Record{Name: strings.TrimSuffix(strings.ToLower(strings.TrimSpace(line[1])), ".")}
+		for i, seen := range []**time.Time{&record.FirstSeen, &record.LastSeen} {
+			if len(line) <= i+2 || strings.TrimSpace(line[i+2]) == "" {
+				continue
+			}
+			t, err := time.Parse(time.RFC3339, strings.TrimSpace(line[i+2]))
+			if err != nil {
+				return nil, fmt.Errorf("passive DNS %s: line %d: %w", path, row, err)
+			}
+			*seen = &t
+		}
+		addr = addr.Unmap()
+		file.records[addr] = append(file.records[addr], record)
+	}
+	return file, nil
+}
+
+func (f *File) Name() string {
+	return f.name
+}
+
+func (f *File) Lookup(ctx context.Context, addr netip.Addr) ([]Record, error) {
+	return f.records[addr.Unmap()], nil
+}
@@ -0,0 +1,60 @@
+package passivedns
+
+import (
+	"context"
+	"net/netip"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func writeFile(t *testing.T, data string) string {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), "pdns.csv")
+	require.NoError(t, os.WriteFile(path, []byte(data), 0o600))
+	return path
+}
+
+func TestLoadFile(t *testing.T) {
+	t.Parallel()
+
+	f, err := LoadFile(writeFile(t, `# ip,hostname,first seen,last seen
+192.0.2.1,www.example.com
+192.0.2.1, Shop.Example.NET., 2023-01-01T00:00:00Z, 2024-06-01T00:00:00Z
+2001:db8::1,www.example.com,,2024-01-01T00:00:00Z
+`))
+	require.NoError(t, err)
+	assert.Equal(t, "pdns.csv", f.Name())
+
+	first := time.Date(2023, 1, 1, 0, 0, 0, 0, time.UTC)
+	last := time.Date(2024, 6, 1, 0, 0, 0, 0, time.UTC)
+	records, err := f.Lookup(context.Background(), netip.MustParseAddr("::ffff:192.0.2.1"))
+	require.NoError(t, err)
+	assert.Equal(t, []Record{
+		{Name: "www.example.com"},
+		{Name: "shop.example.net", FirstSeen: &first, LastSeen: &last},
+	}, records)
+
+	records, err = f.Lookup(context.Background(), netip.MustParseAddr("2001:db8::1"))
+	require.NoError(t, err)
+	require.Len(t, records, 1)
+	assert.Nil(t, records[0].FirstSeen)
+	assert.NotNil(t, records[0].LastSeen)
+
+	records, err = f.Lookup(context.Background(), netip.MustParseAddr("192.0.2.2"))
+	require.NoError(t, err)
+	assert.Empty(t, records)
+
+	for name, data := range map[string]string{
+		"missing hostname": "192.0.2.1\n",
+		"invalid address":  "www.example.com,192.0.2.1\n",
+		"invalid date":     "192.0.2.1,www.example.com,yesterday\n",
+	} {
+		_, err := LoadFile(writeFile(t, data))
+		assert.ErrorContains(t, err, "line 1", name)
+	}
+}
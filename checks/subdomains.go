@@ -0,0 +1,296 @@
+package checks
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/tls"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"slices"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/miekg/dns"
+	"github.com/xray-web/web-check-api/checks/clients/ip"
+	"golang.org/x/net/publicsuffix"
+)
+
+const crtShURL = "https://crt.sh/"
+
+// Sources a subdomain can be discovered from.
+const (
+	SubdomainSourceCT          = "ct"
+	SubdomainSourceBruteForce  = "bruteforce"
+	SubdomainSourceCertificate = "certificate"
+)
+
+const (
+	// subdomainWorkers is the number of names resolved at once.
+	subdomainWorkers = 20
+	// subdomainResolveLimit caps how many names found in CT logs and the
+	// certificate are resolved, popular domains have tens of thousands.
+	subdomainResolveLimit = 1000
+	// maxCTResponse bounds the crt.sh search response.
+	maxCTResponse = 32 << 20
+)
+
+type Subdomain struct {
+	Name string `json:"name"`
+	// Addresses is null if the name wasn't resolved because more than
+	// subdomainResolveLimit names were found.
+	Addresses []string `json:"addresses"`
+	Sources   []string `json:"sources"`
+}
+
+type SubdomainsReport struct {
+	Domain string `json:"domain"`
+	// Wildcard is set if any name under the domain resolves, in which case
+	// brute force results pointing at WildcardAddresses are dropped.
+	Wildcard          bool              `json:"wildcard"`
+	WildcardAddresses []string          `json:"wildcardAddresses"`
+	Subdomains        []Subdomain       `json:"subdomains"`
+	Errors            map[string]string `json:"errors"`
+}
+
+type Subdomains struct {
+	catalogue CatalogueSource
+	client    *http.Client
+	resolver  ip.Exchanger
+	dialer    Dialer
+}
+
+// NewSubdomains returns a check that finds subdomains from Certificate
+// Transparency logs searched with client, the wordlist in the catalogue
+// and the certificate served by the host.
+func NewSubdomains(catalogue CatalogueSource, client *http.Client, resolver ip.Exchanger, dialer Dialer) *Subdomains {
+	return &Subdomains{catalogue: catalogue, client: client, resolver: resolver, dialer: dialer}
+}
+
+// subdomainName normalises name and reports whether it is a subdomain of
+// domain.
+func subdomainName(name, domain string) (string, bool) {
+	name = strings.ToLower(strings.TrimSpace(name))
+	name = strings.TrimPrefix(name, "*.")
+	name = strings.TrimSuffix(name, ".")
+	if _, ok := dns.IsDomainName(name); !ok || name == domain {
+		return "", false
+	}
+	return name, dns.IsSubDomain(domain, name)
+}
+
+// fromCT searches the crt.sh Certificate Transparency log monitor for
+// certificates issued under domain.
+func (s *Subdomains) fromCT(ctx context.Context, domain string) ([]string, error) {
+	query := url.Values{"q": {"%." + domain}, "output": {"json"}}
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, crtShURL+"?"+query.Encode(), nil)
+	if err != nil {
+		return nil, err
+	}
+	resp, err := s.client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("CT search failed with status %d", resp.StatusCode)
+	}
+
+	var entries []struct {
+		NameValue string `json:"name_value"`
+	}
+	if err := json.NewDecoder(io.LimitReader(resp.Body, maxCTResponse)).Decode(&entries); err != nil {
+		return nil, fmt.Errorf("invalid CT search response: %w", err)
+	}
+	var names []string
+	for _, entry := range entries {
+		// each entry lists every name on the certificate, one per line
+		names = append(names, strings.Split(entry.NameValue, "\n")...)
+	}
+	return names, nil
+}
+
+// fromCertificate returns the names on the certificate served by host.
+func (s *Subdomains) fromCertificate(ctx context.Context, host string) ([]string, error) {
+	// the names are wanted even if the certificate isn't trusted
+	conn, err := tlsHandshake(ctx, s.dialer, host, &tls.Config{ServerName: host, InsecureSkipVerify: true})
+	if err != nil {
+		return nil, err
+	}
+	defer conn.Close()
+	certs := conn.ConnectionState().PeerCertificates
+	if len(certs) == 0 {
+		return nil, nil
+	}
+	return certs[0].DNSNames, nil
+}
+
+func (s *Subdomains) resolve(ctx context.Context, name string) []string {
+	addrs := make([]string, 0)
+	records, _ := queryDNS(ctx, s.resolver, name, dns.TypeA)
+	for _, rr := range records {
+		addrs = append(addrs, rr.(*dns.A).A.String())
+	}
+	sort.Strings(addrs)
+	return addrs
+}
+
+// wildcard resolves a random name under domain, any addresses returned
+// are from a wildcard record.
+func (s *Subdomains) wildcard(ctx context.Context, domain string) []string {
+	label := make([]byte, 8)
+	rand.Read(label)
+	return s.resolve(ctx, hex.EncodeToString(label)+"."+domain)
+}
+
+// resolveAll resolves names with a pool of workers.
+func (s *Subdomains) resolveAll(ctx context.Context, names []string) map[string][]string {
+	var mu sync.Mutex
+	var wg sync.WaitGroup
+	results := make(map[string][]string, len(names))
+	queue := make(chan string)
+	for range subdomainWorkers {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for name := range queue {
+				addrs := s.resolve(ctx, name)
+				mu.Lock()
+				results[name] = addrs
+				mu.Unlock()
+			}
+		}()
+	}
+	for _, name := range names {
+		queue <- name
+	}
+	close(queue)
+	wg.Wait()
+	return results
+}
+
+// Discover finds subdomains of the registrable domain of host.
+func (s *Subdomains) Discover(ctx context.Context, host string) (*SubdomainsReport, error) {
+	domain, err := publicsuffix.EffectiveTLDPlusOne(strings.ToLower(host))
+	if err != nil {
+		domain = strings.ToLower(host)
+	}
+	report := &SubdomainsReport{
+		Domain:     domain,
+		Subdomains: make([]Subdomain, 0),
+		Errors:     make(map[string]string),
+	}
+
+	sources := make(map[string][]string)
+	add := func(source string, names []string) {
+		for _, name := range names {
+			if name, ok := subdomainName(name, domain); ok && !slices.Contains(sources[name], source) {
+				sources[name] = append(sources[name], source)
+			}
+		}
+	}
+
+	var mu sync.Mutex
+	var wg sync.WaitGroup
+	wg.Add(3)
+	go func() {
+		defer wg.Done()
+		names, err := s.fromCT(ctx, domain)
+		mu.Lock()
+		defer mu.Unlock()
+		if err != nil {
+			report.Errors[SubdomainSourceCT] = err.Error()
+		}
+		add(SubdomainSourceCT, names)
+	}()
+	go func() {
+		defer wg.Done()
+		names, err := s.fromCertificate(ctx, host)
+		mu.Lock()
+		defer mu.Unlock()
+		if err != nil {
+			report.Errors[SubdomainSourceCertificate] = err.Error()
+		}
+		add(SubdomainSourceCertificate, names)
+	}()
+	go func() {
+		defer wg.Done()
+		report.WildcardAddresses = s.wildcard(ctx, domain)
+	}()
+	wg.Wait()
+	report.Wildcard = len(report.WildcardAddresses) > 0
+
+	var words []string
+	for _, word := range s.catalogue.Catalogue().Subdomains {
+		words = append(words, word+"."+domain)
+	}
+	found := s.resolveAll(ctx, words)
+	for name, addrs := range found {
+		if len(addrs) == 0 {
+			continue
+		}
+		if report.Wildcard && isSubset(addrs, report.WildcardAddresses) {
+			continue
+		}
+		add(SubdomainSourceBruteForce, []string{name})
+	}
+
+	var unresolved []string
+	for name := range sources {
+		if _, ok := found[name]; !ok {
+			unresolved = append(unresolved, name)
+		}
+	}
+	sort.Strings(unresolved)
+	unresolved = unresolved[:min(len(unresolved), subdomainResolveLimit)]
+	for name, addrs := range s.resolveAll(ctx, unresolved) {
+		found[name] = addrs
+	}
+
+	for name, from := range sources {
+		sort.Strings(from)
+		report.Subdomains = append(report.Subdomains, Subdomain{
+			Name:      name,
+			Addresses: found[name],
+			Sources:   from,
+		})
+	}
+	sort.Slice(report.Subdomains, func(i, j int) bool {
+		return report.Subdomains[i].Name < report.Subdomains[j].Name
+	})
+	return report, nil
+}
+
+func isSubset(values, set []string) bool {
+	for _, v := range values {
+		if !slices.Contains(set, v) {
+			return false
+		}
+	}
+	return true
+}
+
+func (s *Subdomains) Name() string {
+	return "subdomains"
+}
+
+func (s *Subdomains) Description() string {
+	return "Finds subdomains from Certificate Transparency logs, a DNS wordlist and the served certificate"
+}
+
+func (s *Subdomains) Input() Input {
+	return InputHostname
+}
+
+func (s *Subdomains) Timeout() time.Duration {
+	return 30 * time.Second
+}
+
+func (s *Subdomains) Run(ctx context.Context, target Target) (any, error) {
+	return s.Discover(ctx, target.Hostname())
+}
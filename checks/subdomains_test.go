@@ -0,0 +1,145 @@
+package checks
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"errors"
+	"fmt"
+	"math/big"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/miekg/dns"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/xray-web/web-check-api/checks/clients/ip"
+	"github.com/xray-web/web-check-api/testutils"
+)
+
+// serveNames starts a TLS server with a self signed certificate for names
+// and returns a dialer that connects to it whatever the address.
+func serveNames(t *testing.T, names ...string) Dialer {
+	t.Helper()
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	require.NoError(t, err)
+	template := &x509.Certificate{
+		SerialNumber: big.NewInt(1),
+		Subject:      pkix.Name{CommonName: names[0]},
+		DNSNames:     names,
+		NotBefore:    time.Now().Add(-time.Hour),
+		NotAfter:     time.Now().Add(time.Hour),
+	}
+	der, err := x509.CreateCertificate(rand.Reader, template, template, key.Public(), key)
+	require.NoError(t, err)
+
+	srv := httptest.NewUnstartedServer(http.NotFoundHandler())
+	srv.TLS = &tls.Config{Certificates: []tls.Certificate{{Certificate: [][]byte{der}, PrivateKey: key}}}
+	srv.StartTLS()
+	t.Cleanup(srv.Close)
+
+	var d net.Dialer
+	return DialFunc(func(ctx context.Context, network, address string) (net.Conn, error) {
+		return d.DialContext(ctx, network, srv.Listener.Addr().String())
+	})
+}
+
+func TestSubdomainsDiscover(t *testing.T) {
+	t.Parallel()
+
+	ct := []map[string]any{
+		{"name_value": "api.example.com\nexample.com", "common_name": "example.com"},
+		{"name_value": "*.dev.example.com", "common_name": "*.dev.example.com"},
+		{"name_value": "old.example.com", "common_name": "old.example.com"},
+		{"name_value": "example.org", "common_name": "example.org"},
+	}
+	resolver := zone(t,
+		"example.com. 300 IN A 192.0.2.1",
+		"www.example.com. 300 IN A 192.0.2.1",
+		"api.example.com. 300 IN A 192.0.2.2",
+		"dev.example.com. 300 IN A 192.0.2.3",
+		"mail.example.com. 300 IN A 192.0.2.4",
+	)
+	catalogue := &Catalogue{Subdomains: []string{"www", "mail", "vpn"}}
+	dialer := serveNames(t, "example.com", "www.example.com", "*.example.com", "example.net")
+
+	s := NewSubdomains(catalogue, testutils.MockClient(testutils.ResponseJSON(http.StatusOK, ct)), resolver, dialer)
+	report, err := s.Discover(context.Background(), "www.example.com")
+	require.NoError(t, err)
+
+	assert.Equal(t, "example.com", report.Domain)
+	assert.False(t, report.Wildcard)
+	assert.Empty(t, report.Errors)
+	assert.Equal(t, []Subdomain{
+		{Name: "api.example.com", Addresses: []string{"192.0.2.2"}, Sources: []string{SubdomainSourceCT}},
+		{Name: "dev.example.com", Addresses: []string{"192.0.2.3"}, Sources: []string{SubdomainSourceCT}},
+		{Name: "mail.example.com", Addresses: []string{"192.0.2.4"}, Sources: []string{SubdomainSourceBruteForce}},
+		{Name: "old.example.com", Addresses: []string{}, Sources: []string{SubdomainSourceCT}},
+		{Name: "www.example.com", Addresses: []string{"192.0.2.1"}, Sources: []string{SubdomainSourceBruteForce, SubdomainSourceCertificate}},
+	}, report.Subdomains)
+
+	t.Run("wildcard", func(t *testing.T) {
+		t.Parallel()
+		// every name resolves, www to its own address
+		wildcard := ip.ExchangeFunc(func(ctx context.Context, msg *dns.Msg) (*dns.Msg, error) {
+			resp := new(dns.Msg)
+			resp.SetReply(msg)
+			q := msg.Question[0]
+			if q.Qtype == dns.TypeA {
+				addr := "192.0.2.99"
+				if strings.HasPrefix(q.Name, "www.") {
+					addr = "192.0.2.1"
+				}
+				rr, _ := dns.NewRR(q.Name + " 300 IN A " + addr)
+				resp.Answer = append(resp.Answer, rr)
+			}
+			return resp, nil
+		})
+		failing := DialFunc(func(ctx context.Context, network, address string) (net.Conn, error) {
+			return nil, errors.New("connection refused")
+		})
+
+		s := NewSubdomains(catalogue, testutils.MockClient(testutils.Response(http.StatusBadGateway, nil)), wildcard, failing)
+		report, err := s.Discover(context.Background(), "example.com")
+		require.NoError(t, err)
+
+		assert.True(t, report.Wildcard)
+		assert.Equal(t, []string{"192.0.2.99"}, report.WildcardAddresses)
+		assert.Equal(t, []Subdomain{
+			{Name: "www.example.com", Addresses: []string{"192.0.2.1"}, Sources: []string{SubdomainSourceBruteForce}},
+		}, report.Subdomains)
+		assert.Equal(t, map[string]string{
+			SubdomainSourceCT:          "CT search failed with status 502",
+			SubdomainSourceCertificate: "error connecting to example.com:443: connection refused",
+		}, report.Errors)
+	})
+
+	t.Run("resolve limit", func(t *testing.T) {
+		t.Parallel()
+		var names []string
+		for i := range subdomainResolveLimit + 5 {
+			names = append(names, fmt.Sprintf("host%04d.example.com", i))
+		}
+		ct := []map[string]any{{"name_value": strings.Join(names, "\n")}}
+		failing := DialFunc(func(ctx context.Context, network, address string) (net.Conn, error) {
+			return nil, errors.New("connection refused")
+		})
+
+		s := NewSubdomains(&Catalogue{}, testutils.MockClient(testutils.ResponseJSON(http.StatusOK, ct)), resolver, failing)
+		report, err := s.Discover(context.Background(), "example.com")
+		require.NoError(t, err)
+
+		require.Len(t, report.Subdomains, len(names))
+		for i, sub := range report.Subdomains {
+			assert.Equal(t, i < subdomainResolveLimit, sub.Addresses != nil, sub.Name)
+		}
+	})
+}
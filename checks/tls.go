@@ -2,77 +2,255 @@ package checks
 
 import (
 	"context"
-	"encoding/json"
-	"errors"
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/rsa"
+	"crypto/sha256"
+	"crypto/tls"
+	"crypto/x509"
+	"encoding/hex"
+	"fmt"
+	"math"
+	"net"
 	"net/http"
-	"net/url"
-	"strconv"
-	"strings"
+	"time"
+
+	"golang.org/x/crypto/cryptobyte"
+	"golang.org/x/crypto/ocsp"
 )
 
+const defaultTLSPort = "443"
+
+type TlsCertificate struct {
+	Subject            string    `json:"subject"`
+	CommonName         string    `json:"commonName"`
+	SubjectAltNames    []string  `json:"subjectAltNames"`
+	Issuer             string    `json:"issuer"`
+	SerialNumber       string    `json:"serialNumber"`
+	NotBefore          time.Time `json:"notBefore"`
+	NotAfter           time.Time `json:"notAfter"`
+	KeyType            string    `json:"keyType"`
+	KeySize            int       `json:"keySize"`
+	SignatureAlgorithm string    `json:"signatureAlgorithm"`
+	IsCA               bool      `json:"isCA"`
+	Fingerprint        string    `json:"fingerprintSha256"`
+}
+
+type TlsReport struct {
+	Host            string           `json:"host"`
+	Version         string           `json:"version"`
+	CipherSuite     string           `json:"cipherSuite"`
+	ALPN            string           `json:"alpn"`
+	Chain           []TlsCertificate `json:"chain"`
+	ChainValid      bool             `json:"chainValid"`
+	ChainError      string           `json:"chainError,omitempty"`
+	HostnameMatch   bool             `json:"hostnameMatch"`
+	HostnameError   string           `json:"hostnameError,omitempty"`
+	DaysUntilExpiry int              `json:"daysUntilExpiry"`
+	Expired         bool             `json:"expired"`
+	SCTs            []TlsSCT         `json:"scts"`
+	SCTError        string           `json:"sctError,omitempty"`
+	OCSPStapled     bool             `json:"ocspStapled"`
+	OCSPStaple      *TlsRevocation   `json:"ocspStaple,omitempty"`
+	Revocation      *TlsRevocation   `json:"revocation"`
+}
+
 type Tls struct {
+	dialer Dialer
+	// client fetches OCSP responses and CRLs
 	client *http.Client
+	// roots used to validate the chain, nil uses the system roots
+	roots *x509.CertPool
 }
 
-func NewTls(client *http.Client) *Tls {
-	return &Tls{client: client}
+func NewTls(dialer Dialer, client *http.Client, roots *x509.CertPool) *Tls {
+	return &Tls{dialer: dialer, client: client, roots: roots}
 }
 
-func (t *Tls) initiateScan(ctx context.Context, domain string) (int, error) {
-	const scanUrl = "https://tls-observatory.services.mozilla.com/api/v1/scan"
-
-	formData := url.Values{"target": {domain}}
-	req, err := http.NewRequestWithContext(ctx, http.MethodPost, scanUrl, strings.NewReader(formData.Encode()))
+// tlsHandshake dials host with the given config, host defaults to port 443
+// if it doesn't include one.
+func tlsHandshake(ctx context.Context, dialer Dialer, host string, config *tls.Config) (*tls.Conn, error) {
+	addr := host
+	if _, _, err := net.SplitHostPort(host); err != nil {
+		addr = net.JoinHostPort(host, defaultTLSPort)
+	}
+	conn, err := dialer.DialContext(ctx, "tcp", addr)
 	if err != nil {
-		return -1, err
+		return nil, fmt.Errorf("error connecting to %s: %w", addr, err)
+	}
+	tlsConn := tls.Client(conn, config)
+	if err := tlsConn.HandshakeContext(ctx); err != nil {
+		conn.Close()
+		return nil, fmt.Errorf("TLS handshake failed: %w", err)
+	}
+	return tlsConn, nil
+}
+
+// tlsServerName strips any port from host.
+func tlsServerName(host string) string {
+	if h, _, err := net.SplitHostPort(host); err == nil {
+		return h
 	}
-	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	return host
+}
 
-	resp, err := t.client.Do(req)
+// GetCertificates performs a TLS handshake with host and reports on the
+// negotiated connection and the certificate chain presented by the server.
+// Chain and hostname validation failures are part of the report rather
+// than errors, only a failed handshake is returned as an error.
+func (t *Tls) GetCertificates(ctx context.Context, host string) (*TlsReport, error) {
+	serverName := tlsServerName(host)
+	conn, err := tlsHandshake(ctx, t.dialer, host, &tls.Config{
+		ServerName: serverName,
+		NextProtos: []string{"h2", "http/1.1"},
+		// the chain is verified below so problems can be reported instead
+		// of failing the handshake
+		InsecureSkipVerify: true,
+	})
 	if err != nil {
-		return -1, err
+		return nil, err
 	}
-	defer resp.Body.Close()
+	defer conn.Close()
 
-	var res struct {
-		ScanID int `json:"scan_id"`
+	state := conn.ConnectionState()
+	if len(state.PeerCertificates) == 0 {
+		return nil, fmt.Errorf("no certificates presented by %s", host)
 	}
-	if err := json.NewDecoder(resp.Body).Decode(&res); err != nil {
-		return -1, err
+
+	report := &TlsReport{
+		Host:        serverName,
+		Version:     tls.VersionName(state.Version),
+		CipherSuite: tls.CipherSuiteName(state.CipherSuite),
+		ALPN:        state.NegotiatedProtocol,
+		Chain:       make([]TlsCertificate, 0, len(state.PeerCertificates)),
+	}
+	for _, cert := range state.PeerCertificates {
+		report.Chain = append(report.Chain, newTlsCertificate(cert))
 	}
 
-	if res.ScanID == 0 {
-		return res.ScanID, errors.New("failed to get scan_id from TLS Observatory")
+	leaf := state.PeerCertificates[0]
+	intermediates := x509.NewCertPool()
+	for _, cert := range state.PeerCertificates[1:] {
+		intermediates.AddCert(cert)
+	}
+	// prefer the issuer from the verified chain, the server may send the
+	// chain out of order
+	var issuer *x509.Certificate
+	if len(state.PeerCertificates) > 1 {
+		issuer = state.PeerCertificates[1]
+	}
+	chains, err := leaf.Verify(x509.VerifyOptions{
+		Roots:         t.roots,
+		Intermediates: intermediates,
+	})
+	if err != nil {
+		report.ChainError = err.Error()
+	} else {
+		report.ChainValid = true
+		if len(chains[0]) > 1 {
+			issuer = chains[0][1]
+		}
 	}
 
-	return res.ScanID, nil
-}
+	if err := leaf.VerifyHostname(serverName); err != nil {
+		report.HostnameError = err.Error()
+	} else {
+		report.HostnameMatch = true
+	}
+
+	remaining := time.Until(leaf.NotAfter)
+	report.DaysUntilExpiry = int(math.Floor(remaining.Hours() / 24))
+	report.Expired = remaining < 0
 
-func (t *Tls) GetScanResults(ctx context.Context, domain string) (map[string]interface{}, error) {
-	scanID, err := t.initiateScan(ctx, domain)
+	report.SCTs, err = embeddedSCTs(leaf)
 	if err != nil {
-		return nil, err
+		report.SCTError = err.Error()
+		report.SCTs = make([]TlsSCT, 0)
+	}
+	for _, raw := range state.SignedCertificateTimestamps {
+		sct, err := parseSCT(cryptobyte.String(raw), sctSourceTLSExtension)
+		if err != nil {
+			report.SCTError = err.Error()
+			continue
+		}
+		report.SCTs = append(report.SCTs, sct)
 	}
 
-	const scanUrl = "https://tls-observatory.services.mozilla.com/api/v1/results"
-	req, err := http.NewRequestWithContext(ctx, http.MethodGet, scanUrl, nil)
-	if err != nil {
-		return nil, err
+	if issuer == nil {
+		report.Revocation = &TlsRevocation{Status: revocationUnknown, Error: "issuer certificate not available"}
+		return report, nil
+	}
+	if len(state.OCSPResponse) > 0 {
+		report.OCSPStapled = true
+		staple, err := ocsp.ParseResponseForCert(state.OCSPResponse, leaf, issuer)
+		if err != nil {
+			report.OCSPStaple = &TlsRevocation{Method: "ocsp", Status: revocationUnknown, Error: err.Error()}
+		} else {
+			report.OCSPStaple = ocspStatus(staple, "stapled")
+		}
 	}
-	q := req.URL.Query()
-	q.Add("id", strconv.Itoa(scanID))
-	req.URL.RawQuery = q.Encode()
+	report.Revocation = t.revocationStatus(ctx, leaf, issuer)
 
-	resp, err := t.client.Do(req)
-	if err != nil {
-		return nil, err
+	return report, nil
+}
+
+func newTlsCertificate(cert *x509.Certificate) TlsCertificate {
+	keyType, keySize := publicKeyInfo(cert.PublicKey)
+	fingerprint := sha256.Sum256(cert.Raw)
+	sans := make([]string, 0, len(cert.DNSNames)+len(cert.IPAddresses))
+	sans = append(sans, cert.DNSNames...)
+	for _, ip := range cert.IPAddresses {
+		sans = append(sans, ip.String())
 	}
-	defer resp.Body.Close()
+	return TlsCertificate{
+		Subject:            cert.Subject.String(),
+		CommonName:         cert.Subject.CommonName,
+		SubjectAltNames:    sans,
+		Issuer:             cert.Issuer.String(),
+		SerialNumber:       cert.SerialNumber.String(),
+		NotBefore:          cert.NotBefore,
+		NotAfter:           cert.NotAfter,
+		KeyType:            keyType,
+		KeySize:            keySize,
+		SignatureAlgorithm: cert.SignatureAlgorithm.String(),
+		IsCA:               cert.IsCA,
+		Fingerprint:        hex.EncodeToString(fingerprint[:]),
+	}
+}
 
-	var result map[string]interface{}
-	if err := json.NewDecoder(resp.Body).Decode(&result); err != nil {
-		return nil, err
+func publicKeyInfo(key any) (string, int) {
+	switch k := key.(type) {
+	case *rsa.PublicKey:
+		return "RSA", k.N.BitLen()
+	case *ecdsa.PublicKey:
+		return "ECDSA", k.Curve.Params().BitSize
+	case ed25519.PublicKey:
+		return "Ed25519", 256
+	default:
+		return "unknown", 0
 	}
+}
+
+func (t *Tls) Name() string {
+	return "tls"
+}
+
+func (t *Tls) Description() string {
+	return "Inspects the TLS handshake and certificate chain of the host"
+}
 
-	return result, nil
+func (t *Tls) Input() Input {
+	return InputHostname
+}
+
+func (t *Tls) Timeout() time.Duration {
+	return 30 * time.Second
+}
+
+func (t *Tls) Run(ctx context.Context, target Target) (any, error) {
+	host := target.Hostname()
+	if port := target.URL.Port(); port != "" {
+		host = net.JoinHostPort(host, port)
+	}
+	return t.GetCertificates(ctx, host)
 }
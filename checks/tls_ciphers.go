@@ -0,0 +1,274 @@
+package checks
+
+import (
+	"context"
+	"crypto/tls"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	weakRC4    = "RC4"
+	weak3DES   = "3DES"
+	weakCBC10  = "CBC on TLS 1.0"
+	weakNonPFS = "no forward secrecy"
+)
+
+var tlsVersions = []uint16{
+	tls.VersionTLS10,
+	tls.VersionTLS11,
+	tls.VersionTLS12,
+	tls.VersionTLS13,
+}
+
+type TlsCipherSuite struct {
+	ID      uint16   `json:"id"`
+	Name    string   `json:"name"`
+	Weak    bool     `json:"weak"`
+	Reasons []string `json:"reasons,omitempty"`
+	// Untestable suites are accepted by the server but not implemented by
+	// crypto/tls, so they aren't graded.
+	Untestable bool `json:"untestable,omitempty"`
+}
+
+type TlsVersionSupport struct {
+	Version      string           `json:"version"`
+	Supported    bool             `json:"supported"`
+	CipherSuites []TlsCipherSuite `json:"cipherSuites"`
+}
+
+type TlsCiphersReport struct {
+	Host              string              `json:"host"`
+	Versions          []TlsVersionSupport `json:"versions"`
+	WeakCiphers       int                 `json:"weakCiphers"`
+	UntestableCiphers int                 `json:"untestableCiphers"`
+	Grade             string              `json:"grade"`
+}
+
+type TlsCiphers struct {
+	dialer Dialer
+	// concurrency is the maximum number of handshakes in flight
+	concurrency int
+	// timeout applies to each handshake
+	timeout time.Duration
+}
+
+func NewTlsCiphers(dialer Dialer, concurrency int, timeout time.Duration) *TlsCiphers {
+	if concurrency < 1 {
+		concurrency = 1
+	}
+	return &TlsCiphers{dialer: dialer, concurrency: concurrency, timeout: timeout}
+}
+
+// accepts reports whether host completes a handshake restricted to version
+// and, below TLS 1.3, the given cipher suites. It returns the negotiated
+// cipher suite.
+func (t *TlsCiphers) accepts(ctx context.Context, host string, version uint16, suites []uint16) (uint16, bool) {
+	ctx, cancel := context.WithTimeout(ctx, t.timeout)
+	defer cancel()
+
+	conn, err := tlsHandshake(ctx, t.dialer, host, &tls.Config{
+		ServerName:         tlsServerName(host),
+		MinVersion:         version,
+		MaxVersion:         version,
+		CipherSuites:       suites,
+		InsecureSkipVerify: true,
+	})
+	if err != nil {
+		return 0, false
+	}
+	defer conn.Close()
+	return conn.ConnectionState().CipherSuite, true
+}
+
+// GetCiphers enumerates the TLS versions and cipher suites host accepts by
+// making a handshake restricted to each in turn.
+//
+// TLS 1.3 cipher suites can't be restricted by crypto/tls, so only the
+// negotiated suite is reported for that version. Below TLS 1.3, suites
+// crypto/tls doesn't implement, such as DHE and CAMELLIA, are found from
+// the ServerHello alone and reported as untestable.
+func (t *TlsCiphers) GetCiphers(ctx context.Context, host string) (*TlsCiphersReport, error) {
+	var wg sync.WaitGroup
+	var mu sync.Mutex
+	sem := make(chan struct{}, t.concurrency)
+	run := func(fn func()) {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			select {
+			case sem <- struct{}{}:
+			case <-ctx.Done():
+				return
+			}
+			defer func() { <-sem }()
+			fn()
+		}()
+	}
+
+	versions := make([]TlsVersionSupport, len(tlsVersions))
+	for i, version := range tlsVersions {
+		versions[i] = TlsVersionSupport{
+			Version:      tls.VersionName(version),
+			CipherSuites: make([]TlsCipherSuite, 0),
+		}
+		run(func() {
+			suite, ok := t.accepts(ctx, host, version, versionSuites(version))
+			if !ok {
+				return
+			}
+			mu.Lock()
+			defer mu.Unlock()
+			versions[i].Supported = true
+			if version == tls.VersionTLS13 {
+				versions[i].CipherSuites = append(versions[i].CipherSuites, newTlsCipherSuite(suite, version))
+			}
+		})
+	}
+	wg.Wait()
+
+	for i, version := range tlsVersions {
+		if version == tls.VersionTLS13 {
+			continue
+		}
+		// a version only supported with untestable suites has nothing more
+		// to enumerate, so this is read before they are looked for
+		supported := versions[i].Supported
+		run(func() {
+			suites := t.untestable(ctx, host, version)
+			if len(suites) == 0 {
+				return
+			}
+			mu.Lock()
+			defer mu.Unlock()
+			versions[i].Supported = true
+			for _, id := range suites {
+				versions[i].CipherSuites = append(versions[i].CipherSuites, TlsCipherSuite{ID: id, Name: untestableSuites[id], Untestable: true})
+			}
+		})
+		if !supported {
+			continue
+		}
+		for _, suite := range versionSuites(version) {
+			run(func() {
+				if _, ok := t.accepts(ctx, host, version, []uint16{suite}); !ok {
+					return
+				}
+				mu.Lock()
+				defer mu.Unlock()
+				versions[i].CipherSuites = append(versions[i].CipherSuites, newTlsCipherSuite(suite, version))
+			})
+		}
+	}
+	wg.Wait()
+
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	report := &TlsCiphersReport{Host: tlsServerName(host), Versions: versions}
+	for _, v := range versions {
+		slices.SortFunc(v.CipherSuites, func(a, b TlsCipherSuite) int {
+			return int(a.ID) - int(b.ID)
+		})
+		for _, suite := range v.CipherSuites {
+			if suite.Weak {
+				report.WeakCiphers++
+			}
+			if suite.Untestable {
+				report.UntestableCiphers++
+			}
+		}
+	}
+	report.Grade = tlsGrade(versions)
+	return report, nil
+}
+
+// versionSuites returns every cipher suite crypto/tls implements for
+// version, including the insecure ones.
+func versionSuites(version uint16) []uint16 {
+	var ids []uint16
+	for _, suite := range append(tls.CipherSuites(), tls.InsecureCipherSuites()...) {
+		if slices.Contains(suite.SupportedVersions, version) {
+			ids = append(ids, suite.ID)
+		}
+	}
+	return ids
+}
+
+func newTlsCipherSuite(id, version uint16) TlsCipherSuite {
+	name := tls.CipherSuiteName(id)
+	var reasons []string
+	switch {
+	case strings.Contains(name, "_RC4_"):
+		reasons = append(reasons, weakRC4)
+	case strings.Contains(name, "_3DES_"):
+		reasons = append(reasons, weak3DES)
+	}
+	if version == tls.VersionTLS10 && strings.Contains(name, "_CBC_") {
+		reasons = append(reasons, weakCBC10)
+	}
+	// TLS 1.3 suites always use an ephemeral key exchange
+	if version != tls.VersionTLS13 && !strings.HasPrefix(name, "TLS_ECDHE_") {
+		reasons = append(reasons, weakNonPFS)
+	}
+	return TlsCipherSuite{ID: id, Name: name, Weak: len(reasons) > 0, Reasons: reasons}
+}
+
+// tlsGrade summarises the enumeration, a version counts as supported even
+// if only untestable suites were accepted:
+//
+//	A+ TLS 1.3 supported, nothing below TLS 1.2 and no weak suites
+//	A  nothing below TLS 1.2 and no weak suites
+//	B  TLS 1.0 or 1.1 supported, or suites without forward secrecy
+//	C  3DES accepted
+//	F  RC4 accepted, or neither TLS 1.2 nor 1.3 supported
+func tlsGrade(versions []TlsVersionSupport) string {
+	supported := make(map[string]bool)
+	reasons := make(map[string]bool)
+	for _, v := range versions {
+		supported[v.Version] = v.Supported
+		for _, suite := range v.CipherSuites {
+			for _, reason := range suite.Reasons {
+				reasons[reason] = true
+			}
+		}
+	}
+
+	tls12, tls13 := supported[tls.VersionName(tls.VersionTLS12)], supported[tls.VersionName(tls.VersionTLS13)]
+	legacy := supported[tls.VersionName(tls.VersionTLS10)] || supported[tls.VersionName(tls.VersionTLS11)]
+	switch {
+	case reasons[weakRC4] || (!tls12 && !tls13):
+		return "F"
+	case reasons[weak3DES]:
+		return "C"
+	case legacy || len(reasons) > 0:
+		return "B"
+	case tls13:
+		return "A+"
+	default:
+		return "A"
+	}
+}
+
+func (t *TlsCiphers) Name() string {
+	return "tls-ciphers"
+}
+
+func (t *TlsCiphers) Description() string {
+	return "Enumerates the TLS versions and cipher suites accepted by the host"
+}
+
+func (t *TlsCiphers) Input() Input {
+	return InputHostname
+}
+
+func (t *TlsCiphers) Timeout() time.Duration {
+	return 60 * time.Second
+}
+
+func (t *TlsCiphers) Run(ctx context.Context, target Target) (any, error) {
+	return t.GetCiphers(ctx, target.Hostname())
+}
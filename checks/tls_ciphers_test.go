@@ -0,0 +1,228 @@
+package checks
+
+import (
+	"context"
+	"crypto/tls"
+	"encoding/binary"
+	"io"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"slices"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"golang.org/x/crypto/cryptobyte"
+)
+
+func TestTlsCiphers(t *testing.T) {
+	t.Parallel()
+
+	serve := func(t *testing.T, config *tls.Config) *TlsCiphers {
+		srv := httptest.NewUnstartedServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+		srv.TLS = config
+		srv.StartTLS()
+		t.Cleanup(srv.Close)
+
+		var d net.Dialer
+		return NewTlsCiphers(DialFunc(func(ctx context.Context, network, address string) (net.Conn, error) {
+			return d.DialContext(ctx, network, srv.Listener.Addr().String())
+		}), 4, time.Second)
+	}
+
+	t.Run("modern", func(t *testing.T) {
+		t.Parallel()
+		c := serve(t, &tls.Config{
+			MinVersion:   tls.VersionTLS12,
+			CipherSuites: []uint16{tls.TLS_ECDHE_RSA_WITH_AES_128_GCM_SHA256},
+		})
+
+		report, err := c.GetCiphers(context.Background(), "example.com")
+		require.NoError(t, err)
+
+		assert.Equal(t, "A+", report.Grade)
+		assert.Zero(t, report.WeakCiphers)
+		require.Len(t, report.Versions, 4)
+		assert.False(t, report.Versions[0].Supported)
+		assert.False(t, report.Versions[1].Supported)
+		assert.Equal(t, TlsVersionSupport{
+			Version:   "TLS 1.2",
+			Supported: true,
+			CipherSuites: []TlsCipherSuite{
+				{ID: tls.TLS_ECDHE_RSA_WITH_AES_128_GCM_SHA256, Name: "TLS_ECDHE_RSA_WITH_AES_128_GCM_SHA256"},
+			},
+		}, report.Versions[2])
+		assert.True(t, report.Versions[3].Supported)
+		assert.Len(t, report.Versions[3].CipherSuites, 1)
+	})
+
+	t.Run("weak", func(t *testing.T) {
+		t.Parallel()
+		c := serve(t, &tls.Config{
+			MinVersion: tls.VersionTLS12,
+			MaxVersion: tls.VersionTLS12,
+			CipherSuites: []uint16{
+				tls.TLS_ECDHE_RSA_WITH_AES_128_GCM_SHA256,
+				tls.TLS_RSA_WITH_AES_128_GCM_SHA256,
+				tls.TLS_ECDHE_RSA_WITH_3DES_EDE_CBC_SHA,
+			},
+		})
+
+		report, err := c.GetCiphers(context.Background(), "example.com")
+		require.NoError(t, err)
+
+		assert.Equal(t, "C", report.Grade)
+		assert.Equal(t, 2, report.WeakCiphers)
+		assert.False(t, report.Versions[3].Supported)
+		assert.Equal(t, []TlsCipherSuite{
+			{ID: tls.TLS_RSA_WITH_AES_128_GCM_SHA256, Name: "TLS_RSA_WITH_AES_128_GCM_SHA256", Weak: true, Reasons: []string{weakNonPFS}},
+			{ID: tls.TLS_ECDHE_RSA_WITH_3DES_EDE_CBC_SHA, Name: "TLS_ECDHE_RSA_WITH_3DES_EDE_CBC_SHA", Weak: true, Reasons: []string{weak3DES}},
+			{ID: tls.TLS_ECDHE_RSA_WITH_AES_128_GCM_SHA256, Name: "TLS_ECDHE_RSA_WITH_AES_128_GCM_SHA256"},
+		}, report.Versions[2].CipherSuites)
+	})
+}
+
+// helloServer answers ClientHellos with a ServerHello choosing the first of
+// suites that is offered, or a handshake failure alert. It can't complete a
+// handshake, so it stands in for a server only offering suites crypto/tls
+// doesn't implement.
+func helloServer(t *testing.T, version uint16, suites ...uint16) *TlsCiphers {
+	t.Helper()
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	t.Cleanup(func() { l.Close() })
+
+	go func() {
+		for {
+			conn, err := l.Accept()
+			if err != nil {
+				return
+			}
+			go func() {
+				defer conn.Close()
+				header := make([]byte, 5)
+				if _, err := io.ReadFull(conn, header); err != nil {
+					return
+				}
+				body := make([]byte, binary.BigEndian.Uint16(header[3:]))
+				if _, err := io.ReadFull(conn, body); err != nil {
+					return
+				}
+				hello := cryptobyte.String(body[4:])
+				var clientVersion uint16
+				var sessionID, offered cryptobyte.String
+				if !hello.ReadUint16(&clientVersion) || !hello.Skip(32) ||
+					!hello.ReadUint8LengthPrefixed(&sessionID) || !hello.ReadUint16LengthPrefixed(&offered) {
+					return
+				}
+				var ids []uint16
+				for !offered.Empty() {
+					var id uint16
+					offered.ReadUint16(&id)
+					ids = append(ids, id)
+				}
+				for _, suite := range suites {
+					if clientVersion == version && slices.Contains(ids, suite) {
+						var b cryptobyte.Builder
+						b.AddUint8(recordHandshake)
+						b.AddUint16(version)
+						b.AddUint16LengthPrefixed(func(b *cryptobyte.Builder) {
+							b.AddUint8(handshakeServer)
+							b.AddUint24LengthPrefixed(func(b *cryptobyte.Builder) {
+								b.AddUint16(version)
+								b.AddBytes(make([]byte, 32))
+								b.AddUint8(0)
+								b.AddUint16(suite)
+								b.AddUint8(0)
+							})
+						})
+						conn.Write(b.BytesOrPanic())
+						return
+					}
+				}
+				conn.Write([]byte{recordAlert, 3, 3, 0, 2, 2, 40})
+			}()
+		}
+	}()
+
+	var d net.Dialer
+	return NewTlsCiphers(DialFunc(func(ctx context.Context, network, address string) (net.Conn, error) {
+		return d.DialContext(ctx, network, l.Addr().String())
+	}), 4, time.Second)
+}
+
+func TestTlsCiphersUntestable(t *testing.T) {
+	t.Parallel()
+
+	// DHE with GCM and CHACHA20 only
+	c := helloServer(t, tls.VersionTLS12, 0x009E, 0xCCAA)
+
+	report, err := c.GetCiphers(context.Background(), "example.com")
+	require.NoError(t, err)
+
+	assert.Equal(t, "A", report.Grade)
+	assert.Zero(t, report.WeakCiphers)
+	assert.Equal(t, 2, report.UntestableCiphers)
+	assert.False(t, report.Versions[0].Supported)
+	assert.Equal(t, TlsVersionSupport{
+		Version:   "TLS 1.2",
+		Supported: true,
+		CipherSuites: []TlsCipherSuite{
+			{ID: 0x009E, Name: "TLS_DHE_RSA_WITH_AES_128_GCM_SHA256", Untestable: true},
+			{ID: 0xCCAA, Name: "TLS_DHE_RSA_WITH_CHACHA20_POLY1305_SHA256", Untestable: true},
+		},
+	}, report.Versions[2])
+}
+
+func TestTlsGrade(t *testing.T) {
+	t.Parallel()
+
+	suite := func(id, version uint16) []TlsCipherSuite {
+		return []TlsCipherSuite{newTlsCipherSuite(id, version)}
+	}
+	tests := []struct {
+		name     string
+		versions []TlsVersionSupport
+		expected string
+	}{
+		{
+			name: "tls 1.2 only",
+			versions: []TlsVersionSupport{
+				{Version: "TLS 1.2", Supported: true, CipherSuites: suite(tls.TLS_ECDHE_ECDSA_WITH_AES_256_GCM_SHA384, tls.VersionTLS12)},
+			},
+			expected: "A",
+		},
+		{
+			name: "legacy version",
+			versions: []TlsVersionSupport{
+				{Version: "TLS 1.0", Supported: true, CipherSuites: suite(tls.TLS_ECDHE_RSA_WITH_AES_128_CBC_SHA, tls.VersionTLS10)},
+				{Version: "TLS 1.2", Supported: true, CipherSuites: suite(tls.TLS_ECDHE_RSA_WITH_AES_128_GCM_SHA256, tls.VersionTLS12)},
+			},
+			expected: "B",
+		},
+		{
+			name: "rc4",
+			versions: []TlsVersionSupport{
+				{Version: "TLS 1.2", Supported: true, CipherSuites: suite(tls.TLS_ECDHE_RSA_WITH_RC4_128_SHA, tls.VersionTLS12)},
+			},
+			expected: "F",
+		},
+		{
+			name: "no modern versions",
+			versions: []TlsVersionSupport{
+				{Version: "TLS 1.1", Supported: true, CipherSuites: suite(tls.TLS_ECDHE_RSA_WITH_AES_128_CBC_SHA, tls.VersionTLS11)},
+			},
+			expected: "F",
+		},
+	}
+
+	for _, tc := range tests {
+		tc := tc
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+			assert.Equal(t, tc.expected, tlsGrade(tc.versions))
+		})
+	}
+}
@@ -0,0 +1,235 @@
+package checks
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/tls"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"slices"
+
+	"golang.org/x/crypto/cryptobyte"
+)
+
+// untestableSuites are cipher suites crypto/tls doesn't implement, so
+// whether a server accepts them is found by sending a ClientHello by hand
+// and reading the suite from the ServerHello.
+var untestableSuites = map[uint16]string{
+	0x0016: "TLS_DHE_RSA_WITH_3DES_EDE_CBC_SHA",
+	0x0032: "TLS_DHE_DSS_WITH_AES_128_CBC_SHA",
+	0x0033: "TLS_DHE_RSA_WITH_AES_128_CBC_SHA",
+	0x0038: "TLS_DHE_DSS_WITH_AES_256_CBC_SHA",
+	0x0039: "TLS_DHE_RSA_WITH_AES_256_CBC_SHA",
+	0x003D: "TLS_RSA_WITH_AES_256_CBC_SHA256",
+	0x0041: "TLS_RSA_WITH_CAMELLIA_128_CBC_SHA",
+	0x0045: "TLS_DHE_RSA_WITH_CAMELLIA_128_CBC_SHA",
+	0x0067: "TLS_DHE_RSA_WITH_AES_128_CBC_SHA256",
+	0x006B: "TLS_DHE_RSA_WITH_AES_256_CBC_SHA256",
+	0x0084: "TLS_RSA_WITH_CAMELLIA_256_CBC_SHA",
+	0x0088: "TLS_DHE_RSA_WITH_CAMELLIA_256_CBC_SHA",
+	0x0096: "TLS_RSA_WITH_SEED_CBC_SHA",
+	0x009E: "TLS_DHE_RSA_WITH_AES_128_GCM_SHA256",
+	0x009F: "TLS_DHE_RSA_WITH_AES_256_GCM_SHA384",
+	0x00A2: "TLS_DHE_DSS_WITH_AES_128_GCM_SHA256",
+	0x00A3: "TLS_DHE_DSS_WITH_AES_256_GCM_SHA384",
+	0xC024: "TLS_ECDHE_ECDSA_WITH_AES_256_CBC_SHA384",
+	0xC028: "TLS_ECDHE_RSA_WITH_AES_256_CBC_SHA384",
+	0xC050: "TLS_RSA_WITH_ARIA_128_GCM_SHA256",
+	0xC05C: "TLS_ECDHE_ECDSA_WITH_ARIA_128_GCM_SHA256",
+	0xC060: "TLS_ECDHE_RSA_WITH_ARIA_128_GCM_SHA256",
+	0xC072: "TLS_ECDHE_ECDSA_WITH_CAMELLIA_128_CBC_SHA256",
+	0xC076: "TLS_ECDHE_RSA_WITH_CAMELLIA_128_CBC_SHA256",
+	0xC09C: "TLS_RSA_WITH_AES_128_CCM",
+	0xC09D: "TLS_RSA_WITH_AES_256_CCM",
+	0xC09E: "TLS_DHE_RSA_WITH_AES_128_CCM",
+	0xC09F: "TLS_DHE_RSA_WITH_AES_256_CCM",
+	0xC0AC: "TLS_ECDHE_ECDSA_WITH_AES_128_CCM",
+	0xC0AD: "TLS_ECDHE_ECDSA_WITH_AES_256_CCM",
+	0xCCAA: "TLS_DHE_RSA_WITH_CHACHA20_POLY1305_SHA256",
+}
+
+// TLS record and handshake message types
+const (
+	recordAlert       = 21
+	recordHandshake   = 22
+	handshakeClient   = 1
+	handshakeServer   = 2
+	extServerName     = 0
+	extGroups         = 10
+	extPointFormats   = 11
+	extSignatureAlgos = 13
+)
+
+var errHelloRejected = errors.New("server rejected the ClientHello")
+
+// clientHello returns a TLS record holding a ClientHello for version, which
+// must be below TLS 1.3, offering suites.
+func clientHello(serverName string, version uint16, suites []uint16) ([]byte, error) {
+	random := make([]byte, 32)
+	if _, err := rand.Read(random); err != nil {
+		return nil, err
+	}
+
+	var hello cryptobyte.Builder
+	hello.AddUint8(recordHandshake)
+	hello.AddUint16(tls.VersionTLS10)
+	hello.AddUint16LengthPrefixed(func(b *cryptobyte.Builder) {
+		b.AddUint8(handshakeClient)
+		b.AddUint24LengthPrefixed(func(b *cryptobyte.Builder) {
+			b.AddUint16(version)
+			b.AddBytes(random)
+			b.AddUint8(0) // session ID
+			b.AddUint16LengthPrefixed(func(b *cryptobyte.Builder) {
+				for _, suite := range suites {
+					b.AddUint16(suite)
+				}
+			})
+			b.AddUint8LengthPrefixed(func(b *cryptobyte.Builder) {
+				b.AddUint8(0) // no compression
+			})
+			b.AddUint16LengthPrefixed(func(b *cryptobyte.Builder) {
+				if net.ParseIP(serverName) == nil {
+					b.AddUint16(extServerName)
+					b.AddUint16LengthPrefixed(func(b *cryptobyte.Builder) {
+						b.AddUint16LengthPrefixed(func(b *cryptobyte.Builder) {
+							b.AddUint8(0) // host name
+							b.AddUint16LengthPrefixed(func(b *cryptobyte.Builder) {
+								b.AddBytes([]byte(serverName))
+							})
+						})
+					})
+				}
+				b.AddUint16(extGroups)
+				b.AddUint16LengthPrefixed(func(b *cryptobyte.Builder) {
+					b.AddUint16LengthPrefixed(func(b *cryptobyte.Builder) {
+						// x25519, P-256, P-384, P-521, ffdhe2048, ffdhe3072
+						for _, group := range []uint16{29, 23, 24, 25, 256, 257} {
+							b.AddUint16(group)
+						}
+					})
+				})
+				b.AddUint16(extPointFormats)
+				b.AddUint16LengthPrefixed(func(b *cryptobyte.Builder) {
+					b.AddUint8LengthPrefixed(func(b *cryptobyte.Builder) {
+						b.AddUint8(0) // uncompressed
+					})
+				})
+				if version == tls.VersionTLS12 {
+					b.AddUint16(extSignatureAlgos)
+					b.AddUint16LengthPrefixed(func(b *cryptobyte.Builder) {
+						b.AddUint16LengthPrefixed(func(b *cryptobyte.Builder) {
+							for _, alg := range []tls.SignatureScheme{
+								tls.ECDSAWithP256AndSHA256, tls.ECDSAWithP384AndSHA384, tls.ECDSAWithP521AndSHA512,
+								tls.PSSWithSHA256, tls.PSSWithSHA384, tls.PSSWithSHA512,
+								tls.PKCS1WithSHA256, tls.PKCS1WithSHA384, tls.PKCS1WithSHA512,
+								tls.PKCS1WithSHA1, tls.ECDSAWithSHA1, 0x0402, // DSA with SHA256
+							} {
+								b.AddUint16(uint16(alg))
+							}
+						})
+					})
+				}
+			})
+		})
+	})
+	return hello.Bytes()
+}
+
+// serverHelloSuite reads the cipher suite chosen in the ServerHello
+// answering a ClientHello for version. errHelloRejected is returned if the
+// server answers with an alert or a different version.
+func serverHelloSuite(r io.Reader, version uint16) (uint16, error) {
+	header := make([]byte, 5)
+	if _, err := io.ReadFull(r, header); err != nil {
+		return 0, err
+	}
+	record := cryptobyte.String(header)
+	var typ uint8
+	var length uint16
+	record.ReadUint8(&typ)
+	record.Skip(2)
+	record.ReadUint16(&length)
+	if typ == recordAlert {
+		return 0, errHelloRejected
+	}
+	if typ != recordHandshake {
+		return 0, fmt.Errorf("unexpected TLS record type %d", typ)
+	}
+	body := make([]byte, length)
+	if _, err := io.ReadFull(r, body); err != nil {
+		return 0, err
+	}
+
+	msg := cryptobyte.String(body)
+	var msgType uint8
+	var hello cryptobyte.String
+	var serverVersion, suite uint16
+	var sessionID cryptobyte.String
+	if !msg.ReadUint8(&msgType) || msgType != handshakeServer ||
+		!msg.ReadUint24LengthPrefixed(&hello) ||
+		!hello.ReadUint16(&serverVersion) ||
+		!hello.Skip(32) ||
+		!hello.ReadUint8LengthPrefixed(&sessionID) ||
+		!hello.ReadUint16(&suite) {
+		return 0, errors.New("malformed ServerHello")
+	}
+	if serverVersion != version {
+		return 0, errHelloRejected
+	}
+	return suite, nil
+}
+
+// helloSuite sends a ClientHello for version offering suites and returns
+// the suite the server chooses.
+func (t *TlsCiphers) helloSuite(ctx context.Context, host string, version uint16, suites []uint16) (uint16, error) {
+	ctx, cancel := context.WithTimeout(ctx, t.timeout)
+	defer cancel()
+
+	hello, err := clientHello(tlsServerName(host), version, suites)
+	if err != nil {
+		return 0, err
+	}
+	addr := host
+	if _, _, err := net.SplitHostPort(host); err != nil {
+		addr = net.JoinHostPort(host, defaultTLSPort)
+	}
+	conn, err := t.dialer.DialContext(ctx, "tcp", addr)
+	if err != nil {
+		return 0, err
+	}
+	defer conn.Close()
+	deadline, _ := ctx.Deadline()
+	conn.SetDeadline(deadline)
+	if _, err := conn.Write(hello); err != nil {
+		return 0, err
+	}
+	return serverHelloSuite(conn, version)
+}
+
+// untestable returns the suites crypto/tls can't negotiate that host
+// accepts for version, offering the rest again after each one is found.
+func (t *TlsCiphers) untestable(ctx context.Context, host string, version uint16) []uint16 {
+	offer := make([]uint16, 0, len(untestableSuites))
+	for id := range untestableSuites {
+		offer = append(offer, id)
+	}
+	slices.Sort(offer)
+
+	var accepted []uint16
+	for len(offer) > 0 && ctx.Err() == nil {
+		suite, err := t.helloSuite(ctx, host, version, offer)
+		if err != nil {
+			break
+		}
+		i := slices.Index(offer, suite)
+		if i < 0 {
+			// a server choosing a suite it wasn't offered is broken
+			break
+		}
+		accepted = append(accepted, suite)
+		offer = slices.Delete(offer, i, i+1)
+	}
+	return accepted
+}
@@ -0,0 +1,188 @@
+package checks
+
+import (
+	"bytes"
+	"context"
+	"crypto/x509"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"time"
+
+	"golang.org/x/crypto/ocsp"
+)
+
+const (
+	revocationGood    = "good"
+	revocationRevoked = "revoked"
+	revocationUnknown = "unknown"
+
+	// maxRevocationResponse bounds OCSP responses and CRLs, large CAs
+	// publish CRLs of a few megabytes.
+	maxRevocationResponse = 20 << 20
+	// maxOCSPAge is how old an OCSP response without a next update time
+	// may be, the longest validity the CA/Browser Forum allows.
+	maxOCSPAge = 10 * 24 * time.Hour
+	// revocationClockSkew allows for responders with clocks slightly ahead.
+	revocationClockSkew = 5 * time.Minute
+)
+
+var errNoRevocationSource = errors.New("certificate has no OCSP responder or CRL distribution point")
+
+// revocationReasons are the CRL reason codes, RFC 5280 section 5.3.1.
+var revocationReasons = map[int]string{
+	ocsp.Unspecified:          "unspecified",
+	ocsp.KeyCompromise:        "keyCompromise",
+	ocsp.CACompromise:         "cACompromise",
+	ocsp.AffiliationChanged:   "affiliationChanged",
+	ocsp.Superseded:           "superseded",
+	ocsp.CessationOfOperation: "cessationOfOperation",
+	ocsp.CertificateHold:      "certificateHold",
+	ocsp.RemoveFromCRL:        "removeFromCRL",
+	ocsp.PrivilegeWithdrawn:   "privilegeWithdrawn",
+	ocsp.AACompromise:         "aACompromise",
+}
+
+type TlsRevocation struct {
+	Method    string     `json:"method,omitempty"`
+	Source    string     `json:"source,omitempty"`
+	Status    string     `json:"status"`
+	RevokedAt *time.Time `json:"revokedAt,omitempty"`
+	Reason    string     `json:"reason,omitempty"`
+	Error     string     `json:"error,omitempty"`
+}
+
+// revocationFresh returns an error if an OCSP response or CRL issued at
+// thisUpdate and superseded at nextUpdate is out of date at now, so an old
+// or replayed good status isn't trusted.
+func revocationFresh(thisUpdate, nextUpdate, now time.Time) error {
+	switch {
+	case thisUpdate.After(now.Add(revocationClockSkew)):
+		return fmt.Errorf("issued in the future at %s", thisUpdate.UTC().Format(time.RFC3339))
+	case !nextUpdate.IsZero() && nextUpdate.Before(now):
+		return fmt.Errorf("expired at %s", nextUpdate.UTC().Format(time.RFC3339))
+	case nextUpdate.IsZero() && thisUpdate.Before(now.Add(-maxOCSPAge)):
+		return fmt.Errorf("issued at %s with no next update", thisUpdate.UTC().Format(time.RFC3339))
+	}
+	return nil
+}
+
+// ocspStatus converts a parsed OCSP response into a TlsRevocation, the
+// status of a stale response is unknown.
+func ocspStatus(resp *ocsp.Response, source string) *TlsRevocation {
+	r := &TlsRevocation{Method: "ocsp", Source: source, Status: revocationUnknown}
+	if err := revocationFresh(resp.ThisUpdate, resp.NextUpdate, time.Now()); err != nil {
+		r.Error = "stale response " + err.Error()
+		return r
+	}
+	switch resp.Status {
+	case ocsp.Good:
+		r.Status = revocationGood
+	case ocsp.Revoked:
+		r.Status = revocationRevoked
+		revokedAt := resp.RevokedAt
+		r.RevokedAt = &revokedAt
+		r.Reason = revocationReasons[resp.RevocationReason]
+	}
+	return r
+}
+
+// revocationStatus asks the OCSP responder named in cert for its status,
+// falling back to the CRL distribution points if there is no responder or
+// it fails.
+func (t *Tls) revocationStatus(ctx context.Context, cert, issuer *x509.Certificate) *TlsRevocation {
+	var errs []error
+	for _, server := range cert.OCSPServer {
+		status, err := t.ocspStatus(ctx, server, cert, issuer)
+		if err == nil {
+			return status
+		}
+		errs = append(errs, fmt.Errorf("OCSP %s: %w", server, err))
+	}
+	for _, dp := range cert.CRLDistributionPoints {
+		status, err := t.crlStatus(ctx, dp, cert, issuer)
+		if err == nil {
+			return status
+		}
+		errs = append(errs, fmt.Errorf("CRL %s: %w", dp, err))
+	}
+	if len(errs) == 0 {
+		errs = append(errs, errNoRevocationSource)
+	}
+	return &TlsRevocation{Status: revocationUnknown, Error: errors.Join(errs...).Error()}
+}
+
+func (t *Tls) ocspStatus(ctx context.Context, server string, cert, issuer *x509.Certificate) (*TlsRevocation, error) {
+	body, err := ocsp.CreateRequest(cert, issuer, nil)
+	if err != nil {
+		return nil, err
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, server, bytes.NewReader(body))
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Content-Type", "application/ocsp-request")
+	raw, err := t.fetch(req)
+	if err != nil {
+		return nil, err
+	}
+	resp, err := ocsp.ParseResponseForCert(raw, cert, issuer)
+	if err != nil {
+		return nil, err
+	}
+	status := ocspStatus(resp, server)
+	if status.Error != "" {
+		return nil, errors.New(status.Error)
+	}
+	return status, nil
+}
+
+func (t *Tls) crlStatus(ctx context.Context, dp string, cert, issuer *x509.Certificate) (*TlsRevocation, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, dp, nil)
+	if err != nil {
+		return nil, err
+	}
+	raw, err := t.fetch(req)
+	if err != nil {
+		return nil, err
+	}
+	crl, err := x509.ParseRevocationList(raw)
+	if err != nil {
+		return nil, err
+	}
+	if err := crl.CheckSignatureFrom(issuer); err != nil {
+		return nil, err
+	}
+	// a CRL must always have a next update time, RFC 5280 section 5.1.2.5
+	if crl.NextUpdate.IsZero() {
+		return nil, errors.New("CRL has no next update time")
+	}
+	if err := revocationFresh(crl.ThisUpdate, crl.NextUpdate, time.Now()); err != nil {
+		return nil, fmt.Errorf("stale CRL %w", err)
+	}
+
+	status := &TlsRevocation{Method: "crl", Source: dp, Status: revocationGood}
+	for _, entry := range crl.RevokedCertificateEntries {
+		if entry.SerialNumber.Cmp(cert.SerialNumber) == 0 {
+			revokedAt := entry.RevocationTime
+			status.Status = revocationRevoked
+			status.RevokedAt = &revokedAt
+			status.Reason = revocationReasons[entry.ReasonCode]
+			break
+		}
+	}
+	return status, nil
+}
+
+func (t *Tls) fetch(req *http.Request) ([]byte, error) {
+	resp, err := t.client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("unexpected status %d", resp.StatusCode)
+	}
+	return io.ReadAll(io.LimitReader(resp.Body, maxRevocationResponse))
+}
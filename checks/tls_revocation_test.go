@@ -0,0 +1,287 @@
+package checks
+
+import (
+	"context"
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/asn1"
+	"io"
+	"math/big"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"golang.org/x/crypto/cryptobyte"
+	"golang.org/x/crypto/ocsp"
+)
+
+// testPKI is a CA along with an OCSP responder and CRL distribution point
+// for the certificates it issues.
+type testPKI struct {
+	ca        *x509.Certificate
+	key       crypto.Signer
+	responder *httptest.Server
+	// ocspStatus is the status returned by the responder, a negative value
+	// makes it fail
+	ocspStatus int
+	revoked    []x509.RevocationListEntry
+	// stale makes the OCSP responses and CRL out of date
+	stale bool
+}
+
+func newTestPKI(t *testing.T) *testPKI {
+	t.Helper()
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	require.NoError(t, err)
+	template := &x509.Certificate{
+		SerialNumber:          big.NewInt(1),
+		Subject:               pkix.Name{CommonName: "Test CA"},
+		NotBefore:             time.Now().Add(-time.Hour),
+		NotAfter:              time.Now().Add(24 * time.Hour),
+		KeyUsage:              x509.KeyUsageCertSign | x509.KeyUsageCRLSign,
+		BasicConstraintsValid: true,
+		IsCA:                  true,
+	}
+	der, err := x509.CreateCertificate(rand.Reader, template, template, key.Public(), key)
+	require.NoError(t, err)
+	ca, err := x509.ParseCertificate(der)
+	require.NoError(t, err)
+
+	pki := &testPKI{ca: ca, key: key}
+	pki.responder = httptest.NewServer(http.HandlerFunc(pki.serve))
+	t.Cleanup(pki.responder.Close)
+	return pki
+}
+
+func (p *testPKI) serve(w http.ResponseWriter, r *http.Request) {
+	switch r.URL.Path {
+	case "/ocsp":
+		if p.ocspStatus < 0 {
+			w.WriteHeader(http.StatusInternalServerError)
+			return
+		}
+		body, _ := io.ReadAll(r.Body)
+		req, err := ocsp.ParseRequest(body)
+		if err != nil {
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+		w.Write(p.ocspResponse(req.SerialNumber, p.ocspStatus))
+	case "/ca.crl":
+		thisUpdate, nextUpdate := p.updates()
+		crl, _ := x509.CreateRevocationList(rand.Reader, &x509.RevocationList{
+			Number:                    big.NewInt(1),
+			ThisUpdate:                thisUpdate,
+			NextUpdate:                nextUpdate,
+			RevokedCertificateEntries: p.revoked,
+		}, p.ca, p.key)
+		w.Write(crl)
+	default:
+		w.WriteHeader(http.StatusNotFound)
+	}
+}
+
+// updates returns the validity of OCSP responses and the CRL.
+func (p *testPKI) updates() (thisUpdate, nextUpdate time.Time) {
+	if p.stale {
+		return time.Now().Add(-48 * time.Hour), time.Now().Add(-24 * time.Hour)
+	}
+	return time.Now().Add(-time.Hour), time.Now().Add(time.Hour)
+}
+
+func (p *testPKI) ocspResponse(serial *big.Int, status int) []byte {
+	thisUpdate, nextUpdate := p.updates()
+	resp, _ := ocsp.CreateResponse(p.ca, p.ca, ocsp.Response{
+		Status:           status,
+		SerialNumber:     serial,
+		ThisUpdate:       thisUpdate,
+		NextUpdate:       nextUpdate,
+		RevokedAt:        time.Now().Add(-time.Minute).Truncate(time.Second),
+		RevocationReason: ocsp.KeyCompromise,
+	}, p.key)
+	return resp
+}
+
+// issue returns a leaf certificate for example.com with an embedded SCT
+// list, pointing at the responder for revocation.
+func (p *testPKI) issue(t *testing.T, scts ...[]byte) (tls.Certificate, *x509.Certificate) {
+	t.Helper()
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	require.NoError(t, err)
+
+	var b cryptobyte.Builder
+	b.AddUint16LengthPrefixed(func(b *cryptobyte.Builder) {
+		for _, sct := range scts {
+			b.AddUint16LengthPrefixed(func(b *cryptobyte.Builder) {
+				b.AddBytes(sct)
+			})
+		}
+	})
+	list, err := asn1.Marshal(b.BytesOrPanic())
+	require.NoError(t, err)
+
+	template := &x509.Certificate{
+		SerialNumber:          big.NewInt(42),
+		Subject:               pkix.Name{CommonName: "example.com"},
+		DNSNames:              []string{"example.com"},
+		NotBefore:             time.Now().Add(-time.Hour),
+		NotAfter:              time.Now().Add(24 * time.Hour),
+		KeyUsage:              x509.KeyUsageDigitalSignature,
+		ExtKeyUsage:           []x509.ExtKeyUsage{x509.ExtKeyUsageServerAuth},
+		OCSPServer:            []string{p.responder.URL + "/ocsp"},
+		CRLDistributionPoints: []string{p.responder.URL + "/ca.crl"},
+		ExtraExtensions:       []pkix.Extension{{Id: oidSCTList, Value: list}},
+	}
+	der, err := x509.CreateCertificate(rand.Reader, template, p.ca, key.Public(), p.key)
+	require.NoError(t, err)
+	leaf, err := x509.ParseCertificate(der)
+	require.NoError(t, err)
+
+	return tls.Certificate{
+		Certificate: [][]byte{der, p.ca.Raw},
+		PrivateKey:  key,
+		Leaf:        leaf,
+	}, leaf
+}
+
+// testSCT serializes an SCT from logID at timestamp.
+func testSCT(logID byte, timestamp time.Time) []byte {
+	var b cryptobyte.Builder
+	b.AddUint8(0)
+	b.AddBytes(make([]byte, 31))
+	b.AddUint8(logID)
+	b.AddUint64(uint64(timestamp.UnixMilli()))
+	b.AddUint16(0)           // extensions
+	b.AddBytes([]byte{4, 3}) // sha256, ecdsa
+	b.AddUint16LengthPrefixed(func(b *cryptobyte.Builder) {
+		b.AddBytes([]byte("signature"))
+	})
+	return b.BytesOrPanic()
+}
+
+func (p *testPKI) serveTLS(t *testing.T, cert tls.Certificate) *Tls {
+	t.Helper()
+	srv := httptest.NewUnstartedServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+	srv.TLS = &tls.Config{Certificates: []tls.Certificate{cert}}
+	srv.StartTLS()
+	t.Cleanup(srv.Close)
+
+	roots := x509.NewCertPool()
+	roots.AddCert(p.ca)
+	var d net.Dialer
+	return NewTls(DialFunc(func(ctx context.Context, network, address string) (net.Conn, error) {
+		return d.DialContext(ctx, network, srv.Listener.Addr().String())
+	}), p.responder.Client(), roots)
+}
+
+func TestRevocationFresh(t *testing.T) {
+	t.Parallel()
+
+	now := time.Date(2024, 6, 1, 12, 0, 0, 0, time.UTC)
+	assert.NoError(t, revocationFresh(now.Add(-time.Hour), now.Add(time.Hour), now))
+	assert.NoError(t, revocationFresh(now.Add(time.Minute), now.Add(time.Hour), now))
+	assert.NoError(t, revocationFresh(now.Add(-24*time.Hour), time.Time{}, now))
+	assert.EqualError(t, revocationFresh(now.Add(-2*time.Hour), now.Add(-time.Hour), now), "expired at 2024-06-01T11:00:00Z")
+	assert.EqualError(t, revocationFresh(now.Add(time.Hour), now.Add(2*time.Hour), now), "issued in the future at 2024-06-01T13:00:00Z")
+	assert.EqualError(t, revocationFresh(now.Add(-30*24*time.Hour), time.Time{}, now), "issued at 2024-05-02T12:00:00Z with no next update")
+}
+
+func TestTlsTransparencyAndRevocation(t *testing.T) {
+	t.Parallel()
+
+	t.Run("embedded scts and ocsp", func(t *testing.T) {
+		t.Parallel()
+		pki := newTestPKI(t)
+		pki.ocspStatus = ocsp.Good
+		logged := time.Date(2024, 6, 1, 12, 0, 0, 0, time.UTC)
+		cert, leaf := pki.issue(t, testSCT(1, logged), testSCT(2, logged))
+		cert.OCSPStaple = pki.ocspResponse(leaf.SerialNumber, ocsp.Good)
+
+		report, err := pki.serveTLS(t, cert).GetCertificates(context.Background(), "example.com")
+		require.NoError(t, err)
+
+		assert.True(t, report.ChainValid)
+		require.Len(t, report.SCTs, 2)
+		assert.Equal(t, TlsSCT{
+			Version:   1,
+			LogID:     "AAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAE=",
+			Timestamp: logged,
+			Source:    sctSourceEmbedded,
+		}, report.SCTs[0])
+		assert.True(t, report.OCSPStapled)
+		assert.Equal(t, &TlsRevocation{Method: "ocsp", Source: "stapled", Status: revocationGood}, report.OCSPStaple)
+		assert.Equal(t, &TlsRevocation{Method: "ocsp", Source: pki.responder.URL + "/ocsp", Status: revocationGood}, report.Revocation)
+	})
+
+	t.Run("revoked by ocsp", func(t *testing.T) {
+		t.Parallel()
+		pki := newTestPKI(t)
+		pki.ocspStatus = ocsp.Revoked
+		cert, _ := pki.issue(t)
+
+		report, err := pki.serveTLS(t, cert).GetCertificates(context.Background(), "example.com")
+		require.NoError(t, err)
+
+		assert.Empty(t, report.SCTs)
+		assert.False(t, report.OCSPStapled)
+		assert.Nil(t, report.OCSPStaple)
+		assert.Equal(t, revocationRevoked, report.Revocation.Status)
+		assert.Equal(t, "keyCompromise", report.Revocation.Reason)
+		assert.NotNil(t, report.Revocation.RevokedAt)
+	})
+
+	t.Run("revoked by crl when ocsp fails", func(t *testing.T) {
+		t.Parallel()
+		pki := newTestPKI(t)
+		pki.ocspStatus = -1
+		revokedAt := time.Now().Add(-time.Hour).UTC().Truncate(time.Second)
+		pki.revoked = []x509.RevocationListEntry{
+			{SerialNumber: big.NewInt(42), RevocationTime: revokedAt, ReasonCode: ocsp.Superseded},
+		}
+		cert, _ := pki.issue(t)
+
+		report, err := pki.serveTLS(t, cert).GetCertificates(context.Background(), "example.com")
+		require.NoError(t, err)
+
+		assert.Equal(t, &TlsRevocation{
+			Method:    "crl",
+			Source:    pki.responder.URL + "/ca.crl",
+			Status:    revocationRevoked,
+			RevokedAt: &revokedAt,
+			Reason:    "superseded",
+		}, report.Revocation)
+	})
+
+	t.Run("stale responses", func(t *testing.T) {
+		t.Parallel()
+		pki := newTestPKI(t)
+		pki.ocspStatus = ocsp.Good
+		pki.stale = true
+		cert, leaf := pki.issue(t)
+		cert.OCSPStaple = pki.ocspResponse(leaf.SerialNumber, ocsp.Good)
+
+		report, err := pki.serveTLS(t, cert).GetCertificates(context.Background(), "example.com")
+		require.NoError(t, err)
+
+		assert.Equal(t, revocationUnknown, report.OCSPStaple.Status)
+		assert.Contains(t, report.OCSPStaple.Error, "stale response expired at")
+		assert.Equal(t, revocationUnknown, report.Revocation.Status)
+		assert.Contains(t, report.Revocation.Error, "stale response expired at")
+		assert.Contains(t, report.Revocation.Error, "stale CRL expired at")
+	})
+
+	t.Run("no revocation source", func(t *testing.T) {
+		t.Parallel()
+		status := (&Tls{}).revocationStatus(context.Background(), &x509.Certificate{}, &x509.Certificate{})
+		assert.Equal(t, &TlsRevocation{Status: revocationUnknown, Error: errNoRevocationSource.Error()}, status)
+	})
+}
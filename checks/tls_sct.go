@@ -0,0 +1,80 @@
+package checks
+
+import (
+	"crypto/x509"
+	"encoding/asn1"
+	"encoding/base64"
+	"errors"
+	"time"
+
+	"golang.org/x/crypto/cryptobyte"
+)
+
+const (
+	sctSourceEmbedded     = "embedded"
+	sctSourceTLSExtension = "tls-extension"
+)
+
+// oidSCTList is the certificate extension holding embedded SCTs, RFC 6962
+// section 3.3.
+var oidSCTList = asn1.ObjectIdentifier{1, 3, 6, 1, 4, 1, 11129, 2, 4, 2}
+
+var errMalformedSCT = errors.New("malformed signed certificate timestamp")
+
+// TlsSCT is a signed certificate timestamp, proof the certificate was
+// submitted to the Certificate Transparency log identified by LogID.
+type TlsSCT struct {
+	Version   int       `json:"version"`
+	LogID     string    `json:"logId"`
+	Timestamp time.Time `json:"timestamp"`
+	Source    string    `json:"source"`
+}
+
+// embeddedSCTs returns the SCTs embedded in cert.
+func embeddedSCTs(cert *x509.Certificate) ([]TlsSCT, error) {
+	scts := make([]TlsSCT, 0)
+	for _, ext := range cert.Extensions {
+		if !ext.Id.Equal(oidSCTList) {
+			continue
+		}
+		var raw []byte
+		if _, err := asn1.Unmarshal(ext.Value, &raw); err != nil {
+			return nil, errMalformedSCT
+		}
+		s := cryptobyte.String(raw)
+		var list cryptobyte.String
+		if !s.ReadUint16LengthPrefixed(&list) || !s.Empty() {
+			return nil, errMalformedSCT
+		}
+		for !list.Empty() {
+			var sct cryptobyte.String
+			if !list.ReadUint16LengthPrefixed(&sct) {
+				return nil, errMalformedSCT
+			}
+			parsed, err := parseSCT(sct, sctSourceEmbedded)
+			if err != nil {
+				return nil, err
+			}
+			scts = append(scts, parsed)
+		}
+	}
+	return scts, nil
+}
+
+// parseSCT decodes a single serialized SCT, RFC 6962 section 3.2. Only the
+// fields that identify the log and when it was logged are kept.
+func parseSCT(s cryptobyte.String, source string) (TlsSCT, error) {
+	var version uint8
+	var logID []byte
+	var timestamp uint64
+	if !s.ReadUint8(&version) || !s.ReadBytes(&logID, 32) || !s.ReadUint64(&timestamp) {
+		return TlsSCT{}, errMalformedSCT
+	}
+	return TlsSCT{
+		// versions are zero indexed on the wire, v1 is 0
+		Version:   int(version) + 1,
+		LogID:     base64.StdEncoding.EncodeToString(logID),
+		Timestamp: time.UnixMilli(int64(timestamp)).UTC(),
+		Source:    source,
+	}, nil
+}
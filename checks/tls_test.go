@@ -2,26 +2,103 @@ package checks
 
 import (
 	"context"
+	"crypto/x509"
+	"errors"
+	"net"
 	"net/http"
+	"net/http/httptest"
+	"net/url"
 	"testing"
 
 	"github.com/stretchr/testify/assert"
-	"github.com/xray-web/web-check-api/testutils"
+	"github.com/stretchr/testify/require"
 )
 
+// tlsServer starts a TLS server and returns a Tls check that dials it
+// whatever the address. If trusted the server certificate is the only root.
+func tlsServer(t *testing.T, trusted bool) *Tls {
+	t.Helper()
+	srv := httptest.NewUnstartedServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+	srv.EnableHTTP2 = true
+	srv.StartTLS()
+	t.Cleanup(srv.Close)
+
+	roots := x509.NewCertPool()
+	if trusted {
+		roots.AddCert(srv.Certificate())
+	}
+	var d net.Dialer
+	return NewTls(DialFunc(func(ctx context.Context, network, address string) (net.Conn, error) {
+		return d.DialContext(ctx, network, srv.Listener.Addr().String())
+	}), srv.Client(), roots)
+}
+
 func TestTLS(t *testing.T) {
 	t.Parallel()
 
-	t.Run("Valid URL with successful scan", func(t *testing.T) {
+	t.Run("trusted certificate", func(t *testing.T) {
+		t.Parallel()
+
+		report, err := tlsServer(t, true).GetCertificates(context.Background(), "example.com")
+		require.NoError(t, err)
+
+		assert.Equal(t, "example.com", report.Host)
+		assert.Equal(t, "TLS 1.3", report.Version)
+		assert.NotEmpty(t, report.CipherSuite)
+		assert.Equal(t, "h2", report.ALPN)
+		assert.True(t, report.ChainValid)
+		assert.Empty(t, report.ChainError)
+		assert.True(t, report.HostnameMatch)
+		assert.False(t, report.Expired)
+		assert.Greater(t, report.DaysUntilExpiry, 0)
+
+		require.Len(t, report.Chain, 1)
+		cert := report.Chain[0]
+		assert.Contains(t, cert.SubjectAltNames, "example.com")
+		assert.Contains(t, cert.SubjectAltNames, "127.0.0.1")
+		assert.Equal(t, "RSA", cert.KeyType)
+		assert.Equal(t, 2048, cert.KeySize)
+		assert.Equal(t, "SHA256-RSA", cert.SignatureAlgorithm)
+		assert.Len(t, cert.Fingerprint, 64)
+	})
+
+	t.Run("untrusted certificate and hostname mismatch", func(t *testing.T) {
 		t.Parallel()
 
-		client := testutils.MockClient(
-			testutils.Response(http.StatusOK, []byte(`{"scan_id": 12345}`)),
-			testutils.Response(http.StatusOK, []byte(`{"grade": "A+"}`)),
-		)
+		report, err := tlsServer(t, false).GetCertificates(context.Background(), "example.org")
+		require.NoError(t, err)
+
+		assert.False(t, report.ChainValid)
+		assert.NotEmpty(t, report.ChainError)
+		assert.False(t, report.HostnameMatch)
+		assert.NotEmpty(t, report.HostnameError)
+	})
+
+	t.Run("connection error", func(t *testing.T) {
+		t.Parallel()
+
+		tls := NewTls(DialFunc(func(ctx context.Context, network, address string) (net.Conn, error) {
+			assert.Equal(t, "example.com:443", address)
+			return nil, errors.New("connection refused")
+		}), nil, nil)
+
+		_, err := tls.GetCertificates(context.Background(), "example.com")
+		assert.ErrorContains(t, err, "connection refused")
+	})
+
+	t.Run("url port", func(t *testing.T) {
+		t.Parallel()
+		srv := httptest.NewTLSServer(http.NotFoundHandler())
+		t.Cleanup(srv.Close)
+		target, err := url.Parse(srv.URL)
+		require.NoError(t, err)
 
-		tls, err := NewTls(client).GetScanResults(context.TODO(), "example.com")
-		assert.NoError(t, err)
-		assert.Equal(t, "A+", tls["grade"])
+		roots := x509.NewCertPool()
+		roots.AddCert(srv.Certificate())
+		report, err := NewTls(&net.Dialer{}, srv.Client(), roots).Run(context.Background(), Target{URL: target})
+		require.NoError(t, err)
+		assert.Equal(t, "127.0.0.1", report.(*TlsReport).Host)
+		assert.True(t, report.(*TlsReport).ChainValid)
+		assert.True(t, report.(*TlsReport).HostnameMatch)
 	})
 }
@@ -0,0 +1,283 @@
+package checks
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"net/netip"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/xray-web/web-check-api/checks/clients/traceroute"
+	"github.com/xray-web/web-check-api/checks/store/ipintel"
+)
+
+// ErrInvalidTraceRoute is returned for trace route parameters that can't be
+// used.
+var ErrInvalidTraceRoute = fmt.Errorf("%w: trace route", ErrInvalidParams)
+
+const (
+	defaultMaxHops = 30
+	maxTraceHops   = 64
+	// probesPerHop are sent to each TTL at once.
+	probesPerHop = 3
+	// defaultProbeTimeout is how long to wait for the answer to a probe.
+	defaultProbeTimeout = time.Second
+	maxProbeTimeout     = 5 * time.Second
+	// traceRoutePort is the port UDP probes go to by default, the first
+	// port classic traceroute uses as nothing is expected to listen there.
+	traceRoutePort = 33434
+	// traceRouteTimeout is the deadline of a whole trace, the hops can't
+	// take longer than this to time out.
+	traceRouteTimeout = 60 * time.Second
+)
+
+// TraceRouteOptions choose how probes are sent.
+type TraceRouteOptions struct {
+	// Protocol is traceroute.UDP, TCP or ICMP.
+	Protocol string
+	// Port is where UDP and TCP probes are sent.
+	Port int
+	// Family is 4 or 6 to only trace that address family, or 0 to trace
+	// the first address found preferring IPv4.
+	Family  int
+	MaxHops int
+	// Timeout is how long to wait for each probe.
+	Timeout time.Duration
+}
+
+// Hop is a router on the path, or the destination on the last hop.
+type Hop struct {
+	TTL int `json:"ttl"`
+	// Address is the first to answer, it is missing if nothing did.
+	Address    net.IP   `json:"ip,omitempty"`
+	ReverseDNS []string `json:"reverseDns,omitempty"`
+	ipintel.Info
+	// RTT has a sample for each probe answered, Loss is the fraction that
+	// weren't.
+	RTT     []float64 `json:"rttMs"`
+	Loss    float64   `json:"loss"`
+	Reached bool      `json:"reached,omitempty"`
+}
+
+type TraceRouteResult struct {
+	Host     string `json:"host"`
+	Address  net.IP `json:"ip"`
+	Family   int    `json:"family"`
+	Protocol string `json:"protocol"`
+	Port     int    `json:"port,omitempty"`
+	Hops     []Hop  `json:"hops"`
+	Reached  bool   `json:"reached"`
+}
+
+type TraceRoute struct {
+	netIp     *NetIp
+	newProber func(protocol string, port int) (traceroute.Prober, error)
+}
+
+// NewTraceRoute returns a check that traces the route to the addresses
+// netIp resolves, describing each hop like netIp does, with probers from
+// newProber.
+func NewTraceRoute(netIp *NetIp, newProber func(protocol string, port int) (traceroute.Prober, error)) *TraceRoute {
+	return &TraceRoute{netIp: netIp, newProber: newProber}
+}
+
+// ParseTraceRouteOptions reads the protocol, port, family, max-hops and
+// timeout parameters, defaulting the port to that of the target URL.
+func ParseTraceRouteOptions(target Target) (TraceRouteOptions, error) {
+	opts := TraceRouteOptions{
+		Protocol: traceroute.UDP,
+		MaxHops:  defaultMaxHops,
+		Timeout:  defaultProbeTimeout,
+	}
+	params := target.Params
+	if protocol := params.Get("protocol"); protocol != "" {
+		opts.Protocol = protocol
+	}
+	var err error
+	switch opts.Protocol {
+	case traceroute.ICMP:
+	case traceroute.UDP, traceroute.TCP:
+		port := params.Get("port")
+		if port == "" {
+			port = target.URL.Port()
+		}
+		switch {
+		case port != "":
+			if opts.Port, err = strconv.Atoi(port); err != nil || opts.Port < 1 || opts.Port > 65535 {
+				return opts, fmt.Errorf("%w: invalid port %q", ErrInvalidTraceRoute, port)
+			}
+		case opts.Protocol == traceroute.UDP:
+			opts.Port = traceRoutePort
+		case target.URL.Scheme == "https":
+			opts.Port = 443
+		default:
+			opts.Port = 80
+		}
+	default:
+		return opts, fmt.Errorf("%w: unknown protocol %q", ErrInvalidTraceRoute, opts.Protocol)
+	}
+
+	switch family := params.Get("family"); family {
+	case "":
+	case "4", "6":
+		opts.Family, _ = strconv.Atoi(family)
+	default:
+		return opts, fmt.Errorf("%w: family %q isn't 4 or 6", ErrInvalidTraceRoute, family)
+	}
+	if maxHops := params.Get("max-hops"); maxHops != "" {
+		if opts.MaxHops, err = strconv.Atoi(maxHops); err != nil || opts.MaxHops < 1 || opts.MaxHops > maxTraceHops {
+			return opts, fmt.Errorf("%w: max-hops %q isn't between 1 and %d", ErrInvalidTraceRoute, maxHops, maxTraceHops)
+		}
+	}
+	if timeout := params.Get("timeout"); timeout != "" {
+		if opts.Timeout, err = time.ParseDuration(timeout); err != nil || opts.Timeout <= 0 || opts.Timeout > maxProbeTimeout {
+			return opts, fmt.Errorf("%w: timeout %q isn't a duration up to %s", ErrInvalidTraceRoute, timeout, maxProbeTimeout)
+		}
+	}
+	if time.Duration(opts.MaxHops)*opts.Timeout > traceRouteTimeout {
+		return opts, fmt.Errorf("%w: %d hops with a %s timeout could take longer than %s", ErrInvalidTraceRoute, opts.MaxHops, opts.Timeout, traceRouteTimeout)
+	}
+	return opts, nil
+}
+
+// destination resolves the address of host to trace.
+func (t *TraceRoute) destination(ctx context.Context, host string, family int) (netip.Addr, error) {
+	networks := []string{"ip4", "ip6"}
+	switch family {
+	case 4:
+		networks = networks[:1]
+	case 6:
+		networks = networks[1:]
+	}
+	var errs []error
+	for _, network := range networks {
+		ips, err := t.netIp.lookup.LookupIP(ctx, network, host)
+		if len(ips) > 0 {
+			addr, _ := netip.AddrFromSlice(ips[0])
+			return addr.Unmap(), nil
+		}
+		errs = append(errs, err)
+	}
+	if err := errors.Join(errs...); err != nil {
+		return netip.Addr{}, err
+	}
+	return netip.Addr{}, fmt.Errorf("no addresses found for %s", host)
+}
+
+// hop sends probesPerHop probes to dst at once, an error is only returned
+// if a probe couldn't be sent.
+func (t *TraceRoute) hop(ctx context.Context, prober traceroute.Prober, dst netip.Addr, ttl int, timeout time.Duration) (Hop, error) {
+	replies := make([]traceroute.Reply, probesPerHop)
+	errs := make([]error, probesPerHop)
+	var wg sync.WaitGroup
+	for i := range probesPerHop {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			ctx, cancel := context.WithTimeout(ctx, timeout)
+			defer cancel()
+			replies[i], errs[i] = prober.Probe(ctx, dst, ttl)
+		}()
+	}
+	wg.Wait()
+
+	hop := Hop{TTL: ttl, RTT: make([]float64, 0, probesPerHop)}
+	for i, reply := range replies {
+		if err := errs[i]; err != nil {
+			if !errors.Is(err, context.DeadlineExceeded) && !errors.Is(err, context.Canceled) {
+				return hop, err
+			}
+			continue
+		}
+		if hop.Address == nil {
+			hop.Address = reply.From.AsSlice()
+		}
+		hop.RTT = append(hop.RTT, float64(reply.RTT.Microseconds())/1000)
+		hop.Reached = hop.Reached || reply.Reached
+	}
+	hop.Loss = float64(probesPerHop-len(hop.RTT)) / probesPerHop
+
+	if hop.Address != nil {
+		if t.netIp.intel != nil {
+			hop.Info = t.netIp.intel.GetIPInfo(hop.Address)
+		}
+		if t.netIp.resolver != nil {
+			hop.ReverseDNS = t.netIp.reverseDNS(ctx, hop.Address)
+		}
+	}
+	return hop, nil
+}
+
+// Trace probes each TTL in turn until the destination answers, calling emit
+// with each hop as it is found. The hops found so far are returned if ctx
+// is done first.
+func (t *TraceRoute) Trace(ctx context.Context, host string, opts TraceRouteOptions, emit func(Hop)) (*TraceRouteResult, error) {
+	prober, err := t.newProber(opts.Protocol, opts.Port)
+	if err != nil {
+		return nil, err
+	}
+	dst, err := t.destination(ctx, host, opts.Family)
+	if err != nil {
+		return nil, err
+	}
+
+	result := &TraceRouteResult{
+		Host:     host,
+		Address:  dst.AsSlice(),
+		Family:   4,
+		Protocol: opts.Protocol,
+		Port:     opts.Port,
+		Hops:     make([]Hop, 0),
+	}
+	if dst.Is6() {
+		result.Family = 6
+	}
+	for ttl := 1; ttl <= opts.MaxHops && ctx.Err() == nil; ttl++ {
+		hop, err := t.hop(ctx, prober, dst, ttl, opts.Timeout)
+		if err != nil {
+			return nil, fmt.Errorf("error sending probe: %w", err)
+		}
+		if ctx.Err() != nil {
+			break
+		}
+		result.Hops = append(result.Hops, hop)
+		emit(hop)
+		if hop.Reached {
+			result.Reached = true
+			break
+		}
+	}
+	return result, nil
+}
+
+func (t *TraceRoute) Name() string {
+	return "trace-route"
+}
+
+func (t *TraceRoute) Description() string {
+	return "Traces the network route to the host with UDP, TCP or ICMP probes (protocol, port, family, max-hops and timeout parameters)"
+}
+
+func (t *TraceRoute) Input() Input {
+	return InputHostname
+}
+
+func (t *TraceRoute) Timeout() time.Duration {
+	return traceRouteTimeout
+}
+
+func (t *TraceRoute) Run(ctx context.Context, target Target) (any, error) {
+	return t.RunStream(ctx, target, func(any) {})
+}
+
+// RunStream emits each Hop as it is found.
+func (t *TraceRoute) RunStream(ctx context.Context, target Target, emit func(v any)) (any, error) {
+	opts, err := ParseTraceRouteOptions(target)
+	if err != nil {
+		return nil, err
+	}
+	return t.Trace(ctx, target.Hostname(), opts, func(hop Hop) { emit(hop) })
+}
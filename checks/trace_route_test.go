@@ -0,0 +1,188 @@
+package checks
+
+import (
+	"context"
+	"net"
+	"net/netip"
+	"net/url"
+	"sync/atomic"
+	"syscall"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/xray-web/web-check-api/checks/clients/ip"
+	"github.com/xray-web/web-check-api/checks/clients/traceroute"
+	"github.com/xray-web/web-check-api/checks/store/ipintel"
+)
+
+func TestParseTraceRouteOptions(t *testing.T) {
+	t.Parallel()
+
+	parse := func(rawURL string, params url.Values) (TraceRouteOptions, error) {
+		u, err := url.Parse(rawURL)
+		require.NoError(t, err)
+		return ParseTraceRouteOptions(Target{URL: u, Params: params})
+	}
+
+	opts, err := parse("http://example.com", nil)
+	require.NoError(t, err)
+	assert.Equal(t, TraceRouteOptions{Protocol: traceroute.UDP, Port: 33434, MaxHops: 30, Timeout: time.Second}, opts)
+
+	opts, err = parse("https://example.com", url.Values{"protocol": {"tcp"}, "family": {"6"}, "max-hops": {"10"}, "timeout": {"500ms"}})
+	require.NoError(t, err)
+	assert.Equal(t, TraceRouteOptions{Protocol: traceroute.TCP, Port: 443, Family: 6, MaxHops: 10, Timeout: 500 * time.Millisecond}, opts)
+
+	opts, err = parse("http://example.com", url.Values{"max-hops": {"12"}, "timeout": {"5s"}})
+	require.NoError(t, err)
+	assert.Equal(t, 12, opts.MaxHops)
+
+	opts, err = parse("http://example.com:8080", url.Values{"protocol": {"tcp"}})
+	require.NoError(t, err)
+	assert.Equal(t, 8080, opts.Port)
+
+	opts, err = parse("http://example.com:8080", url.Values{"protocol": {"udp"}, "port": {"53"}})
+	require.NoError(t, err)
+	assert.Equal(t, 53, opts.Port)
+
+	opts, err = parse("http://example.com:8080", url.Values{"protocol": {"icmp"}})
+	require.NoError(t, err)
+	assert.Zero(t, opts.Port)
+
+	for _, params := range []url.Values{
+		{"protocol": {"sctp"}},
+		{"port": {"0"}},
+		{"port": {"http"}},
+		{"family": {"5"}},
+		{"max-hops": {"0"}},
+		{"max-hops": {"65"}},
+		{"timeout": {"1"}},
+		{"timeout": {"10s"}},
+		{"max-hops": {"64"}, "timeout": {"5s"}},
+		{"timeout": {"3s"}},
+	} {
+		_, err := parse("http://example.com", params)
+		assert.ErrorIs(t, err, ErrInvalidParams, params.Encode())
+	}
+}
+
+// route returns a NewTraceRoute prober factory for a path through routers
+// to the destination, an invalid address is a router that never answers.
+func route(routers ...string) func(protocol string, port int) (traceroute.Prober, error) {
+	return func(protocol string, port int) (traceroute.Prober, error) {
+		return traceroute.ProberFunc(func(ctx context.Context, dst netip.Addr, ttl int) (traceroute.Reply, error) {
+			if ttl > len(routers) {
+				return traceroute.Reply{From: dst, RTT: time.Duration(ttl) * time.Millisecond, Reached: true}, nil
+			}
+			addr, err := netip.ParseAddr(routers[ttl-1])
+			if err != nil {
+				<-ctx.Done()
+				return traceroute.Reply{}, ctx.Err()
+			}
+			return traceroute.Reply{From: addr, RTT: time.Duration(ttl) * time.Millisecond}, nil
+		}), nil
+	}
+}
+
+func TestTraceRoute(t *testing.T) {
+	t.Parallel()
+
+	netIp := NewNetIp(ip.LookupFunc(func(ctx context.Context, network, host string) ([]net.IP, error) {
+		if network == "ip6" {
+			return []net.IP{net.ParseIP("2001:db8::1")}, nil
+		}
+		return []net.IP{net.ParseIP("192.0.2.1")}, nil
+	}), zone(t,
+		"1.0.0.10.in-addr.arpa. 3600 IN PTR gateway.local.",
+	), ipintel.GetterFunc(func(addr net.IP) ipintel.Info {
+		if addr.Equal(net.ParseIP("198.51.100.1")) {
+			return ipintel.Info{ASN: 64500, Organisation: "EXAMPLE-TRANSIT"}
+		}
+		return ipintel.Info{}
+	}))
+	opts := TraceRouteOptions{Protocol: traceroute.UDP, Port: 33434, MaxHops: 30, Timeout: 50 * time.Millisecond}
+
+	var emitted []int
+	tr := NewTraceRoute(netIp, route("10.0.0.1", "*", "198.51.100.1"))
+	result, err := tr.Trace(context.Background(), "example.com", opts, func(hop Hop) {
+		emitted = append(emitted, hop.TTL)
+	})
+	require.NoError(t, err)
+	assert.Equal(t, &TraceRouteResult{
+		Host:     "example.com",
+		Address:  netip.MustParseAddr("192.0.2.1").AsSlice(),
+		Family:   4,
+		Protocol: traceroute.UDP,
+		Port:     33434,
+		Reached:  true,
+		Hops: []Hop{
+			{TTL: 1, Address: net.IP{10, 0, 0, 1}, ReverseDNS: []string{"gateway.local"}, RTT: []float64{1, 1, 1}},
+			{TTL: 2, RTT: []float64{}, Loss: 1},
+			{TTL: 3, Address: net.IP{198, 51, 100, 1}, Info: ipintel.Info{ASN: 64500, Organisation: "EXAMPLE-TRANSIT"}, RTT: []float64{3, 3, 3}},
+			{TTL: 4, Address: net.IP{192, 0, 2, 1}, RTT: []float64{4, 4, 4}, Reached: true},
+		},
+	}, result)
+	assert.Equal(t, []int{1, 2, 3, 4}, emitted)
+
+	t.Run("ipv6", func(t *testing.T) {
+		t.Parallel()
+		opts := opts
+		opts.Family = 6
+		result, err := NewTraceRoute(netIp, route()).Trace(context.Background(), "example.com", opts, func(Hop) {})
+		require.NoError(t, err)
+		assert.Equal(t, 6, result.Family)
+		assert.Equal(t, "2001:db8::1", result.Hops[0].Address.String())
+	})
+
+	t.Run("max hops", func(t *testing.T) {
+		t.Parallel()
+		opts := opts
+		opts.MaxHops = 2
+		result, err := NewTraceRoute(netIp, route("10.0.0.1", "10.0.0.2", "10.0.0.3")).Trace(context.Background(), "example.com", opts, func(Hop) {})
+		require.NoError(t, err)
+		assert.False(t, result.Reached)
+		assert.Len(t, result.Hops, 2)
+	})
+
+	t.Run("partial loss", func(t *testing.T) {
+		t.Parallel()
+		var sent atomic.Int32
+		tr := NewTraceRoute(netIp, func(protocol string, port int) (traceroute.Prober, error) {
+			return traceroute.ProberFunc(func(ctx context.Context, dst netip.Addr, ttl int) (traceroute.Reply, error) {
+				if sent.Add(1) == 2 {
+					<-ctx.Done()
+					return traceroute.Reply{}, ctx.Err()
+				}
+				return traceroute.Reply{From: dst, RTT: time.Millisecond, Reached: true}, nil
+			}), nil
+		})
+		result, err := tr.Trace(context.Background(), "example.com", opts, func(Hop) {})
+		require.NoError(t, err)
+		require.Len(t, result.Hops, 1)
+		assert.Equal(t, []float64{1, 1}, result.Hops[0].RTT)
+		assert.InDelta(t, 1.0/3, result.Hops[0].Loss, 0.001)
+	})
+
+	t.Run("cancelled", func(t *testing.T) {
+		t.Parallel()
+		ctx, cancel := context.WithTimeout(context.Background(), 120*time.Millisecond)
+		defer cancel()
+		result, err := NewTraceRoute(netIp, route("10.0.0.1", "*", "*", "*", "*")).Trace(ctx, "example.com", opts, func(Hop) {})
+		require.NoError(t, err)
+		assert.False(t, result.Reached)
+		assert.NotEmpty(t, result.Hops)
+		assert.Less(t, len(result.Hops), 4)
+	})
+
+	t.Run("not permitted", func(t *testing.T) {
+		t.Parallel()
+		tr := NewTraceRoute(netIp, func(protocol string, port int) (traceroute.Prober, error) {
+			return traceroute.ProberFunc(func(ctx context.Context, dst netip.Addr, ttl int) (traceroute.Reply, error) {
+				return traceroute.Reply{}, &net.OpError{Op: "listen", Net: "ip4:icmp", Err: syscall.EPERM}
+			}), nil
+		})
+		_, err := tr.Trace(context.Background(), "example.com", opts, func(Hop) {})
+		assert.ErrorIs(t, err, syscall.EPERM)
+	})
+}
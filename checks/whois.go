@@ -0,0 +1,99 @@
+package checks
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math"
+	"strings"
+	"time"
+
+	"github.com/xray-web/web-check-api/checks/clients/whois"
+	"golang.org/x/net/publicsuffix"
+)
+
+// RegistrationLookup finds the registration data for a domain.
+type RegistrationLookup interface {
+	Lookup(ctx context.Context, domain string) (*whois.Record, error)
+}
+
+type RegistrationLookupFunc func(ctx context.Context, domain string) (*whois.Record, error)
+
+func (fn RegistrationLookupFunc) Lookup(ctx context.Context, domain string) (*whois.Record, error) {
+	return fn(ctx, domain)
+}
+
+type WhoisReport struct {
+	*whois.Record
+	// DaysToExpiry is negative once the domain has expired, and missing if
+	// the expiry date isn't known.
+	DaysToExpiry *int `json:"daysToExpiry"`
+	// RDAPError is why the port 43 WHOIS fallback was used.
+	RDAPError string `json:"rdapError,omitempty"`
+}
+
+type Whois struct {
+	rdap  RegistrationLookup
+	whois RegistrationLookup
+	now   func() time.Time
+}
+
+// NewWhois returns a check that looks domains up with rdap, falling back
+// to the WHOIS protocol for TLDs without RDAP or when it fails.
+func NewWhois(rdap, whois RegistrationLookup) *Whois {
+	return &Whois{rdap: rdap, whois: whois, now: time.Now}
+}
+
+func (w *Whois) Lookup(ctx context.Context, host string) (*WhoisReport, error) {
+	domain, err := publicsuffix.EffectiveTLDPlusOne(strings.ToLower(strings.TrimSuffix(host, ".")))
+	if err != nil {
+		return nil, fmt.Errorf("no registrable domain for %s: %w", host, err)
+	}
+
+	report := &WhoisReport{}
+	report.Record, err = w.rdap.Lookup(ctx, domain)
+	// a registry that says the domain doesn't exist over RDAP won't say
+	// otherwise over WHOIS
+	if errors.Is(err, whois.ErrNotFound) {
+		return nil, err
+	}
+	if err != nil {
+		report.RDAPError = err.Error()
+		report.Record, err = w.whois.Lookup(ctx, domain)
+		if err != nil {
+			return nil, fmt.Errorf("RDAP: %s, WHOIS: %w", report.RDAPError, err)
+		}
+	}
+
+	if report.Status == nil {
+		report.Status = make([]string, 0)
+	}
+	if report.Nameservers == nil {
+		report.Nameservers = make([]string, 0)
+	}
+	if report.Expires != nil {
+		days := int(math.Floor(report.Expires.Sub(w.now()).Hours() / 24))
+		report.DaysToExpiry = &days
+	}
+	return report, nil
+}
+
+func (w *Whois) Name() string {
+	return "whois"
+}
+
+func (w *Whois) Description() string {
+	return "Looks up the domain registration with RDAP or WHOIS"
+}
+
+func (w *Whois) Input() Input {
+	return InputHostname
+}
+
+func (w *Whois) Timeout() time.Duration {
+	return 20 * time.Second
+}
+
+func (w *Whois) Run(ctx context.Context, target Target) (any, error) {
+	return w.Lookup(ctx, target.Hostname())
+}
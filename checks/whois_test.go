@@ -0,0 +1,77 @@
+package checks
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/xray-web/web-check-api/checks/clients/whois"
+)
+
+func TestWhoisLookup(t *testing.T) {
+	t.Parallel()
+
+	expires := time.Date(2025, 8, 13, 4, 0, 0, 0, time.UTC)
+	record := func(source string) RegistrationLookupFunc {
+		return func(ctx context.Context, domain string) (*whois.Record, error) {
+			return &whois.Record{Domain: domain, Registrar: "Example Registrar, Inc.", Expires: &expires, Source: source}, nil
+		}
+	}
+	failing := func(err error) RegistrationLookupFunc {
+		return func(ctx context.Context, domain string) (*whois.Record, error) {
+			return nil, err
+		}
+	}
+	newWhois := func(rdap, port43 RegistrationLookup) *Whois {
+		w := NewWhois(rdap, port43)
+		w.now = func() time.Time { return time.Date(2025, 7, 14, 12, 0, 0, 0, time.UTC) }
+		return w
+	}
+
+	report, err := newWhois(record(whois.SourceRDAP), failing(errors.New("unused"))).Lookup(context.Background(), "www.example.co.uk")
+	require.NoError(t, err)
+	assert.Equal(t, "example.co.uk", report.Domain)
+	assert.Equal(t, whois.SourceRDAP, report.Source)
+	assert.Equal(t, 29, *report.DaysToExpiry)
+	assert.Empty(t, report.RDAPError)
+	assert.Equal(t, []string{}, report.Nameservers)
+
+	t.Run("fallback", func(t *testing.T) {
+		t.Parallel()
+		report, err := newWhois(failing(whois.ErrNoServer), record(whois.SourceWhois)).Lookup(context.Background(), "example.com")
+		require.NoError(t, err)
+		assert.Equal(t, whois.SourceWhois, report.Source)
+		assert.Equal(t, "no registration data server for TLD", report.RDAPError)
+	})
+
+	t.Run("expired", func(t *testing.T) {
+		t.Parallel()
+		w := newWhois(record(whois.SourceRDAP), nil)
+		w.now = func() time.Time { return expires.Add(36 * time.Hour) }
+		report, err := w.Lookup(context.Background(), "example.com")
+		require.NoError(t, err)
+		assert.Equal(t, -2, *report.DaysToExpiry)
+	})
+
+	t.Run("not registered", func(t *testing.T) {
+		t.Parallel()
+		_, err := newWhois(failing(whois.ErrNotFound), failing(errors.New("unused"))).Lookup(context.Background(), "example.com")
+		assert.ErrorIs(t, err, whois.ErrNotFound)
+	})
+
+	t.Run("both fail", func(t *testing.T) {
+		t.Parallel()
+		_, err := newWhois(failing(errors.New("RDAP request failed with status 500")), failing(whois.ErrNotFound)).Lookup(context.Background(), "example.com")
+		assert.EqualError(t, err, "RDAP: RDAP request failed with status 500, WHOIS: domain not registered")
+		assert.ErrorIs(t, err, whois.ErrNotFound)
+	})
+
+	t.Run("no registrable domain", func(t *testing.T) {
+		t.Parallel()
+		_, err := newWhois(nil, nil).Lookup(context.Background(), "co.uk")
+		assert.Error(t, err)
+	})
+}
@@ -4,24 +4,104 @@ import (
 	"cmp"
 	"fmt"
 	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
 )
 
 type Config struct {
 	Host          string
 	Port          string
 	AllowedOrigin string
+	// CheckTimeout is the default deadline applied to each check when
+	// several checks are run together as part of a scan.
+	CheckTimeout time.Duration
+	// JobWorkers is the number of asynchronous scan jobs run at once, with up
+	// to JobQueueSize more waiting. Finished jobs are kept for JobTTL.
+	JobWorkers   int
+	JobQueueSize int
+	JobTTL       time.Duration
+	// CatalogueFile is a YAML or JSON file listing the resolvers, block IPs
+	// and ports used by the checks. The built in lists are used if empty.
+	CatalogueFile string
+
+	// DNSResolver is the host:port of the resolver the DNS checks query.
+	DNSResolver string
+	// DKIMSelectors are tried by the mail-security check as well as the
+	// common selectors.
+	DKIMSelectors []string
+	// GeoIPCityDB and GeoIPASNDB are MaxMind databases, and IPRanges are
+	// JSON files of published cloud ranges, used to describe addresses.
+	// Fields are left out of results when a file isn't given.
+	GeoIPCityDB string
+	GeoIPASNDB  string
+	IPRanges    []string
+	// PassiveDNSFiles are searched by the reverse-ip check for names seen
+	// on an address.
+	PassiveDNSFiles []string
+	// RDAPBootstrapCache is where the IANA RDAP bootstrap file is cached.
+	RDAPBootstrapCache string
+	// TLSCiphersConcurrency is the number of handshakes the tls-ciphers
+	// check makes at once, each allowed TLSCiphersTimeout.
+	TLSCiphersConcurrency int
+	TLSCiphersTimeout     time.Duration
+	// GoogleCloudAPIKey is used by the quality check to call PageSpeed.
+	GoogleCloudAPIKey string
 }
 
 func New() Config {
 	host := getEnvDefault("HOST", "0.0.0.0")
 	port := getEnvDefault("PORT", "8080")
 	return Config{
-		Host:          host,
-		Port:          port,
-		AllowedOrigin: getEnvDefault("ALLOWED_ORIGINS", fmt.Sprintf("http://%s:%s", host, port)),
+		Host:                  host,
+		Port:                  port,
+		AllowedOrigin:         getEnvDefault("ALLOWED_ORIGINS", fmt.Sprintf("http://%s:%s", host, port)),
+		CheckTimeout:          getEnvDurationDefault("CHECK_TIMEOUT", 15*time.Second),
+		JobWorkers:            getEnvIntDefault("JOB_WORKERS", 4),
+		JobQueueSize:          getEnvIntDefault("JOB_QUEUE_SIZE", 100),
+		JobTTL:                getEnvDurationDefault("JOB_TTL", time.Hour),
+		CatalogueFile:         os.Getenv("CATALOGUE_FILE"),
+		DNSResolver:           getEnvDefault("DNS_RESOLVER", "8.8.8.8:53"),
+		DKIMSelectors:         getEnvList("DKIM_SELECTORS"),
+		GeoIPCityDB:           os.Getenv("GEOIP_CITY_DB"),
+		GeoIPASNDB:            os.Getenv("GEOIP_ASN_DB"),
+		IPRanges:              getEnvList("IP_RANGES"),
+		PassiveDNSFiles:       getEnvList("PASSIVE_DNS_FILES"),
+		RDAPBootstrapCache:    getEnvDefault("RDAP_BOOTSTRAP_CACHE", filepath.Join(os.TempDir(), "rdap-dns.json")),
+		TLSCiphersConcurrency: getEnvIntDefault("TLS_CIPHERS_CONCURRENCY", 8),
+		TLSCiphersTimeout:     getEnvDurationDefault("TLS_CIPHERS_TIMEOUT", 5*time.Second),
+		GoogleCloudAPIKey:     os.Getenv("GOOGLE_CLOUD_API_KEY"),
 	}
 }
 
 func getEnvDefault(key, def string) string {
 	return cmp.Or(os.Getenv(key), def)
 }
+
+// getEnvList splits a comma separated variable, skipping empty entries.
+func getEnvList(key string) []string {
+	var list []string
+	for _, v := range strings.Split(os.Getenv(key), ",") {
+		if v = strings.TrimSpace(v); v != "" {
+			list = append(list, v)
+		}
+	}
+	return list
+}
+
+func getEnvIntDefault(key string, def int) int {
+	i, err := strconv.Atoi(os.Getenv(key))
+	if err != nil || i <= 0 {
+		return def
+	}
+	return i
+}
+
+func getEnvDurationDefault(key string, def time.Duration) time.Duration {
+	d, err := time.ParseDuration(os.Getenv(key))
+	if err != nil || d <= 0 {
+		return def
+	}
+	return d
+}
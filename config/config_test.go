@@ -0,0 +1,38 @@
+package config
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestNew(t *testing.T) {
+	t.Setenv("CHECK_TIMEOUT", "")
+	t.Setenv("DNS_RESOLVER", "")
+	t.Setenv("DKIM_SELECTORS", "")
+	t.Setenv("RDAP_BOOTSTRAP_CACHE", "")
+	t.Setenv("TLS_CIPHERS_CONCURRENCY", "")
+	t.Setenv("TLS_CIPHERS_TIMEOUT", "")
+
+	conf := New()
+	assert.Equal(t, 15*time.Second, conf.CheckTimeout)
+	assert.Equal(t, "8.8.8.8:53", conf.DNSResolver)
+	assert.Empty(t, conf.DKIMSelectors)
+	assert.Equal(t, filepath.Join(os.TempDir(), "rdap-dns.json"), conf.RDAPBootstrapCache)
+	assert.Equal(t, 8, conf.TLSCiphersConcurrency)
+	assert.Equal(t, 5*time.Second, conf.TLSCiphersTimeout)
+
+	t.Setenv("CHECK_TIMEOUT", "30s")
+	t.Setenv("DNS_RESOLVER", "1.1.1.1:53")
+	t.Setenv("DKIM_SELECTORS", "google, ,mail2024,")
+	t.Setenv("PASSIVE_DNS_FILES", "a.json,b.csv")
+
+	conf = New()
+	assert.Equal(t, 30*time.Second, conf.CheckTimeout)
+	assert.Equal(t, "1.1.1.1:53", conf.DNSResolver)
+	assert.Equal(t, []string{"google", "mail2024"}, conf.DKIMSelectors)
+	assert.Equal(t, []string{"a.json", "b.csv"}, conf.PassiveDNSFiles)
+}
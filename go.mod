@@ -5,6 +5,9 @@ go 1.22.4
 require (
 	github.com/chromedp/cdproto v0.0.0-20240602235142-49d0e97b7881
 	github.com/chromedp/chromedp v0.9.5
+	github.com/miekg/dns v1.1.59
+	github.com/oschwald/maxminddb-golang v1.13.0
+	golang.org/x/crypto v0.24.0
 )
 
 require (
@@ -18,14 +21,15 @@ require (
 	github.com/kr/pretty v0.3.1 // indirect
 	github.com/mailru/easyjson v0.7.7 // indirect
 	github.com/pmezard/go-difflib v1.0.1-0.20181226105442-5d4384ee4fb2 // indirect
+	golang.org/x/mod v0.16.0 // indirect
+	golang.org/x/tools v0.19.0 // indirect
 	gopkg.in/check.v1 v1.0.0-20190902080502-41f04d3bba15 // indirect
 )
 
 require (
 	github.com/PuerkitoBio/goquery v1.9.2
-	github.com/aeden/traceroute v0.0.0-20210211061815-03f5f7cb7908
 	github.com/stretchr/testify v1.9.0
 	golang.org/x/net v0.26.0
 	golang.org/x/sys v0.21.0 // indirect
-	gopkg.in/yaml.v3 v3.0.1 // indirect
+	gopkg.in/yaml.v3 v3.0.1
 )
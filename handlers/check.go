@@ -0,0 +1,150 @@
+package handlers
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/url"
+	"slices"
+	"time"
+
+	"github.com/xray-web/web-check-api/checks"
+)
+
+// defaultCheckTimeout is the deadline of checks mounted on their own with
+// the per-check handlers, matching the default scan deadline.
+const defaultCheckTimeout = 15 * time.Second
+
+// checkTimeout returns the deadline of c, or timeout if it doesn't have
+// its own.
+func checkTimeout(c checks.Check, timeout time.Duration) time.Duration {
+	if tc, ok := c.(checks.TimeoutCheck); ok {
+		return tc.Timeout()
+	}
+	return timeout
+}
+
+// requestTarget reads the url query parameter of r for checks, writing a
+// 400 response and returning false if it is missing or any of the checks
+// need a hostname and it doesn't have one.
+func requestTarget(w http.ResponseWriter, r *http.Request, list ...checks.Check) (*url.URL, bool) {
+	rawURL, err := extractURL(r)
+	if err != nil {
+		JSONError(w, ErrMissingURLParameter, http.StatusBadRequest)
+		return nil, false
+	}
+	needsHost := slices.ContainsFunc(list, func(c checks.Check) bool {
+		return c.Input() == checks.InputHostname
+	})
+	if needsHost && rawURL.Hostname() == "" {
+		JSONError(w, ErrInvalidURL, http.StatusBadRequest)
+		return nil, false
+	}
+	return rawURL, true
+}
+
+// HandleCheck runs c against the url query parameter, with the check's own
+// deadline or timeout. The remaining query parameters are passed to the
+// check as options. If c is a checks.StreamingCheck its partial results are
+// streamed when the stream parameter is given.
+func HandleCheck(c checks.Check, timeout time.Duration) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		rawURL, ok := requestTarget(w, r, c)
+		if !ok {
+			return
+		}
+
+		ctx, cancel := context.WithTimeout(r.Context(), checkTimeout(c, timeout))
+		defer cancel()
+		r = r.WithContext(ctx)
+
+		target := checks.Target{URL: rawURL, Params: r.URL.Query()}
+		if streaming, ok := c.(checks.StreamingCheck); ok && r.URL.Query().Has("stream") {
+			streamResults(w, r, streaming, target)
+			return
+		}
+		result, err := c.Run(r.Context(), target)
+		if err != nil {
+			checkError(w, err)
+			return
+		}
+
+		JSON(w, result, http.StatusOK)
+	})
+}
+
+func checkError(w http.ResponseWriter, err error) {
+	var upstream *checks.UpstreamError
+	if errors.As(err, &upstream) {
+		JSON(w, upstream.Body, upstream.StatusCode)
+		return
+	}
+	if errors.Is(err, checks.ErrInvalidParams) {
+		JSONError(w, err, http.StatusBadRequest)
+		return
+	}
+	JSONError(w, err, http.StatusInternalServerError)
+}
+
+// streamResults runs c writing a progress event for each partial result and
+// a final result or error event. An error before anything is streamed is
+// returned as JSON like HandleCheck does.
+func streamResults(w http.ResponseWriter, r *http.Request, c checks.StreamingCheck, target checks.Target) {
+	flusher, ok := w.(http.Flusher)
+	if !ok {
+		JSONError(w, errors.New("streaming unsupported"), http.StatusInternalServerError)
+		return
+	}
+	started := false
+	start := func() {
+		if started {
+			return
+		}
+		started = true
+		w.Header().Set("Content-Type", "text/event-stream")
+		w.Header().Set("Cache-Control", "no-cache")
+		w.Header().Set("Connection", "keep-alive")
+		w.Header().Set("X-Accel-Buffering", "no")
+		w.WriteHeader(http.StatusOK)
+	}
+
+	result, err := c.RunStream(r.Context(), target, func(v any) {
+		start()
+		writeEvent(w, "progress", v)
+		flusher.Flush()
+	})
+	if err != nil && !started {
+		checkError(w, err)
+		return
+	}
+	start()
+	if err != nil {
+		writeEvent(w, "error", ResponseError{Error: err.Error()})
+	} else {
+		writeEvent(w, "result", result)
+	}
+	flusher.Flush()
+}
+
+// HandleListChecks describes every check in the registry.
+func HandleListChecks(reg *checks.Registry) http.Handler {
+	type Response struct {
+		Name        string       `json:"name"`
+		Description string       `json:"description"`
+		Input       checks.Input `json:"input"`
+		Path        string       `json:"path"`
+	}
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		list := reg.Checks()
+		response := make([]Response, 0, len(list))
+		for _, c := range list {
+			response = append(response, Response{
+				Name:        c.Name(),
+				Description: c.Description(),
+				Input:       c.Input(),
+				Path:        "/api/" + c.Name(),
+			})
+		}
+		JSON(w, response, http.StatusOK)
+	})
+}
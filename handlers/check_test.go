@@ -0,0 +1,94 @@
+package handlers
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/xray-web/web-check-api/checks"
+)
+
+func TestHandleCheck(t *testing.T) {
+	t.Parallel()
+
+	echo := checks.NewCheck("echo", "Echoes the hostname", checks.InputHostname, func(ctx context.Context, target checks.Target) (any, error) {
+		return KV{"host": target.Hostname()}, nil
+	})
+	failing := checks.NewCheck("failing", "Always fails", checks.InputURL, func(ctx context.Context, target checks.Target) (any, error) {
+		return nil, errors.New("boom")
+	})
+
+	t.Run("missing URL parameter", func(t *testing.T) {
+		t.Parallel()
+		rec := httptest.NewRecorder()
+		HandleCheck(echo, time.Second).ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/echo", nil))
+
+		assert.Equal(t, http.StatusBadRequest, rec.Code)
+		assert.JSONEq(t, `{"error": "missing URL parameter"}`, rec.Body.String())
+	})
+
+	t.Run("result", func(t *testing.T) {
+		t.Parallel()
+		rec := httptest.NewRecorder()
+		HandleCheck(echo, time.Second).ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/echo?url=example.com", nil))
+
+		assert.Equal(t, http.StatusOK, rec.Code)
+		assert.JSONEq(t, `{"host": "example.com"}`, rec.Body.String())
+	})
+
+	t.Run("params", func(t *testing.T) {
+		t.Parallel()
+		params := checks.NewCheck("params", "Echoes the type parameter", checks.InputHostname, func(ctx context.Context, target checks.Target) (any, error) {
+			return KV{"type": target.Params.Get("type")}, nil
+		})
+		rec := httptest.NewRecorder()
+		HandleCheck(params, time.Second).ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/params?url=example.com&type=MX", nil))
+
+		assert.Equal(t, http.StatusOK, rec.Code)
+		assert.JSONEq(t, `{"type": "MX"}`, rec.Body.String())
+	})
+
+	t.Run("error", func(t *testing.T) {
+		t.Parallel()
+		rec := httptest.NewRecorder()
+		HandleCheck(failing, time.Second).ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/failing?url=example.com", nil))
+
+		assert.Equal(t, http.StatusInternalServerError, rec.Code)
+		assert.JSONEq(t, `{"error": "boom"}`, rec.Body.String())
+	})
+
+	t.Run("deadline", func(t *testing.T) {
+		t.Parallel()
+		slow := checks.NewCheck("slow", "Waits for the deadline", checks.InputURL, func(ctx context.Context, target checks.Target) (any, error) {
+			<-ctx.Done()
+			return nil, ctx.Err()
+		})
+		for _, c := range []checks.Check{slow, checks.WithTimeout(slow, 10*time.Millisecond)} {
+			timeout := time.Hour
+			if _, ok := c.(checks.TimeoutCheck); !ok {
+				timeout = 10 * time.Millisecond
+			}
+			rec := httptest.NewRecorder()
+			HandleCheck(c, timeout).ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/slow?url=example.com", nil))
+
+			assert.Equal(t, http.StatusInternalServerError, rec.Code)
+			assert.JSONEq(t, `{"error": "context deadline exceeded"}`, rec.Body.String())
+		}
+	})
+
+	t.Run("list checks", func(t *testing.T) {
+		t.Parallel()
+		reg := checks.NewRegistry()
+		assert.NoError(t, reg.Register(echo))
+
+		rec := httptest.NewRecorder()
+		HandleListChecks(reg).ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/checks", nil))
+
+		assert.Equal(t, http.StatusOK, rec.Code)
+		assert.JSONEq(t, `[{"name": "echo", "description": "Echoes the hostname", "input": "hostname", "path": "/api/echo"}]`, rec.Body.String())
+	})
+}
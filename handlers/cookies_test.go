@@ -16,7 +16,7 @@ func TestHandlerCookies(t *testing.T) {
 		req := httptest.NewRequest(http.MethodGet, "/cookies", nil)
 		rec := httptest.NewRecorder()
 
-		HandleCookies().ServeHTTP(rec, req)
+		HandleCookies(nil).ServeHTTP(rec, req)
 
 		assert.Equal(t, http.StatusBadRequest, rec.Code)
 		assert.JSONEq(t, `{"error": "missing URL parameter"}`, rec.Body.String())
@@ -0,0 +1,25 @@
+package handlers
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/xray-web/web-check-api/checks"
+)
+
+func TestHandleDnsPropagation(t *testing.T) {
+	t.Parallel()
+
+	t.Run("unsupported record type", func(t *testing.T) {
+		t.Parallel()
+		rec := httptest.NewRecorder()
+		req := httptest.NewRequest(http.MethodGet, "/dns-propagation?url=example.com&type=FOO", nil)
+		HandleCheck(checks.NewDnsPropagation(checks.DefaultCatalogue(), nil, nil), time.Second).ServeHTTP(rec, req)
+
+		assert.Equal(t, http.StatusBadRequest, rec.Code)
+		assert.JSONEq(t, `{"error": "invalid parameters: unsupported record type: FOO"}`, rec.Body.String())
+	})
+}
@@ -1,11 +1,17 @@
 package handlers
 
 import (
+	"context"
+	"errors"
+	"net"
 	"net/http"
 	"net/http/httptest"
 	"testing"
 
+	"github.com/miekg/dns"
 	"github.com/stretchr/testify/assert"
+	"github.com/xray-web/web-check-api/checks"
+	"github.com/xray-web/web-check-api/checks/clients/ip"
 )
 
 func TestHandleDNSServer(t *testing.T) {
@@ -26,10 +32,37 @@ func TestHandleDNSServer(t *testing.T) {
 			name:         "Valid URL",
 			url:          "https://example.com",
 			expectedCode: http.StatusOK,
-			expectedBody: `{"domain":"example.com","dns":[{"address":"93.184.215.14","hostname":null,"dohDirectSupports":false}]}`,
+			expectedBody: `{"domain":"example.com","dns":[{
+				"address":"93.184.215.14",
+				"hostname":null,
+				"role":"host",
+				"recursive":false,
+				"dohDirectSupports":false,
+				"dns":{"supported":false,"latencyMs":0,"error":"connection refused"},
+				"doh":{"supported":false,"latencyMs":0,"error":"Post \"https://example.com/dns-query\": connection refused"},
+				"dot":{"supported":false,"latencyMs":0,"error":"connection refused"},
+				"doq":{"supported":false,"latencyMs":0,"error":"connection refused"}
+			}]}`,
 		},
 	}
 
+	exchanger := ip.ExchangeFunc(func(ctx context.Context, msg *dns.Msg) (*dns.Msg, error) {
+		resp := new(dns.Msg)
+		resp.SetReply(msg)
+		q := msg.Question[0]
+		switch {
+		case q.Name != "example.com.":
+			resp.Rcode = dns.RcodeNameError
+		case q.Qtype == dns.TypeA:
+			rr, _ := dns.NewRR("example.com. 300 IN A 93.184.215.14")
+			resp.Answer = append(resp.Answer, rr)
+		}
+		return resp, nil
+	})
+	dialer := checks.DialFunc(func(ctx context.Context, network, address string) (net.Conn, error) {
+		return nil, errors.New("connection refused")
+	})
+
 	for _, tc := range testCases {
 		tc := tc
 		t.Run(tc.name, func(t *testing.T) {
@@ -37,7 +70,7 @@ func TestHandleDNSServer(t *testing.T) {
 			req := httptest.NewRequest("GET", "/dns?url="+tc.url, nil)
 			rec := httptest.NewRecorder()
 
-			HandleDNSServer().ServeHTTP(rec, req)
+			HandleDNSServer(checks.NewDnsServer(exchanger, dialer, nil)).ServeHTTP(rec, req)
 
 			assert.Equal(t, tc.expectedCode, rec.Code)
 			assert.JSONEq(t, tc.expectedBody, rec.Body.String())
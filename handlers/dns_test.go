@@ -1,13 +1,45 @@
 package handlers
 
 import (
+	"context"
 	"net/http"
 	"net/http/httptest"
 	"testing"
 
+	"github.com/miekg/dns"
 	"github.com/stretchr/testify/assert"
+	"github.com/xray-web/web-check-api/checks"
+	"github.com/xray-web/web-check-api/checks/clients/ip"
 )
 
+// exampleZone answers every query for example.com with a record of that
+// type from a fixed set, other names don't exist.
+var exampleZone = ip.ExchangeFunc(func(ctx context.Context, msg *dns.Msg) (*dns.Msg, error) {
+	resp := new(dns.Msg)
+	resp.SetReply(msg)
+	q := msg.Question[0]
+	if q.Name != "example.com." {
+		resp.Rcode = dns.RcodeNameError
+		return resp, nil
+	}
+	for _, record := range []string{
+		"example.com. 300 IN A 93.184.216.34",
+		"example.com. 300 IN AAAA 2606:2800:220:1::",
+		"example.com. 3600 IN MX 0 .",
+		`example.com. 300 IN TXT "v=spf1 -all"`,
+		"example.com. 86400 IN NS a.iana-servers.net.",
+	} {
+		rr, err := dns.NewRR(record)
+		if err != nil {
+			return nil, err
+		}
+		if rr.Header().Rrtype == q.Qtype {
+			resp.Answer = append(resp.Answer, rr)
+		}
+	}
+	return resp, nil
+})
+
 func TestHandleDNS(t *testing.T) {
 	t.Parallel()
 	testCases := []struct {
@@ -34,7 +66,7 @@ func TestHandleDNS(t *testing.T) {
 			req := httptest.NewRequest("GET", "/dns?url="+tc.url, nil)
 			rec := httptest.NewRecorder()
 
-			HandleDNS().ServeHTTP(rec, req)
+			HandleDNS(checks.NewDns(exampleZone)).ServeHTTP(rec, req)
 
 			assert.Equal(t, tc.expectedCode, rec.Code)
 		})
@@ -6,6 +6,7 @@ import (
 	"testing"
 
 	"github.com/stretchr/testify/assert"
+	"github.com/xray-web/web-check-api/checks"
 )
 
 func TestHandleDnsSec(t *testing.T) {
@@ -34,7 +35,7 @@ func TestHandleDnsSec(t *testing.T) {
 			req := httptest.NewRequest("GET", "/dnssec?url="+tc.url, nil)
 			rec := httptest.NewRecorder()
 
-			HandleDnsSec().ServeHTTP(rec, req)
+			HandleDnsSec(checks.NewDnsSec(exampleZone, nil)).ServeHTTP(rec, req)
 
 			assert.Equal(t, tc.expectedCode, rec.Code)
 		})
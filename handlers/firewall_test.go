@@ -6,24 +6,33 @@ import (
 	"testing"
 
 	"github.com/stretchr/testify/assert"
+	"github.com/xray-web/web-check-api/checks"
+	"github.com/xray-web/web-check-api/testutils"
 )
 
 func TestHandleFirewall(t *testing.T) {
 	t.Parallel()
+	cloudflare := testutils.Response(http.StatusOK, nil)
+	cloudflare.Header = http.Header{"Server": {"cloudflare"}}
 	testCases := []struct {
 		name         string
 		url          string
+		response     *http.Response
 		expectedCode int
+		expectedBody string
 	}{
 		{
 			name:         "Missing URL",
 			url:          "",
 			expectedCode: http.StatusBadRequest,
+			expectedBody: `{"error": "missing URL parameter"}`,
 		},
 		{
 			name:         "Valid URL",
 			url:          "example.com",
+			response:     cloudflare,
 			expectedCode: http.StatusOK,
+			expectedBody: `{"hasWaf": true, "waf": "Cloudflare"}`,
 		},
 	}
 
@@ -34,9 +43,10 @@ func TestHandleFirewall(t *testing.T) {
 			req := httptest.NewRequest("GET", "/firewall?url="+tc.url, nil)
 			rec := httptest.NewRecorder()
 
-			HandleFirewall().ServeHTTP(rec, req)
+			HandleFirewall(checks.NewFirewall(testutils.MockClient(tc.response))).ServeHTTP(rec, req)
 
 			assert.Equal(t, tc.expectedCode, rec.Code)
+			assert.JSONEq(t, tc.expectedBody, rec.Body.String())
 		})
 	}
 }
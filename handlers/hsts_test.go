@@ -7,17 +7,19 @@ import (
 	"testing"
 
 	"github.com/stretchr/testify/assert"
+	"github.com/xray-web/web-check-api/checks"
+	"github.com/xray-web/web-check-api/testutils"
 )
 
 func TestHandleHsts(t *testing.T) {
 	t.Parallel()
 	req := httptest.NewRequest("GET", "/check-hsts?url=example.com", nil)
 	rec := httptest.NewRecorder()
-	HandleHsts().ServeHTTP(rec, req)
+	HandleHsts(checks.NewHsts(testutils.MockClient(testutils.Response(http.StatusOK, nil)))).ServeHTTP(rec, req)
 
 	assert.Equal(t, http.StatusOK, rec.Code)
 
-	var response HSTSResponse
+	var response checks.HSTSResponse
 	err := json.Unmarshal(rec.Body.Bytes(), &response)
 	assert.NoError(t, err)
 
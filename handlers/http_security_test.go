@@ -7,17 +7,24 @@ import (
 	"testing"
 
 	"github.com/stretchr/testify/assert"
+	"github.com/xray-web/web-check-api/checks"
+	"github.com/xray-web/web-check-api/testutils"
 )
 
 func TestHandleHttpSecurity(t *testing.T) {
 	t.Parallel()
 	req := httptest.NewRequest("GET", "/check-http-security?url=www.google.com", nil)
 	rec := httptest.NewRecorder()
-	HandleHttpSecurity().ServeHTTP(rec, req)
+	resp := testutils.Response(http.StatusOK, nil)
+	resp.Header = http.Header{
+		"X-Frame-Options":  {"SAMEORIGIN"},
+		"X-Xss-Protection": {"0"},
+	}
+	HandleHttpSecurity(checks.NewHttpSecurity(testutils.MockClient(resp))).ServeHTTP(rec, req)
 
 	assert.Equal(t, http.StatusOK, rec.Code)
 
-	var response HTTPSecurityResponse
+	var response checks.HTTPSecurityResponse
 	err := json.Unmarshal(rec.Body.Bytes(), &response)
 	assert.NoError(t, err)
 
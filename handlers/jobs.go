@@ -0,0 +1,84 @@
+package handlers
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/url"
+	"time"
+
+	"github.com/xray-web/web-check-api/checks"
+	"github.com/xray-web/web-check-api/jobs"
+)
+
+// scanTask runs a full scan of u as a background job.
+func scanTask(list []checks.Check, u *url.URL, timeout time.Duration) jobs.Task {
+	return func(ctx context.Context, report func(done, total int)) (any, error) {
+		start := time.Now()
+		response := ScanResponse{
+			URL:    u.String(),
+			Checks: make(map[string]CheckResult, len(list)),
+		}
+		report(0, len(list))
+		for res := range runScan(ctx, list, u, timeout) {
+			response.Checks[res.Name] = res
+			report(len(response.Checks), len(list))
+		}
+		response.Duration = time.Since(start).Milliseconds()
+		return response, ctx.Err()
+	}
+}
+
+func jobError(w http.ResponseWriter, err error) {
+	switch {
+	case errors.Is(err, jobs.ErrNotFound):
+		JSONError(w, err, http.StatusNotFound)
+	case errors.Is(err, jobs.ErrQueueFull), errors.Is(err, jobs.ErrClosed):
+		JSONError(w, err, http.StatusServiceUnavailable)
+	default:
+		JSONError(w, err, http.StatusInternalServerError)
+	}
+}
+
+func HandleCreateJob(q *jobs.Queue, reg *checks.Registry, timeout time.Duration) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		rawURL, err := extractURL(r)
+		if err != nil {
+			JSONError(w, ErrMissingURLParameter, http.StatusBadRequest)
+			return
+		}
+
+		job, err := q.Submit(scanTask(reg.Checks(), rawURL, timeout))
+		if err != nil {
+			jobError(w, err)
+			return
+		}
+
+		w.Header().Set("Location", "/api/jobs/"+job.ID)
+		JSON(w, job, http.StatusAccepted)
+	})
+}
+
+func HandleGetJob(q *jobs.Queue) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		job, err := q.Get(r.PathValue("id"))
+		if err != nil {
+			jobError(w, err)
+			return
+		}
+
+		JSON(w, job, http.StatusOK)
+	})
+}
+
+func HandleCancelJob(q *jobs.Queue) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		job, err := q.Cancel(r.PathValue("id"))
+		if err != nil {
+			jobError(w, err)
+			return
+		}
+
+		JSON(w, job, http.StatusOK)
+	})
+}
@@ -0,0 +1,97 @@
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/xray-web/web-check-api/checks"
+	"github.com/xray-web/web-check-api/jobs"
+)
+
+func TestScanTask(t *testing.T) {
+	t.Parallel()
+
+	u, _ := url.Parse("http://example.com")
+	list := []checks.Check{
+		checks.NewCheck("a", "", checks.InputURL, func(ctx context.Context, target checks.Target) (any, error) { return 1, nil }),
+		checks.NewCheck("b", "", checks.InputURL, func(ctx context.Context, target checks.Target) (any, error) { return 2, nil }),
+	}
+
+	var progress []int
+	result, err := scanTask(list, u, time.Second)(context.Background(), func(done, total int) {
+		assert.Equal(t, 2, total)
+		progress = append(progress, done)
+	})
+	require.NoError(t, err)
+	assert.Equal(t, []int{0, 1, 2}, progress)
+
+	response := result.(ScanResponse)
+	assert.Equal(t, "http://example.com", response.URL)
+	assert.Equal(t, 1, response.Checks["a"].Result)
+	assert.Equal(t, 2, response.Checks["b"].Result)
+}
+
+func TestHandleJobs(t *testing.T) {
+	t.Parallel()
+
+	t.Run("create job missing URL parameter", func(t *testing.T) {
+		t.Parallel()
+		q := jobs.NewQueue(1, 1, time.Minute)
+		defer q.Close()
+
+		req := httptest.NewRequest(http.MethodPost, "/api/jobs", nil)
+		rec := httptest.NewRecorder()
+		HandleCreateJob(q, nil, time.Second).ServeHTTP(rec, req)
+
+		assert.Equal(t, http.StatusBadRequest, rec.Code)
+		assert.JSONEq(t, `{"error": "missing URL parameter"}`, rec.Body.String())
+	})
+
+	t.Run("get and cancel job", func(t *testing.T) {
+		t.Parallel()
+		q := jobs.NewQueue(1, 1, time.Minute)
+		defer q.Close()
+
+		job, err := q.Submit(func(ctx context.Context, report func(done, total int)) (any, error) {
+			<-ctx.Done()
+			return nil, ctx.Err()
+		})
+		require.NoError(t, err)
+
+		mux := http.NewServeMux()
+		mux.Handle("GET /api/jobs/{id}", HandleGetJob(q))
+		mux.Handle("DELETE /api/jobs/{id}", HandleCancelJob(q))
+
+		rec := httptest.NewRecorder()
+		mux.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/api/jobs/"+job.ID, nil))
+		assert.Equal(t, http.StatusOK, rec.Code)
+		var got jobs.Job
+		require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &got))
+		assert.Equal(t, job.ID, got.ID)
+
+		rec = httptest.NewRecorder()
+		mux.ServeHTTP(rec, httptest.NewRequest(http.MethodDelete, "/api/jobs/"+job.ID, nil))
+		assert.Equal(t, http.StatusOK, rec.Code)
+	})
+
+	t.Run("unknown job", func(t *testing.T) {
+		t.Parallel()
+		q := jobs.NewQueue(1, 1, time.Minute)
+		defer q.Close()
+
+		mux := http.NewServeMux()
+		mux.Handle("GET /api/jobs/{id}", HandleGetJob(q))
+
+		rec := httptest.NewRecorder()
+		mux.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/api/jobs/missing", nil))
+		assert.Equal(t, http.StatusNotFound, rec.Code)
+		assert.JSONEq(t, `{"error": "job not found"}`, rec.Body.String())
+	})
+}
@@ -0,0 +1,31 @@
+package handlers
+
+import (
+	"net/http"
+	"path/filepath"
+	"time"
+
+	"github.com/xray-web/web-check-api/checks"
+)
+
+// HandleMeta describes the catalogue the checks are currently using.
+func HandleMeta(store *checks.CatalogueStore) http.Handler {
+	type Response struct {
+		// Source is the name of the catalogue file, without the directory
+		// so the server's layout isn't exposed, or "built-in".
+		Source    string            `json:"source"`
+		LoadedAt  time.Time         `json:"loadedAt"`
+		Catalogue *checks.Catalogue `json:"catalogue"`
+	}
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		source := "built-in"
+		if path := store.Path(); path != "" {
+			source = filepath.Base(path)
+		}
+		JSON(w, Response{
+			Source:    source,
+			LoadedAt:  store.LoadedAt(),
+			Catalogue: store.Catalogue(),
+		}, http.StatusOK)
+	})
+}
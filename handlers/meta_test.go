@@ -0,0 +1,49 @@
+package handlers
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/xray-web/web-check-api/checks"
+)
+
+func TestHandleMeta(t *testing.T) {
+	t.Parallel()
+
+	store, err := checks.NewCatalogueStore("")
+	require.NoError(t, err)
+
+	rec := httptest.NewRecorder()
+	HandleMeta(store).ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/api/meta", nil))
+	assert.Equal(t, http.StatusOK, rec.Code)
+
+	var body struct {
+		Source    string           `json:"source"`
+		Catalogue checks.Catalogue `json:"catalogue"`
+	}
+	require.NoError(t, json.NewDecoder(rec.Body).Decode(&body))
+	assert.Equal(t, "built-in", body.Source)
+	assert.Equal(t, *checks.DefaultCatalogue(), body.Catalogue)
+
+	t.Run("file", func(t *testing.T) {
+		t.Parallel()
+		path := filepath.Join(t.TempDir(), "catalogue.json")
+		require.NoError(t, os.WriteFile(path, []byte(`{"ports": [22, 443]}`), 0o644))
+		store, err := checks.NewCatalogueStore(path)
+		require.NoError(t, err)
+
+		rec := httptest.NewRecorder()
+		HandleMeta(store).ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/api/meta", nil))
+		var body struct {
+			Source string `json:"source"`
+		}
+		require.NoError(t, json.NewDecoder(rec.Body).Decode(&body))
+		assert.Equal(t, "catalogue.json", body.Source)
+	})
+}
@@ -2,11 +2,14 @@ package handlers
 
 import (
 	"encoding/json"
+	"net"
 	"net/http"
 	"net/http/httptest"
 	"testing"
 
 	"github.com/stretchr/testify/assert"
+	"github.com/xray-web/web-check-api/checks"
+	"github.com/xray-web/web-check-api/checks/clients/ip"
 )
 
 func TestHandleGetPorts(t *testing.T) {
@@ -16,6 +19,10 @@ func TestHandleGetPorts(t *testing.T) {
 		expectedCode int
 		expectedBody map[string][]int
 	}{
+		{
+			url:          "open.com&ports=1-70000",
+			expectedCode: http.StatusBadRequest,
+		},
 		{
 			url:          "open.com",
 			expectedCode: http.StatusOK,
@@ -40,23 +47,23 @@ func TestHandleGetPorts(t *testing.T) {
 			t.Parallel()
 			req := httptest.NewRequest("GET", "/check-ports?url="+tc.url, nil)
 			rec := httptest.NewRecorder()
-			HandleGetPorts().ServeHTTP(rec, req)
+			HandleGetPorts(checks.NewPorts(checks.DefaultCatalogue(), &ip.NetLookup{}, &net.Dialer{}, &net.ListenConfig{})).ServeHTTP(rec, req)
 
 			assert.Equal(t, tc.expectedCode, rec.Code)
 
 			if tc.expectedCode == http.StatusOK {
-				var response map[string][]int
+				var response struct {
+					Ports       []checks.PortResult `json:"ports"`
+					OpenPorts   []int               `json:"openPorts"`
+					FailedPorts []int               `json:"failedPorts"`
+				}
 				err := json.Unmarshal(rec.Body.Bytes(), &response)
 				assert.NoError(t, err)
 
-				openPorts, ok1 := response["openPorts"]
-				failedPorts, ok2 := response["failedPorts"]
-
-				assert.True(t, ok1)
-				assert.True(t, ok2)
-				assert.NotNil(t, openPorts)
-				assert.NotNil(t, failedPorts)
-			} else {
+				assert.NotNil(t, response.OpenPorts)
+				assert.NotNil(t, response.FailedPorts)
+				assert.Len(t, response.Ports, len(checks.DefaultCatalogue().Ports))
+			} else if tc.url == "" {
 				var response map[string]string
 				err := json.Unmarshal(rec.Body.Bytes(), &response)
 				assert.NoError(t, err)
@@ -4,14 +4,14 @@ import (
 	"encoding/json"
 	"net/http"
 	"net/http/httptest"
-	"os"
 	"testing"
 
 	"github.com/stretchr/testify/assert"
+	"github.com/xray-web/web-check-api/checks"
+	"github.com/xray-web/web-check-api/testutils"
 )
 
 func TestHandleGetQuality(t *testing.T) {
-	t.Skip("broken due to api key handling inside handler")
 	t.Parallel()
 	tests := []struct {
 		name         string
@@ -47,15 +47,13 @@ func TestHandleGetQuality(t *testing.T) {
 		tc := tc
 		t.Run(tc.name, func(t *testing.T) {
 			t.Parallel()
-			if tc.apiKey != "" {
-				os.Setenv("GOOGLE_CLOUD_API_KEY", tc.apiKey)
-			} else {
-				os.Unsetenv("GOOGLE_CLOUD_API_KEY")
-			}
+			client := testutils.MockClient(testutils.ResponseJSON(http.StatusBadRequest, map[string]any{
+				"error": "Failed to fetch the Pagespeed data",
+			}))
 
 			req := httptest.NewRequest("GET", "/check-quality?url="+tc.url, nil)
 			w := httptest.NewRecorder()
-			HandleGetQuality().ServeHTTP(w, req)
+			HandleGetQuality(checks.NewQuality(client, tc.apiKey)).ServeHTTP(w, req)
 
 			assert.Equal(t, tc.expectedCode, w.Code)
 
@@ -7,19 +7,5 @@ import (
 )
 
 func HandleGetRank(ra *checks.Rank) http.Handler {
-	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
-		rawURL, err := extractURL(r)
-		if err != nil {
-			JSONError(w, ErrMissingURLParameter, http.StatusBadRequest)
-			return
-		}
-
-		result, err := ra.GetRank(r.Context(), rawURL.Hostname())
-		if err != nil {
-			JSONError(w, err, http.StatusInternalServerError)
-			return
-		}
-
-		JSON(w, result, http.StatusOK)
-	})
+	return HandleCheck(ra, defaultCheckTimeout)
 }
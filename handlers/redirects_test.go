@@ -7,6 +7,8 @@ import (
 	"testing"
 
 	"github.com/stretchr/testify/assert"
+	"github.com/xray-web/web-check-api/checks"
+	"github.com/xray-web/web-check-api/testutils"
 )
 
 func TestHandleGetRedirects(t *testing.T) {
@@ -17,7 +19,7 @@ func TestHandleGetRedirects(t *testing.T) {
 		req := httptest.NewRequest("GET", "/redirects?url=", nil)
 		rec := httptest.NewRecorder()
 
-		HandleGetRedirects().ServeHTTP(rec, req)
+		HandleGetRedirects(checks.NewRedirects(testutils.MockClient())).ServeHTTP(rec, req)
 
 		assert.Equal(t, http.StatusBadRequest, rec.Code)
 		var response KV
@@ -31,7 +33,8 @@ func TestHandleGetRedirects(t *testing.T) {
 		req := httptest.NewRequest("GET", "/redirects?url=invalid-url", nil)
 		rec := httptest.NewRecorder()
 
-		HandleGetRedirects().ServeHTTP(rec, req)
+		// the host can't be reached
+		HandleGetRedirects(checks.NewRedirects(testutils.MockClient())).ServeHTTP(rec, req)
 
 		assert.Equal(t, http.StatusInternalServerError, rec.Code)
 		var response KV
@@ -45,7 +48,7 @@ func TestHandleGetRedirects(t *testing.T) {
 		req := httptest.NewRequest("GET", "/redirects?url=example.com", nil)
 		rec := httptest.NewRecorder()
 
-		HandleGetRedirects().ServeHTTP(rec, req)
+		HandleGetRedirects(checks.NewRedirects(testutils.MockClient(testutils.Response(http.StatusOK, nil)))).ServeHTTP(rec, req)
 
 		assert.Equal(t, http.StatusOK, rec.Code)
 		var response KV
@@ -53,4 +56,20 @@ func TestHandleGetRedirects(t *testing.T) {
 		assert.NoError(t, err)
 		assert.Equal(t, KV{"redirects": []interface{}{"http://example.com"}}, response)
 	})
+
+	t.Run("Valid URL with redirects", func(t *testing.T) {
+		t.Parallel()
+		req := httptest.NewRequest("GET", "/redirects?url=example.com", nil)
+		rec := httptest.NewRecorder()
+		redirect := testutils.Response(http.StatusMovedPermanently, nil)
+		redirect.Header = http.Header{"Location": {"https://www.example.com/"}}
+
+		HandleGetRedirects(checks.NewRedirects(testutils.MockClient(redirect, testutils.Response(http.StatusOK, nil)))).ServeHTTP(rec, req)
+
+		assert.Equal(t, http.StatusOK, rec.Code)
+		var response KV
+		err := json.Unmarshal(rec.Body.Bytes(), &response)
+		assert.NoError(t, err)
+		assert.Equal(t, KV{"redirects": []interface{}{"http://example.com", "https://www.example.com/"}}, response)
+	})
 }
@@ -0,0 +1,109 @@
+package handlers
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/url"
+	"sync"
+	"time"
+
+	"github.com/xray-web/web-check-api/checks"
+)
+
+// CheckResult is the outcome of a single check within a scan.
+type CheckResult struct {
+	Name     string `json:"-"`
+	Result   any    `json:"result,omitempty"`
+	Error    string `json:"error,omitempty"`
+	Duration int64  `json:"durationMs"`
+}
+
+// ScanResponse holds the results of every check keyed by check name.
+type ScanResponse struct {
+	URL      string                 `json:"url"`
+	Duration int64                  `json:"durationMs"`
+	Checks   map[string]CheckResult `json:"checks"`
+}
+
+// runCheck runs a single check with its own deadline. Checks that do not
+// honour context cancellation are abandoned once the deadline passes so a
+// slow check can never hold up the rest of the scan.
+func runCheck(ctx context.Context, c checks.Check, target checks.Target, timeout time.Duration) CheckResult {
+	ctx, cancel := context.WithTimeout(ctx, checkTimeout(c, timeout))
+	defer cancel()
+
+	type outcome struct {
+		result any
+		err    error
+	}
+	done := make(chan outcome, 1)
+	start := time.Now()
+	go func() {
+		defer func() {
+			if r := recover(); r != nil {
+				done <- outcome{err: fmt.Errorf("check panicked: %v", r)}
+			}
+		}()
+		result, err := c.Run(ctx, target)
+		done <- outcome{result: result, err: err}
+	}()
+
+	res := CheckResult{Name: c.Name()}
+	select {
+	case o := <-done:
+		res.Result = o.result
+		if o.err != nil {
+			res.Result = nil
+			res.Error = o.err.Error()
+		}
+	case <-ctx.Done():
+		res.Error = fmt.Sprintf("check did not complete: %v", ctx.Err())
+	}
+	res.Duration = time.Since(start).Milliseconds()
+	return res
+}
+
+// runScan runs every check concurrently and sends each result on the
+// returned channel as soon as it is available. The channel is closed once
+// all checks have finished.
+func runScan(ctx context.Context, list []checks.Check, u *url.URL, timeout time.Duration) <-chan CheckResult {
+	target := checks.Target{URL: u}
+	results := make(chan CheckResult, len(list))
+	var wg sync.WaitGroup
+	for _, c := range list {
+		wg.Add(1)
+		go func(c checks.Check) {
+			defer wg.Done()
+			results <- runCheck(ctx, c, target, timeout)
+		}(c)
+	}
+	go func() {
+		wg.Wait()
+		close(results)
+	}()
+	return results
+}
+
+func HandleScan(reg *checks.Registry, timeout time.Duration) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		list := reg.Checks()
+		// a target every check can't use is rejected before any of them run
+		rawURL, ok := requestTarget(w, r, list...)
+		if !ok {
+			return
+		}
+
+		start := time.Now()
+		response := ScanResponse{
+			URL:    rawURL.String(),
+			Checks: make(map[string]CheckResult, len(list)),
+		}
+		for res := range runScan(r.Context(), list, rawURL, timeout) {
+			response.Checks[res.Name] = res
+		}
+		response.Duration = time.Since(start).Milliseconds()
+
+		JSON(w, response, http.StatusOK)
+	})
+}
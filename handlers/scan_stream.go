@@ -0,0 +1,120 @@
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"time"
+
+	"github.com/xray-web/web-check-api/checks"
+)
+
+// heartbeatInterval is how often a comment is written to an idle stream to
+// stop proxies from closing the connection while slow checks run.
+const heartbeatInterval = 15 * time.Second
+
+type streamStart struct {
+	URL    string   `json:"url"`
+	Checks []string `json:"checks"`
+}
+
+type streamCheck struct {
+	Name string `json:"name"`
+	CheckResult
+}
+
+type streamComplete struct {
+	URL      string `json:"url"`
+	Duration int64  `json:"durationMs"`
+	Total    int    `json:"total"`
+	Failed   int    `json:"failed"`
+}
+
+func writeEvent(w io.Writer, event string, v any) error {
+	b, err := json.Marshal(v)
+	if err != nil {
+		return err
+	}
+	_, err = fmt.Fprintf(w, "event: %s\ndata: %s\n\n", event, b)
+	return err
+}
+
+func HandleScanStream(reg *checks.Registry, timeout time.Duration) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		flusher, ok := w.(http.Flusher)
+		if !ok {
+			JSONError(w, errors.New("streaming unsupported"), http.StatusInternalServerError)
+			return
+		}
+
+		w.Header().Set("Content-Type", "text/event-stream")
+		w.Header().Set("Cache-Control", "no-cache")
+		w.Header().Set("Connection", "keep-alive")
+		w.Header().Set("X-Accel-Buffering", "no")
+
+		rawURL, err := extractURL(r)
+		if err != nil {
+			w.WriteHeader(http.StatusBadRequest)
+			writeEvent(w, "error", ResponseError{Error: ErrMissingURLParameter.Error()})
+			return
+		}
+
+		w.WriteHeader(http.StatusOK)
+		streamScan(r.Context(), w, flusher, reg.Checks(), rawURL, timeout)
+	})
+}
+
+// streamScan writes a start event, one check event per check as it
+// finishes and a final complete event. It stops early if the client goes
+// away.
+func streamScan(ctx context.Context, w io.Writer, flusher http.Flusher, list []checks.Check, u *url.URL, timeout time.Duration) {
+	start := time.Now()
+	names := make([]string, 0, len(list))
+	for _, c := range list {
+		names = append(names, c.Name())
+	}
+
+	if err := writeEvent(w, "start", streamStart{URL: u.String(), Checks: names}); err != nil {
+		return
+	}
+	flusher.Flush()
+
+	heartbeat := time.NewTicker(heartbeatInterval)
+	defer heartbeat.Stop()
+
+	failed := 0
+	results := runScan(ctx, list, u, timeout)
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-heartbeat.C:
+			if _, err := io.WriteString(w, ": ping\n\n"); err != nil {
+				return
+			}
+			flusher.Flush()
+		case res, ok := <-results:
+			if !ok {
+				writeEvent(w, "complete", streamComplete{
+					URL:      u.String(),
+					Duration: time.Since(start).Milliseconds(),
+					Total:    len(list),
+					Failed:   failed,
+				})
+				flusher.Flush()
+				return
+			}
+			if res.Error != "" {
+				failed++
+			}
+			if err := writeEvent(w, "check", streamCheck{Name: res.Name, CheckResult: res}); err != nil {
+				return
+			}
+			flusher.Flush()
+		}
+	}
+}
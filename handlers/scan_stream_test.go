@@ -0,0 +1,73 @@
+package handlers
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/xray-web/web-check-api/checks"
+)
+
+func TestHandleScanStream(t *testing.T) {
+	t.Parallel()
+
+	t.Run("missing URL parameter", func(t *testing.T) {
+		t.Parallel()
+		req := httptest.NewRequest(http.MethodGet, "/scan/stream", nil)
+		rec := httptest.NewRecorder()
+
+		HandleScanStream(nil, time.Second).ServeHTTP(rec, req)
+
+		assert.Equal(t, http.StatusBadRequest, rec.Code)
+		assert.Equal(t, "text/event-stream", rec.Header().Get("Content-Type"))
+		assert.Equal(t, "event: error\ndata: {\"error\":\"missing URL parameter\"}\n\n", rec.Body.String())
+	})
+
+	t.Run("stream events", func(t *testing.T) {
+		t.Parallel()
+		u, _ := url.Parse("http://example.com")
+		list := []checks.Check{
+			checks.NewCheck("ok", "", checks.InputURL, func(ctx context.Context, target checks.Target) (any, error) {
+				return "fine", nil
+			}),
+			checks.NewCheck("failed", "", checks.InputURL, func(ctx context.Context, target checks.Target) (any, error) {
+				return nil, errors.New("boom")
+			}),
+		}
+		rec := httptest.NewRecorder()
+
+		streamScan(context.Background(), rec, rec, list, u, time.Second)
+
+		body := rec.Body.String()
+		assert.True(t, strings.HasPrefix(body, "event: start\ndata: {\"url\":\"http://example.com\",\"checks\":[\"ok\",\"failed\"]}\n\n"))
+		assert.Contains(t, body, "event: check\ndata: {\"name\":\"ok\",\"result\":\"fine\",")
+		assert.Contains(t, body, "event: check\ndata: {\"name\":\"failed\",\"error\":\"boom\",")
+		assert.Contains(t, body, "event: complete\ndata: {\"url\":\"http://example.com\",")
+		assert.Contains(t, body, "\"total\":2,\"failed\":1}")
+		assert.Equal(t, 4, strings.Count(body, "event: "))
+	})
+
+	t.Run("client disconnect", func(t *testing.T) {
+		t.Parallel()
+		u, _ := url.Parse("http://example.com")
+		list := []checks.Check{
+			checks.NewCheck("slow", "", checks.InputURL, func(ctx context.Context, target checks.Target) (any, error) {
+				<-ctx.Done()
+				return nil, ctx.Err()
+			}),
+		}
+		ctx, cancel := context.WithCancel(context.Background())
+		cancel()
+		rec := httptest.NewRecorder()
+
+		streamScan(ctx, rec, rec, list, u, time.Minute)
+
+		assert.NotContains(t, rec.Body.String(), "event: complete")
+	})
+}
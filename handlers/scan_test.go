@@ -0,0 +1,82 @@
+package handlers
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/xray-web/web-check-api/checks"
+)
+
+func TestRunScan(t *testing.T) {
+	t.Parallel()
+
+	u, _ := url.Parse("http://example.com")
+	list := []checks.Check{
+		checks.NewCheck("ok", "", checks.InputURL, func(ctx context.Context, target checks.Target) (any, error) {
+			return target.Hostname(), nil
+		}),
+		checks.NewCheck("failed", "", checks.InputURL, func(ctx context.Context, target checks.Target) (any, error) {
+			return nil, errors.New("boom")
+		}),
+		checks.NewCheck("slow", "", checks.InputURL, func(ctx context.Context, target checks.Target) (any, error) {
+			time.Sleep(time.Second)
+			return "too late", nil
+		}),
+		checks.NewCheck("panics", "", checks.InputURL, func(ctx context.Context, target checks.Target) (any, error) {
+			panic("oops")
+		}),
+	}
+
+	results := make(map[string]CheckResult)
+	for res := range runScan(context.Background(), list, u, 50*time.Millisecond) {
+		results[res.Name] = res
+	}
+
+	assert.Len(t, results, 4)
+	assert.Equal(t, "example.com", results["ok"].Result)
+	assert.Empty(t, results["ok"].Error)
+	assert.Equal(t, "boom", results["failed"].Error)
+	assert.Nil(t, results["failed"].Result)
+	assert.Contains(t, results["slow"].Error, "deadline exceeded")
+	assert.Nil(t, results["slow"].Result)
+	assert.Contains(t, results["panics"].Error, "oops")
+}
+
+func TestHandleScan(t *testing.T) {
+	t.Parallel()
+
+	t.Run("missing URL parameter", func(t *testing.T) {
+		t.Parallel()
+		req := httptest.NewRequest(http.MethodGet, "/scan", nil)
+		rec := httptest.NewRecorder()
+
+		HandleScan(checks.NewRegistry(), time.Second).ServeHTTP(rec, req)
+
+		assert.Equal(t, http.StatusBadRequest, rec.Code)
+		assert.JSONEq(t, `{"error": "missing URL parameter"}`, rec.Body.String())
+	})
+
+	t.Run("missing hostname", func(t *testing.T) {
+		t.Parallel()
+		ran := make(chan string, 1)
+		reg := checks.NewRegistry()
+		reg.Register(checks.NewCheck("host", "", checks.InputHostname, func(ctx context.Context, target checks.Target) (any, error) {
+			ran <- target.Hostname()
+			return nil, nil
+		}))
+		req := httptest.NewRequest(http.MethodGet, "/scan?url="+url.QueryEscape("http:///path"), nil)
+		rec := httptest.NewRecorder()
+
+		HandleScan(reg, time.Second).ServeHTTP(rec, req)
+
+		assert.Equal(t, http.StatusBadRequest, rec.Code)
+		assert.JSONEq(t, `{"error": "invalid URL"}`, rec.Body.String())
+		assert.Empty(t, ran)
+	})
+}
@@ -7,17 +7,5 @@ import (
 )
 
 func HandleGetSocialTags(s *checks.SocialTags) http.Handler {
-	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
-		rawURL, err := extractURL(r)
-		if err != nil {
-			JSONError(w, ErrMissingURLParameter, http.StatusBadRequest)
-			return
-		}
-		tags, err := s.GetSocialTags(r.Context(), rawURL.String())
-		if err != nil {
-			JSONError(w, err, http.StatusInternalServerError)
-			return
-		}
-		JSON(w, tags, http.StatusOK)
-	})
+	return HandleCheck(s, defaultCheckTimeout)
 }
@@ -1,14 +1,16 @@
 package handlers
 
 import (
+	"context"
 	"encoding/json"
+	"errors"
+	"net"
 	"net/http"
 	"net/http/httptest"
 	"testing"
 
 	"github.com/stretchr/testify/assert"
 	"github.com/xray-web/web-check-api/checks"
-	"github.com/xray-web/web-check-api/testutils"
 )
 
 func TestHandleTLS(t *testing.T) {
@@ -20,7 +22,7 @@ func TestHandleTLS(t *testing.T) {
 		req := httptest.NewRequest("GET", "/tls?url=", nil)
 		rec := httptest.NewRecorder()
 
-		HandleTLS(checks.NewTls(nil)).ServeHTTP(rec, req)
+		HandleTLS(checks.NewTls(nil, nil, nil)).ServeHTTP(rec, req)
 
 		assert.Equal(t, http.StatusBadRequest, rec.Code)
 		var responseBody map[string]interface{}
@@ -32,19 +34,19 @@ func TestHandleTLS(t *testing.T) {
 	t.Run("Invalid URL", func(t *testing.T) {
 		t.Parallel()
 
-		client := testutils.MockClient(
-			testutils.Response(http.StatusOK, []byte(`{"scan_id": 0}`)),
-		)
+		dialer := checks.DialFunc(func(ctx context.Context, network, address string) (net.Conn, error) {
+			return nil, errors.New("no such host")
+		})
 		req := httptest.NewRequest("GET", "/tls?url=http://invalid-url", nil)
 		rec := httptest.NewRecorder()
 
-		HandleTLS(checks.NewTls(client)).ServeHTTP(rec, req)
+		HandleTLS(checks.NewTls(dialer, nil, nil)).ServeHTTP(rec, req)
 
 		assert.Equal(t, http.StatusInternalServerError, rec.Code)
 		var responseBody map[string]interface{}
 		err := json.Unmarshal(rec.Body.Bytes(), &responseBody)
 		assert.NoError(t, err)
-		assert.Equal(t, map[string]interface{}{"error": "failed to get scan_id from TLS Observatory"}, responseBody)
+		assert.Equal(t, map[string]interface{}{"error": "error connecting to invalid-url:443: no such host"}, responseBody)
 	})
 
 }
@@ -1,14 +1,42 @@
 package handlers
 
 import (
+	"context"
 	"encoding/json"
+	"net"
 	"net/http"
 	"net/http/httptest"
+	"net/netip"
+	"strings"
 	"testing"
+	"time"
 
 	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/xray-web/web-check-api/checks"
+	"github.com/xray-web/web-check-api/checks/clients/ip"
+	"github.com/xray-web/web-check-api/checks/clients/traceroute"
 )
 
+// threeHops traces a route to 192.0.2.1 through 10.0.0.1 and 10.0.0.2.
+func threeHops(t *testing.T) *checks.TraceRoute {
+	netIp := checks.NewNetIp(ip.LookupFunc(func(ctx context.Context, network, host string) ([]net.IP, error) {
+		if network == "ip6" {
+			return nil, nil
+		}
+		return []net.IP{net.ParseIP("192.0.2.1")}, nil
+	}), nil, nil)
+	return checks.NewTraceRoute(netIp, func(protocol string, port int) (traceroute.Prober, error) {
+		assert.Equal(t, traceroute.UDP, protocol)
+		return traceroute.ProberFunc(func(ctx context.Context, dst netip.Addr, ttl int) (traceroute.Reply, error) {
+			if ttl == 3 {
+				return traceroute.Reply{From: dst, RTT: 3 * time.Millisecond, Reached: true}, nil
+			}
+			return traceroute.Reply{From: netip.AddrFrom4([4]byte{10, 0, 0, byte(ttl)}), RTT: time.Millisecond}, nil
+		}), nil
+	})
+}
+
 func TestHandleTraceRoute(t *testing.T) {
 	t.Parallel()
 	tests := []struct {
@@ -23,6 +51,12 @@ func TestHandleTraceRoute(t *testing.T) {
 			expectedStatus: http.StatusBadRequest,
 			expectedBody:   KV{"error": "missing URL parameter"},
 		},
+		{
+			name:           "Invalid protocol",
+			urlParam:       "example.com&protocol=sctp",
+			expectedStatus: http.StatusBadRequest,
+			expectedBody:   KV{"error": `invalid parameters: trace route: unknown protocol "sctp"`},
+		},
 	}
 
 	for _, tc := range tests {
@@ -31,7 +65,7 @@ func TestHandleTraceRoute(t *testing.T) {
 			t.Parallel()
 			req := httptest.NewRequest("GET", "/trace-route?url="+tc.urlParam, nil)
 			rec := httptest.NewRecorder()
-			HandleTraceRoute().ServeHTTP(rec, req)
+			HandleTraceRoute(threeHops(t)).ServeHTTP(rec, req)
 
 			assert.Equal(t, tc.expectedStatus, rec.Code)
 
@@ -41,4 +75,47 @@ func TestHandleTraceRoute(t *testing.T) {
 			assert.Equal(t, tc.expectedBody, responseBody)
 		})
 	}
+
+	t.Run("hops", func(t *testing.T) {
+		t.Parallel()
+		req := httptest.NewRequest("GET", "/trace-route?url=example.com", nil)
+		rec := httptest.NewRecorder()
+		HandleTraceRoute(threeHops(t)).ServeHTTP(rec, req)
+		require.Equal(t, http.StatusOK, rec.Code)
+
+		var result checks.TraceRouteResult
+		require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &result))
+		assert.True(t, result.Reached)
+		require.Len(t, result.Hops, 3)
+		assert.Equal(t, "10.0.0.2", result.Hops[1].Address.String())
+	})
+
+	t.Run("stream", func(t *testing.T) {
+		t.Parallel()
+		req := httptest.NewRequest("GET", "/trace-route?url=example.com&stream", nil)
+		rec := httptest.NewRecorder()
+		HandleTraceRoute(threeHops(t)).ServeHTTP(rec, req)
+		require.Equal(t, http.StatusOK, rec.Code)
+		assert.Equal(t, "text/event-stream", rec.Header().Get("Content-Type"))
+
+		events := strings.Split(strings.TrimSpace(rec.Body.String()), "\n\n")
+		require.Len(t, events, 4)
+		for i, event := range events[:3] {
+			name, data, _ := strings.Cut(event, "\n")
+			assert.Equal(t, "event: progress", name)
+			var hop checks.Hop
+			require.NoError(t, json.Unmarshal([]byte(strings.TrimPrefix(data, "data: ")), &hop))
+			assert.Equal(t, i+1, hop.TTL)
+		}
+		assert.True(t, strings.HasPrefix(events[3], "event: result\n"))
+	})
+
+	t.Run("stream invalid", func(t *testing.T) {
+		t.Parallel()
+		req := httptest.NewRequest("GET", "/trace-route?url=example.com&stream&max-hops=100", nil)
+		rec := httptest.NewRecorder()
+		HandleTraceRoute(threeHops(t)).ServeHTTP(rec, req)
+		assert.Equal(t, http.StatusBadRequest, rec.Code)
+		assert.Contains(t, rec.Body.String(), "max-hops")
+	})
 }
@@ -0,0 +1,281 @@
+package jobs
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+)
+
+var (
+	ErrNotFound  = errors.New("job not found")
+	ErrQueueFull = errors.New("job queue is full")
+	ErrClosed    = errors.New("job queue is closed")
+)
+
+type Status string
+
+const (
+	StatusQueued    Status = "queued"
+	StatusRunning   Status = "running"
+	StatusCompleted Status = "completed"
+	StatusFailed    Status = "failed"
+	StatusCancelled Status = "cancelled"
+)
+
+type Progress struct {
+	Done  int `json:"done"`
+	Total int `json:"total"`
+}
+
+// Task is the work performed by a job. It should return promptly once ctx
+// is cancelled and may call report to publish how far through it is.
+type Task func(ctx context.Context, report func(done, total int)) (any, error)
+
+// Job is a point in time snapshot of a submitted task.
+type Job struct {
+	ID         string     `json:"id"`
+	Status     Status     `json:"status"`
+	Progress   Progress   `json:"progress"`
+	Result     any        `json:"result,omitempty"`
+	Error      string     `json:"error,omitempty"`
+	CreatedAt  time.Time  `json:"createdAt"`
+	StartedAt  *time.Time `json:"startedAt,omitempty"`
+	FinishedAt *time.Time `json:"finishedAt,omitempty"`
+	ExpiresAt  *time.Time `json:"expiresAt,omitempty"`
+}
+
+type job struct {
+	mu     sync.Mutex
+	state  Job
+	task   Task
+	ctx    context.Context
+	cancel context.CancelFunc
+}
+
+func (j *job) snapshot() Job {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+	return j.state
+}
+
+// Queue runs submitted tasks on a fixed number of workers and keeps the
+// finished jobs around until their TTL expires.
+type Queue struct {
+	ttl     time.Duration
+	pending chan *job
+
+	mu     sync.Mutex
+	jobs   map[string]*job
+	closed bool
+
+	ctx    context.Context
+	cancel context.CancelFunc
+	wg     sync.WaitGroup
+}
+
+// NewQueue starts a queue with the given number of workers that accepts up
+// to size pending jobs. Finished jobs are discarded once ttl has elapsed.
+func NewQueue(workers, size int, ttl time.Duration) *Queue {
+	ctx, cancel := context.WithCancel(context.Background())
+	q := &Queue{
+		ttl:     ttl,
+		pending: make(chan *job, size),
+		jobs:    make(map[string]*job),
+		ctx:     ctx,
+		cancel:  cancel,
+	}
+	for range max(workers, 1) {
+		q.wg.Add(1)
+		go q.worker()
+	}
+	q.wg.Add(1)
+	go q.janitor()
+	return q
+}
+
+// Submit enqueues task and returns the newly created job.
+func (q *Queue) Submit(task Task) (Job, error) {
+	id, err := newID()
+	if err != nil {
+		return Job{}, err
+	}
+	ctx, cancel := context.WithCancel(q.ctx)
+	j := &job{
+		state: Job{
+			ID:        id,
+			Status:    StatusQueued,
+			CreatedAt: time.Now(),
+		},
+		task:   task,
+		ctx:    ctx,
+		cancel: cancel,
+	}
+
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	if q.closed {
+		cancel()
+		return Job{}, ErrClosed
+	}
+	select {
+	case q.pending <- j:
+	default:
+		cancel()
+		return Job{}, ErrQueueFull
+	}
+	q.jobs[id] = j
+	return j.snapshot(), nil
+}
+
+// Get returns the current state of the job with the given id.
+func (q *Queue) Get(id string) (Job, error) {
+	q.mu.Lock()
+	j, ok := q.jobs[id]
+	q.mu.Unlock()
+	if !ok {
+		return Job{}, ErrNotFound
+	}
+	return j.snapshot(), nil
+}
+
+// Cancel stops a queued or running job. Cancelling a job that has already
+// finished is a no-op.
+func (q *Queue) Cancel(id string) (Job, error) {
+	q.mu.Lock()
+	j, ok := q.jobs[id]
+	q.mu.Unlock()
+	if !ok {
+		return Job{}, ErrNotFound
+	}
+
+	j.mu.Lock()
+	if j.state.Status == StatusQueued {
+		q.finish(j, StatusCancelled, nil, context.Canceled)
+	}
+	j.mu.Unlock()
+	j.cancel()
+	return j.snapshot(), nil
+}
+
+// Close cancels all outstanding jobs and waits for the workers to exit.
+func (q *Queue) Close() {
+	q.mu.Lock()
+	if q.closed {
+		q.mu.Unlock()
+		return
+	}
+	q.closed = true
+	close(q.pending)
+	q.mu.Unlock()
+
+	q.cancel()
+	q.wg.Wait()
+}
+
+func (q *Queue) worker() {
+	defer q.wg.Done()
+	for j := range q.pending {
+		q.run(j)
+	}
+}
+
+func (q *Queue) run(j *job) {
+	j.mu.Lock()
+	if j.state.Status != StatusQueued {
+		j.mu.Unlock()
+		return
+	}
+	if err := j.ctx.Err(); err != nil {
+		q.finish(j, StatusCancelled, nil, err)
+		j.mu.Unlock()
+		return
+	}
+	now := time.Now()
+	j.state.Status = StatusRunning
+	j.state.StartedAt = &now
+	j.mu.Unlock()
+
+	report := func(done, total int) {
+		j.mu.Lock()
+		defer j.mu.Unlock()
+		j.state.Progress = Progress{Done: done, Total: total}
+	}
+
+	result, err := q.safeRun(j, report)
+
+	j.mu.Lock()
+	defer j.mu.Unlock()
+	switch {
+	case j.ctx.Err() != nil:
+		q.finish(j, StatusCancelled, result, j.ctx.Err())
+	case err != nil:
+		q.finish(j, StatusFailed, result, err)
+	default:
+		q.finish(j, StatusCompleted, result, nil)
+	}
+	j.cancel()
+}
+
+func (q *Queue) safeRun(j *job, report func(done, total int)) (result any, err error) {
+	defer func() {
+		if r := recover(); r != nil {
+			err = fmt.Errorf("job panicked: %v", r)
+		}
+	}()
+	return j.task(j.ctx, report)
+}
+
+// finish records the final state of j, the caller must hold j.mu.
+func (q *Queue) finish(j *job, status Status, result any, err error) {
+	now := time.Now()
+	expires := now.Add(q.ttl)
+	j.state.Status = status
+	j.state.Result = result
+	j.state.FinishedAt = &now
+	j.state.ExpiresAt = &expires
+	if err != nil {
+		j.state.Error = err.Error()
+	}
+}
+
+// janitor periodically removes finished jobs whose TTL has expired.
+func (q *Queue) janitor() {
+	defer q.wg.Done()
+	interval := time.Minute
+	if q.ttl > 0 && q.ttl < interval {
+		interval = q.ttl
+	}
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-q.ctx.Done():
+			return
+		case now := <-ticker.C:
+			q.expire(now)
+		}
+	}
+}
+
+func (q *Queue) expire(now time.Time) {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	for id, j := range q.jobs {
+		state := j.snapshot()
+		if state.ExpiresAt != nil && now.After(*state.ExpiresAt) {
+			delete(q.jobs, id)
+		}
+	}
+}
+
+func newID() (string, error) {
+	b := make([]byte, 16)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(b), nil
+}
@@ -0,0 +1,141 @@
+package jobs
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func waitFor(t *testing.T, q *Queue, id string, status Status) Job {
+	t.Helper()
+	var job Job
+	require.Eventually(t, func() bool {
+		var err error
+		job, err = q.Get(id)
+		return err == nil && job.Status == status
+	}, 2*time.Second, 5*time.Millisecond)
+	return job
+}
+
+func TestQueue(t *testing.T) {
+	t.Parallel()
+
+	t.Run("completed job", func(t *testing.T) {
+		t.Parallel()
+		q := NewQueue(1, 1, time.Minute)
+		defer q.Close()
+
+		job, err := q.Submit(func(ctx context.Context, report func(done, total int)) (any, error) {
+			report(1, 1)
+			return "done", nil
+		})
+		require.NoError(t, err)
+		assert.Equal(t, StatusQueued, job.Status)
+
+		job = waitFor(t, q, job.ID, StatusCompleted)
+		assert.Equal(t, "done", job.Result)
+		assert.Equal(t, Progress{Done: 1, Total: 1}, job.Progress)
+		assert.NotNil(t, job.StartedAt)
+		assert.NotNil(t, job.FinishedAt)
+		assert.NotNil(t, job.ExpiresAt)
+	})
+
+	t.Run("failed job", func(t *testing.T) {
+		t.Parallel()
+		q := NewQueue(1, 1, time.Minute)
+		defer q.Close()
+
+		job, err := q.Submit(func(ctx context.Context, report func(done, total int)) (any, error) {
+			return nil, errors.New("boom")
+		})
+		require.NoError(t, err)
+
+		job = waitFor(t, q, job.ID, StatusFailed)
+		assert.Equal(t, "boom", job.Error)
+	})
+
+	t.Run("cancel running job", func(t *testing.T) {
+		t.Parallel()
+		q := NewQueue(1, 1, time.Minute)
+		defer q.Close()
+
+		started := make(chan struct{})
+		job, err := q.Submit(func(ctx context.Context, report func(done, total int)) (any, error) {
+			close(started)
+			<-ctx.Done()
+			return nil, ctx.Err()
+		})
+		require.NoError(t, err)
+		<-started
+
+		_, err = q.Cancel(job.ID)
+		require.NoError(t, err)
+		job = waitFor(t, q, job.ID, StatusCancelled)
+		assert.Equal(t, context.Canceled.Error(), job.Error)
+	})
+
+	t.Run("queue full", func(t *testing.T) {
+		t.Parallel()
+		q := NewQueue(1, 1, time.Minute)
+		defer q.Close()
+
+		block := func(ctx context.Context, report func(done, total int)) (any, error) {
+			<-ctx.Done()
+			return nil, ctx.Err()
+		}
+		running, err := q.Submit(block)
+		require.NoError(t, err)
+		waitFor(t, q, running.ID, StatusRunning)
+
+		queued, err := q.Submit(block)
+		require.NoError(t, err)
+		_, err = q.Submit(block)
+		assert.ErrorIs(t, err, ErrQueueFull)
+
+		job, err := q.Cancel(queued.ID)
+		require.NoError(t, err)
+		assert.Equal(t, StatusCancelled, job.Status)
+	})
+
+	t.Run("unknown job", func(t *testing.T) {
+		t.Parallel()
+		q := NewQueue(1, 1, time.Minute)
+		defer q.Close()
+
+		_, err := q.Get("missing")
+		assert.ErrorIs(t, err, ErrNotFound)
+		_, err = q.Cancel("missing")
+		assert.ErrorIs(t, err, ErrNotFound)
+	})
+
+	t.Run("expired job", func(t *testing.T) {
+		t.Parallel()
+		q := NewQueue(1, 1, 10*time.Millisecond)
+		defer q.Close()
+
+		job, err := q.Submit(func(ctx context.Context, report func(done, total int)) (any, error) {
+			return nil, nil
+		})
+		require.NoError(t, err)
+
+		assert.Eventually(t, func() bool {
+			_, err := q.Get(job.ID)
+			return errors.Is(err, ErrNotFound)
+		}, 2*time.Second, 5*time.Millisecond)
+	})
+
+	t.Run("submit after close", func(t *testing.T) {
+		t.Parallel()
+		q := NewQueue(1, 1, time.Minute)
+		q.Close()
+
+		_, err := q.Submit(func(ctx context.Context, report func(done, total int)) (any, error) {
+			return nil, nil
+		})
+		assert.ErrorIs(t, err, ErrClosed)
+	})
+}
@@ -14,11 +14,26 @@ import (
 )
 
 func main() {
-	srv := server.New(config.New())
+	srv, err := server.New(config.New())
+	if err != nil {
+		log.Fatalf("Server setup failed: %v", err)
+	}
 
 	done := make(chan os.Signal, 1)
 	signal.Notify(done, os.Interrupt, syscall.SIGINT, syscall.SIGTERM)
 
+	reload := make(chan os.Signal, 1)
+	signal.Notify(reload, syscall.SIGHUP)
+	go func() {
+		for range reload {
+			if err := srv.ReloadCatalogue(); err != nil {
+				log.Printf("Catalogue reload failed, keeping the current catalogue: %v", err)
+				continue
+			}
+			log.Println("Catalogue reloaded")
+		}
+	}()
+
 	go func() {
 		if err := srv.Run(); err != nil && err != http.ErrServerClosed {
 			log.Fatalf("listen: %v\n", err)
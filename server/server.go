@@ -5,26 +5,67 @@ import (
 	"fmt"
 	"log"
 	"net/http"
+	"slices"
+	"time"
 
 	"github.com/xray-web/web-check-api/checks"
 	"github.com/xray-web/web-check-api/config"
 	"github.com/xray-web/web-check-api/handlers"
+	"github.com/xray-web/web-check-api/jobs"
 )
 
+// reservedNames are /api routes that are not checks.
+var reservedNames = []string{"checks", "jobs", "meta", "scan"}
+
 type Server struct {
-	conf   config.Config
-	mux    *http.ServeMux
-	checks *checks.Checks
-	srv    *http.Server
+	conf      config.Config
+	mux       *http.ServeMux
+	registry  *checks.Registry
+	catalogue *checks.CatalogueStore
+	jobs      *jobs.Queue
+	srv       *http.Server
 }
 
-func New(conf config.Config) *Server {
+// New creates a server, returning an error if the catalogue file in conf
+// can't be loaded.
+func New(conf config.Config) (*Server, error) {
+	catalogue, err := checks.NewCatalogueStore(conf.CatalogueFile)
+	if err != nil {
+		return nil, err
+	}
+
+	registry := checks.NewRegistry()
+	// built in check names are fixed so registering them can only fail if
+	// two of them clash, which is a programming error
+	if err := registry.Register(checks.NewChecks(conf, catalogue).All()...); err != nil {
+		panic(err)
+	}
 	return &Server{
-		srv:    &http.Server{},
-		conf:   conf,
-		mux:    http.NewServeMux(),
-		checks: checks.NewChecks(),
+		srv:       &http.Server{ReadHeaderTimeout: 10 * time.Second},
+		conf:      conf,
+		mux:       http.NewServeMux(),
+		registry:  registry,
+		catalogue: catalogue,
+		jobs:      jobs.NewQueue(conf.JobWorkers, conf.JobQueueSize, conf.JobTTL),
+	}, nil
+}
+
+// ReloadCatalogue reads the catalogue file again, the checks pick up the
+// new lists the next time they run. The current catalogue is kept if the
+// file is invalid.
+func (s *Server) ReloadCatalogue() error {
+	return s.catalogue.Reload()
+}
+
+// Register adds checks to the server. Each check is mounted at
+// /api/{name} and included in scans. It must be called before Run.
+func (s *Server) Register(c ...checks.Check) error {
+	for _, check := range c {
+		if slices.Contains(reservedNames, check.Name()) {
+			return fmt.Errorf("check name %q is reserved", check.Name())
+		}
 	}
+	return s.registry.Register(c...)
 }
 
 func (s *Server) routes() {
@@ -32,26 +73,17 @@ func (s *Server) routes() {
 
 	s.mux.Handle("GET /health", HealthCheck())
 
-	s.mux.Handle("GET /api/block-lists", handlers.HandleBlockLists(s.checks.BlockList))
-	s.mux.Handle("GET /api/carbon", handlers.HandleCarbon(s.checks.Carbon))
-	s.mux.Handle("GET /api/cookies", handlers.HandleCookies())
-	s.mux.Handle("GET /api/dns-server", handlers.HandleDNSServer())
-	s.mux.Handle("GET /api/dns", handlers.HandleDNS())
-	s.mux.Handle("GET /api/dnssec", handlers.HandleDnsSec())
-	s.mux.Handle("GET /api/firewall", handlers.HandleFirewall())
-	s.mux.Handle("GET /api/get-ip", handlers.HandleGetIP(s.checks.IpAddress))
-	s.mux.Handle("GET /api/headers", handlers.HandleGetHeaders(s.checks.Headers))
-	s.mux.Handle("GET /api/hsts", handlers.HandleHsts())
-	s.mux.Handle("GET /api/http-security", handlers.HandleHttpSecurity())
-	s.mux.Handle("GET /api/legacy-rank", handlers.HandleLegacyRank(s.checks.LegacyRank))
-	s.mux.Handle("GET /api/linked-pages", handlers.HandleGetLinks(s.checks.LinkedPages))
-	s.mux.Handle("GET /api/ports", handlers.HandleGetPorts())
-	s.mux.Handle("GET /api/quality", handlers.HandleGetQuality())
-	s.mux.Handle("GET /api/rank", handlers.HandleGetRank(s.checks.Rank))
-	s.mux.Handle("GET /api/redirects", handlers.HandleGetRedirects())
-	s.mux.Handle("GET /api/social-tags", handlers.HandleGetSocialTags(s.checks.SocialTags))
-	s.mux.Handle("GET /api/tls", handlers.HandleTLS(s.checks.Tls))
-	s.mux.Handle("GET /api/trace-route", handlers.HandleTraceRoute())
+	for _, c := range s.registry.Checks() {
+		s.mux.Handle("GET /api/"+c.Name(), handlers.HandleCheck(c, s.conf.CheckTimeout))
+	}
+
+	s.mux.Handle("GET /api/checks", handlers.HandleListChecks(s.registry))
+	s.mux.Handle("GET /api/meta", handlers.HandleMeta(s.catalogue))
+	s.mux.Handle("POST /api/jobs", handlers.HandleCreateJob(s.jobs, s.registry, s.conf.CheckTimeout))
+	s.mux.Handle("GET /api/jobs/{id}", handlers.HandleGetJob(s.jobs))
+	s.mux.Handle("DELETE /api/jobs/{id}", handlers.HandleCancelJob(s.jobs))
+	s.mux.Handle("GET /api/scan", handlers.HandleScan(s.registry, s.conf.CheckTimeout))
+	s.mux.Handle("GET /api/scan/stream", handlers.HandleScanStream(s.registry, s.conf.CheckTimeout))
 
 	s.srv.Handler = s.CORS(s.mux)
 }
@@ -66,5 +98,6 @@ func (s *Server) Run() error {
 }
 
 func (s *Server) Shutdown(ctx context.Context) error {
+	defer s.jobs.Close()
 	return s.srv.Shutdown(ctx)
 }
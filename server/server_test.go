@@ -1,12 +1,15 @@
 package server
 
 import (
+	"encoding/json"
 	"net/http"
 	"net/http/httptest"
 	"testing"
 	"time"
 
 	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/xray-web/web-check-api/checks"
 	"github.com/xray-web/web-check-api/config"
 	"golang.org/x/net/context"
 )
@@ -17,7 +20,8 @@ func TestServer(t *testing.T) {
 	t.Run("start server", func(t *testing.T) {
 		t.Parallel()
 
-		srv := New(config.New())
+		srv, err := New(config.New())
+		require.NoError(t, err)
 		srv.routes()
 		ts := httptest.NewServer(srv.CORS(srv.mux))
 		defer ts.Close()
@@ -35,7 +39,34 @@ func TestServer(t *testing.T) {
 		}
 		ctx, cancel = context.WithTimeout(context.Background(), 5*time.Second)
 		defer cancel()
-		err := srv.Shutdown(ctx)
+		err = srv.Shutdown(ctx)
 		assert.NoError(t, err)
 	})
 }
+
+func TestServerRegister(t *testing.T) {
+	t.Parallel()
+
+	srv, err := New(config.New())
+	require.NoError(t, err)
+	defer srv.Shutdown(context.Background())
+
+	custom := checks.NewCheck("custom", "An internal check", checks.InputHostname, func(ctx context.Context, target checks.Target) (any, error) {
+		return map[string]string{"host": target.Hostname()}, nil
+	})
+	assert.NoError(t, srv.Register(custom))
+	assert.ErrorIs(t, srv.Register(custom), checks.ErrDuplicateCheck)
+	assert.Error(t, srv.Register(checks.NewCheck("scan", "", checks.InputURL, nil)))
+
+	srv.routes()
+	ts := httptest.NewServer(srv.CORS(srv.mux))
+	defer ts.Close()
+
+	resp, err := http.Get(ts.URL + "/api/custom?url=example.com")
+	assert.NoError(t, err)
+	defer resp.Body.Close()
+	assert.Equal(t, http.StatusOK, resp.StatusCode)
+	var body map[string]string
+	assert.NoError(t, json.NewDecoder(resp.Body).Decode(&body))
+	assert.Equal(t, "example.com", body["host"])
+}